@@ -464,6 +464,6 @@ func shouldSkipAndroidMkProcessing(module *ModuleBase) bool {
 
 	return !module.Enabled() ||
 		module.commonProperties.SkipInstall ||
-		// Make does not understand LinuxBionic
-		module.Os() == LinuxBionic
+		// Make does not understand LinuxBionic or LinuxMusl
+		module.Os() == LinuxBionic || module.Os() == LinuxMusl
 }
@@ -28,12 +28,13 @@ import (
 var (
 	archTypeList []ArchType
 
-	Arm    = newArch("arm", "lib32")
-	Arm64  = newArch("arm64", "lib64")
-	Mips   = newArch("mips", "lib32")
-	Mips64 = newArch("mips64", "lib64")
-	X86    = newArch("x86", "lib32")
-	X86_64 = newArch("x86_64", "lib64")
+	Arm     = newArch("arm", "lib32")
+	Arm64   = newArch("arm64", "lib64")
+	Mips    = newArch("mips", "lib32")
+	Mips64  = newArch("mips64", "lib64")
+	Riscv64 = newArch("riscv64", "lib64")
+	X86     = newArch("x86", "lib32")
+	X86_64  = newArch("x86_64", "lib64")
 
 	Common = ArchType{
 		Name: "common",
@@ -41,12 +42,13 @@ var (
 )
 
 var archTypeMap = map[string]ArchType{
-	"arm":    Arm,
-	"arm64":  Arm64,
-	"mips":   Mips,
-	"mips64": Mips64,
-	"x86":    X86,
-	"x86_64": X86_64,
+	"arm":     Arm,
+	"arm64":   Arm64,
+	"mips":    Mips,
+	"mips64":  Mips64,
+	"riscv64": Riscv64,
+	"x86":     X86,
+	"x86_64":  X86_64,
 }
 
 /*
@@ -67,6 +69,9 @@ module {
         mips64: {
             // Host or device variants with mips64 architecture
         },
+        riscv64: {
+            // Host or device variants with riscv64 architecture
+        },
         x86: {
             // Host or device variants with x86 architecture
         },
@@ -92,6 +97,12 @@ module {
         linux_glibc: {
             // Linux host variants
         },
+        linux_musl: {
+            // Linux musl host variants
+        },
+        musl: {
+            // Linux musl host variants of any architecture
+        },
         darwin: {
             // Darwin host variants
         },
@@ -154,6 +165,10 @@ var archVariants = map[ArchType][]string{
 		"mips64r2",
 		"mips64r6",
 	},
+	Riscv64: {
+		"rv64gc",
+		"rv64gcv",
+	},
 	X86: {
 		"amberlake",
 		"atom",
@@ -199,6 +214,9 @@ var archFeatures = map[ArchType][]string{
 		"rev6",
 		"msa",
 	},
+	Riscv64: {
+		"v",
+	},
 	X86: {
 		"ssse3",
 		"sse4",
@@ -249,6 +267,11 @@ var archFeatureMap = map[ArchType]map[string][]string{
 			"rev6",
 		},
 	},
+	Riscv64: {
+		"rv64gcv": {
+			"v",
+		},
+	},
 	X86: {
 		"amberlake": {
 			"ssse3",
@@ -601,6 +624,7 @@ var (
 	Linux       = NewOsType("linux_glibc", Host, false)
 	Darwin      = NewOsType("darwin", Host, false)
 	LinuxBionic = NewOsType("linux_bionic", Host, false)
+	LinuxMusl   = NewOsType("linux_musl", HostCross, false)
 	Windows     = NewOsType("windows", HostCross, true)
 	Android     = NewOsType("android", Device, false)
 	Fuchsia     = NewOsType("fuchsia", Device, false)
@@ -608,9 +632,10 @@ var (
 	osArchTypeMap = map[OsType][]ArchType{
 		Linux:       []ArchType{X86, X86_64},
 		LinuxBionic: []ArchType{X86_64},
+		LinuxMusl:   []ArchType{X86, X86_64},
 		Darwin:      []ArchType{X86_64},
 		Windows:     []ArchType{X86, X86_64},
-		Android:     []ArchType{Arm, Arm64, Mips, Mips64, X86, X86_64},
+		Android:     []ArchType{Arm, Arm64, Mips, Mips64, Riscv64, X86, X86_64},
 		Fuchsia:     []ArchType{Arm64, X86_64},
 	}
 )
@@ -655,7 +680,11 @@ func (os OsType) Bionic() bool {
 }
 
 func (os OsType) Linux() bool {
-	return os == Android || os == Linux || os == LinuxBionic
+	return os == Android || os == Linux || os == LinuxBionic || os == LinuxMusl
+}
+
+func (os OsType) Musl() bool {
+	return os == LinuxMusl
 }
 
 func NewOsType(name string, class OsClass, defDisabled bool) OsType {
@@ -1026,6 +1055,7 @@ func createArchType(props reflect.Type) []reflect.Type {
 			"Android32",
 			"Bionic",
 			"Linux",
+			"Musl",
 			"Not_windows",
 			"Arm_on_x86",
 			"Arm_on_x86_64",
@@ -1048,6 +1078,12 @@ func createArchType(props reflect.Type) []reflect.Type {
 						targets = append(targets, target)
 					}
 				}
+				if os.Musl() {
+					target := "Musl_" + archType.Name
+					if !InList(target, targets) {
+						targets = append(targets, target)
+					}
+				}
 			}
 		}
 
@@ -1275,6 +1311,27 @@ func (a *ModuleBase) setArchProperties(ctx BottomUpMutatorContext) {
 				}
 			}
 
+			// Handle target OS generalities of the form:
+			// target: {
+			//     musl: {
+			//         key: value,
+			//     },
+			//     musl_x86: {
+			//         key: value,
+			//     },
+			// }
+			if os.Musl() {
+				field = "Musl"
+				prefix = "target.musl"
+				a.appendProperties(ctx, genProps, targetProp, field, prefix)
+
+				if arch.ArchType != Common {
+					field = "Musl_" + t.Name
+					prefix = "target.musl_" + t.Name
+					a.appendProperties(ctx, genProps, targetProp, field, prefix)
+				}
+			}
+
 			// Handle target OS properties in the form:
 			// target: {
 			//     linux_glibc: {
@@ -1414,6 +1471,16 @@ func decodeTargetProductVariables(config *config) (map[OsType][]Target, error) {
 		addTarget(LinuxBionic, "x86_64", nil, nil, nil)
 	}
 
+	// musl host targets are built alongside the glibc host targets so that a single build can
+	// produce hermetic, statically linked host tools.
+	if Bool(config.Host_musl) {
+		addTarget(LinuxMusl, *variables.HostArch, nil, nil, nil)
+
+		if variables.HostSecondaryArch != nil && *variables.HostSecondaryArch != "" {
+			addTarget(LinuxMusl, *variables.HostSecondaryArch, nil, nil, nil)
+		}
+	}
+
 	if String(variables.CrossHost) != "" {
 		crossHostOs := osByName(*variables.CrossHost)
 		if crossHostOs == NoOsType {
@@ -1543,6 +1610,8 @@ func getMegaDeviceConfig() []archConfig {
 		// mips64r2 is mismatching 64r2 and 64r6 libraries during linking to libgcc
 		//{"mips64", "mips64r2", "", []string{"mips64"}},
 		{"mips64", "mips64r6", "", []string{"mips64"}},
+		{"riscv64", "rv64gc", "", []string{"riscv64"}},
+		{"riscv64", "rv64gcv", "", []string{"riscv64"}},
 		{"x86", "", "", []string{"x86"}},
 		{"x86", "atom", "", []string{"x86"}},
 		{"x86", "haswell", "", []string{"x86"}},
@@ -230,3 +230,55 @@ func TestFilterArchStruct(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeArch(t *testing.T) {
+	tests := []struct {
+		name        string
+		arch        string
+		archVariant string
+		out         Arch
+	}{
+		{
+			name:        "riscv64",
+			arch:        "riscv64",
+			archVariant: "rv64gc",
+			out: Arch{
+				ArchType:    Riscv64,
+				ArchVariant: "rv64gc",
+				Native:      true,
+			},
+		},
+		{
+			name:        "riscv64 vector",
+			arch:        "riscv64",
+			archVariant: "rv64gcv",
+			out: Arch{
+				ArchType:     Riscv64,
+				ArchVariant:  "rv64gcv",
+				ArchFeatures: []string{"v"},
+				Native:       true,
+			},
+		},
+		{
+			name:        "generic riscv64",
+			arch:        "riscv64",
+			archVariant: "riscv64",
+			out: Arch{
+				ArchType: Riscv64,
+				Native:   true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := decodeArch(Android, test.arch, &test.archVariant, nil, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(out, test.out) {
+				t.Errorf("expected %#v, got %#v", test.out, out)
+			}
+		})
+	}
+}
@@ -42,6 +42,7 @@ const productVariablesFileName = "soong.variables"
 type FileConfigurableOptions struct {
 	Mega_device *bool `json:",omitempty"`
 	Host_bionic *bool `json:",omitempty"`
+	Host_musl   *bool `json:",omitempty"`
 }
 
 func (f *FileConfigurableOptions) SetDefaultConfig() {
@@ -375,6 +376,15 @@ func (c *config) HostToolPath(ctx PathContext, tool string) Path {
 	return PathForOutput(ctx, "host", c.PrebuiltOS(), "bin", tool)
 }
 
+// BuildDateFile returns the file that soong_ui writes the date of the build to, in seconds since
+// the Unix epoch.  It is rewritten by every build, so rules that use it are rerun by every build.
+func (c *config) BuildDateFile() OptionalPath {
+	if file := c.Getenv("BUILD_DATETIME_FILE"); file != "" {
+		return OptionalPathForPath(outsidePath{basePath{file, Config{c}, ""}})
+	}
+	return OptionalPath{}
+}
+
 // HostSystemTool looks for non-hermetic tools from the system we're running on.
 // Generally shouldn't be used, but useful to find the XCode SDK, etc.
 func (c *config) HostSystemTool(name string) string {
@@ -718,6 +728,18 @@ func (c *config) RunErrorProne() bool {
 	return c.IsEnvTrue("RUN_ERROR_PRONE")
 }
 
+// ActionCacheDir returns the directory of the local action cache used by rules that are run with sbox and sandboxed
+// inputs, or an empty string if the action cache is disabled.
+func (c *config) ActionCacheDir() string {
+	return c.Getenv("SOONG_ACTION_CACHE_DIR")
+}
+
+// ActionCacheMaxSize returns the size, in bytes with an optional K, M or G suffix, above which the least recently used
+// entries are evicted from the action cache, or an empty string if the size of the action cache is not limited.
+func (c *config) ActionCacheMaxSize() string {
+	return c.Getenv("SOONG_ACTION_CACHE_MAX_SIZE")
+}
+
 // Returns true if -source 1.9 -target 1.9 is being passed to javac
 func (c *config) TargetOpenJDK9() bool {
 	return c.targetOpenJDK9
@@ -891,7 +913,13 @@ func (c *deviceConfig) SpecificCameraParametersLibrary() string {
 }
 
 func (c *deviceConfig) NativeCoverageEnabled() bool {
-	return Bool(c.config.productVariables.NativeCoverage)
+	return Bool(c.config.productVariables.NativeCoverage) || Bool(c.config.productVariables.ClangCoverage)
+}
+
+// ClangCoverageEnabled returns true if native coverage uses clang source-based coverage instead of
+// gcov.
+func (c *deviceConfig) ClangCoverageEnabled() bool {
+	return Bool(c.config.productVariables.ClangCoverage)
 }
 
 func (c *deviceConfig) CoverageEnabledForPath(path string) bool {
@@ -1061,3 +1089,7 @@ func (c *config) ProductHiddenAPIStubsTest() []string {
 func (c *deviceConfig) TargetFSConfigGen() []string {
 	return c.config.productVariables.TargetFSConfigGen
 }
+
+func (c *config) NeverallowRulesFiles() []string {
+	return c.productVariables.NeverallowRulesFiles
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"sort"
+
+	"github.com/google/blueprint"
+)
+
+func init() {
+	RegisterModuleType("license", LicenseFactory)
+}
+
+type licensesDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+type licenseKindsDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+var (
+	licensesTag     licensesDependencyTag
+	licenseKindsTag licenseKindsDependencyTag
+)
+
+type licenseProperties struct {
+	// Specifies the kinds of license that apply, by the names of license_kind modules.
+	License_kinds []string
+
+	// Specifies a short copyright notice to use for the license.
+	Copyright_notice *string
+
+	// Specifies the paths or labels of the files containing the text of the license.
+	License_text []string `android:"path"`
+
+	// Specifies the name of the package that the license applies to, e.g. the name of the
+	// upstream project.
+	Package_name *string
+}
+
+type licenseModule struct {
+	ModuleBase
+
+	properties licenseProperties
+
+	// Set by GenerateAndroidBuildActions
+	kinds        []string
+	conditions   []string
+	licenseTexts Paths
+}
+
+func (m *licenseModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), licenseKindsTag, m.properties.License_kinds...)
+}
+
+func (m *licenseModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	if len(m.properties.License_kinds) == 0 {
+		ctx.PropertyErrorf("license_kinds", "must contain at least one license_kind")
+	}
+
+	m.kinds = nil
+	m.conditions = nil
+	ctx.VisitDirectDepsWithTag(licenseKindsTag, func(dep Module) {
+		if kind, ok := dep.(*licenseKindModule); ok {
+			m.kinds = append(m.kinds, ctx.OtherModuleName(dep))
+			m.conditions = append(m.conditions, kind.Conditions()...)
+		} else {
+			ctx.PropertyErrorf("license_kinds", "module %q is not a license_kind module",
+				ctx.OtherModuleName(dep))
+		}
+	})
+	m.conditions = FirstUniqueStrings(m.conditions)
+	sort.Strings(m.conditions)
+
+	m.licenseTexts = PathsForModuleSrc(ctx, m.properties.License_text)
+}
+
+// Kinds returns the names of the license_kind modules of this license.
+func (m *licenseModule) Kinds() []string {
+	return m.kinds
+}
+
+// Conditions returns the union of the conditions of all the kinds of this license.
+func (m *licenseModule) Conditions() []string {
+	return m.conditions
+}
+
+// LicenseTexts returns the paths to the files containing the text of this license.
+func (m *licenseModule) LicenseTexts() Paths {
+	return m.licenseTexts
+}
+
+// CopyrightNotice returns the short copyright notice of this license.
+func (m *licenseModule) CopyrightNotice() string {
+	return String(m.properties.Copyright_notice)
+}
+
+// PackageName returns the name of the package this license applies to.
+func (m *licenseModule) PackageName() string {
+	return String(m.properties.Package_name)
+}
+
+// license describes the license of a set of modules.  Modules refer to license modules in their
+// licenses property, or receive them from the default_licenses property of the closest package
+// module.
+func LicenseFactory() Module {
+	module := &licenseModule{}
+
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+// isLicenseModule returns true for the license and license_kind modules, which never have
+// licenses of their own.
+func isLicenseModule(m Module) bool {
+	switch m.(type) {
+	case *licenseModule, *licenseKindModule:
+		return true
+	default:
+		return false
+	}
+}
+
+// The licenses deps mutator needs to run after the package defaults mutator so that modules
+// depend on the default_licenses of their package.
+func RegisterLicensesDepsMutator(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("licenses_deps", licensesDepsMutator).Parallel()
+}
+
+func licensesDepsMutator(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(Module)
+	if !ok || isLicenseModule(m) {
+		return
+	}
+
+	ctx.AddDependency(m, licensesTag, m.base().commonProperties.Licenses...)
+}
+
+// collectLicenses sets the licenses of a module from its licenses property, and the licenses
+// of everything that is statically linked into it from its direct dependencies.  It must be
+// called before the GenerateAndroidBuildActions of the module, after the
+// GenerateAndroidBuildActions of its dependencies.
+func (a *ModuleBase) collectLicenses(ctx ModuleContext) {
+	a.licenses = nil
+	a.staticLicenses = nil
+
+	if isLicenseModule(a.module) {
+		return
+	}
+
+	seen := make(map[*licenseModule]bool)
+	addStatic := func(l *licenseModule) {
+		if !seen[l] {
+			seen[l] = true
+			a.staticLicenses = append(a.staticLicenses, l)
+		}
+	}
+
+	ctx.VisitDirectDepsBlueprint(func(dep blueprint.Module) {
+		tag := ctx.OtherModuleDependencyTag(dep)
+		if tag == licensesTag {
+			if l, ok := dep.(*licenseModule); ok {
+				a.licenses = append(a.licenses, l)
+				addStatic(l)
+			} else {
+				ctx.PropertyErrorf("licenses", "module %q is not a license module",
+					ctx.OtherModuleName(dep))
+			}
+		} else if DependencyTagLinkage(tag) == StaticLinkage {
+			if m, ok := dep.(Module); ok {
+				for _, l := range m.base().staticLicenses {
+					addStatic(l)
+				}
+			}
+		}
+	})
+}
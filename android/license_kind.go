@@ -0,0 +1,103 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+func init() {
+	RegisterModuleType("license_kind", LicenseKindFactory)
+}
+
+// The conditions that a license_kind may impose on the use of the modules it applies to.
+const (
+	// The license text must be distributed with the module.
+	LicenseConditionNotice = "notice"
+
+	// Source code of the module must be made available when it is distributed.
+	LicenseConditionReciprocal = "reciprocal"
+
+	// Source code of the module and of everything it is linked into must be made available when
+	// it is distributed.
+	LicenseConditionRestricted = "restricted"
+
+	// The module may only be used with a commercial license.
+	LicenseConditionProprietary = "proprietary"
+
+	// The license places no conditions other than attribution on the module.
+	LicenseConditionPermissive = "permissive"
+
+	// The module may be used without any conditions.
+	LicenseConditionUnencumbered = "unencumbered"
+
+	// The module may only be used in projects that have been approved to use it.
+	LicenseConditionByExceptionOnly = "by_exception_only"
+)
+
+var licenseConditions = []string{
+	LicenseConditionNotice,
+	LicenseConditionReciprocal,
+	LicenseConditionRestricted,
+	LicenseConditionProprietary,
+	LicenseConditionPermissive,
+	LicenseConditionUnencumbered,
+	LicenseConditionByExceptionOnly,
+}
+
+type licenseKindProperties struct {
+	// Specifies the conditions for all licenses of the kind.  Each condition must be one of
+	// "notice", "reciprocal", "restricted", "proprietary", "permissive", "unencumbered" or
+	// "by_exception_only".
+	Conditions []string
+
+	// Specifies the url to the canonical license definition.
+	Url *string
+}
+
+type licenseKindModule struct {
+	ModuleBase
+
+	properties licenseKindProperties
+}
+
+func (m *licenseKindModule) DepsMutator(ctx BottomUpMutatorContext) {
+	// Nothing to do.
+}
+
+func (m *licenseKindModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	for _, c := range m.properties.Conditions {
+		if !InList(c, licenseConditions) {
+			ctx.PropertyErrorf("conditions", "unknown license condition %q, must be one of %q",
+				c, licenseConditions)
+		}
+	}
+}
+
+// Conditions returns the conditions imposed by licenses of this kind.
+func (m *licenseKindModule) Conditions() []string {
+	return m.properties.Conditions
+}
+
+// license_kind describes a kind of license, for example a particular version of the Apache
+// license, and the conditions it imposes on the modules it applies to.  license modules refer to
+// license_kind modules in their license_kinds property.
+//
+// By convention license_kind modules for licenses with an SPDX identifier are named
+// "SPDX-license-identifier-<identifier>", which allows the license report to use the identifier
+// directly.
+func LicenseKindFactory() Module {
+	module := &licenseKindModule{}
+
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
@@ -0,0 +1,287 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+// This file implements the license report singleton, which writes the licenses of every installed
+// file to a JSON and an SPDX report, and checks that the conditions of the licenses statically
+// linked into each module allow it to be installed where it is.
+
+func init() {
+	RegisterSingletonType("license_report", LicenseReportSingleton)
+}
+
+var (
+	// Sets the SPDX Created tag of the report to the date of the build, which is read when the
+	// report is built rather than when Soong runs.  Darwin's date takes the seconds with -r.
+	spdxCreated = pctx.AndroidStaticRule("spdxCreated",
+		blueprint.RuleParams{
+			Command: `seconds=$$(cat ${buildDateFile}) && ` +
+				`created=$$(date -u -d @$${seconds} +%Y-%m-%dT%H:%M:%SZ 2>/dev/null || ` +
+				`date -u -r $${seconds} +%Y-%m-%dT%H:%M:%SZ) && ` +
+				`sed -e "1,/^Created: /s/^Created: .*/Created: $${created}/" $in > $out`,
+		}, "buildDateFile")
+)
+
+const (
+	licenseReportJsonFileName = "license-report.json"
+	licenseReportSpdxFileName = "license-report.spdx"
+
+	// The SPDX report with the Unix epoch as its Created tag, which is replaced by the date of the
+	// build when the report is built.
+	licenseReportSpdxInFileName = licenseReportSpdxFileName + ".in"
+
+	// The SPDX Created tag of the report when the date of the build is not known.
+	spdxEpoch = "1970-01-01T00:00:00Z"
+
+	// The prefix of the names of license_kind modules for licenses with an SPDX identifier.
+	spdxLicenseKindPrefix = "SPDX-license-identifier-"
+)
+
+// SPDX license references may only contain letters, digits, "." and "-".
+var spdxInvalidIdChars = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+func LicenseReportSingleton() Singleton {
+	return &licenseReportSingleton{
+		SingletonOutput: NewSingletonOutput("license-report", "SOONG_LICENSE_REPORT_SPDX"),
+	}
+}
+
+type licenseReportSingleton struct {
+	SingletonOutput
+
+	// The entries of the report, sorted by install path.
+	entries []licenseReportEntry
+}
+
+type licenseReportEntry struct {
+	Install_path string                 `json:"install_path"`
+	Module       string                 `json:"module"`
+	Dir          string                 `json:"dir"`
+	Licenses     []licenseReportLicense `json:"licenses"`
+}
+
+type licenseReportLicense struct {
+	Name             string   `json:"name"`
+	Package_name     string   `json:"package_name,omitempty"`
+	Kinds            []string `json:"kinds"`
+	Conditions       []string `json:"conditions"`
+	License_texts    []string `json:"license_texts,omitempty"`
+	Copyright_notice string   `json:"copyright_notice,omitempty"`
+}
+
+func (s *licenseReportSingleton) GenerateBuildActions(ctx SingletonContext) {
+	s.entries = nil
+
+	ctx.VisitAllModules(func(module Module) {
+		base := module.base()
+		if !base.Enabled() || isLicenseModule(module) {
+			return
+		}
+
+		checkLicenseConditions(ctx, module)
+
+		if len(base.installFiles) == 0 {
+			return
+		}
+
+		var licenses []licenseReportLicense
+		for _, l := range base.staticLicenses {
+			licenses = append(licenses, licenseReportLicense{
+				Name:             ctx.ModuleName(l),
+				Package_name:     l.PackageName(),
+				Kinds:            l.Kinds(),
+				Conditions:       l.Conditions(),
+				License_texts:    l.LicenseTexts().Strings(),
+				Copyright_notice: l.CopyrightNotice(),
+			})
+		}
+
+		for _, installFile := range base.installFiles {
+			s.entries = append(s.entries, licenseReportEntry{
+				Install_path: installFile.String(),
+				Module:       ctx.ModuleName(module),
+				Dir:          ctx.ModuleDir(module),
+				Licenses:     licenses,
+			})
+		}
+	})
+
+	if ctx.Failed() {
+		return
+	}
+
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.entries[i].Install_path < s.entries[j].Install_path
+	})
+
+	jsonData, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal %s: %s", licenseReportJsonFileName, err)
+		return
+	}
+	writeOutputFileIfChanged(ctx, licenseReportJsonFileName, jsonData)
+
+	writeOutputFileIfChanged(ctx, licenseReportSpdxInFileName, s.spdx(spdxEpoch))
+	spdxIn := PathForOutput(ctx, licenseReportSpdxInFileName)
+	spdx := PathForOutput(ctx, licenseReportSpdxFileName)
+	if buildDateFile := ctx.Config().BuildDateFile(); buildDateFile.Valid() {
+		ctx.Build(pctx, BuildParams{
+			Rule:        spdxCreated,
+			Description: "license report " + spdx.Base(),
+			Input:       spdxIn,
+			Implicit:    buildDateFile.Path(),
+			Output:      spdx,
+			Args: map[string]string{
+				"buildDateFile": buildDateFile.String(),
+			},
+		})
+	} else {
+		ctx.Build(pctx, BuildParams{
+			Rule:        Cp,
+			Description: "license report " + spdx.Base(),
+			Input:       spdxIn,
+			Output:      spdx,
+		})
+	}
+	s.SetOutput(ctx, spdx)
+}
+
+// checkLicenseConditions reports an error if the licenses of the code statically linked into
+// a module that is not installed on the system partition impose the restricted condition, as
+// the source code of the module would then have to be made available.  A module's own licenses
+// are not checked, only those that it inherits from its static dependencies.
+func checkLicenseConditions(ctx SingletonContext, module Module) {
+	base := module.base()
+	if base.Platform() {
+		return
+	}
+
+	for _, l := range base.staticLicenses {
+		if inLicenseList(l, base.licenses) {
+			continue
+		}
+		if InList(LicenseConditionRestricted, l.Conditions()) {
+			ctx.ModuleErrorf(module, "statically links code licensed under %q, which has the %q "+
+				"condition, into a module that is not installed on the system partition",
+				ctx.ModuleName(l), LicenseConditionRestricted)
+		}
+	}
+}
+
+func inLicenseList(l *licenseModule, list []*licenseModule) bool {
+	for _, x := range list {
+		if x == l {
+			return true
+		}
+	}
+	return false
+}
+
+// spdx returns the report in the SPDX tag-value format.  license_kind modules named with the
+// SPDX identifier prefix are reported by their SPDX identifier, all others as LicenseRef-<name>.
+func (s *licenseReportSingleton) spdx(created string) []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintln(buf, "SPDXVersion: SPDX-2.2")
+	fmt.Fprintln(buf, "DataLicense: CC0-1.0")
+	fmt.Fprintln(buf, "SPDXID: SPDXRef-DOCUMENT")
+	fmt.Fprintln(buf, "DocumentName: license-report")
+	fmt.Fprintln(buf, "Creator: Tool: soong")
+	fmt.Fprintf(buf, "Created: %s\n", created)
+
+	var licenseRefs []string
+	for i, entry := range s.entries {
+		var ids, copyrights []string
+		for _, l := range entry.Licenses {
+			for _, kind := range l.Kinds {
+				id := spdxLicenseId(kind)
+				ids = append(ids, id)
+				if strings.HasPrefix(id, "LicenseRef-") {
+					licenseRefs = append(licenseRefs, id)
+				}
+			}
+			if l.Copyright_notice != "" {
+				copyrights = append(copyrights, l.Copyright_notice)
+			}
+		}
+		ids = FirstUniqueStrings(ids)
+
+		fmt.Fprintln(buf)
+		fmt.Fprintf(buf, "FileName: %s\n", entry.Install_path)
+		fmt.Fprintf(buf, "SPDXID: SPDXRef-File-%d\n", i)
+		if len(ids) == 0 {
+			fmt.Fprintln(buf, "LicenseConcluded: NOASSERTION")
+			fmt.Fprintln(buf, "LicenseInfoInFile: NOASSERTION")
+		} else {
+			fmt.Fprintf(buf, "LicenseConcluded: %s\n", strings.Join(ids, " AND "))
+			for _, id := range ids {
+				fmt.Fprintf(buf, "LicenseInfoInFile: %s\n", id)
+			}
+		}
+		if len(copyrights) == 0 {
+			fmt.Fprintln(buf, "FileCopyrightText: NOASSERTION")
+		} else {
+			fmt.Fprintf(buf, "FileCopyrightText: <text>%s</text>\n", strings.Join(copyrights, "\n"))
+		}
+	}
+
+	licenseRefs = FirstUniqueStrings(licenseRefs)
+	sort.Strings(licenseRefs)
+	for _, ref := range licenseRefs {
+		fmt.Fprintln(buf)
+		fmt.Fprintf(buf, "LicenseID: %s\n", ref)
+		fmt.Fprintf(buf, "LicenseName: %s\n", strings.TrimPrefix(ref, "LicenseRef-"))
+		fmt.Fprintln(buf, "ExtractedText: <text>See the license_text of the license module.</text>")
+	}
+
+	return buf.Bytes()
+}
+
+// spdxLicenseId returns the SPDX license identifier for a license_kind module name.
+func spdxLicenseId(kind string) string {
+	if strings.HasPrefix(kind, spdxLicenseKindPrefix) {
+		return strings.TrimPrefix(kind, spdxLicenseKindPrefix)
+	}
+	return "LicenseRef-" + spdxInvalidIdChars.ReplaceAllString(kind, "-")
+}
+
+// writeOutputFileIfChanged writes data to the named file in the output directory, leaving the file
+// untouched if it already has the same contents so that its timestamp does not change.
+func writeOutputFileIfChanged(ctx SingletonContext, name string, data []byte) {
+	outFile := PathForOutput(ctx, name).String()
+
+	if _, err := os.Stat(outFile); err == nil {
+		if old, err := ioutil.ReadFile(outFile); err == nil && bytes.Equal(old, data) {
+			return
+		}
+	}
+
+	if err := ioutil.WriteFile(outFile, data, 0666); err != nil {
+		ctx.Errorf(err.Error())
+	}
+}
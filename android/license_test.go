@@ -0,0 +1,370 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+var licenseKinds = []byte(`
+	license_kind {
+		name: "SPDX-license-identifier-Apache-2.0",
+		conditions: ["notice"],
+	}
+
+	license_kind {
+		name: "SPDX-license-identifier-GPL-2.0",
+		conditions: ["restricted"],
+	}
+
+	license_kind {
+		name: "acme_proprietary",
+		conditions: ["proprietary", "by_exception_only"],
+	}`)
+
+var licenseTests = []struct {
+	name             string
+	fs               map[string][]byte
+	expectedErrors   []string
+	expectedLicenses map[string][]string
+	expectedReport   map[string][]string
+	expectedSpdx     []string
+}{
+	{
+		name: "licenses from the module and its package",
+		fs: map[string][]byte{
+			"build/Blueprints": licenseKinds,
+			"external/Blueprints": []byte(`
+				license {
+					name: "external_license",
+					license_kinds: ["SPDX-license-identifier-Apache-2.0"],
+					copyright_notice: "Copyright (C) The Android Open Source Project",
+				}`),
+			"external/foo/Blueprints": []byte(`
+				package {
+					default_licenses: ["external_license"],
+				}
+
+				license {
+					name: "foo_license",
+					license_kinds: ["acme_proprietary"],
+					package_name: "foo",
+				}
+
+				mock_licensed {
+					name: "libdefaulted",
+				}
+
+				mock_licensed {
+					name: "libexplicit",
+					licenses: ["foo_license"],
+				}`),
+		},
+		expectedLicenses: map[string][]string{
+			"libdefaulted": []string{"external_license"},
+			"libexplicit":  []string{"foo_license"},
+		},
+		expectedSpdx: []string{
+			"Creator: Tool: soong\nCreated: 1970-01-01T00:00:00Z\n",
+			"LicenseConcluded: Apache-2.0\n",
+			"LicenseConcluded: LicenseRef-acme-proprietary\n",
+			"FileCopyrightText: <text>Copyright (C) The Android Open Source Project</text>\n",
+			"LicenseID: LicenseRef-acme-proprietary\n",
+		},
+	},
+	{
+		name: "only static dependencies contribute licenses",
+		fs: map[string][]byte{
+			"build/Blueprints": licenseKinds,
+			"Blueprints": []byte(`
+				license {
+					name: "apache",
+					license_kinds: ["SPDX-license-identifier-Apache-2.0"],
+				}
+
+				license {
+					name: "gpl",
+					license_kinds: ["SPDX-license-identifier-GPL-2.0"],
+				}
+
+				mock_licensed {
+					name: "libstatic",
+					licenses: ["apache"],
+				}
+
+				mock_licensed {
+					name: "libshared",
+					licenses: ["gpl"],
+				}
+
+				mock_licensed {
+					name: "libtop",
+					static_libs: ["libstatic"],
+					shared_libs: ["libshared"],
+				}`),
+		},
+		expectedLicenses: map[string][]string{
+			"libtop": []string{"apache"},
+		},
+		expectedReport: map[string][]string{
+			"libtop":    []string{"apache"},
+			"libshared": []string{"gpl"},
+		},
+	},
+	{
+		name: "restricted license statically linked into a vendor module",
+		fs: map[string][]byte{
+			"build/Blueprints": licenseKinds,
+			"Blueprints": []byte(`
+				license {
+					name: "gpl",
+					license_kinds: ["SPDX-license-identifier-GPL-2.0"],
+				}
+
+				mock_licensed {
+					name: "libgpl",
+					licenses: ["gpl"],
+				}
+
+				mock_licensed {
+					name: "libvendor",
+					vendor: true,
+					static_libs: ["libgpl"],
+				}
+
+				mock_licensed {
+					name: "libvendor_shared",
+					vendor: true,
+					shared_libs: ["libgpl"],
+				}
+
+				mock_licensed {
+					name: "libsystem",
+					static_libs: ["libgpl"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libvendor" variant "android_common": statically links code licensed under "gpl", ` +
+				`which has the "restricted" condition`,
+		},
+	},
+	{
+		name: "unknown license condition",
+		fs: map[string][]byte{
+			"Blueprints": []byte(`
+				license_kind {
+					name: "bad_kind",
+					conditions: ["free"],
+				}`),
+		},
+		expectedErrors: []string{`conditions: unknown license condition "free"`},
+	},
+	{
+		name: "licenses must be license modules",
+		fs: map[string][]byte{
+			"build/Blueprints": licenseKinds,
+			"Blueprints": []byte(`
+				license {
+					name: "no_kinds",
+				}
+
+				license {
+					name: "bad_kinds",
+					license_kinds: ["no_kinds"],
+				}
+
+				mock_licensed {
+					name: "libother",
+				}
+
+				mock_licensed {
+					name: "libbad",
+					licenses: ["libother"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "no_kinds": license_kinds: must contain at least one license_kind`,
+			`module "bad_kinds": license_kinds: module "no_kinds" is not a license_kind module`,
+			`licenses: module "libother" is not a license module`,
+		},
+	},
+}
+
+func TestLicenses(t *testing.T) {
+	for _, test := range licenseTests {
+		t.Run(test.name, func(t *testing.T) {
+			buildDir, err := ioutil.TempDir("", "soong_license_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(buildDir)
+
+			ctx, errs := testLicenses(buildDir, nil, test.fs)
+
+			if test.expectedErrors == nil {
+				FailIfErrored(t, errs)
+			} else {
+				for _, expectedError := range test.expectedErrors {
+					FailIfNoMatchingErrors(t, expectedError, errs)
+				}
+				if len(errs) != len(test.expectedErrors) {
+					t.Errorf("expected %d errors, got %q", len(test.expectedErrors), errs)
+				}
+				return
+			}
+
+			for name, expected := range test.expectedLicenses {
+				m := ctx.ModuleForTests(name, "android_common").Module()
+				var licenses []string
+				for _, l := range m.base().staticLicenses {
+					licenses = append(licenses, l.Name())
+				}
+				if !reflect.DeepEqual(licenses, expected) {
+					t.Errorf("expected licenses of %q to be %q, got %q", name, expected, licenses)
+				}
+			}
+
+			report := ctx.SingletonForTests("license_report").Singleton().(*licenseReportSingleton)
+			for name, expected := range test.expectedReport {
+				found := false
+				for _, entry := range report.entries {
+					if entry.Module != name {
+						continue
+					}
+					found = true
+					var licenses []string
+					for _, l := range entry.Licenses {
+						licenses = append(licenses, l.Name)
+					}
+					if !reflect.DeepEqual(licenses, expected) {
+						t.Errorf("expected report licenses of %q to be %q, got %q", name, expected, licenses)
+					}
+				}
+				if !found {
+					t.Errorf("no report entry for %q", name)
+				}
+			}
+
+			if test.expectedSpdx != nil {
+				spdx, err := ioutil.ReadFile(filepath.Join(buildDir, licenseReportSpdxInFileName))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, expected := range test.expectedSpdx {
+					if !strings.Contains(string(spdx), expected) {
+						t.Errorf("expected SPDX report to contain %q, got:\n%s", expected, spdx)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLicenseReportBuildDate(t *testing.T) {
+	buildDir, err := ioutil.TempDir("", "soong_license_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	env := map[string]string{
+		"BUILD_DATETIME_FILE": "out/build_date.txt",
+	}
+	ctx, errs := testLicenses(buildDir, env, licenseTests[0].fs)
+	FailIfErrored(t, errs)
+
+	spdx := ctx.SingletonForTests("license_report").Output(licenseReportSpdxFileName)
+	if spdx.Rule != spdxCreated {
+		t.Errorf("expected the SPDX report to be built with the spdxCreated rule, got %q", spdx.Rule)
+	}
+	if spdx.Input.String() != filepath.Join(buildDir, licenseReportSpdxInFileName) {
+		t.Errorf("expected the SPDX report to be built from %q, got %q", licenseReportSpdxInFileName, spdx.Input)
+	}
+	if spdx.Implicit == nil || spdx.Implicit.String() != "out/build_date.txt" {
+		t.Errorf("expected the SPDX report to depend on the build date file, got %q", spdx.Implicit)
+	}
+	if spdx.Args["buildDateFile"] != "out/build_date.txt" {
+		t.Errorf("expected the SPDX report to read the build date file, got %q", spdx.Args["buildDateFile"])
+	}
+}
+
+func testLicenses(buildDir string, env map[string]string, fs map[string][]byte) (*TestContext, []error) {
+
+	// Create a new config per test as package information is stored in the config.
+	config := TestArchConfig(buildDir, env)
+
+	ctx := NewTestArchContext()
+	ctx.RegisterModuleType("package", ModuleFactoryAdaptor(PackageFactory))
+	ctx.RegisterModuleType("license", ModuleFactoryAdaptor(LicenseFactory))
+	ctx.RegisterModuleType("license_kind", ModuleFactoryAdaptor(LicenseKindFactory))
+	ctx.RegisterModuleType("mock_licensed", ModuleFactoryAdaptor(newMockLicensedModule))
+	ctx.RegisterSingletonType("license_report", SingletonFactoryAdaptor(LicenseReportSingleton))
+	ctx.PreArchMutators(RegisterPackageMutator)
+	ctx.PreArchMutators(RegisterPackageDefaultsMutator)
+	ctx.PreArchMutators(RegisterLicensesDepsMutator)
+	ctx.Register()
+
+	ctx.MockFileSystem(fs)
+
+	_, errs := ctx.ParseBlueprintsFiles("Blueprints")
+	if len(errs) > 0 {
+		return ctx, errs
+	}
+
+	_, errs = ctx.PrepareBuildActions(config)
+	return ctx, errs
+}
+
+type mockLicensedProperties struct {
+	Static_libs []string
+	Shared_libs []string
+}
+
+type mockLicensedModule struct {
+	ModuleBase
+	properties mockLicensedProperties
+}
+
+func newMockLicensedModule() Module {
+	m := &mockLicensedModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidArchModule(m, DeviceSupported, MultilibCommon)
+	return m
+}
+
+type linkageDependencyTag struct {
+	blueprint.BaseDependencyTag
+	linkage Linkage
+}
+
+func (d linkageDependencyTag) Linkage() Linkage {
+	return d.linkage
+}
+
+func (m *mockLicensedModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddVariationDependencies(nil, linkageDependencyTag{linkage: StaticLinkage}, m.properties.Static_libs...)
+	ctx.AddVariationDependencies(nil, linkageDependencyTag{linkage: SharedLinkage}, m.properties.Shared_libs...)
+}
+
+func (m *mockLicensedModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	ctx.InstallFile(PathForModuleInstall(ctx, "lib"), ctx.ModuleName(), PathForModuleOut(ctx, ctx.ModuleName()))
+}
@@ -274,6 +274,35 @@ type commonProperties struct {
 	// relative path to a file to include in the list of notices for the device
 	Notice *string `android:"path"`
 
+	// names of the license modules that describe the licenses of this module.  Defaults to the
+	// default_licenses of the closest package module.
+	Licenses []string
+
+	// Controls the visibility of this module to other modules. Allowable values are one or more of
+	// these formats:
+	//
+	//  ["//visibility:public"]: Anyone can use this module.
+	//  ["//visibility:private"]: Only rules in the module's package (not its subpackages) can use
+	//      this module.
+	//  ["//some/package:__pkg__", "//other/package:__pkg__"]: Only modules in some/package and
+	//      other/package (defined in some/package/*.bp and other/package/*.bp) have access to
+	//      this module. Note that sub-packages do not have access to the rule; for example,
+	//      //some/package/foo:bar or //other/package/testing:bla wouldn't have access. __pkg__
+	//      is a special module and must be used verbatim. It represents all of the modules in the
+	//      package.
+	//  ["//project:__subpackages__", "//other:__subpackages__"]: Only modules in packages project
+	//      or other or in one of their sub-packages have access to this module. For example,
+	//      //project:rule, //project/library:lib or //other/testing/internal:munge are allowed
+	//      to depend on this rule (but not //independent:evil)
+	//  [":__subpackages__"]: This is shorthand for ["//project:__subpackages__"] where
+	//      //project is the module's package. e.g. using [":__subpackages__"] in
+	//      packages/apps/Settings/Android.bp is equivalent to
+	//      //packages/apps/Settings:__subpackages__.
+	//
+	// If a module does not specify the `visibility` property then it is visible to all modules.
+	// Modules in the same package are always visible to each other.
+	Visibility []string
+
 	Dist struct {
 		// copy the output of this module to the $DIST_DIR when `dist` is specified on the
 		// command line and  any of these targets are also on the command line, or otherwise
@@ -305,6 +334,14 @@ type commonProperties struct {
 
 	NamespaceExportedToMake bool `blueprint:"mutated"`
 
+	// The package (the directory of the Android.bp file) this module was defined in, as used by
+	// the visibility rules.  Set by the visibility rule gatherer.
+	Package_dir string `blueprint:"mutated"`
+
+	// The type of this module, as used by neverallow rules that match the types of dependencies.
+	// Set by the neverallow mutator.
+	Module_type string `blueprint:"mutated"`
+
 	// Whether this module provides a boot jar
 	BootJarProvider bool `blueprint:"mutated"`
 }
@@ -474,6 +511,19 @@ type ModuleBase struct {
 	checkbuildFiles    Paths
 	noticeFile         OptionalPath
 
+	// The license modules listed in the licenses property, and those plus the license modules
+	// of everything statically linked into this module.  Set by collectLicenses.
+	licenses       []*licenseModule
+	staticLicenses []*licenseModule
+
+	// The paths of the files installed by this module, whether they are installed by Soong or,
+	// in builds that are embedded in Make, by Make, the partition or host directory that Make
+	// installs the module into if it doesn't install any files through Soong, and the names of its
+	// dependencies.  Used by moduleInfoJsonSingleton.
+	installPaths       Paths
+	installRoot        OutputPath
+	moduleInfoDepNames []string
+
 	// Used by buildTargetSingleton to create checkbuild and per-directory build targets
 	// Only set on the final variant of each module
 	installTarget    WritablePath
@@ -870,12 +920,17 @@ func (a *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 			a.noticeFile = ExistentPathForSource(ctx, noticePath)
 		}
 
+		a.collectLicenses(ctx)
+		a.collectModuleInfoDepNames(ctx)
+
 		a.module.GenerateAndroidBuildActions(ctx)
 		if ctx.Failed() {
 			return
 		}
 
 		a.installFiles = append(a.installFiles, ctx.installFiles...)
+		a.installPaths = ctx.installPaths
+		a.installRoot = PathForModuleInstall(ctx)
 		a.checkbuildFiles = append(a.checkbuildFiles, ctx.checkbuildFiles...)
 	}
 
@@ -905,6 +960,7 @@ type androidModuleContext struct {
 	androidBaseContextImpl
 	installDeps     Paths
 	installFiles    Paths
+	installPaths    Paths
 	checkbuildFiles Paths
 	missingDeps     []string
 	module          Module
@@ -1302,6 +1358,14 @@ func (a *androidModuleContext) skipInstall(fullInstallPath OutputPath) bool {
 	return false
 }
 
+// recordInstallPath records a path that the module installs to, including those that are
+// skipped here because Make installs them.
+func (a *androidModuleContext) recordInstallPath(fullInstallPath OutputPath) {
+	if !a.module.base().commonProperties.SkipInstall {
+		a.installPaths = append(a.installPaths, fullInstallPath)
+	}
+}
+
 func (a *androidModuleContext) InstallFile(installPath OutputPath, name string, srcPath Path,
 	deps ...Path) OutputPath {
 	return a.installFile(installPath, name, srcPath, Cp, deps)
@@ -1317,6 +1381,7 @@ func (a *androidModuleContext) installFile(installPath OutputPath, name string,
 
 	fullInstallPath := installPath.Join(a, name)
 	a.module.base().hooks.runInstallHooks(a, fullInstallPath, false)
+	a.recordInstallPath(fullInstallPath)
 
 	if !a.skipInstall(fullInstallPath) {
 
@@ -1351,6 +1416,7 @@ func (a *androidModuleContext) installFile(installPath OutputPath, name string,
 func (a *androidModuleContext) InstallSymlink(installPath OutputPath, name string, srcPath OutputPath) OutputPath {
 	fullInstallPath := installPath.Join(a, name)
 	a.module.base().hooks.runInstallHooks(a, fullInstallPath, true)
+	a.recordInstallPath(fullInstallPath)
 
 	if !a.skipInstall(fullInstallPath) {
 
@@ -1380,6 +1446,7 @@ func (a *androidModuleContext) InstallSymlink(installPath OutputPath, name strin
 func (a *androidModuleContext) InstallAbsoluteSymlink(installPath OutputPath, name string, absPath string) OutputPath {
 	fullInstallPath := installPath.Join(a, name)
 	a.module.base().hooks.runInstallHooks(a, fullInstallPath, true)
+	a.recordInstallPath(fullInstallPath)
 
 	if !a.skipInstall(fullInstallPath) {
 		a.Build(pctx, BuildParams{
@@ -1437,6 +1504,50 @@ type sourceDependencyTag struct {
 
 var SourceDepTag sourceDependencyTag
 
+// Linkage describes how a dependency is linked into the module that depends on it.
+type Linkage int
+
+const (
+	// The dependency is not linked into the depending module, for example a tool or a data file.
+	NoLinkage Linkage = iota
+
+	// The dependency is copied into the output of the depending module, for example a static
+	// library or a java static_libs entry.
+	StaticLinkage
+
+	// The dependency is loaded by the depending module at runtime, for example a shared library.
+	SharedLinkage
+)
+
+func (l Linkage) String() string {
+	switch l {
+	case NoLinkage:
+		return "none"
+	case StaticLinkage:
+		return "static"
+	case SharedLinkage:
+		return "shared"
+	default:
+		panic(fmt.Errorf("unknown linkage %d", l))
+	}
+}
+
+// LinkageDependencyTag is implemented by dependency tags that describe how the dependency is
+// linked into the depending module.  Dependency tags that don't implement it are treated as
+// NoLinkage.
+type LinkageDependencyTag interface {
+	blueprint.DependencyTag
+	Linkage() Linkage
+}
+
+// DependencyTagLinkage returns the Linkage of a dependency tag.
+func DependencyTagLinkage(tag blueprint.DependencyTag) Linkage {
+	if l, ok := tag.(LinkageDependencyTag); ok {
+		return l.Linkage()
+	}
+	return NoLinkage
+}
+
 // Adds necessary dependencies to satisfy filegroup or generated sources modules listed in srcFiles
 // using ":module" syntax, if any.
 //
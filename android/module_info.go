@@ -0,0 +1,253 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+// This file implements the module_info_json singleton, which writes module-info.json, the
+// description of every module that tools such as atest use to find tests and their installed
+// files.  It is written from the AndroidMkEntries and AndroidMkData of each module and the data in
+// its ModuleBase, so it describes the same modules that Make would, but it does not need Make to
+// run and so is also available in builds that skip Make.
+
+func init() {
+	RegisterSingletonType("module_info_json", ModuleInfoJsonSingleton)
+}
+
+const moduleInfoJsonFileName = "module-info.json"
+
+func ModuleInfoJsonSingleton() Singleton {
+	return &moduleInfoJsonSingleton{}
+}
+
+type moduleInfoJsonSingleton struct {
+	// The entries of module-info.json, keyed by the name of the module in Make.
+	modules map[string]*moduleInfoJson
+}
+
+// moduleInfoJson is the description of a single module in module-info.json.  The fields are
+// lists, as in the file written by Make, because the variants of a module share an entry.
+type moduleInfoJson struct {
+	Class                []string `json:"class"`
+	Path                 []string `json:"path"`
+	Tags                 []string `json:"tags"`
+	Installed            []string `json:"installed"`
+	Compatibility_suites []string `json:"compatibility_suites"`
+	Module_name          string   `json:"module_name"`
+	Test_config          []string `json:"test_config"`
+	Dependencies         []string `json:"dependencies"`
+	Owner                []string `json:"owner"`
+	Android_bp           []string `json:"android_bp"`
+}
+
+func (s *moduleInfoJsonSingleton) GenerateBuildActions(ctx SingletonContext) {
+	s.modules = make(map[string]*moduleInfoJson)
+
+	ctx.VisitAllModules(func(module Module) {
+		s.addModule(ctx, module)
+	})
+
+	if ctx.Failed() {
+		return
+	}
+
+	for _, info := range s.modules {
+		info.sortAndUniquify()
+	}
+
+	data, err := json.MarshalIndent(s.modules, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal %s: %s", moduleInfoJsonFileName, err)
+		return
+	}
+	writeOutputFileIfChanged(ctx, moduleInfoJsonFileName, data)
+}
+
+// ModuleInfoJsonTestProvider is implemented by test modules that describe themselves to Make with
+// AndroidMkData, whose Extra functions only write Make text, so that module-info.json can list
+// their test suites and test config.  Modules that use AndroidMkEntries don't need it.
+type ModuleInfoJsonTestProvider interface {
+	// TestSuites returns the names of the test suites that the module is a part of.
+	TestSuites() []string
+	// TestConfig returns the test config of the module, or nil if it doesn't have one.
+	TestConfig() Path
+}
+
+// addModule adds the Make module that describes a module to module-info.json.
+func (s *moduleInfoJsonSingleton) addModule(ctx SingletonContext, module Module) {
+	base := module.base()
+	if shouldSkipAndroidMkProcessing(base) {
+		return
+	}
+
+	var entries AndroidMkEntries
+	switch x := module.(type) {
+	case AndroidMkDataProvider:
+		data := x.AndroidMk()
+		if data.Disabled || !data.OutputFile.Valid() {
+			return
+		}
+		entries = AndroidMkEntries{
+			Class:      data.Class,
+			SubName:    data.SubName,
+			OutputFile: data.OutputFile,
+			Include:    data.Include,
+			Required:   data.Required,
+		}
+	case AndroidMkEntriesProvider:
+		entries = x.AndroidMkEntries()
+	default:
+		return
+	}
+	bpFile := ctx.BlueprintFile(module)
+	entries.fillInEntries(ctx.Config(), bpFile, module)
+
+	name := firstOrEmpty(entries.EntryMap["LOCAL_MODULE"])
+	info := s.modules[name]
+	if info == nil {
+		info = &moduleInfoJson{Module_name: name}
+		s.modules[name] = info
+	}
+
+	modulePath := filepath.Dir(bpFile)
+	info.Class = append(info.Class, entries.Class)
+	info.Path = append(info.Path, modulePath)
+	info.Tags = append(info.Tags, entries.EntryMap["LOCAL_MODULE_TAGS"]...)
+	info.Compatibility_suites = append(info.Compatibility_suites, entries.EntryMap["LOCAL_COMPATIBILITY_SUITE"]...)
+	info.Test_config = append(info.Test_config, entries.EntryMap["LOCAL_FULL_TEST_CONFIG"]...)
+	// LOCAL_TEST_CONFIG is relative to the directory of the module.
+	for _, testConfig := range entries.EntryMap["LOCAL_TEST_CONFIG"] {
+		if testConfig != "" {
+			info.Test_config = append(info.Test_config, filepath.Join(modulePath, testConfig))
+		}
+	}
+	if test, ok := module.(ModuleInfoJsonTestProvider); ok {
+		info.Compatibility_suites = append(info.Compatibility_suites, test.TestSuites()...)
+		if testConfig := test.TestConfig(); testConfig != nil {
+			info.Test_config = append(info.Test_config, testConfig.String())
+		}
+	}
+	info.Dependencies = append(info.Dependencies, entries.Required...)
+	info.Dependencies = append(info.Dependencies, base.moduleInfoDepNames...)
+
+	if len(base.installPaths) > 0 {
+		info.Installed = append(info.Installed, base.installPaths.Strings()...)
+	} else if installed := makeInstallPath(ctx, base, &entries); installed != "" {
+		info.Installed = append(info.Installed, installed)
+	}
+
+	if base.commonProperties.Owner != nil {
+		info.Owner = append(info.Owner, *base.commonProperties.Owner)
+	}
+	info.Android_bp = append(info.Android_bp, bpFile)
+}
+
+// makeInstallPath returns the path that Make installs a module that Soong doesn't install into,
+// or "" if Make doesn't install it or the path depends on Make variables.  It follows the rules
+// in build/make/core/base_rules.mk for the module classes that Soong exports to Make.
+func makeInstallPath(ctx SingletonContext, base *ModuleBase, entries *AndroidMkEntries) string {
+	if !entries.OutputFile.Valid() || firstOrEmpty(entries.EntryMap["LOCAL_UNINSTALLABLE_MODULE"]) == "true" {
+		return ""
+	}
+
+	name := firstOrEmpty(entries.EntryMap["LOCAL_MODULE"])
+	lib := "lib"
+	if base.Arch().ArchType.Multilib == "lib64" {
+		lib = "lib64"
+	}
+
+	dir := firstOrEmpty(entries.EntryMap["LOCAL_MODULE_PATH"])
+	if strings.Contains(dir, "$(") {
+		return ""
+	} else if dir == "" {
+		switch entries.Class {
+		case "EXECUTABLES":
+			dir = base.installRoot.Join(ctx, "bin").String()
+		case "SHARED_LIBRARIES":
+			dir = base.installRoot.Join(ctx, lib).String()
+		case "ETC":
+			dir = base.installRoot.Join(ctx, "etc").String()
+		case "JAVA_LIBRARIES":
+			dir = base.installRoot.Join(ctx, "framework").String()
+		case "APPS":
+			dir = base.installRoot.Join(ctx, "app", name).String()
+		case "NATIVE_TESTS":
+			// Native tests are installed into the data partition on the device, whichever
+			// partition the module would be installed into otherwise.
+			root := base.installRoot
+			if base.Os().Class == Device {
+				root = PathForOutput(ctx, "target", "product", ctx.Config().DeviceName(), "data")
+			}
+			nativetest := "nativetest"
+			if lib == "lib64" {
+				nativetest = "nativetest64"
+			}
+			dir = root.Join(ctx, nativetest, name).String()
+		default:
+			return ""
+		}
+	}
+
+	stem := firstOrEmpty(entries.EntryMap["LOCAL_MODULE_STEM"])
+	if stem == "" {
+		stem = name
+	}
+	suffix := filepath.Ext(entries.OutputFile.String())
+	if suffixes, ok := entries.EntryMap["LOCAL_MODULE_SUFFIX"]; ok {
+		suffix = firstOrEmpty(suffixes)
+	}
+	return filepath.Join(dir, firstOrEmpty(entries.EntryMap["LOCAL_MODULE_RELATIVE_PATH"]), stem+suffix)
+}
+
+func (info *moduleInfoJson) sortAndUniquify() {
+	for _, list := range []*[]string{&info.Class, &info.Path, &info.Tags, &info.Installed,
+		&info.Compatibility_suites, &info.Test_config, &info.Dependencies, &info.Owner,
+		&info.Android_bp} {
+
+		*list = FirstUniqueStrings(*list)
+		sort.Strings(*list)
+		// Write empty lists as [] rather than null, as Make does.
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+}
+
+func firstOrEmpty(list []string) string {
+	if len(list) > 0 {
+		return list[0]
+	}
+	return ""
+}
+
+// collectModuleInfoDepNames records the names of the direct dependencies of a module for
+// module-info.json.
+func (a *ModuleBase) collectModuleInfoDepNames(ctx ModuleContext) {
+	a.moduleInfoDepNames = nil
+
+	ctx.VisitDirectDepsBlueprint(func(dep blueprint.Module) {
+		if _, ok := dep.(Module); !ok || ctx.OtherModuleDependencyTag(dep) == licensesTag {
+			return
+		}
+		a.moduleInfoDepNames = append(a.moduleInfoDepNames, ctx.OtherModuleName(dep))
+	})
+}
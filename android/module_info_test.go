@@ -0,0 +1,176 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestModuleInfoJson(t *testing.T) {
+	buildDir, err := ioutil.TempDir("", "soong_module_info_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	config := TestArchConfig(buildDir, nil)
+
+	ctx := NewTestArchContext()
+	ctx.RegisterModuleType("sh_test", ModuleFactoryAdaptor(ShTestFactory))
+	ctx.RegisterModuleType("mock_module_info", ModuleFactoryAdaptor(newMockModuleInfoModule))
+	ctx.RegisterSingletonType("module_info_json", SingletonFactoryAdaptor(ModuleInfoJsonSingleton))
+	ctx.Register()
+
+	ctx.MockFileSystem(map[string][]byte{
+		"tests/Blueprints": []byte(`
+			sh_test {
+				name: "foo_test",
+				src: "test.sh",
+				test_suites: ["device-tests", "general-tests"],
+				test_config: "AndroidTest.xml",
+				owner: "acme",
+			}`),
+		"tests/test.sh": nil,
+		"libs/Blueprints": []byte(`
+			mock_module_info {
+				name: "libfoo",
+				deps: ["libbar"],
+				required: ["foo_test"],
+				test_suites: ["vts"],
+			}
+
+			mock_module_info {
+				name: "libbar",
+			}
+
+			mock_module_info {
+				name: "libdisabled",
+				enabled: false,
+			}`),
+	})
+
+	_, errs := ctx.ParseBlueprintsFiles("Blueprints")
+	FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	FailIfErrored(t, errs)
+
+	data, err := ioutil.ReadFile(filepath.Join(buildDir, moduleInfoJsonFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modules map[string]*moduleInfoJson
+	if err := json.Unmarshal(data, &modules); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]*moduleInfoJson{
+		"foo_test": {
+			Class:                []string{"NATIVE_TESTS"},
+			Path:                 []string{"tests"},
+			Tags:                 []string{},
+			Installed:            []string{filepath.Join(buildDir, "target/product/test_device/data/nativetest64/foo_test/foo_test")},
+			Compatibility_suites: []string{"device-tests", "general-tests"},
+			Module_name:          "foo_test",
+			Test_config:          []string{"tests/AndroidTest.xml"},
+			Dependencies:         []string{},
+			Owner:                []string{"acme"},
+			Android_bp:           []string{"tests/Blueprints"},
+		},
+		"libfoo": {
+			Class:                []string{"SHARED_LIBRARIES"},
+			Path:                 []string{"libs"},
+			Tags:                 []string{},
+			Installed:            []string{filepath.Join(buildDir, "target/product/test_device/system/lib/libfoo")},
+			Compatibility_suites: []string{"vts"},
+			Module_name:          "libfoo",
+			Test_config:          []string{},
+			Dependencies:         []string{"foo_test", "libbar"},
+			Owner:                []string{},
+			Android_bp:           []string{"libs/Blueprints"},
+		},
+		"libbar": {
+			Class:                []string{"SHARED_LIBRARIES"},
+			Path:                 []string{"libs"},
+			Tags:                 []string{},
+			Installed:            []string{filepath.Join(buildDir, "target/product/test_device/system/lib/libbar")},
+			Compatibility_suites: []string{},
+			Module_name:          "libbar",
+			Test_config:          []string{},
+			Dependencies:         []string{},
+			Owner:                []string{},
+			Android_bp:           []string{"libs/Blueprints"},
+		},
+	}
+
+	if !reflect.DeepEqual(modules, expected) {
+		t.Errorf("expected module-info.json:\n%s\ngot:\n%s", toJson(t, expected), data)
+	}
+}
+
+func toJson(t *testing.T, v interface{}) []byte {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+type mockModuleInfoProperties struct {
+	Deps        []string
+	Test_suites []string
+}
+
+type mockModuleInfoModule struct {
+	ModuleBase
+	properties mockModuleInfoProperties
+
+	outputFile Path
+}
+
+func newMockModuleInfoModule() Module {
+	m := &mockModuleInfoModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidArchModule(m, DeviceSupported, MultilibCommon)
+	return m
+}
+
+func (m *mockModuleInfoModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddVariationDependencies(nil, linkageDependencyTag{linkage: SharedLinkage}, m.properties.Deps...)
+}
+
+func (m *mockModuleInfoModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.outputFile = PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.InstallFile(PathForModuleInstall(ctx, "lib"), ctx.ModuleName(), m.outputFile)
+}
+
+func (m *mockModuleInfoModule) AndroidMk() AndroidMkData {
+	return AndroidMkData{
+		Class:      "SHARED_LIBRARIES",
+		OutputFile: OptionalPathForPath(m.outputFile),
+	}
+}
+
+func (m *mockModuleInfoModule) TestSuites() []string {
+	return m.properties.Test_suites
+}
+
+func (m *mockModuleInfoModule) TestConfig() Path {
+	return nil
+}
@@ -77,8 +77,13 @@ var preArch = []RegisterMutatorFunc{
 		ctx.TopDown("load_hooks", LoadHookMutator).Parallel()
 	},
 	RegisterNamespaceMutator,
+	RegisterPackageMutator,
 	RegisterPrebuiltsPreArchMutators,
+	RegisterVisibilityRuleChecker,
 	RegisterDefaultsPreArchMutators,
+	RegisterPackageDefaultsMutator,
+	RegisterLicensesDepsMutator,
+	RegisterVisibilityRuleGatherer,
 	RegisterOverridePreArchMutators,
 }
 
@@ -95,6 +100,7 @@ var postDeps = []RegisterMutatorFunc{
 	registerPathDepsMutator,
 	RegisterPrebuiltsPostDepsMutators,
 	registerNeverallowMutator,
+	RegisterVisibilityRuleEnforcer,
 }
 
 func PreArchMutators(f RegisterMutatorFunc) {
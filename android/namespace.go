@@ -179,6 +179,12 @@ func (r *NameResolver) NewModule(ctx blueprint.NamespaceContext, moduleGroup blu
 		return nil, nil
 	}
 
+	// package modules are found by directory rather than by name, so don't save them into the
+	// namespace; every directory may contain one.
+	if _, ok := module.(*packageModule); ok {
+		return nil, nil
+	}
+
 	// if this module is not a namespace, then save it into the appropriate namespace
 	ns := r.findNamespaceFromCtx(ctx)
 
@@ -40,9 +40,17 @@ import (
 // - - if the property is a list, any of the values in the list being matches
 //     counts as a match
 // - it has none of the "without" properties matched (same rules as above)
+//
+// A rule with dependency predicates ("dependsOn", "dependsOnModuleType" or "withLinkage")
+// disallows dependency edges instead of modules.  A direct dependency of a module that matches
+// the rule as above is disallowed if all of the following are true:
+// - its name is one of the "dependsOn" names
+// - its module type is one of the "dependsOnModuleType" types
+// - the linkage of its dependency tag is one of the "withLinkage" linkages
 
 func registerNeverallowMutator(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("neverallow", neverallowMutator).Parallel()
+	ctx.TopDown("neverallow_deps", neverallowDepsMutator).Parallel()
 }
 
 var neverallows = createNeverAllows()
@@ -155,23 +163,46 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 		return
 	}
 
-	dir := ctx.ModuleDir() + "/"
-	properties := m.GetProperties()
+	// Record the module type so that the neverallow deps mutator can match it on dependencies.
+	m.base().commonProperties.Module_type = ctx.ModuleType()
+
+	for _, n := range neverallowRules(ctx.Config()) {
+		if n.hasDependencyPredicates() {
+			continue
+		}
 
-	for _, n := range neverallows {
-		if !n.appliesToPath(dir) {
+		if !n.appliesToModule(ctx, m) {
 			continue
 		}
 
-		if !n.appliesToModuleType(ctx.ModuleType()) {
+		ctx.ModuleErrorf("violates " + n.String())
+	}
+}
+
+func neverallowDepsMutator(ctx TopDownMutatorContext) {
+	m := ctx.Module()
+
+	for _, n := range neverallowRules(ctx.Config()) {
+		if !n.hasDependencyPredicates() {
 			continue
 		}
 
-		if !n.appliesToProperties(properties) {
+		if !n.appliesToModule(ctx, m) {
 			continue
 		}
 
-		ctx.ModuleErrorf("violates " + n.String())
+		ctx.VisitDirectDeps(func(dep Module) {
+			depName := ctx.OtherModuleName(dep)
+			depType := dep.base().commonProperties.Module_type
+			linkage := DependencyTagLinkage(ctx.OtherModuleDependencyTag(dep))
+
+			if !n.appliesToDependency(depName, depType, linkage) {
+				return
+			}
+
+			ctx.ModuleErrorf("violates %s: depends on %q of module type %q with %s linkage",
+				n.String(), depName, depType, linkage)
+		})
 	}
 }
 
@@ -184,6 +215,9 @@ type rule struct {
 	// User string for why this is a thing.
 	reason string
 
+	// Where the rule was declared, for rules loaded from policy files.
+	location string
+
 	paths       []string
 	unlessPaths []string
 
@@ -192,6 +226,10 @@ type rule struct {
 
 	props       []ruleProperty
 	unlessProps []ruleProperty
+
+	depNames       []string
+	depModuleTypes []string
+	depLinkages    []Linkage
 }
 
 func neverallow() *rule {
@@ -234,6 +272,21 @@ func (r *rule) without(properties, value string) *rule {
 	return r
 }
 
+func (r *rule) dependsOn(names ...string) *rule {
+	r.depNames = append(r.depNames, names...)
+	return r
+}
+
+func (r *rule) dependsOnModuleType(types ...string) *rule {
+	r.depModuleTypes = append(r.depModuleTypes, types...)
+	return r
+}
+
+func (r *rule) withLinkage(linkages ...Linkage) *rule {
+	r.depLinkages = append(r.depLinkages, linkages...)
+	return r
+}
+
 func (r *rule) because(reason string) *rule {
 	r.reason = reason
 	return r
@@ -259,12 +312,47 @@ func (r *rule) String() string {
 	for _, v := range r.unlessProps {
 		s += " -" + strings.Join(v.fields, ".") + "=" + v.value
 	}
+	for _, v := range r.depNames {
+		s += " dep:" + v
+	}
+	for _, v := range r.depModuleTypes {
+		s += " dep_type:" + v
+	}
+	for _, v := range r.depLinkages {
+		s += " linkage:" + v.String()
+	}
 	if len(r.reason) != 0 {
 		s += " which is restricted because " + r.reason
 	}
+	if len(r.location) != 0 {
+		s += " (rule declared in " + r.location + ")"
+	}
 	return s
 }
 
+func (r *rule) appliesToModule(ctx BaseModuleContext, m Module) bool {
+	return r.appliesToPath(ctx.ModuleDir()+"/") &&
+		r.appliesToModuleType(ctx.ModuleType()) &&
+		r.appliesToProperties(m.GetProperties())
+}
+
+func (r *rule) hasDependencyPredicates() bool {
+	return len(r.depNames) > 0 || len(r.depModuleTypes) > 0 || len(r.depLinkages) > 0
+}
+
+func (r *rule) appliesToDependency(name, moduleType string, linkage Linkage) bool {
+	if len(r.depNames) > 0 && !InList(name, r.depNames) {
+		return false
+	}
+	if len(r.depModuleTypes) > 0 && !InList(moduleType, r.depModuleTypes) {
+		return false
+	}
+	if len(r.depLinkages) > 0 && !inLinkageList(linkage, r.depLinkages) {
+		return false
+	}
+	return true
+}
+
 func (r *rule) appliesToPath(dir string) bool {
 	includePath := len(r.paths) == 0 || hasAnyPrefix(dir, r.paths)
 	excludePath := hasAnyPrefix(dir, r.unlessPaths)
@@ -299,6 +387,15 @@ func fieldNamesForProperties(propertyNames string) []string {
 	return names
 }
 
+func inLinkageList(linkage Linkage, list []Linkage) bool {
+	for _, l := range list {
+		if l == linkage {
+			return true
+		}
+	}
+	return false
+}
+
 func hasAnyPrefix(s string, prefixes []string) bool {
 	for _, prefix := range prefixes {
 		if strings.HasPrefix(s, prefix) {
@@ -0,0 +1,278 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// This file implements declarative neverallow rules, which are loaded from neverallow_rules
+// modules in Android.bp files and from the JSON policy files listed in the NeverallowRulesFiles
+// product variable, in addition to the rules built into neverallow.go.
+//
+// A JSON policy file contains a list of objects with the same properties as the
+// neverallow_rules module type, for example:
+//
+// [
+//     {
+//         "in": ["vendor/acme"],
+//         "module_type": ["cc_binary"],
+//         "with": ["static_executable=true"],
+//         "because": "acme binaries must be dynamically linked"
+//     }
+// ]
+
+func init() {
+	RegisterModuleType("neverallow_rules", NeverallowRulesFactory)
+	RegisterSingletonType("neverallow_rules_files", NeverallowRulesFilesSingleton)
+}
+
+type neverallowRuleProperties struct {
+	// Directories that the rule applies to.  Defaults to all directories.
+	In []string `json:"in"`
+
+	// Directories that the rule does not apply to.
+	Not_in []string `json:"not_in"`
+
+	// Module types that the rule applies to.  Defaults to all module types.
+	Module_type []string `json:"module_type"`
+
+	// Module types that the rule does not apply to.
+	Not_module_type []string `json:"not_module_type"`
+
+	// Property values that a module must all have for the rule to apply, in the form
+	// "property=value".  Nested properties are separated by '.', and a value of "*" matches
+	// any value.
+	With []string `json:"with"`
+
+	// Property values, in the same form as with, that prevent the rule from applying.
+	Without []string `json:"without"`
+
+	// Names of dependencies that the rule disallows.  If any of depends_on,
+	// depends_on_module_type or dependency_linkage is set the rule disallows the direct
+	// dependencies of the matching modules that match all of them, instead of the modules
+	// themselves.
+	Depends_on []string `json:"depends_on"`
+
+	// Module types of dependencies that the rule disallows.
+	Depends_on_module_type []string `json:"depends_on_module_type"`
+
+	// Linkages of dependencies that the rule disallows, each one of "static", "shared" or "none".
+	Dependency_linkage []string `json:"dependency_linkage"`
+
+	// The reason for the rule, included in the error reported for modules that violate it.
+	Because *string `json:"because"`
+}
+
+// newDeclarativeRule returns the rule described by props, or an error naming the property that
+// is malformed.
+func newDeclarativeRule(props *neverallowRuleProperties, location string) (*rule, string, error) {
+	r := neverallow().
+		in(props.In...).
+		notIn(props.Not_in...).
+		moduleType(props.Module_type...).
+		notModuleType(props.Not_module_type...).
+		dependsOn(props.Depends_on...).
+		dependsOnModuleType(props.Depends_on_module_type...).
+		because(String(props.Because))
+	r.location = location
+
+	for _, with := range props.With {
+		property, value, err := splitPropertyValue(with)
+		if err != nil {
+			return nil, "with", err
+		}
+		r.with(property, value)
+	}
+
+	for _, without := range props.Without {
+		property, value, err := splitPropertyValue(without)
+		if err != nil {
+			return nil, "without", err
+		}
+		r.without(property, value)
+	}
+
+	for _, linkage := range props.Dependency_linkage {
+		l, err := parseLinkage(linkage)
+		if err != nil {
+			return nil, "dependency_linkage", err
+		}
+		r.withLinkage(l)
+	}
+
+	return r, "", nil
+}
+
+func parseLinkage(s string) (Linkage, error) {
+	for _, l := range []Linkage{NoLinkage, StaticLinkage, SharedLinkage} {
+		if l.String() == s {
+			return l, nil
+		}
+	}
+	return NoLinkage, fmt.Errorf("unknown linkage %q, must be one of \"static\", \"shared\" or \"none\"", s)
+}
+
+func splitPropertyValue(s string) (string, string, error) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("%q must be of the form property=value", s)
+	}
+	return s[:i], s[i+1:], nil
+}
+
+type neverallowRulesModule struct {
+	ModuleBase
+
+	properties neverallowRuleProperties
+}
+
+func (m *neverallowRulesModule) DepsMutator(ctx BottomUpMutatorContext) {
+	// Record the rule here so that it is known to the neverallow mutator, which runs after
+	// dependencies have been added.
+	location := fmt.Sprintf("%s: neverallow_rules %q", ctx.BlueprintsFile(), ctx.ModuleName())
+	r, property, err := newDeclarativeRule(&m.properties, location)
+	if err != nil {
+		ctx.PropertyErrorf(property, "%s", err)
+		return
+	}
+	neverallowModuleRules(ctx.Config()).Store(ctx.ModuleName(), r)
+}
+
+func (m *neverallowRulesModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+// neverallow_rules declares a neverallow rule in an Android.bp file, which fails the build for
+// any module that matches it.  A module matches the rule if it is defined in one of the in
+// directories and none of the not_in directories, is of one of the module_type types and none
+// of the not_module_type types, and has all of the with property values and none of the without
+// property values.  If any of depends_on, depends_on_module_type or dependency_linkage is set
+// the rule instead fails the build for the dependencies of matching modules that match all of
+// them.  Violations are reported together with the location of the neverallow_rules module.
+func NeverallowRulesFactory() Module {
+	module := &neverallowRulesModule{}
+
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+var neverallowModuleRulesKey = NewOnceKey("neverallowModuleRules")
+
+// neverallowModuleRules returns the map from the name of each neverallow_rules module to its rule.
+func neverallowModuleRules(config Config) *sync.Map {
+	return config.Once(neverallowModuleRulesKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+type neverallowFileRules struct {
+	rules []*rule
+	err   error
+}
+
+var neverallowFileRulesKey = NewOnceKey("neverallowFileRules")
+
+// neverallowRulesFromFiles returns the rules loaded from the files listed in the
+// NeverallowRulesFiles product variable.  Errors are reported by the neverallow_rules_files
+// singleton.
+func neverallowRulesFromFiles(config Config) neverallowFileRules {
+	return config.Once(neverallowFileRulesKey, func() interface{} {
+		var ret neverallowFileRules
+		for _, file := range config.NeverallowRulesFiles() {
+			rules, err := loadNeverallowRulesFile(file)
+			if err != nil {
+				ret.err = err
+				break
+			}
+			ret.rules = append(ret.rules, rules...)
+		}
+		return ret
+	}).(neverallowFileRules)
+}
+
+func loadNeverallowRulesFile(file string) ([]*rule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open neverallow rules file: %s", err)
+	}
+	defer f.Close()
+
+	var propsList []neverallowRuleProperties
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&propsList); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse neverallow rules: %s", file, err)
+	}
+
+	var rules []*rule
+	for i := range propsList {
+		location := fmt.Sprintf("%s: rule %d", file, i+1)
+		r, property, err := newDeclarativeRule(&propsList[i], location)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %s", location, property, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+var neverallowRulesKey = NewOnceKey("neverallowRules")
+
+// neverallowRules returns all the neverallow rules: the rules built into Soong, followed by the
+// rules from policy files and the rules from neverallow_rules modules ordered by module name.
+// It must not be called before the deps mutator has recorded the rules of every neverallow_rules
+// module.
+func neverallowRules(config Config) []*rule {
+	return config.Once(neverallowRulesKey, func() interface{} {
+		rules := append([]*rule(nil), neverallows...)
+		rules = append(rules, neverallowRulesFromFiles(config).rules...)
+
+		var names []string
+		moduleRules := neverallowModuleRules(config)
+		moduleRules.Range(func(key, value interface{}) bool {
+			names = append(names, key.(string))
+			return true
+		})
+		sort.Strings(names)
+		for _, name := range names {
+			r, _ := moduleRules.Load(name)
+			rules = append(rules, r.(*rule))
+		}
+
+		return rules
+	}).([]*rule)
+}
+
+func NeverallowRulesFilesSingleton() Singleton {
+	return &neverallowRulesFilesSingleton{}
+}
+
+type neverallowRulesFilesSingleton struct{}
+
+func (s *neverallowRulesFilesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	files := ctx.Config().NeverallowRulesFiles()
+	if err := neverallowRulesFromFiles(ctx.Config()).err; err != nil {
+		ctx.Errorf("%s", err)
+	}
+
+	// Rerun soong_build if any of the policy files change.
+	ctx.AddNinjaFileDeps(files...)
+}
@@ -17,6 +17,7 @@ package android
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -184,6 +185,8 @@ func testNeverallow(t *testing.T, config Config, fs map[string][]byte) (*TestCon
 	ctx.RegisterModuleType("cc_library", ModuleFactoryAdaptor(newMockCcLibraryModule))
 	ctx.RegisterModuleType("java_library", ModuleFactoryAdaptor(newMockJavaLibraryModule))
 	ctx.RegisterModuleType("java_device_for_host", ModuleFactoryAdaptor(newMockJavaLibraryModule))
+	ctx.RegisterModuleType("neverallow_rules", ModuleFactoryAdaptor(NeverallowRulesFactory))
+	ctx.RegisterSingletonType("neverallow_rules_files", SingletonFactoryAdaptor(NeverallowRulesFilesSingleton))
 	ctx.PostDepsMutators(registerNeverallowMutator)
 	ctx.Register()
 
@@ -198,9 +201,229 @@ func testNeverallow(t *testing.T, config Config, fs map[string][]byte) (*TestCon
 	return ctx, errs
 }
 
+var neverallowRulesTests = []struct {
+	name          string
+	fs            map[string][]byte
+	rulesFile     string
+	expectedError string
+}{
+	{
+		name: "neverallow_rules module",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				neverallow_rules {
+					name: "acme_policy",
+					in: ["vendor/acme"],
+					module_type: ["cc_library"],
+					with: ["vendor_available=true"],
+					because: "acme libraries must not be vendor_available",
+				}`),
+			"vendor/acme/Blueprints": []byte(`
+				cc_library {
+					name: "libacme",
+					vendor_available: true,
+				}`),
+		},
+		expectedError: `module "libacme": violates neverallow dir:vendor/acme/\* type:cc_library ` +
+			`Vendor_available=true which is restricted because acme libraries must not be ` +
+			`vendor_available \(rule declared in vendor/Blueprints: neverallow_rules "acme_policy"\)`,
+	},
+	{
+		name: "neverallow_rules module does not match",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				neverallow_rules {
+					name: "acme_policy",
+					in: ["vendor/acme"],
+					with: ["vendor_available=true"],
+				}`),
+			"vendor/other/Blueprints": []byte(`
+				cc_library {
+					name: "libother",
+					vendor_available: true,
+				}`),
+			"vendor/acme/Blueprints": []byte(`
+				cc_library {
+					name: "libacme",
+				}
+				java_library {
+					name: "libacme_java",
+				}`),
+		},
+	},
+	{
+		name: "neverallow_rules module with malformed property",
+		fs: map[string][]byte{
+			"Blueprints": []byte(`
+				neverallow_rules {
+					name: "bad_policy",
+					without: ["vendor_available"],
+				}`),
+		},
+		expectedError: `module "bad_policy": without: "vendor_available" must be of the form property=value`,
+	},
+	{
+		name: "neverallow_rules module on dependency names",
+		fs: map[string][]byte{
+			"Blueprints": []byte(`
+				neverallow_rules {
+					name: "no_internal",
+					not_in: ["internal"],
+					depends_on: ["libfoo_internal"],
+					because: "libfoo_internal is internal",
+				}`),
+			"internal/Blueprints": []byte(`
+				cc_library {
+					name: "libfoo_internal",
+				}
+
+				cc_library {
+					name: "libfoo",
+					static_libs: ["libfoo_internal"],
+				}`),
+			"vendor/Blueprints": []byte(`
+				cc_library {
+					name: "libvendor",
+					shared_libs: ["libfoo_internal"],
+				}`),
+		},
+		expectedError: `module "libvendor": violates neverallow -dir:internal/\* dep:libfoo_internal ` +
+			`which is restricted because libfoo_internal is internal \(rule declared in ` +
+			`Blueprints: neverallow_rules "no_internal"\): depends on "libfoo_internal" of module ` +
+			`type "cc_library" with shared linkage`,
+	},
+	{
+		name: "neverallow_rules module on dependency module types and linkage",
+		fs: map[string][]byte{
+			"Blueprints": []byte(`
+				neverallow_rules {
+					name: "no_static_java",
+					module_type: ["cc_library"],
+					depends_on_module_type: ["java_library"],
+					dependency_linkage: ["static"],
+				}
+
+				java_library {
+					name: "libjava",
+				}
+
+				cc_library {
+					name: "libstatic",
+					static_libs: ["libjava"],
+				}
+
+				cc_library {
+					name: "libshared",
+					shared_libs: ["libjava"],
+				}`),
+		},
+		expectedError: `module "libstatic": violates neverallow type:cc_library dep_type:java_library ` +
+			`linkage:static \(rule declared in Blueprints: neverallow_rules "no_static_java"\): ` +
+			`depends on "libjava" of module type "java_library" with static linkage`,
+	},
+	{
+		name: "neverallow_rules module with unknown linkage",
+		fs: map[string][]byte{
+			"Blueprints": []byte(`
+				neverallow_rules {
+					name: "bad_policy",
+					dependency_linkage: ["dynamic"],
+				}`),
+		},
+		expectedError: `module "bad_policy": dependency_linkage: unknown linkage "dynamic"`,
+	},
+	{
+		name: "rules file",
+		fs: map[string][]byte{
+			"vendor/acme/Blueprints": []byte(`
+				java_library {
+					name: "libacme",
+					libs: ["libinternal"],
+				}`),
+		},
+		rulesFile: `[
+			{
+				"in": ["vendor"],
+				"not_module_type": ["cc_library"],
+				"with": ["libs=libinternal"],
+				"because": "libinternal is internal"
+			}
+		]`,
+		expectedError: `module "libacme": violates neverallow dir:vendor/\* -type:cc_library ` +
+			`Libs=libinternal which is restricted because libinternal is internal ` +
+			`\(rule declared in .*/neverallow.json: rule 1\)`,
+	},
+	{
+		name: "malformed rules file",
+		fs: map[string][]byte{
+			"Blueprints": []byte(`
+				cc_library {
+					name: "libfoo",
+				}`),
+		},
+		rulesFile: `[
+			{
+				"in": ["vendor"],
+				"because": "missing a comma"
+				"with": ["libs=libinternal"]
+			}
+		]`,
+		expectedError: `neverallow.json: failed to parse neverallow rules`,
+	},
+	{
+		name: "rules file with unknown property",
+		fs: map[string][]byte{
+			"Blueprints": []byte(`
+				cc_library {
+					name: "libfoo",
+				}`),
+		},
+		rulesFile: `[
+			{
+				"in_dirs": ["vendor"]
+			}
+		]`,
+		expectedError: `neverallow.json: failed to parse neverallow rules: json: unknown field "in_dirs"`,
+	},
+}
+
+func TestNeverallowRules(t *testing.T) {
+	for _, test := range neverallowRulesTests {
+		t.Run(test.name, func(t *testing.T) {
+			buildDir, err := ioutil.TempDir("", "soong_neverallow_rules_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(buildDir)
+
+			// Create a new config per test as neverallow rules are stored in the config.
+			config := TestConfig(buildDir, nil)
+
+			if test.rulesFile != "" {
+				rulesFile := filepath.Join(buildDir, "neverallow.json")
+				if err := ioutil.WriteFile(rulesFile, []byte(test.rulesFile), 0666); err != nil {
+					t.Fatal(err)
+				}
+				config.productVariables.NeverallowRulesFiles = []string{rulesFile}
+			}
+
+			_, errs := testNeverallow(t, config, test.fs)
+
+			if test.expectedError == "" {
+				FailIfErrored(t, errs)
+			} else {
+				FailIfNoMatchingErrors(t, test.expectedError, errs)
+			}
+		})
+	}
+}
+
 type mockCcLibraryProperties struct {
 	Vendor_available *bool
 
+	Static_libs []string
+	Shared_libs []string
+
 	Vndk struct {
 		Enabled                *bool
 		Support_system_process *bool
@@ -230,6 +453,11 @@ func newMockCcLibraryModule() Module {
 	return m
 }
 
+func (p *mockCcLibraryModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), linkageDependencyTag{linkage: StaticLinkage}, p.properties.Static_libs...)
+	ctx.AddDependency(ctx.Module(), linkageDependencyTag{linkage: SharedLinkage}, p.properties.Shared_libs...)
+}
+
 func (p *mockCcLibraryModule) GenerateAndroidBuildActions(ModuleContext) {
 }
 
@@ -0,0 +1,187 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+// This file implements the package module, which provides default values for properties of all
+// the modules defined in the same directory and its subdirectories.
+
+func init() {
+	RegisterModuleType("package", PackageFactory)
+}
+
+type packageProperties struct {
+	// Specifies the default visibility for all modules defined in this package and its
+	// subpackages that do not set the visibility property themselves.  Relative rules such as
+	// ":__subpackages__" are resolved against the directory of this package module.
+	Default_visibility []string
+
+	// Specifies the default license modules for all modules defined in this package and its
+	// subpackages that do not set the licenses property themselves.
+	Default_licenses []string
+
+	// Specifies the default owner for all modules defined in this package and its subpackages
+	// that do not set the owner property themselves.
+	Default_owner *string
+
+	// Specifies whether all modules defined in this package and its subpackages are specific
+	// to an SoC, unless they set one of the soc_specific, vendor, proprietary, device_specific,
+	// product_specific or product_services_specific properties themselves.
+	Default_soc_specific *bool
+}
+
+type packageModule struct {
+	ModuleBase
+
+	properties packageProperties
+
+	// The directory of the Android.bp file that defines this package module.
+	dir string
+}
+
+func (p *packageModule) GenerateAndroidBuildActions(ModuleContext) {
+}
+
+func (p *packageModule) GenerateBuildActions(blueprint.ModuleContext) {
+}
+
+func (p *packageModule) Name() string {
+	return "package"
+}
+
+// package provides default values for properties of all the modules in an Android.bp file and
+// the Android.bp files in its subdirectories.  A package module in a subdirectory overrides the
+// defaults provided by package modules in its parent directories for the properties it sets,
+// and inherits the rest.  Modules always take precedence over the defaults they receive from
+// their package, either by setting a property directly or through a defaults module.  At most
+// one package module may be defined per directory.
+func PackageFactory() Module {
+	module := &packageModule{}
+
+	module.AddProperties(&module.properties)
+	return module
+}
+
+var packagesKey = NewOnceKey("packages")
+
+// packageMap returns the map from directory to the package module defined in that directory.
+func packageMap(config Config) *sync.Map {
+	return config.Once(packagesKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+// findPackage returns the package module closest to dir, searching dir and then each of its
+// parent directories, for which has returns true, or nil if there is no such package module.
+func findPackage(config Config, dir string, has func(*packageProperties) bool) *packageModule {
+	packages := packageMap(config)
+	for {
+		if p, ok := packages.Load(dir); ok {
+			if pkg := p.(*packageModule); has(&pkg.properties) {
+				return pkg
+			}
+		}
+		if dir == "." || dir == "/" {
+			return nil
+		}
+		dir = parentDir(dir)
+	}
+}
+
+// The package mutator needs to run before any mutator that calls findPackage so that every
+// package module has been recorded.
+func RegisterPackageMutator(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("packages", packageMutator).Parallel()
+}
+
+// The package defaults mutator needs to run after defaults have been applied so that modules
+// only receive package defaults for properties that were not set by a defaults module.
+func RegisterPackageDefaultsMutator(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("package_defaults", packageDefaultsMutator).Parallel()
+}
+
+func packageMutator(ctx BottomUpMutatorContext) {
+	if p, ok := ctx.Module().(*packageModule); ok {
+		p.dir = ctx.ModuleDir()
+
+		if visibility := p.properties.Default_visibility; visibility != nil {
+			checkVisibilityRulesForProperty(ctx, "default_visibility", visibilityPackage(p.dir), visibility)
+		}
+
+		if _, loaded := packageMap(ctx.Config()).LoadOrStore(p.dir, p); loaded {
+			ctx.ModuleErrorf("a package module has already been defined in %q", p.dir)
+		}
+	}
+}
+
+func packageDefaultsMutator(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(Module)
+	if !ok {
+		return
+	}
+	if _, ok := m.(*packageModule); ok {
+		return
+	}
+	if _, ok := m.(Defaults); ok {
+		return
+	}
+
+	props := &m.base().commonProperties
+	dir := ctx.ModuleDir()
+
+	if props.Licenses == nil && !isLicenseModule(m) {
+		if p := findPackage(ctx.Config(), dir, func(p *packageProperties) bool {
+			return p.Default_licenses != nil
+		}); p != nil {
+			props.Licenses = CopyOf(p.properties.Default_licenses)
+		}
+	}
+
+	if props.Owner == nil {
+		if p := findPackage(ctx.Config(), dir, func(p *packageProperties) bool {
+			return p.Default_owner != nil
+		}); p != nil {
+			owner := *p.properties.Default_owner
+			props.Owner = &owner
+		}
+	}
+
+	if props.Vendor == nil && props.Proprietary == nil && props.Soc_specific == nil &&
+		props.Device_specific == nil && props.Product_specific == nil &&
+		props.Product_services_specific == nil {
+		if p := findPackage(ctx.Config(), dir, func(p *packageProperties) bool {
+			return p.Default_soc_specific != nil
+		}); p != nil {
+			props.Soc_specific = boolPtr(*p.properties.Default_soc_specific)
+		}
+	}
+}
+
+// packageDefaultVisibility returns the default visibility rules from the closest package module
+// to dir that sets default_visibility, or nil if there are none.
+func packageDefaultVisibility(config Config, dir string) compositeRule {
+	p := findPackage(config, dir, func(p *packageProperties) bool {
+		return p.Default_visibility != nil
+	})
+	if p == nil {
+		return nil
+	}
+	return parseVisibilityRules(visibilityPackage(p.dir), p.properties.Default_visibility)
+}
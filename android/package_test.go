@@ -0,0 +1,249 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var packageTests = []struct {
+	name           string
+	fs             map[string][]byte
+	expectedErrors []string
+	expectedOwners map[string]string
+	expectedSoc    map[string]bool
+}{
+	{
+		name: "default_owner is inherited by subpackages",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				package {
+					default_owner: "acme",
+				}
+
+				mock_library {
+					name: "libtop",
+				}`),
+			"vendor/nested/Blueprints": []byte(`
+				mock_library {
+					name: "libnested",
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+				}`),
+		},
+		expectedOwners: map[string]string{
+			"libtop":    "acme",
+			"libnested": "acme",
+			"libother":  "",
+		},
+	},
+	{
+		name: "closest package wins per property",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				package {
+					default_owner: "acme",
+					default_soc_specific: true,
+				}`),
+			"vendor/nested/Blueprints": []byte(`
+				package {
+					default_owner: "widgets",
+				}
+
+				mock_library {
+					name: "libnested",
+				}`),
+		},
+		expectedOwners: map[string]string{
+			"libnested": "widgets",
+		},
+		expectedSoc: map[string]bool{
+			"libnested": true,
+		},
+	},
+	{
+		name: "module and defaults properties take precedence",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				package {
+					default_owner: "acme",
+					default_soc_specific: true,
+				}
+
+				mock_defaults {
+					name: "libdefaults",
+					owner: "defaults",
+				}
+
+				mock_library {
+					name: "libexplicit",
+					owner: "explicit",
+					product_specific: true,
+				}
+
+				mock_library {
+					name: "libdefaulted",
+					defaults: ["libdefaults"],
+				}`),
+		},
+		expectedOwners: map[string]string{
+			"libexplicit":  "explicit",
+			"libdefaulted": "defaults",
+		},
+		expectedSoc: map[string]bool{
+			"libexplicit":  false,
+			"libdefaulted": true,
+		},
+	},
+	{
+		name: "only one package per directory",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				package {
+					default_owner: "acme",
+				}
+
+				package {
+					default_owner: "widgets",
+				}`),
+		},
+		expectedErrors: []string{`a package module has already been defined in "vendor"`},
+	},
+	{
+		name: "invalid default_visibility",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				package {
+					default_visibility: ["//visibility:public", "//other"],
+				}`),
+		},
+		expectedErrors: []string{`default_visibility: cannot mix "//visibility:public" with any other visibility rules`},
+	},
+	{
+		name: "default_visibility is relative to the package",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				package {
+					default_visibility: [":__subpackages__"],
+				}`),
+			"vendor/nested/Blueprints": []byte(`
+				mock_library {
+					name: "libnested",
+				}`),
+			"vendor/other/Blueprints": []byte(`
+				mock_library {
+					name: "libvendor",
+					deps: ["libnested"],
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libnested"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libother" variant "android_common": depends on //vendor/nested:libnested which is not` +
+				` visible to this module`,
+		},
+	},
+	{
+		name: "visibility property overrides default_visibility",
+		fs: map[string][]byte{
+			"vendor/Blueprints": []byte(`
+				package {
+					default_visibility: ["//visibility:private"],
+				}
+
+				mock_library {
+					name: "libvendor",
+					visibility: ["//other"],
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libvendor"],
+				}`),
+		},
+	},
+}
+
+func TestPackage(t *testing.T) {
+	buildDir, err := ioutil.TempDir("", "soong_package_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	for _, test := range packageTests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, errs := testPackage(buildDir, test.fs)
+
+			if test.expectedErrors == nil {
+				FailIfErrored(t, errs)
+			} else {
+				for _, expectedError := range test.expectedErrors {
+					FailIfNoMatchingErrors(t, expectedError, errs)
+				}
+				return
+			}
+
+			for name, expected := range test.expectedOwners {
+				m := ctx.ModuleForTests(name, "android_common").Module()
+				if owner := m.base().Owner(); owner != expected {
+					t.Errorf("expected owner of %q to be %q, got %q", name, expected, owner)
+				}
+			}
+
+			for name, expected := range test.expectedSoc {
+				m := ctx.ModuleForTests(name, "android_common").Module()
+				if soc := m.base().SocSpecific(); soc != expected {
+					t.Errorf("expected soc_specific of %q to be %t, got %t", name, expected, soc)
+				}
+			}
+		})
+	}
+}
+
+func testPackage(buildDir string, fs map[string][]byte) (*TestContext, []error) {
+
+	// Create a new config per test as package information is stored in the config.
+	config := TestArchConfig(buildDir, nil)
+
+	ctx := NewTestArchContext()
+	ctx.RegisterModuleType("package", ModuleFactoryAdaptor(PackageFactory))
+	ctx.RegisterModuleType("mock_library", ModuleFactoryAdaptor(newMockLibraryModule))
+	ctx.RegisterModuleType("mock_defaults", ModuleFactoryAdaptor(defaultsFactory))
+	ctx.PreArchMutators(RegisterPackageMutator)
+	ctx.PreArchMutators(RegisterVisibilityRuleChecker)
+	ctx.PreArchMutators(RegisterDefaultsPreArchMutators)
+	ctx.PreArchMutators(RegisterPackageDefaultsMutator)
+	ctx.PreArchMutators(RegisterVisibilityRuleGatherer)
+	ctx.PostDepsMutators(RegisterVisibilityRuleEnforcer)
+	ctx.Register()
+
+	ctx.MockFileSystem(fs)
+
+	_, errs := ctx.ParseBlueprintsFiles("Blueprints")
+	if len(errs) > 0 {
+		return ctx, errs
+	}
+
+	_, errs = ctx.PrepareBuildActions(config)
+	return ctx, errs
+}
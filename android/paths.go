@@ -1015,11 +1015,11 @@ func pathForModule(ctx ModuleContext) OutputPath {
 	return PathForOutput(ctx, ".intermediates", ctx.ModuleDir(), ctx.ModuleName(), ctx.ModuleSubDir())
 }
 
-// PathForVndkRefAbiDump returns an OptionalPath representing the path of the
-// reference abi dump for the given module. This is not guaranteed to be valid.
-func PathForVndkRefAbiDump(ctx ModuleContext, version, fileName string,
-	isLlndk, isGzip bool) OptionalPath {
-
+// RefAbiDumpPath returns the path relative to the top of the source tree of the reference abi
+// dump fileName for the current architecture in dir, which is laid out like
+// prebuilts/abi-dumps/vndk: <dir>/<version>/<binder bitness>/<arch>_<arch variant>/source-based/.
+// The returned path does not necessarily exist.
+func RefAbiDumpPath(ctx ModuleContext, dir, version, fileName string) string {
 	arches := ctx.DeviceConfig().Arches()
 	if len(arches) == 0 {
 		panic("device build with no primary arch")
@@ -1030,25 +1030,9 @@ func PathForVndkRefAbiDump(ctx ModuleContext, version, fileName string,
 		archNameAndVariant += "_" + currentArch.ArchVariant
 	}
 
-	var dirName string
-	if isLlndk {
-		dirName = "ndk"
-	} else {
-		dirName = "vndk"
-	}
-
 	binderBitness := ctx.DeviceConfig().BinderBitness()
 
-	var ext string
-	if isGzip {
-		ext = ".lsdump.gz"
-	} else {
-		ext = ".lsdump"
-	}
-
-	return ExistentPathForSource(ctx, "prebuilts", "abi-dumps", dirName,
-		version, binderBitness, archNameAndVariant, "source-based",
-		fileName+ext)
+	return filepath.Join(dir, version, binderBitness, archNameAndVariant, "source-based", fileName)
 }
 
 // PathForModuleOut returns a Path representing the paths... under the module's
@@ -1233,6 +1217,14 @@ func (p PhonyPath) writablePath() {}
 var _ Path = PhonyPath{}
 var _ WritablePath = PhonyPath{}
 
+// outsidePath is a path outside of the source and output directories, such as the files that
+// soong_ui writes to $OUT_DIR.
+type outsidePath struct {
+	basePath
+}
+
+var _ Path = outsidePath{}
+
 type testPath struct {
 	basePath
 }
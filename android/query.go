@@ -0,0 +1,777 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+// This file implements queries over the module graph, used by soong_build --soong_query.
+//
+// A query is an expression that evaluates to a set of module variants:
+// - "name" matches all the variants of the module with that name
+// - "//dir:name" matches all the variants of the module with that name defined in dir
+// - "//dir" or "//dir:all" matches all the modules defined in dir
+// - "//dir/..." matches all the modules defined in dir and its subdirectories, "//..." matches
+//   all modules
+// - "deps(x)" and "deps(x, depth)" match x and the modules that x transitively depends on
+// - "rdeps(u, x)" and "rdeps(u, x, depth)" match the modules in deps(u) that transitively
+//   depend on x, including x
+// - "somepath(x, y)" matches the modules on one dependency path from x to y
+// - "allpaths(x, y)" matches the modules on all the dependency paths from x to y
+// - "kind(pattern, x)" matches the modules in x whose module type matches the regular
+//   expression pattern
+// - "attr(name, pattern, x)" matches the modules in x with a property name, where nested
+//   properties are separated by '.', whose value matches the regular expression pattern.  A
+//   list property matches if any of its values match.
+// - "x + y", "x - y" and "x ^ y" are the union, difference and intersection of x and y, and are
+//   evaluated left to right.  Parentheses can be used for grouping.
+//
+// Operators must be separated from their operands by whitespace.  Words containing whitespace,
+// parentheses or commas, such as most regular expressions, must be quoted with double quotes.
+
+// QueryGraph is the module graph that queries are evaluated against, usually a Context after
+// its mutators have run.
+type QueryGraph interface {
+	VisitAllModules(visit func(blueprint.Module))
+	VisitDirectDeps(module blueprint.Module, visit func(blueprint.Module))
+	ModuleName(module blueprint.Module) string
+	ModuleDir(module blueprint.Module) string
+	ModuleType(module blueprint.Module) string
+	ModuleSubDir(module blueprint.Module) string
+}
+
+// The output formats of RunQuery.
+const (
+	QueryFormatText  = "text"
+	QueryFormatJson  = "json"
+	QueryFormatGraph = "graph"
+)
+
+// RunQuery evaluates query against graph and writes the matching modules to w in format, one of
+// QueryFormatText, QueryFormatJson or QueryFormatGraph.  Modules are written so that each
+// module comes before the modules it depends on.
+func RunQuery(graph QueryGraph, query, format string, w io.Writer) error {
+	switch format {
+	case QueryFormatText, QueryFormatJson, QueryFormatGraph:
+	default:
+		return fmt.Errorf("unknown query output format %q, must be %q, %q or %q",
+			format, QueryFormatText, QueryFormatJson, QueryFormatGraph)
+	}
+
+	expr, err := parseQuery(query)
+	if err != nil {
+		return err
+	}
+
+	q := newQueryGraph(graph)
+	result, err := expr.eval(q)
+	if err != nil {
+		return err
+	}
+	modules := q.ordered(result)
+
+	switch format {
+	case QueryFormatJson:
+		return q.writeJson(w, modules)
+	case QueryFormatGraph:
+		return q.writeGraph(w, modules, result)
+	default:
+		for _, m := range modules {
+			if _, err := fmt.Fprintln(w, q.id(m)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// moduleSet is a set of module variants.
+type moduleSet map[blueprint.Module]bool
+
+// queryGraph caches the module graph in both directions.
+type queryGraph struct {
+	graph QueryGraph
+
+	// All the module variants, sorted by name, directory and variant.
+	modules []blueprint.Module
+
+	deps  map[blueprint.Module][]blueprint.Module
+	rdeps map[blueprint.Module][]blueprint.Module
+}
+
+func newQueryGraph(graph QueryGraph) *queryGraph {
+	q := &queryGraph{
+		graph: graph,
+		deps:  make(map[blueprint.Module][]blueprint.Module),
+		rdeps: make(map[blueprint.Module][]blueprint.Module),
+	}
+
+	graph.VisitAllModules(func(m blueprint.Module) {
+		q.modules = append(q.modules, m)
+	})
+
+	sort.SliceStable(q.modules, func(i, j int) bool {
+		a, b := q.modules[i], q.modules[j]
+		if an, bn := graph.ModuleName(a), graph.ModuleName(b); an != bn {
+			return an < bn
+		}
+		if ad, bd := graph.ModuleDir(a), graph.ModuleDir(b); ad != bd {
+			return ad < bd
+		}
+		return graph.ModuleSubDir(a) < graph.ModuleSubDir(b)
+	})
+
+	for _, m := range q.modules {
+		seen := make(moduleSet)
+		graph.VisitDirectDeps(m, func(dep blueprint.Module) {
+			if !seen[dep] {
+				seen[dep] = true
+				q.deps[m] = append(q.deps[m], dep)
+				q.rdeps[dep] = append(q.rdeps[dep], m)
+			}
+		})
+	}
+
+	return q
+}
+
+// label returns the "//dir:name" label of a module.
+func (q *queryGraph) label(m blueprint.Module) string {
+	return "//" + visibilityPackage(q.graph.ModuleDir(m)) + ":" + q.graph.ModuleName(m)
+}
+
+// id returns the label of a module followed by its variant, if it has one.
+func (q *queryGraph) id(m blueprint.Module) string {
+	if variant := q.graph.ModuleSubDir(m); variant != "" {
+		return q.label(m) + " " + variant
+	}
+	return q.label(m)
+}
+
+// matchTarget returns the modules that match a target pattern.
+func (q *queryGraph) matchTarget(pattern string) (moduleSet, error) {
+	set := make(moduleSet)
+
+	if !strings.HasPrefix(pattern, "//") {
+		for _, m := range q.modules {
+			if q.graph.ModuleName(m) == pattern {
+				set[m] = true
+			}
+		}
+		if len(set) == 0 {
+			return nil, fmt.Errorf("no module named %q", pattern)
+		}
+		return set, nil
+	}
+
+	pkg, name := pattern[2:], ""
+	if i := strings.Index(pkg, ":"); i >= 0 {
+		pkg, name = pkg[:i], pkg[i+1:]
+	}
+
+	recursive := false
+	if pkg == "..." {
+		pkg, recursive = "", true
+	} else if strings.HasSuffix(pkg, "/...") {
+		pkg, recursive = strings.TrimSuffix(pkg, "/..."), true
+	}
+	allNames := name == "" || name == "all"
+
+	for _, m := range q.modules {
+		dir := visibilityPackage(q.graph.ModuleDir(m))
+		if recursive {
+			if pkg != "" && dir != pkg && !strings.HasPrefix(dir, pkg+"/") {
+				continue
+			}
+		} else if dir != pkg {
+			continue
+		}
+		if !allNames && q.graph.ModuleName(m) != name {
+			continue
+		}
+		set[m] = true
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("no modules match %q", pattern)
+	}
+	return set, nil
+}
+
+// queryClosure returns the modules reachable from set through edges, including set, following
+// at most depth edges if depth is not negative.  Only modules for which include returns true
+// are followed.
+func queryClosure(set moduleSet, edges map[blueprint.Module][]blueprint.Module, depth int,
+	include func(blueprint.Module) bool) moduleSet {
+
+	result := make(moduleSet)
+	var queue []blueprint.Module
+	for m := range set {
+		if include(m) {
+			result[m] = true
+			queue = append(queue, m)
+		}
+	}
+
+	for level := 0; len(queue) > 0 && (depth < 0 || level < depth); level++ {
+		var next []blueprint.Module
+		for _, m := range queue {
+			for _, e := range edges[m] {
+				if !result[e] && include(e) {
+					result[e] = true
+					next = append(next, e)
+				}
+			}
+		}
+		queue = next
+	}
+
+	return result
+}
+
+func queryAll(blueprint.Module) bool { return true }
+
+func (q *queryGraph) somepath(from, to moduleSet) moduleSet {
+	// Breadth first search from each module in from, in order, for the shortest path to any
+	// module in to.
+	for _, start := range q.modules {
+		if !from[start] {
+			continue
+		}
+		parent := map[blueprint.Module]blueprint.Module{start: nil}
+		queue := []blueprint.Module{start}
+		for len(queue) > 0 {
+			m := queue[0]
+			queue = queue[1:]
+			if to[m] {
+				path := make(moduleSet)
+				for ; m != nil; m = parent[m] {
+					path[m] = true
+				}
+				return path
+			}
+			for _, dep := range q.deps[m] {
+				if _, seen := parent[dep]; !seen {
+					parent[dep] = m
+					queue = append(queue, dep)
+				}
+			}
+		}
+	}
+	return moduleSet{}
+}
+
+// ordered returns the modules in set ordered so that each module comes before the modules in
+// set that it depends on.
+func (q *queryGraph) ordered(set moduleSet) []blueprint.Module {
+	var postOrder []blueprint.Module
+	visited := make(moduleSet)
+
+	var visit func(m blueprint.Module)
+	visit = func(m blueprint.Module) {
+		visited[m] = true
+		for _, dep := range q.deps[m] {
+			if set[dep] && !visited[dep] {
+				visit(dep)
+			}
+		}
+		postOrder = append(postOrder, m)
+	}
+
+	for _, m := range q.modules {
+		if set[m] && !visited[m] {
+			visit(m)
+		}
+	}
+
+	result := make([]blueprint.Module, len(postOrder))
+	for i, m := range postOrder {
+		result[len(postOrder)-1-i] = m
+	}
+	return result
+}
+
+type queryJsonModule struct {
+	Name    string   `json:"name"`
+	Dir     string   `json:"dir"`
+	Type    string   `json:"type"`
+	Variant string   `json:"variant"`
+	Deps    []string `json:"deps"`
+}
+
+func (q *queryGraph) writeJson(w io.Writer, modules []blueprint.Module) error {
+	list := make([]queryJsonModule, 0, len(modules))
+	for _, m := range modules {
+		deps := []string{}
+		for _, dep := range q.deps[m] {
+			deps = append(deps, q.id(dep))
+		}
+		list = append(list, queryJsonModule{
+			Name:    q.graph.ModuleName(m),
+			Dir:     q.graph.ModuleDir(m),
+			Type:    q.graph.ModuleType(m),
+			Variant: q.graph.ModuleSubDir(m),
+			Deps:    deps,
+		})
+	}
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// writeGraph writes the modules and the dependencies between them in the graphviz dot format.
+func (q *queryGraph) writeGraph(w io.Writer, modules []blueprint.Module, set moduleSet) error {
+	buf := &strings.Builder{}
+	fmt.Fprintln(buf, "digraph soong_query {")
+	for _, m := range modules {
+		fmt.Fprintf(buf, "  %q;\n", q.id(m))
+	}
+	for _, m := range modules {
+		for _, dep := range q.deps[m] {
+			if set[dep] {
+				fmt.Fprintf(buf, "  %q -> %q;\n", q.id(m), q.id(dep))
+			}
+		}
+	}
+	fmt.Fprintln(buf, "}")
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// queryPropertyMatches returns true if any of the values of the property with the given field
+// names in the property structs of m match re.
+func queryPropertyMatches(m blueprint.Module, fields []string, re *regexp.Regexp) bool {
+	module, ok := m.(Module)
+	if !ok {
+		return false
+	}
+
+	for _, props := range module.GetProperties() {
+		value := reflect.ValueOf(props).Elem()
+		for _, field := range fields {
+			if value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					value = reflect.Value{}
+					break
+				}
+				value = value.Elem()
+			}
+			if value.Kind() != reflect.Struct {
+				value = reflect.Value{}
+				break
+			}
+			value = value.FieldByName(field)
+		}
+		if !value.IsValid() {
+			continue
+		}
+
+		for _, v := range queryPropertyValues(value) {
+			if re.MatchString(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queryPropertyValues returns the string values of a property, or nil if the property is not a
+// string, bool, int or list of strings.
+func queryPropertyValues(value reflect.Value) []string {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return []string{""}
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return []string{value.String()}
+	case reflect.Bool:
+		return []string{strconv.FormatBool(value.Bool())}
+	case reflect.Int, reflect.Int64:
+		return []string{strconv.FormatInt(value.Int(), 10)}
+	case reflect.Slice:
+		if list, ok := value.Interface().([]string); ok {
+			return list
+		}
+	}
+	return nil
+}
+
+// Parsing
+
+type queryExpr interface {
+	eval(q *queryGraph) (moduleSet, error)
+}
+
+type queryTarget string
+
+func (t queryTarget) eval(q *queryGraph) (moduleSet, error) {
+	return q.matchTarget(string(t))
+}
+
+type queryBinaryOp struct {
+	op       string
+	lhs, rhs queryExpr
+}
+
+func (b *queryBinaryOp) eval(q *queryGraph) (moduleSet, error) {
+	lhs, err := b.lhs.eval(q)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := b.rhs.eval(q)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(moduleSet)
+	switch b.op {
+	case "+":
+		for m := range lhs {
+			result[m] = true
+		}
+		for m := range rhs {
+			result[m] = true
+		}
+	case "-":
+		for m := range lhs {
+			if !rhs[m] {
+				result[m] = true
+			}
+		}
+	case "^":
+		for m := range lhs {
+			if rhs[m] {
+				result[m] = true
+			}
+		}
+	}
+	return result, nil
+}
+
+type queryArgType int
+
+const (
+	queryExprArg queryArgType = iota
+	queryWordArg
+	queryIntArg
+)
+
+type queryFunction struct {
+	args []queryArgType
+	// The number of trailing arguments that may be omitted.
+	optional int
+	// eval is passed a moduleSet for each expression argument, a string for each word
+	// argument and an int for each integer argument, or -1 for an omitted integer argument.
+	eval func(q *queryGraph, args []interface{}) (moduleSet, error)
+}
+
+var queryFunctions = map[string]queryFunction{
+	"deps": {
+		args:     []queryArgType{queryExprArg, queryIntArg},
+		optional: 1,
+		eval: func(q *queryGraph, args []interface{}) (moduleSet, error) {
+			return queryClosure(args[0].(moduleSet), q.deps, args[1].(int), queryAll), nil
+		},
+	},
+	"rdeps": {
+		args:     []queryArgType{queryExprArg, queryExprArg, queryIntArg},
+		optional: 1,
+		eval: func(q *queryGraph, args []interface{}) (moduleSet, error) {
+			universe := queryClosure(args[0].(moduleSet), q.deps, -1, queryAll)
+			return queryClosure(args[1].(moduleSet), q.rdeps, args[2].(int), func(m blueprint.Module) bool {
+				return universe[m]
+			}), nil
+		},
+	},
+	"somepath": {
+		args: []queryArgType{queryExprArg, queryExprArg},
+		eval: func(q *queryGraph, args []interface{}) (moduleSet, error) {
+			return q.somepath(args[0].(moduleSet), args[1].(moduleSet)), nil
+		},
+	},
+	"allpaths": {
+		args: []queryArgType{queryExprArg, queryExprArg},
+		eval: func(q *queryGraph, args []interface{}) (moduleSet, error) {
+			from := queryClosure(args[0].(moduleSet), q.deps, -1, queryAll)
+			return queryClosure(args[1].(moduleSet), q.rdeps, -1, func(m blueprint.Module) bool {
+				return from[m]
+			}), nil
+		},
+	},
+	"kind": {
+		args: []queryArgType{queryWordArg, queryExprArg},
+		eval: func(q *queryGraph, args []interface{}) (moduleSet, error) {
+			re, err := regexp.Compile(args[0].(string))
+			if err != nil {
+				return nil, fmt.Errorf("kind: invalid pattern: %s", err)
+			}
+			result := make(moduleSet)
+			for m := range args[1].(moduleSet) {
+				if re.MatchString(q.graph.ModuleType(m)) {
+					result[m] = true
+				}
+			}
+			return result, nil
+		},
+	},
+	"attr": {
+		args: []queryArgType{queryWordArg, queryWordArg, queryExprArg},
+		eval: func(q *queryGraph, args []interface{}) (moduleSet, error) {
+			fields := fieldNamesForProperties(args[0].(string))
+			re, err := regexp.Compile(args[1].(string))
+			if err != nil {
+				return nil, fmt.Errorf("attr: invalid pattern: %s", err)
+			}
+			result := make(moduleSet)
+			for m := range args[2].(moduleSet) {
+				if queryPropertyMatches(m, fields, re) {
+					result[m] = true
+				}
+			}
+			return result, nil
+		},
+	},
+}
+
+type queryCall struct {
+	name string
+	fn   queryFunction
+	args []interface{} // a queryExpr, string or int for each argument
+}
+
+func (c *queryCall) eval(q *queryGraph) (moduleSet, error) {
+	args := make([]interface{}, len(c.fn.args))
+	for i, argType := range c.fn.args {
+		switch {
+		case i >= len(c.args):
+			args[i] = -1
+		case argType == queryExprArg:
+			set, err := c.args[i].(queryExpr).eval(q)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = set
+		default:
+			args[i] = c.args[i]
+		}
+	}
+	return c.fn.eval(q, args)
+}
+
+type queryToken struct {
+	text   string
+	quoted bool
+	pos    int
+}
+
+func (t queryToken) is(s string) bool {
+	return !t.quoted && t.text == s
+}
+
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, queryToken{text: string(c), pos: i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted word at offset %d", i)
+			}
+			tokens = append(tokens, queryToken{text: query[i+1 : i+1+end], quoted: true, pos: i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(query) && !strings.ContainsRune(" \t\n(),\"", rune(query[i])) {
+				i++
+			}
+			tokens = append(tokens, queryToken{text: query[start:i], pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	end    int
+}
+
+func parseQuery(query string) (queryExpr, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens, end: len(query)}
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+	return expr, nil
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) next() (queryToken, error) {
+	t, ok := p.peek()
+	if !ok {
+		return t, fmt.Errorf("unexpected end of query at offset %d", p.end)
+	}
+	p.pos++
+	return t, nil
+}
+
+func (p *queryParser) expect(s string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if !t.is(s) {
+		return fmt.Errorf("expected %q at offset %d, found %q", s, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *queryParser) parseExpr() (queryExpr, error) {
+	expr, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, ok := p.peek()
+		if !ok || !(t.is("+") || t.is("-") || t.is("^")) {
+			return expr, nil
+		}
+		p.pos++
+		rhs, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		expr = &queryBinaryOp{op: t.text, lhs: expr, rhs: rhs}
+	}
+}
+
+func (p *queryParser) parseTerm() (queryExpr, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.is("(") {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+
+	if t.is(")") || t.is(",") {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+
+	if next, ok := p.peek(); ok && next.is("(") && !t.quoted {
+		fn, ok := queryFunctions[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q at offset %d", t.text, t.pos)
+		}
+		p.pos++
+		return p.parseCall(t, fn)
+	}
+
+	return queryTarget(t.text), nil
+}
+
+func (p *queryParser) parseCall(name queryToken, fn queryFunction) (queryExpr, error) {
+	call := &queryCall{name: name.text, fn: fn}
+
+	for i, argType := range fn.args {
+		if i > 0 {
+			t, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			if t.is(")") && i >= len(fn.args)-fn.optional {
+				return call, nil
+			}
+			if !t.is(",") {
+				return nil, fmt.Errorf("expected \",\" at offset %d in %s(), found %q",
+					t.pos, name.text, t.text)
+			}
+		}
+
+		switch argType {
+		case queryExprArg:
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, expr)
+		case queryWordArg, queryIntArg:
+			t, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			if t.is("(") || t.is(")") || t.is(",") {
+				return nil, fmt.Errorf("unexpected %q at offset %d in %s()", t.text, t.pos, name.text)
+			}
+			if argType == queryWordArg {
+				call.args = append(call.args, t.text)
+			} else {
+				n, err := strconv.Atoi(t.text)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("expected a depth at offset %d in %s(), found %q",
+						t.pos, name.text, t.text)
+				}
+				call.args = append(call.args, n)
+			}
+		}
+	}
+
+	return call, p.expect(")")
+}
@@ -0,0 +1,262 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var queryTestFs = map[string][]byte{
+	"frameworks/native/Blueprints": []byte(`
+		cc_library {
+			name: "libbinder",
+			shared_libs: ["libutils"],
+		}
+
+		cc_library {
+			name: "libgui",
+			shared_libs: ["libbinder"],
+		}`),
+	"frameworks/base/Blueprints": []byte(`
+		cc_library {
+			name: "libandroid_runtime",
+			shared_libs: ["libgui", "libutils"],
+		}
+
+		java_library {
+			name: "framework",
+		}`),
+	"system/core/Blueprints": []byte(`
+		cc_library {
+			name: "libutils",
+			static_libs: ["libbase"],
+		}
+
+		cc_library {
+			name: "libbase",
+			vendor_available: true,
+		}`),
+	"vendor/Blueprints": []byte(`
+		cc_library {
+			name: "libvendor",
+			shared_libs: ["libbinder"],
+		}`),
+}
+
+var queryTests = []struct {
+	name          string
+	query         string
+	expected      []string
+	expectedError string
+}{
+	{
+		name:  "rdeps",
+		query: "rdeps(//frameworks/..., libbinder)",
+		expected: []string{
+			"//frameworks/base:libandroid_runtime",
+			"//frameworks/native:libgui",
+			"//frameworks/native:libbinder",
+		},
+	},
+	{
+		name:  "rdeps with depth",
+		query: "rdeps(//..., //frameworks/native:libbinder, 1)",
+		expected: []string{
+			"//vendor:libvendor",
+			"//frameworks/native:libgui",
+			"//frameworks/native:libbinder",
+		},
+	},
+	{
+		name:  "deps with depth",
+		query: "deps(libgui, 1)",
+		expected: []string{
+			"//frameworks/native:libgui",
+			"//frameworks/native:libbinder",
+		},
+	},
+	{
+		name:  "somepath",
+		query: "somepath(libandroid_runtime, libbase)",
+		expected: []string{
+			"//frameworks/base:libandroid_runtime",
+			"//system/core:libutils",
+			"//system/core:libbase",
+		},
+	},
+	{
+		name:  "allpaths",
+		query: "allpaths(libandroid_runtime, libutils)",
+		expected: []string{
+			"//frameworks/base:libandroid_runtime",
+			"//frameworks/native:libgui",
+			"//frameworks/native:libbinder",
+			"//system/core:libutils",
+		},
+	},
+	{
+		name:     "kind",
+		query:    "kind(java_library, //...)",
+		expected: []string{"//frameworks/base:framework"},
+	},
+	{
+		name:     "attr",
+		query:    "attr(vendor_available, true, //...)",
+		expected: []string{"//system/core:libbase"},
+	},
+	{
+		name:     "attr on a list property",
+		query:    `attr(shared_libs, "^libbinder$", //frameworks/...)`,
+		expected: []string{"//frameworks/native:libgui"},
+	},
+	{
+		name:     "operators",
+		query:    `//frameworks/... - kind("cc_.*", //...) + (//system/core ^ attr(static_libs, ., //...))`,
+		expected: []string{"//frameworks/base:framework", "//system/core:libutils"},
+	},
+	{
+		name:          "unknown module",
+		query:         "rdeps(//frameworks/..., libmissing)",
+		expectedError: `no module named "libmissing"`,
+	},
+	{
+		name:          "unknown directory",
+		query:         "//external/...",
+		expectedError: `no modules match "//external/..."`,
+	},
+	{
+		name:          "unknown function",
+		query:         "frob(libgui)",
+		expectedError: `unknown function "frob" at offset 0`,
+	},
+	{
+		name:          "missing parenthesis",
+		query:         "deps(libgui",
+		expectedError: "unexpected end of query at offset 11",
+	},
+	{
+		name:          "invalid depth",
+		query:         "deps(libgui, all)",
+		expectedError: `expected a depth at offset 13 in deps(), found "all"`,
+	},
+}
+
+func TestQuery(t *testing.T) {
+	ctx := testQueryContext(t)
+
+	for _, test := range queryTests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := RunQuery(ctx, test.query, QueryFormatText, buf)
+
+			if test.expectedError != "" {
+				if err == nil || err.Error() != test.expectedError {
+					t.Errorf("expected error %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected:\n  %q\ngot:\n  %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestQueryFormats(t *testing.T) {
+	ctx := testQueryContext(t)
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := RunQuery(ctx, "libgui", QueryFormatJson, buf); err != nil {
+			t.Fatal(err)
+		}
+		expected := `[
+  {
+    "name": "libgui",
+    "dir": "frameworks/native",
+    "type": "cc_library",
+    "variant": "",
+    "deps": [
+      "//frameworks/native:libbinder"
+    ]
+  }
+]
+`
+		if buf.String() != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := RunQuery(ctx, "deps(libgui)", QueryFormatGraph, buf); err != nil {
+			t.Fatal(err)
+		}
+		expected := `digraph soong_query {
+  "//frameworks/native:libgui";
+  "//frameworks/native:libbinder";
+  "//system/core:libutils";
+  "//system/core:libbase";
+  "//frameworks/native:libgui" -> "//frameworks/native:libbinder";
+  "//frameworks/native:libbinder" -> "//system/core:libutils";
+  "//system/core:libutils" -> "//system/core:libbase";
+}
+`
+		if buf.String() != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		err := RunQuery(ctx, "libgui", "xml", &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), `unknown query output format "xml"`) {
+			t.Errorf("expected an unknown format error, got %v", err)
+		}
+	})
+}
+
+func testQueryContext(t *testing.T) *TestContext {
+	buildDir, err := ioutil.TempDir("", "soong_query_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	config := TestConfig(buildDir, nil)
+
+	ctx := NewTestContext()
+	ctx.RegisterModuleType("cc_library", ModuleFactoryAdaptor(newMockCcLibraryModule))
+	ctx.RegisterModuleType("java_library", ModuleFactoryAdaptor(newMockJavaLibraryModule))
+	ctx.Register()
+
+	ctx.MockFileSystem(queryTestFs)
+
+	_, errs := ctx.ParseBlueprintsFiles("Blueprints")
+	FailIfErrored(t, errs)
+	_, errs = ctx.ResolveDependencies(config)
+	FailIfErrored(t, errs)
+
+	return ctx
+}
@@ -16,6 +16,7 @@ package android
 
 import (
 	"fmt"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -34,6 +35,7 @@ type RuleBuilder struct {
 	restat         bool
 	sbox           bool
 	sboxOutDir     WritablePath
+	sboxInputs     bool
 	missingDeps    []string
 }
 
@@ -107,6 +109,23 @@ func (r *RuleBuilder) Sbox(outputDir WritablePath) *RuleBuilder {
 	return r
 }
 
+// SandboxInputs marks the rule as needing to run in a sandbox that only contains the paths returned by Inputs and
+// Tools.  sbox will fail the rule if it reads any other file in the source or output directories, and will list the
+// undeclared files that the command appears to have tried to read.  Outputs that are not in the sbox output directory
+// are written in place in the sandbox, and sbox moves them into place after the command succeeds.
+//
+// SandboxInputs requires Sbox(), and may not be called after Command()
+func (r *RuleBuilder) SandboxInputs() *RuleBuilder {
+	if !r.sbox {
+		panic("SandboxInputs() requires Sbox()")
+	}
+	if len(r.commands) > 0 {
+		panic("SandboxInputs() may not be called after Command()")
+	}
+	r.sboxInputs = true
+	return r
+}
+
 // Install associates an output of the rule with an install location, which can be retrieved later using
 // RuleBuilder.Installs.
 func (r *RuleBuilder) Install(from Path, to string) {
@@ -120,6 +139,7 @@ func (r *RuleBuilder) Command() *RuleBuilderCommand {
 	command := &RuleBuilderCommand{
 		sbox:       r.sbox,
 		sboxOutDir: r.sboxOutDir,
+		sboxInputs: r.sboxInputs,
 	}
 	r.commands = append(r.commands, command)
 	return command
@@ -336,10 +356,35 @@ func (r *RuleBuilder) Build(pctx PackageContext, ctx BuilderContext, name string
 
 	commandString := strings.Join(proptools.NinjaEscapeList(commands), " && ")
 
+	var rspFile, rspFileContent string
+	var rspFileInputs Paths
+	var commandRspFile WritablePath
+	for _, c := range r.commands {
+		if c.rspFile != nil {
+			if commandRspFile != nil {
+				panic("Only one RSP file per rule is supported")
+			}
+			commandRspFile = c.rspFile
+			rspFileInputs = c.rspFileInputs
+		}
+	}
+	if commandRspFile != nil {
+		rspFile = commandRspFile.String()
+		rspFileContent = "$in"
+	}
+	rspFileInputsSet := make(map[string]bool)
+	for _, input := range rspFileInputs {
+		rspFileInputsSet[input.String()] = true
+	}
+
 	if r.sbox {
 		sboxOutputs := make([]string, len(outputs))
 		for i, output := range outputs {
-			sboxOutputs[i] = "__SBOX_OUT_DIR__/" + Rel(ctx, r.sboxOutDir.String(), output.String())
+			if _, isRel, _ := maybeRelErr(r.sboxOutDir.String(), output.String()); !isRel && r.sboxInputs {
+				sboxOutputs[i] = output.String()
+			} else {
+				sboxOutputs[i] = "__SBOX_OUT_DIR__/" + Rel(ctx, r.sboxOutDir.String(), output.String())
+			}
 		}
 
 		commandString = proptools.ShellEscape(commandString)
@@ -353,6 +398,50 @@ func (r *RuleBuilder) Build(pctx PackageContext, ctx BuilderContext, name string
 			Flag("--sandbox-path").Text(shared.TempDirForOutDir(PathForOutput(ctx).String())).
 			Flag("--output-root").Text(r.sboxOutDir.String())
 
+		if commandRspFile != nil {
+			// The command reads the RSP file after sbox has deleted the output directory.
+			if _, isRel, _ := maybeRelErr(r.sboxOutDir.String(), rspFile); isRel {
+				reportPathErrorf(ctx, "RSP file %q must not be inside the sbox output directory %q",
+					rspFile, r.sboxOutDir.String())
+			}
+		}
+
+		// The action cache hashes the inputs and tools, so only commands that are sandboxed to their declared
+		// inputs are cached.  Commands with a depfile may still have undeclared inputs, and are not cached.
+		actionCacheDir := ctx.Config().ActionCacheDir()
+		useActionCache := actionCacheDir != "" && r.sboxInputs && depFile == nil
+
+		if r.sboxInputs {
+			sboxCmd.Flag("--sandbox-inputs")
+		}
+
+		if r.sboxInputs && commandRspFile != nil {
+			// Ninja only writes one RSP file per rule, so sbox reads the inputs in the command's RSP file from it
+			// and gets the remaining inputs on the command line.
+			sboxCmd.Flag("--input-list").Text(rspFile).
+				Flag("--input").Text(rspFile)
+			for _, input := range append(r.Inputs(), tools...) {
+				if !rspFileInputsSet[input.String()] {
+					sboxCmd.Flag("--input").Text(input.String())
+				}
+			}
+		} else if r.sboxInputs {
+			// Pass the inputs to sbox in a response file written by ninja, as there may be too many of them
+			// for the command line.  sbox reads it before it deletes the output directory.
+			rspFile = filepath.Join(r.sboxOutDir.String(), "sbox_inputs.rsp")
+			rspFileContent = strings.Join(proptools.NinjaEscapeList(append(r.Inputs().Strings(),
+				tools.Strings()...)), " ")
+			sboxCmd.Flag("--input-list").Text(rspFile)
+		}
+
+		if useActionCache {
+			sboxCmd.Flag("--action-cache").Text(actionCacheDir).
+				Flag("--action-cache-stats").Text(PathForOutput(ctx, shared.ActionCacheStatsFile).String())
+			if maxSize := ctx.Config().ActionCacheMaxSize(); maxSize != "" {
+				sboxCmd.Flag("--action-cache-max-size").Text(maxSize)
+			}
+		}
+
 		if depFile != nil {
 			sboxCmd.Flag("--depfile-out").Text(depFile.String())
 		}
@@ -369,13 +458,25 @@ func (r *RuleBuilder) Build(pctx PackageContext, ctx BuilderContext, name string
 	output := outputs[0]
 	implicitOutputs := outputs[1:]
 
+	// The inputs of the RSP file are passed to ninja as explicit inputs so that they are written to it as $in,
+	// all other inputs are implicit.
+	var implicits Paths
+	for _, input := range r.Inputs() {
+		if !rspFileInputsSet[input.String()] {
+			implicits = append(implicits, input)
+		}
+	}
+
 	ctx.Build(pctx, BuildParams{
 		Rule: ctx.Rule(pctx, name, blueprint.RuleParams{
-			Command:     commandString,
-			CommandDeps: tools.Strings(),
-			Restat:      r.restat,
+			Command:        commandString,
+			CommandDeps:    tools.Strings(),
+			Restat:         r.restat,
+			Rspfile:        rspFile,
+			RspfileContent: rspFileContent,
 		}),
-		Implicits:       r.Inputs(),
+		Inputs:          rspFileInputs,
+		Implicits:       implicits,
 		Output:          output,
 		ImplicitOutputs: implicitOutputs,
 		Depfile:         depFile,
@@ -395,8 +496,12 @@ type RuleBuilderCommand struct {
 	depFiles WritablePaths
 	tools    Paths
 
+	rspFile       WritablePath
+	rspFileInputs Paths
+
 	sbox       bool
 	sboxOutDir WritablePath
+	sboxInputs bool
 }
 
 func (c *RuleBuilderCommand) addInput(path Path) string {
@@ -412,7 +517,11 @@ func (c *RuleBuilderCommand) addInput(path Path) string {
 func (c *RuleBuilderCommand) outputStr(path Path) string {
 	if c.sbox {
 		// Errors will be handled in RuleBuilder.Build where we have a context to report them
-		rel, _, _ := maybeRelErr(c.sboxOutDir.String(), path.String())
+		rel, isRel, _ := maybeRelErr(c.sboxOutDir.String(), path.String())
+		if !isRel && c.sboxInputs {
+			// Outputs outside the sbox output directory are written in place in the input sandbox.
+			return path.String()
+		}
 		return "__SBOX_OUT_DIR__/" + rel
 	}
 	return path.String()
@@ -574,6 +683,29 @@ func (c *RuleBuilderCommand) FlagWithInputList(flag string, paths Paths, sep str
 	return c.FlagWithList(flag, strs, sep)
 }
 
+// FlagWithRspFileInputList adds the specified flag and path to an RSP file to the command line, with no separator
+// between them.  The input paths are written to the RSP file by ninja when the rule runs instead of being added to the
+// command line, for commands whose inputs may not fit on the command line.  The input paths will also be added to the
+// dependencies returned by RuleBuilder.Inputs.  A rule may only have a single RSP file.  If the rule uses Sbox() the
+// RSP file must be outside the sbox output directory, and the input paths must not be in it.
+func (c *RuleBuilderCommand) FlagWithRspFileInputList(flag string, rspFile WritablePath, paths Paths) *RuleBuilderCommand {
+	if c.rspFile != nil {
+		panic("FlagWithRspFileInputList cannot be called more than once per command")
+	}
+	c.rspFile = rspFile
+	for _, path := range paths {
+		if c.sbox {
+			if _, isRel, _ := maybeRelErr(c.sboxOutDir.String(), path.String()); isRel {
+				panic(fmt.Errorf("RSP file input %q must not be inside the sbox output directory %q",
+					path, c.sboxOutDir))
+			}
+		}
+		c.addInput(path)
+	}
+	c.rspFileInputs = append(c.rspFileInputs, paths...)
+	return c.Text(flag + rspFile.String())
+}
+
 // FlagForEachInput adds the specified flag joined with each input path to the command line.  The input paths will also
 // be added to the dependencies returned by RuleBuilder.Inputs.  The result is identical to calling FlagWithInput for
 // each input path.
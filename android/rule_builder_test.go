@@ -219,6 +219,16 @@ func ExampleRuleBuilderCommand_FlagWithInputList() {
 	// java -classpath=a.jar:b.jar
 }
 
+func ExampleRuleBuilderCommand_FlagWithRspFileInputList() {
+	ctx := pathContext()
+	fmt.Println(NewRuleBuilder().Command().
+		Tool(PathForSource(ctx, "merge_zips")).
+		Output(PathForOutput(ctx, "out.jar")).
+		FlagWithRspFileInputList("@", PathForOutput(ctx, "out.jar.rsp"), PathsForTesting("a.jar", "b.jar")))
+	// Output:
+	// merge_zips out/out.jar @out/out.jar.rsp
+}
+
 func ExampleRuleBuilderCommand_FlagWithInput() {
 	ctx := pathContext()
 	fmt.Println(NewRuleBuilder().Command().
@@ -361,6 +371,29 @@ func TestRuleBuilder(t *testing.T) {
 	})
 }
 
+func TestRuleBuilder_SandboxInputsInPlaceOutputs(t *testing.T) {
+	ctx := PathContextForTesting(TestConfig("out", nil), map[string][]byte{
+		"input": nil,
+	})
+
+	rule := NewRuleBuilder().Sbox(PathForOutput(ctx, "gen")).SandboxInputs()
+	rule.Command().
+		Text("command").
+		Input(PathForSource(ctx, "input")).
+		Output(PathForOutput(ctx, "gen", "output")).
+		FlagWithOutput("-o ", PathForOutput(ctx, "in_place"))
+
+	wantCommands := []string{"command input __SBOX_OUT_DIR__/output -o out/in_place"}
+	if g, w := rule.Commands(), wantCommands; !reflect.DeepEqual(g, w) {
+		t.Errorf("\nwant rule.Commands() = %#v\n                   got %#v", w, g)
+	}
+
+	wantOutputs := PathsForOutput(ctx, []string{"gen/output", "in_place"})
+	if g, w := rule.Outputs(), wantOutputs; !reflect.DeepEqual(w, g) {
+		t.Errorf("\nwant rule.Outputs() = %#v\n                  got %#v", w, g)
+	}
+}
+
 func testRuleBuilderFactory() Module {
 	module := &testRuleBuilderModule{}
 	module.AddProperties(&module.properties)
@@ -373,8 +406,9 @@ type testRuleBuilderModule struct {
 	properties struct {
 		Src string
 
-		Restat bool
-		Sbox   bool
+		Restat      bool
+		Sbox        bool
+		Sbox_inputs bool
 	}
 }
 
@@ -384,7 +418,8 @@ func (t *testRuleBuilderModule) GenerateAndroidBuildActions(ctx ModuleContext) {
 	outDep := PathForModuleOut(ctx, ctx.ModuleName()+".d")
 	outDir := PathForModuleOut(ctx)
 
-	testRuleBuilder_Build(ctx, in, out, outDep, outDir, t.properties.Restat, t.properties.Sbox)
+	testRuleBuilder_Build(ctx, in, out, outDep, outDir, t.properties.Restat, t.properties.Sbox,
+		t.properties.Sbox_inputs)
 }
 
 type testRuleBuilderSingleton struct{}
@@ -398,16 +433,20 @@ func (t *testRuleBuilderSingleton) GenerateBuildActions(ctx SingletonContext) {
 	out := PathForOutput(ctx, "baz")
 	outDep := PathForOutput(ctx, "baz.d")
 	outDir := PathForOutput(ctx)
-	testRuleBuilder_Build(ctx, in, out, outDep, outDir, true, false)
+	testRuleBuilder_Build(ctx, in, out, outDep, outDir, true, false, false)
 }
 
-func testRuleBuilder_Build(ctx BuilderContext, in Path, out, outDep, outDir WritablePath, restat, sbox bool) {
+func testRuleBuilder_Build(ctx BuilderContext, in Path, out, outDep, outDir WritablePath, restat, sbox, sboxInputs bool) {
 	rule := NewRuleBuilder()
 
 	if sbox {
 		rule.Sbox(outDir)
 	}
 
+	if sboxInputs {
+		rule.SandboxInputs()
+	}
+
 	rule.Command().Tool(PathForSource(ctx, "cp")).Input(in).Output(out).ImplicitDepFile(outDep)
 
 	if restat {
@@ -435,6 +474,12 @@ func TestRuleBuilder_Build(t *testing.T) {
 			src: "bar",
 			sbox: true,
 		}
+		rule_builder_test {
+			name: "foo_sbox_inputs",
+			src: "bar",
+			sbox: true,
+			sbox_inputs: true,
+		}
 	`
 
 	config := TestConfig(buildDir, nil)
@@ -507,9 +552,255 @@ func TestRuleBuilder_Build(t *testing.T) {
 		check(t, ctx.ModuleForTests("foo_sbox", "").Rule("rule"),
 			cmd, outFile, depFile, false, []string{sbox})
 	})
+	t.Run("sbox inputs", func(t *testing.T) {
+		outDir := filepath.Join(buildDir, ".intermediates", "foo_sbox_inputs")
+		outFile := filepath.Join(outDir, "foo_sbox_inputs")
+		depFile := filepath.Join(outDir, "foo_sbox_inputs.d")
+		rspFile := filepath.Join(outDir, "sbox_inputs.rsp")
+		sbox := filepath.Join(buildDir, "host", config.PrebuiltOS(), "bin/sbox")
+		sandboxPath := shared.TempDirForOutDir(buildDir)
+
+		cmd := sbox + ` -c 'cp bar __SBOX_OUT_DIR__/foo_sbox_inputs' --sandbox-path ` + sandboxPath +
+			" --output-root " + outDir + " --sandbox-inputs --input-list " + rspFile +
+			" --depfile-out " + depFile + " __SBOX_OUT_DIR__/foo_sbox_inputs"
+
+		params := ctx.ModuleForTests("foo_sbox_inputs", "").Rule("rule")
+		check(t, params, cmd, outFile, depFile, false, []string{sbox})
+
+		if params.RuleParams.Rspfile != rspFile {
+			t.Errorf("want RuleParams.Rspfile = %q, got %q", rspFile, params.RuleParams.Rspfile)
+		}
+		if g, w := params.RuleParams.RspfileContent, "bar cp"; g != w {
+			t.Errorf("want RuleParams.RspfileContent = %q, got %q", w, g)
+		}
+	})
 	t.Run("singleton", func(t *testing.T) {
 		outFile := filepath.Join(buildDir, "baz")
 		check(t, ctx.SingletonForTests("rule_builder_test").Rule("rule"),
 			"cp bar "+outFile, outFile, outFile+".d", true, nil)
 	})
 }
+
+func testRuleBuilderRspFileFactory() Module {
+	module := &testRuleBuilderRspFileModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+type testRuleBuilderRspFileModule struct {
+	ModuleBase
+	properties struct {
+		Srcs     []string
+		Manifest string
+
+		Sbox        bool
+		Sbox_inputs bool
+	}
+}
+
+func (t *testRuleBuilderRspFileModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	rule := NewRuleBuilder()
+	out := PathForModuleOut(ctx, ctx.ModuleName())
+
+	if t.properties.Sbox {
+		// The RSP file must be outside the sbox output directory.
+		rule.Sbox(PathForModuleOut(ctx, "gen"))
+		out = PathForModuleOut(ctx, "gen", ctx.ModuleName())
+	}
+	if t.properties.Sbox_inputs {
+		rule.SandboxInputs()
+	}
+
+	rule.Command().
+		Tool(PathForSource(ctx, "merge_zips")).
+		FlagWithInput("-m ", PathForSource(ctx, t.properties.Manifest)).
+		Output(out).
+		FlagWithRspFileInputList("@", PathForModuleOut(ctx, ctx.ModuleName()+".rsp"),
+			PathsForSource(ctx, t.properties.Srcs))
+
+	rule.Build(pctx, ctx, "rule", "desc")
+}
+
+func TestRuleBuilder_RspFile(t *testing.T) {
+	buildDir, err := ioutil.TempDir("", "soong_test_rule_builder_rsp_file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	bp := `
+		rule_builder_rsp_file_test {
+			name: "foo",
+			srcs: ["a.jar", "b.jar"],
+			manifest: "manifest",
+		}
+		rule_builder_rsp_file_test {
+			name: "foo_sbox",
+			srcs: ["a.jar", "b.jar"],
+			manifest: "manifest",
+			sbox: true,
+		}
+		rule_builder_rsp_file_test {
+			name: "foo_sbox_inputs",
+			srcs: ["a.jar", "b.jar"],
+			manifest: "manifest",
+			sbox: true,
+			sbox_inputs: true,
+		}
+	`
+
+	config := TestConfig(buildDir, nil)
+	ctx := NewTestContext()
+	ctx.MockFileSystem(map[string][]byte{
+		"Android.bp": []byte(bp),
+		"a.jar":      nil,
+		"b.jar":      nil,
+		"manifest":   nil,
+		"merge_zips": nil,
+	})
+	ctx.RegisterModuleType("rule_builder_rsp_file_test", ModuleFactoryAdaptor(testRuleBuilderRspFileFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	FailIfErrored(t, errs)
+
+	sbox := filepath.Join(buildDir, "host", config.PrebuiltOS(), "bin/sbox")
+	sandboxPath := shared.TempDirForOutDir(buildDir)
+
+	check := func(t *testing.T, params TestingBuildParams, wantCommand, wantRspFile string) {
+		t.Helper()
+		if params.RuleParams.Command != wantCommand {
+			t.Errorf("\nwant RuleParams.Command = %q\n                      got %q", wantCommand, params.RuleParams.Command)
+		}
+		if params.RuleParams.Rspfile != wantRspFile {
+			t.Errorf("want RuleParams.Rspfile = %q, got %q", wantRspFile, params.RuleParams.Rspfile)
+		}
+		if g, w := params.RuleParams.RspfileContent, "$in"; g != w {
+			t.Errorf("want RuleParams.RspfileContent = %q, got %q", w, g)
+		}
+		if g, w := params.Inputs.Strings(), []string{"a.jar", "b.jar"}; !reflect.DeepEqual(g, w) {
+			t.Errorf("want Inputs = %q, got %q", w, g)
+		}
+		if g, w := params.Implicits.Strings(), []string{"manifest"}; !reflect.DeepEqual(g, w) {
+			t.Errorf("want Implicits = %q, got %q", w, g)
+		}
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		outDir := filepath.Join(buildDir, ".intermediates", "foo")
+		rspFile := filepath.Join(outDir, "foo.rsp")
+		check(t, ctx.ModuleForTests("foo", "").Rule("rule"),
+			"merge_zips -m manifest "+filepath.Join(outDir, "foo")+" @"+rspFile, rspFile)
+	})
+
+	t.Run("sbox", func(t *testing.T) {
+		outDir := filepath.Join(buildDir, ".intermediates", "foo_sbox")
+		rspFile := filepath.Join(outDir, "foo_sbox.rsp")
+		cmd := sbox + ` -c 'merge_zips -m manifest __SBOX_OUT_DIR__/foo_sbox @` + rspFile + `'` +
+			" --sandbox-path " + sandboxPath + " --output-root " + filepath.Join(outDir, "gen") +
+			" __SBOX_OUT_DIR__/foo_sbox"
+		check(t, ctx.ModuleForTests("foo_sbox", "").Rule("rule"), cmd, rspFile)
+	})
+
+	t.Run("sbox inputs", func(t *testing.T) {
+		outDir := filepath.Join(buildDir, ".intermediates", "foo_sbox_inputs")
+		rspFile := filepath.Join(outDir, "foo_sbox_inputs.rsp")
+		cmd := sbox + ` -c 'merge_zips -m manifest __SBOX_OUT_DIR__/foo_sbox_inputs @` + rspFile + `'` +
+			" --sandbox-path " + sandboxPath + " --output-root " + filepath.Join(outDir, "gen") +
+			" --sandbox-inputs --input-list " + rspFile + " --input " + rspFile +
+			" --input manifest --input merge_zips" +
+			" __SBOX_OUT_DIR__/foo_sbox_inputs"
+		check(t, ctx.ModuleForTests("foo_sbox_inputs", "").Rule("rule"), cmd, rspFile)
+	})
+}
+
+func TestRuleBuilder_ActionCache(t *testing.T) {
+	buildDir, err := ioutil.TempDir("", "soong_test_rule_builder_action_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	bp := `
+		rule_builder_test {
+			name: "foo_sbox",
+			src: "bar",
+			sbox: true,
+		}
+		rule_builder_rsp_file_test {
+			name: "foo_rsp_file",
+			srcs: ["a.jar", "b.jar"],
+			manifest: "manifest",
+			sbox: true,
+			sbox_inputs: true,
+		}
+		rule_builder_rsp_file_test {
+			name: "foo_not_sandboxed",
+			srcs: ["a.jar", "b.jar"],
+			manifest: "manifest",
+			sbox: true,
+		}
+	`
+
+	config := TestConfig(buildDir, map[string]string{
+		"SOONG_ACTION_CACHE_DIR":      "/action_cache",
+		"SOONG_ACTION_CACHE_MAX_SIZE": "10G",
+	})
+	ctx := NewTestContext()
+	ctx.MockFileSystem(map[string][]byte{
+		"Android.bp": []byte(bp),
+		"bar":        nil,
+		"cp":         nil,
+		"a.jar":      nil,
+		"b.jar":      nil,
+		"manifest":   nil,
+		"merge_zips": nil,
+	})
+	ctx.RegisterModuleType("rule_builder_test", ModuleFactoryAdaptor(testRuleBuilderFactory))
+	ctx.RegisterModuleType("rule_builder_rsp_file_test", ModuleFactoryAdaptor(testRuleBuilderRspFileFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	FailIfErrored(t, errs)
+
+	sbox := filepath.Join(buildDir, "host", config.PrebuiltOS(), "bin/sbox")
+	sandboxPath := shared.TempDirForOutDir(buildDir)
+	actionCacheArgs := " --action-cache /action_cache --action-cache-stats " +
+		filepath.Join(buildDir, shared.ActionCacheStatsFile) + " --action-cache-max-size 10G"
+
+	t.Run("depfile", func(t *testing.T) {
+		// Rules with a depfile may have undeclared inputs, and are not cached.
+		params := ctx.ModuleForTests("foo_sbox", "").Rule("rule")
+		if strings.Contains(params.RuleParams.Command, "--action-cache") {
+			t.Errorf("expected a rule with a depfile not to use the action cache, got %q", params.RuleParams.Command)
+		}
+	})
+
+	t.Run("not sandboxed", func(t *testing.T) {
+		// Rules that don't sandbox their inputs may read undeclared inputs, and are not cached.
+		params := ctx.ModuleForTests("foo_not_sandboxed", "").Rule("rule")
+		if strings.Contains(params.RuleParams.Command, "--action-cache") {
+			t.Errorf("expected a rule without sandboxed inputs not to use the action cache, got %q",
+				params.RuleParams.Command)
+		}
+	})
+
+	t.Run("rsp file", func(t *testing.T) {
+		outDir := filepath.Join(buildDir, ".intermediates", "foo_rsp_file")
+		rspFile := filepath.Join(outDir, "foo_rsp_file.rsp")
+		wantCommand := sbox + ` -c 'merge_zips -m manifest __SBOX_OUT_DIR__/foo_rsp_file @` + rspFile + `'` +
+			" --sandbox-path " + sandboxPath + " --output-root " + filepath.Join(outDir, "gen") +
+			" --sandbox-inputs --input-list " + rspFile + " --input " + rspFile + " --input manifest --input merge_zips" +
+			actionCacheArgs + " __SBOX_OUT_DIR__/foo_rsp_file"
+
+		params := ctx.ModuleForTests("foo_rsp_file", "").Rule("rule")
+		if params.RuleParams.Command != wantCommand {
+			t.Errorf("\nwant RuleParams.Command = %q\n                      got %q", wantCommand, params.RuleParams.Command)
+		}
+	})
+}
@@ -206,3 +206,34 @@ func (s *singletonContextAdaptor) PrimaryModule(module Module) Module {
 func (s *singletonContextAdaptor) FinalModule(module Module) Module {
 	return s.SingletonContext.FinalModule(module).(Module)
 }
+
+// SingletonOutput is embedded in singletons that build a single file for the whole build, for
+// example a report, which is built by a phony goal and exported to Make.
+type SingletonOutput struct {
+	goal    string
+	makeVar string
+	output  OptionalPath
+}
+
+// NewSingletonOutput returns a SingletonOutput whose file is built by goal and exported to Make as
+// makeVar.
+func NewSingletonOutput(goal, makeVar string) SingletonOutput {
+	return SingletonOutput{goal: goal, makeVar: makeVar}
+}
+
+// SetOutput records the file built by the singleton and creates the phony goal that builds it.
+func (s *SingletonOutput) SetOutput(ctx SingletonContext, output Path) {
+	s.output = OptionalPathForPath(output)
+	ctx.Build(pctx, BuildParams{
+		Rule:   Phony,
+		Output: PathForPhony(ctx, s.goal),
+		Input:  output,
+	})
+}
+
+// MakeVars exports the file built by the singleton to Make, if there is one.
+func (s *SingletonOutput) MakeVars(ctx MakeVarsContext) {
+	if s.output.Valid() {
+		ctx.Strict(s.makeVar, s.output.String())
+	}
+}
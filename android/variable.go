@@ -234,6 +234,7 @@ type productVariables struct {
 	TidyChecks *string `json:",omitempty"`
 
 	NativeCoverage       *bool    `json:",omitempty"`
+	ClangCoverage        *bool    `json:",omitempty"`
 	CoveragePaths        []string `json:",omitempty"`
 	CoverageExcludePaths []string `json:",omitempty"`
 
@@ -295,6 +296,8 @@ type productVariables struct {
 
 	TargetFSConfigGen []string `json:",omitempty"`
 
+	NeverallowRulesFiles []string `json:",omitempty"`
+
 	// include Lineage variables
 	Lineage android.ProductVariables
 }
@@ -0,0 +1,373 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Enforces visibility rules between modules.
+//
+// Each module can provide a visibility property that contains a list of visibility rules.
+// Relative paths are resolved against the package (the directory of the Android.bp file) in
+// which the module is defined.
+//
+// Visibility is checked in three stages:
+//
+// 1. During the pre-arch phase the visibility rules of every module (and defaults module) are
+//    syntax checked.
+//
+// 2. Still during the pre-arch phase, after defaults have been applied, the visibility rules of
+//    every module are parsed and stored in a map from qualified module name to the rules.
+//
+// 3. During the post-deps phase every direct dependency edge is checked against the rules of
+//    the module that is being depended upon.
+//
+// Rules are of the form:
+//
+//   //visibility:public        - anyone can use this module.
+//   //visibility:private       - only modules in the same package can use this module.
+//   //some/package:__pkg__     - only modules in some/package can use this module.
+//   //some/package:__subpackages__
+//                              - only modules in some/package or its subpackages can use this
+//                                module.
+//   :__pkg__, :__subpackages__ - shorthand for the above relative to the module's own package.
+//
+// A module without a visibility property uses the default_visibility of the closest package
+// module, and is visible to every other module if there is none.
+
+const (
+	visibilityPropertyName = "visibility"
+	visibilityPrefix       = "//visibility:"
+)
+
+// A visibility rule is associated with a module and determines which other modules it is visible
+// to, i.e. which other modules can depend on the rule's module.
+type visibilityRule interface {
+	// Check to see whether this rules matches m.
+	// Returns true if it does, false otherwise.
+	matches(m qualifiedModuleName) bool
+
+	String() string
+}
+
+// A compositeRule is a visibility rule composed from a list of atomic visibility rules.
+//
+// The list corresponds to the list of strings in the visibility property after defaults mutation
+// has been applied.
+type compositeRule []visibilityRule
+
+// A compositeRule matches if and only if any of its rules matches.
+func (c compositeRule) matches(m qualifiedModuleName) bool {
+	for _, r := range c {
+		if r.matches(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c compositeRule) String() string {
+	s := make([]string, 0, len(c))
+	for _, r := range c {
+		s = append(s, r.String())
+	}
+
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+// A packageRule is a visibility rule that matches modules in a specific package (i.e. directory).
+type packageRule struct {
+	pkg string
+}
+
+func (r packageRule) matches(m qualifiedModuleName) bool {
+	return m.pkg == r.pkg
+}
+
+func (r packageRule) String() string {
+	return fmt.Sprintf("//%s:__pkg__", r.pkg)
+}
+
+// A subpackagesRule is a visibility rule that matches modules in a specific package (i.e.
+// directory) or any of its subpackages (i.e. subdirectories).
+type subpackagesRule struct {
+	pkgPrefix string
+}
+
+func (r subpackagesRule) matches(m qualifiedModuleName) bool {
+	return isAncestor(r.pkgPrefix, m.pkg)
+}
+
+func isAncestor(p1 string, p2 string) bool {
+	return p1 == "" || p1 == p2 || strings.HasPrefix(p2, p1+"/")
+}
+
+func (r subpackagesRule) String() string {
+	return fmt.Sprintf("//%s:__subpackages__", r.pkgPrefix)
+}
+
+// A publicRule is a visibility rule that matches all modules.
+type publicRule struct{}
+
+func (r publicRule) matches(_ qualifiedModuleName) bool {
+	return true
+}
+
+func (r publicRule) String() string {
+	return visibilityPrefix + "public"
+}
+
+// A privateRule is a visibility rule that matches no modules outside the module's own package,
+// which is always allowed before the rules are consulted.
+type privateRule struct{}
+
+func (r privateRule) matches(_ qualifiedModuleName) bool {
+	return false
+}
+
+func (r privateRule) String() string {
+	return visibilityPrefix + "private"
+}
+
+// A qualifiedModuleName identifies a module by the package in which it is defined and its name.
+type qualifiedModuleName struct {
+	// The package (i.e. directory) in which the module is defined, without a trailing /
+	pkg string
+
+	// The name of the module.
+	name string
+}
+
+func (q qualifiedModuleName) String() string {
+	return fmt.Sprintf("//%s:%s", q.pkg, q.name)
+}
+
+// The visibility rules of each module, keyed by qualifiedModuleName.
+var visibilityRuleMap = NewOnceKey("visibilityRuleMap")
+
+func moduleToVisibilityRuleMap(ctx BaseModuleContext) *sync.Map {
+	return ctx.Config().Once(visibilityRuleMap, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+// The rule checker needs to be registered before defaults expansion to correctly check that
+// //visibility:xxx isn't combined with other packages in the same list in any one module.
+func RegisterVisibilityRuleChecker(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("visibilityRuleChecker", visibilityRuleChecker).Parallel()
+}
+
+// Visibility is not dependent on arch so this must be registered before the arch phase to avoid
+// having to process multiple variants for each module. This goes after defaults expansion to gather
+// the complete visibility lists from flat lists.
+func RegisterVisibilityRuleGatherer(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("visibilityRuleGatherer", visibilityRuleGatherer).Parallel()
+}
+
+// This must be registered after the deps have been resolved.
+func RegisterVisibilityRuleEnforcer(ctx RegisterMutatorsContext) {
+	ctx.TopDown("visibilityRuleEnforcer", visibilityRuleEnforcer).Parallel()
+}
+
+// Checks the per-module visibility rule lists before defaults expansion.
+func visibilityRuleChecker(ctx BottomUpMutatorContext) {
+	if d, ok := ctx.Module().(Defaults); ok {
+		// Defaults modules don't store the payload properties in m.base().
+		for _, props := range d.properties() {
+			if cp, ok := props.(*commonProperties); ok {
+				if visibility := cp.Visibility; visibility != nil {
+					checkVisibilityRules(ctx, visibilityPackage(ctx.ModuleDir()), visibility)
+				}
+			}
+		}
+	} else if m, ok := ctx.Module().(Module); ok {
+		if visibility := m.base().commonProperties.Visibility; visibility != nil {
+			checkVisibilityRules(ctx, visibilityPackage(ctx.ModuleDir()), visibility)
+		}
+	}
+}
+
+func checkVisibilityRules(ctx BaseModuleContext, currentPkg string, visibility []string) {
+	checkVisibilityRulesForProperty(ctx, visibilityPropertyName, currentPkg, visibility)
+}
+
+func checkVisibilityRulesForProperty(ctx BaseModuleContext, property string, currentPkg string, visibility []string) {
+	ruleCount := len(visibility)
+	if ruleCount == 0 {
+		// This prohibits an empty list as its meaning is unclear, e.g. it could mean no visibility and
+		// it could mean public visibility. Requiring at least one rule makes the owner's intent
+		// clearer.
+		ctx.PropertyErrorf(property, "must contain at least one visibility rule")
+		return
+	}
+
+	for _, v := range visibility {
+		ok, pkg, name := splitVisibilityRule(v, currentPkg)
+		if !ok {
+			// Visibility rule is invalid so ignore it. Keep going rather than aborting straight away to
+			// ensure all the rules on this module are checked.
+			ctx.PropertyErrorf(property,
+				"invalid visibility pattern %q must match"+
+					" //<package>:<module>, //<package> or :<module>",
+				v)
+			continue
+		}
+
+		if pkg == "visibility" {
+			if ruleCount != 1 {
+				ctx.PropertyErrorf(property, "cannot mix %q with any other visibility rules", v)
+				continue
+			}
+			switch name {
+			case "private", "public":
+			default:
+				ctx.PropertyErrorf(property, "unrecognized visibility rule %q", v)
+				continue
+			}
+		} else if name != "__pkg__" && name != "__subpackages__" {
+			// Rules on individual modules are not supported yet, only packages.
+			ctx.PropertyErrorf(property,
+				"invalid visibility pattern %q, the module name must be __pkg__ or __subpackages__", v)
+			continue
+		}
+	}
+}
+
+// Gathers the flattened visibility rules after defaults expansion, parses them and stores them in
+// a map by qualified module name.
+//
+// See ../README.md#visibility for information on the format of the visibility rules.
+func visibilityRuleGatherer(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(Module)
+	if !ok {
+		return
+	}
+	if _, ok := m.(*packageModule); ok {
+		return
+	}
+
+	currentPkg := visibilityPackage(ctx.ModuleDir())
+	m.base().commonProperties.Package_dir = currentPkg
+
+	var rule compositeRule
+	if visibility := m.base().commonProperties.Visibility; visibility != nil {
+		rule = parseVisibilityRules(currentPkg, visibility)
+	} else {
+		// Fall back to the default visibility of the closest package module, if any.
+		rule = packageDefaultVisibility(ctx.Config(), ctx.ModuleDir())
+	}
+
+	if len(rule) > 0 {
+		qualified := qualifiedModuleName{currentPkg, ctx.ModuleName()}
+		moduleToVisibilityRuleMap(ctx).Store(qualified, rule)
+	}
+}
+
+func parseVisibilityRules(currentPkg string, visibility []string) compositeRule {
+	rules := make(compositeRule, 0, len(visibility))
+	for _, v := range visibility {
+		ok, pkg, name := splitVisibilityRule(v, currentPkg)
+		if !ok {
+			continue
+		}
+
+		var r visibilityRule
+		if pkg == "visibility" {
+			switch name {
+			case "private":
+				r = privateRule{}
+			case "public":
+				r = publicRule{}
+			default:
+				continue
+			}
+		} else {
+			switch name {
+			case "__pkg__":
+				r = packageRule{pkg}
+			case "__subpackages__":
+				r = subpackagesRule{pkg}
+			default:
+				continue
+			}
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+// Matches a visibility rule of the form //<package>:<module>, //<package> or :<module>.
+var visibilityRuleRegexp = regexp.MustCompile(`^(//[^/:]*(?:/[^/:]+)*)?(?::([^/:]+))?$`)
+
+// visibilityPackage converts a module directory into the package name used by visibility rules,
+// where the top level directory is the empty string.
+func visibilityPackage(dir string) string {
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+func splitVisibilityRule(ruleExpression string, currentPkg string) (bool, string, string) {
+	// Make sure that the rule is of the correct format.
+	matches := visibilityRuleRegexp.FindStringSubmatch(ruleExpression)
+	if ruleExpression == "" || matches == nil || (matches[1] == "//" && matches[2] == "") {
+		return false, "", ""
+	}
+
+	// Extract the package and name.
+	pkg := strings.TrimPrefix(matches[1], "//")
+	name := matches[2]
+
+	// Normalize the short hands
+	if matches[1] == "" {
+		pkg = currentPkg
+	}
+	if name == "" {
+		name = "__pkg__"
+	}
+
+	return true, pkg, name
+}
+
+// Checks that every direct dependency of a module is visible to it.
+func visibilityRuleEnforcer(ctx TopDownMutatorContext) {
+	qualified := qualifiedModuleName{visibilityPackage(ctx.ModuleDir()), ctx.ModuleName()}
+
+	moduleToVisibilityRule := moduleToVisibilityRuleMap(ctx)
+
+	// Visit all the dependencies making sure that this module has access to them all.
+	ctx.VisitDirectDeps(func(dep Module) {
+		depQualified := qualifiedModuleName{dep.base().commonProperties.Package_dir, ctx.OtherModuleName(dep)}
+
+		// Targets are always visible to other targets in their own package.
+		if depQualified.pkg == qualified.pkg {
+			return
+		}
+
+		rule, ok := moduleToVisibilityRule.Load(depQualified)
+		if ok {
+			if !rule.(compositeRule).matches(qualified) {
+				ctx.ModuleErrorf("depends on %s which is not visible to this module", depQualified)
+			}
+		}
+	})
+}
@@ -826,6 +826,7 @@ var propertyPrefixes = []struct{ mk, bp string }{
 	{"arm64", "arch.arm64"},
 	{"mips", "arch.mips"},
 	{"mips64", "arch.mips64"},
+	{"riscv64", "arch.riscv64"},
 	{"x86", "arch.x86"},
 	{"x86_64", "arch.x86_64"},
 	{"32", "multilib.lib32"},
@@ -291,6 +291,10 @@ type apexTargetBundleProperties struct {
 		Linux_glibc struct {
 			Multilib apexMultilibProperties
 		}
+		// Multilib properties only for host linux_musl.
+		Linux_musl struct {
+			Multilib apexMultilibProperties
+		}
 	}
 }
 
@@ -436,6 +440,8 @@ func (a *apexBundle) combineProperties(ctx android.BottomUpMutatorContext) {
 		proptools.AppendProperties(&a.properties.Multilib, &a.targetProperties.Target.Host.Multilib, nil)
 		if ctx.Os().Bionic() {
 			proptools.AppendProperties(&a.properties.Multilib, &a.targetProperties.Target.Linux_bionic.Multilib, nil)
+		} else if ctx.Os().Musl() {
+			proptools.AppendProperties(&a.properties.Multilib, &a.targetProperties.Target.Linux_musl.Multilib, nil)
 		} else {
 			proptools.AppendProperties(&a.properties.Multilib, &a.targetProperties.Target.Linux_glibc.Multilib, nil)
 		}
@@ -1346,6 +1352,9 @@ type PrebuiltProperties struct {
 		Arm64 struct {
 			Src *string
 		}
+		Riscv64 struct {
+			Src *string
+		}
 		X86 struct {
 			Src *string
 		}
@@ -1406,6 +1415,8 @@ func (p *Prebuilt) DepsMutator(ctx android.BottomUpMutatorContext) {
 		src = String(p.properties.Arch.Arm.Src)
 	case android.Arm64:
 		src = String(p.properties.Arch.Arm64.Src)
+	case android.Riscv64:
+		src = String(p.properties.Arch.Riscv64.Src)
 	case android.X86:
 		src = String(p.properties.Arch.X86.Src)
 	case android.X86_64:
@@ -63,6 +63,10 @@ func (c *Module) AndroidMk() android.AndroidMkData {
 		}
 	}
 
+	// AndroidMk is called by both the androidmk and the module_info_json singletons, so the
+	// sub-providers that ran for a previous call must run again.
+	c.subAndroidMkOnce = nil
+
 	ret := android.AndroidMkData{
 		OutputFile: c.outputFile,
 		// TODO(jiyong): add the APEXes providing shared libs to the required modules
@@ -103,6 +107,9 @@ func (c *Module) AndroidMk() android.AndroidMkData {
 	if c.sanitize != nil {
 		c.subAndroidMk(&ret, c.sanitize)
 	}
+	if c.pgo != nil {
+		c.subAndroidMk(&ret, c.pgo)
+	}
 	c.subAndroidMk(&ret, c.installer)
 
 	if c.useVndk() && c.hasVendorVariant() {
@@ -177,6 +184,9 @@ func (library *libraryDecorator) AndroidMk(ctx AndroidMkContext, ret *android.An
 				fmt.Fprintln(w, "HEADER_ABI_DIFFS += ", library.sAbiDiff.String())
 			}
 		}
+		if library.stubSymbolsCheck.Valid() {
+			fmt.Fprintln(w, "LOCAL_ADDITIONAL_DEPENDENCIES += ", library.stubSymbolsCheck.String())
+		}
 
 		_, _, ext := splitFileExt(outputFile.Base())
 
@@ -283,6 +293,28 @@ func (test *testBinary) AndroidMk(ctx AndroidMkContext, ret *android.AndroidMkDa
 	androidMkWriteTestData(test.data, ctx, ret)
 }
 
+func (fuzz *fuzzBinary) AndroidMk(ctx AndroidMkContext, ret *android.AndroidMkData) {
+	ctx.subAndroidMk(ret, fuzz.binaryDecorator)
+
+	var fuzzFiles []string
+	for _, path := range fuzz.corpus {
+		fuzzFiles = append(fuzzFiles, filepath.Dir(filepath.Dir(path.String()))+":corpus/"+path.Base())
+	}
+	if fuzz.dictionary != nil {
+		fuzzFiles = append(fuzzFiles, filepath.Dir(fuzz.dictionary.String())+":"+fuzz.dictionary.Base())
+	}
+	if fuzz.config != nil {
+		fuzzFiles = append(fuzzFiles, filepath.Dir(fuzz.config.String())+":"+fuzz.config.Base())
+	}
+
+	ret.Extra = append(ret.Extra, func(w io.Writer, outputFile android.Path) {
+		fmt.Fprintln(w, "LOCAL_IS_FUZZ_TARGET := true")
+		if len(fuzzFiles) > 0 {
+			fmt.Fprintln(w, "LOCAL_TEST_DATA := "+strings.Join(fuzzFiles, " "))
+		}
+	})
+}
+
 func (test *testLibrary) AndroidMk(ctx AndroidMkContext, ret *android.AndroidMkData) {
 	ctx.subAndroidMk(ret, test.libraryDecorator)
 }
@@ -295,6 +327,16 @@ func (library *toolchainLibraryDecorator) AndroidMk(ctx AndroidMkContext, ret *a
 	})
 }
 
+func (pgo *pgo) AndroidMk(ctx AndroidMkContext, ret *android.AndroidMkData) {
+	// The profile check is only built with the module when it can fail the build, otherwise it is
+	// only built for the PGO report.
+	if pgo.profileCheck.Valid() && pgo.enforceProfileMatch {
+		ret.Extra = append(ret.Extra, func(w io.Writer, outputFile android.Path) {
+			fmt.Fprintln(w, "LOCAL_ADDITIONAL_DEPENDENCIES +=", pgo.profileCheck.String())
+		})
+	}
+}
+
 func (installer *baseInstaller) AndroidMk(ctx AndroidMkContext, ret *android.AndroidMkData) {
 	// Soong installation is only supported for host modules. Have Make
 	// installation trigger Soong installation.
@@ -85,6 +85,9 @@ type binaryDecorator struct {
 	// Location of the linked, unstripped binary
 	unstrippedOutputFile android.Path
 
+	// Location of the debug info split out of the unstripped binary
+	debugFile android.OptionalPath
+
 	// Names of symlinks to be installed for use in LOCAL_MODULE_SYMLINKS
 	symlinks []string
 
@@ -200,6 +203,12 @@ func (binary *binaryDecorator) linkerInit(ctx BaseModuleContext) {
 			if binary.Properties.Static_executable == nil && ctx.Config().HostStaticBinaries() {
 				binary.Properties.Static_executable = BoolPtr(true)
 			}
+		} else if ctx.Os() == android.LinuxMusl {
+			// musl host binaries are statically linked by default so that they don't depend on
+			// the libc of the machine they run on.
+			if binary.Properties.Static_executable == nil {
+				binary.Properties.Static_executable = BoolPtr(true)
+			}
 		} else if !ctx.Fuchsia() {
 			// Static executables are not supported on Darwin or Windows
 			binary.Properties.Static_executable = nil
@@ -326,7 +335,7 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 		}
 		strippedOutputFile := outputFile
 		outputFile = android.PathForModuleOut(ctx, "unstripped", fileName)
-		binary.stripper.strip(ctx, outputFile, strippedOutputFile, builderFlags)
+		binary.debugFile = binary.stripper.stripAndSplitDebugInfo(ctx, outputFile, strippedOutputFile, builderFlags)
 	}
 
 	binary.unstrippedOutputFile = outputFile
@@ -388,7 +397,9 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 
 	objs.coverageFiles = append(objs.coverageFiles, deps.StaticLibObjs.coverageFiles...)
 	objs.coverageFiles = append(objs.coverageFiles, deps.WholeStaticLibObjs.coverageFiles...)
-	binary.coverageOutputFile = TransformCoverageFilesToZip(ctx, objs, binary.getStem(ctx))
+	if !flags.ClangCoverage {
+		binary.coverageOutputFile = TransformCoverageFilesToZip(ctx, objs, binary.getStem(ctx))
+	}
 
 	// Need to determine symlinks early since some targets (ie APEX) need this
 	// information but will not call 'install'
@@ -413,6 +424,10 @@ func (binary *binaryDecorator) unstrippedOutputFilePath() android.Path {
 	return binary.unstrippedOutputFile
 }
 
+func (binary *binaryDecorator) debugFilePath() android.OptionalPath {
+	return binary.debugFile
+}
+
 func (binary *binaryDecorator) symlinkList() []string {
 	return binary.symlinks
 }
@@ -425,6 +440,11 @@ func (binary *binaryDecorator) coverageOutputFilePath() android.OptionalPath {
 	return binary.coverageOutputFile
 }
 
+func (binary *binaryDecorator) zipCoverageMapping(ctx ModuleContext) {
+	binary.coverageOutputFile = TransformCoverageMappingToZip(ctx, binary.unstrippedOutputFile,
+		binary.baseInstaller.onDevicePath(ctx), binary.getStem(ctx))
+}
+
 // /system/bin/linker -> /apex/com.android.runtime/bin/linker
 func (binary *binaryDecorator) installSymlinkToRuntimeApex(ctx ModuleContext, file android.Path) {
 	dir := binary.baseInstaller.installDir(ctx)
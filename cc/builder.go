@@ -74,6 +74,18 @@ var (
 		},
 		"ldCmd", "crtBegin", "libFlags", "crtEnd", "ldFlags")
 
+	// Like ld, but also writes the wall time of the link in milliseconds to ${linkTimeFile}.
+	timedLd = pctx.AndroidStaticRule("timedLd",
+		blueprint.RuleParams{
+			Command: "$timeCmd -o ${linkTimeFile} " +
+				"$ldCmd ${crtBegin} @${out}.rsp ${libFlags} ${crtEnd} -o ${out} ${ldFlags}",
+			CommandDeps:    []string{"$timeCmd", "$ldCmd"},
+			Rspfile:        "${out}.rsp",
+			RspfileContent: "${in}",
+			Restat:         true,
+		},
+		"ldCmd", "crtBegin", "libFlags", "crtEnd", "ldFlags", "linkTimeFile")
+
 	partialLd = pctx.AndroidStaticRule("partialLd",
 		blueprint.RuleParams{
 			// Without -no-pie, clang 7.0 adds -pie to link Android files,
@@ -213,7 +225,9 @@ var (
 		func(ctx android.PackageRuleContext) blueprint.RuleParams {
 			// TODO(b/78139997): Add -check-all-apis back
 			commandStr := "($sAbiDiffer ${allowFlags} -lib ${libName} -arch ${arch} -o ${out} -new ${in} -old ${referenceDump})"
-			commandStr += "|| (echo 'error: Please update ABI references with: $$ANDROID_BUILD_TOP/development/vndk/tools/header-checker/utils/create_reference_dumps.py ${createReferenceDumpFlags} -l ${libName}'"
+			commandStr += "|| (echo 'error: The ABI of ${libName} (${arch}) is incompatible with ${referenceDump}:'"
+			commandStr += " && cat ${out}"
+			commandStr += " && echo 'error: If the ABI change is intended, update the reference ABI dumps with: m ${updateTarget}'"
 			commandStr += " && (mkdir -p $$DIST_DIR/abidiffs && cp ${out} $$DIST_DIR/abidiffs/)"
 			commandStr += " && exit 1)"
 			return blueprint.RuleParams{
@@ -221,7 +235,21 @@ var (
 				CommandDeps: []string{"$sAbiDiffer"},
 			}
 		},
-		"allowFlags", "referenceDump", "libName", "arch", "createReferenceDumpFlags")
+		"allowFlags", "referenceDump", "libName", "arch", "updateTarget")
+
+	// Fails the ABI check of a library that opted in to it, but has no reference dump yet. This runs
+	// in ninja rather than failing the analysis, so that the update target can still create the dump.
+	sAbiMissingRefDump = pctx.AndroidStaticRule("sAbiMissingRefDump",
+		blueprint.RuleParams{
+			Command: "echo 'error: ${libName} (${arch}) has header_abi_checker enabled, but there is no reference ABI dump ${refDump}' && " +
+				"echo 'error: Create the reference ABI dumps with: m ${updateTarget}' && exit 1",
+		}, "libName", "arch", "refDump", "updateTarget")
+
+	// The output is never created, so the reference dump is updated every time the rule is built.
+	updateRefSAbiDump = pctx.AndroidStaticRule("updateRefSAbiDump",
+		blueprint.RuleParams{
+			Command: "mkdir -p $$(dirname ${refDump}) && rm -f ${refDump}.gz && cp -f $in ${refDump}",
+		}, "refDump")
 
 	unzipRefSAbiDump = pctx.AndroidStaticRule("unzipRefSAbiDump",
 		blueprint.RuleParams{
@@ -249,6 +277,7 @@ func init() {
 	}
 
 	pctx.HostBinToolVariable("SoongZipCmd", "soong_zip")
+	pctx.HostBinToolVariable("timeCmd", "time_cmd")
 }
 
 type builderFlags struct {
@@ -277,11 +306,15 @@ type builderFlags struct {
 
 	groupStaticLibs bool
 
+	ldFlagsOutputs android.WritablePaths
+	linkTimeFile   android.WritablePath
+
 	stripKeepSymbols       bool
 	stripKeepSymbolsList   string
 	stripKeepMiniDebugInfo bool
 	stripAddGnuDebuglink   bool
 	stripUseGnuStrip       bool
+	stripDebugFile         android.WritablePath
 
 	proto            android.ProtoFlags
 	protoC           bool
@@ -666,19 +699,29 @@ func TransformObjToDynamicBinary(ctx android.ModuleContext,
 		deps = append(deps, crtBegin.Path(), crtEnd.Path())
 	}
 
+	rule := ld
+	args := map[string]string{
+		"ldCmd":    ldCmd,
+		"crtBegin": crtBegin.String(),
+		"libFlags": strings.Join(libFlagsList, " "),
+		"ldFlags":  flags.ldFlags,
+		"crtEnd":   crtEnd.String(),
+	}
+	implicitOutputs := append(android.WritablePaths(nil), flags.ldFlagsOutputs...)
+	if flags.linkTimeFile != nil {
+		rule = timedLd
+		args["linkTimeFile"] = flags.linkTimeFile.String()
+		implicitOutputs = append(implicitOutputs, flags.linkTimeFile)
+	}
+
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        ld,
-		Description: "link " + outputFile.Base(),
-		Output:      outputFile,
-		Inputs:      objFiles,
-		Implicits:   deps,
-		Args: map[string]string{
-			"ldCmd":    ldCmd,
-			"crtBegin": crtBegin.String(),
-			"libFlags": strings.Join(libFlagsList, " "),
-			"ldFlags":  flags.ldFlags,
-			"crtEnd":   crtEnd.String(),
-		},
+		Rule:            rule,
+		Description:     "link " + outputFile.Base(),
+		Output:          outputFile,
+		ImplicitOutputs: implicitOutputs,
+		Inputs:          objFiles,
+		Implicits:       deps,
+		Args:            args,
 	})
 }
 
@@ -733,11 +776,10 @@ func UnzipRefDump(ctx android.ModuleContext, zippedRefDump android.Path, baseNam
 }
 
 func SourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceDump android.Path,
-	baseName, exportedHeaderFlags string, isLlndk, isVndkExt bool) android.OptionalPath {
+	baseName, exportedHeaderFlags string, diffFlags []string, isLlndk, isVndkExt bool) android.OptionalPath {
 
 	outputFile := android.PathForModuleOut(ctx, baseName+".abidiff")
 	libName := strings.TrimSuffix(baseName, filepath.Ext(baseName))
-	createReferenceDumpFlags := ""
 
 	localAbiCheckAllowFlags := append([]string(nil), abiCheckAllowFlags...)
 	if exportedHeaderFlags == "" {
@@ -745,11 +787,11 @@ func SourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceD
 	}
 	if isLlndk {
 		localAbiCheckAllowFlags = append(localAbiCheckAllowFlags, "-consider-opaque-types-different")
-		createReferenceDumpFlags = "--llndk"
 	}
 	if isVndkExt {
 		localAbiCheckAllowFlags = append(localAbiCheckAllowFlags, "-allow-extensions")
 	}
+	localAbiCheckAllowFlags = append(localAbiCheckAllowFlags, diffFlags...)
 
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        sAbiDiff,
@@ -758,11 +800,49 @@ func SourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceD
 		Input:       inputDump,
 		Implicit:    referenceDump,
 		Args: map[string]string{
-			"referenceDump":            referenceDump.String(),
-			"libName":                  libName,
-			"arch":                     ctx.Arch().ArchType.Name,
-			"allowFlags":               strings.Join(localAbiCheckAllowFlags, " "),
-			"createReferenceDumpFlags": createReferenceDumpFlags,
+			"referenceDump": referenceDump.String(),
+			"libName":       libName,
+			"arch":          ctx.Arch().ArchType.Name,
+			"allowFlags":    strings.Join(localAbiCheckAllowFlags, " "),
+			"updateTarget":  updateRefAbiDumpsTarget(ctx.ModuleName()),
+		},
+	})
+	return android.OptionalPathForPath(outputFile)
+}
+
+// MissingRefAbiDump generates a rule in place of the header-abi-diff of a library that fails with
+// instructions to create the missing reference dump refDump.
+func MissingRefAbiDump(ctx android.ModuleContext, inputDump android.Path, baseName, refDump string) android.OptionalPath {
+	outputFile := android.PathForModuleOut(ctx, baseName+".abidiff")
+	libName := strings.TrimSuffix(baseName, filepath.Ext(baseName))
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        sAbiMissingRefDump,
+		Description: "missing reference ABI dump " + refDump,
+		Output:      outputFile,
+		Input:       inputDump,
+		Args: map[string]string{
+			"libName":      libName,
+			"arch":         ctx.Arch().ArchType.Name,
+			"refDump":      refDump,
+			"updateTarget": updateRefAbiDumpsTarget(ctx.ModuleName()),
+		},
+	})
+	return android.OptionalPathForPath(outputFile)
+}
+
+// Generate a rule that copies a linked ABI dump over the reference ABI dump refDump, which is a path
+// relative to the top of the source tree.
+func UpdateRefAbiDump(ctx android.ModuleContext, inputDump android.Path, refDump string) android.OptionalPath {
+	outputFile := android.PathForModuleOut(ctx, "update_ref_abi_dump", filepath.Base(refDump))
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        updateRefSAbiDump,
+		Description: "update reference ABI dump " + refDump,
+		Output:      outputFile,
+		Input:       inputDump,
+		Args: map[string]string{
+			"refDump": refDump,
 		},
 	})
 	return android.OptionalPathForPath(outputFile)
@@ -853,12 +933,18 @@ func TransformStrip(ctx android.ModuleContext, inputFile android.Path,
 	if flags.stripUseGnuStrip {
 		args += " --use-gnu-strip"
 	}
+	var implicitOutputs android.WritablePaths
+	if flags.stripDebugFile != nil {
+		args += " -g " + flags.stripDebugFile.String()
+		implicitOutputs = append(implicitOutputs, flags.stripDebugFile)
+	}
 
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        strip,
-		Description: "strip " + outputFile.Base(),
-		Output:      outputFile,
-		Input:       inputFile,
+		Rule:            strip,
+		Description:     "strip " + outputFile.Base(),
+		Output:          outputFile,
+		ImplicitOutputs: implicitOutputs,
+		Input:           inputFile,
 		Args: map[string]string{
 			"crossCompile": crossCompile,
 			"args":         args,
@@ -896,6 +982,45 @@ func TransformCoverageFilesToZip(ctx android.ModuleContext,
 	return android.OptionalPath{}
 }
 
+// Generate a rule for zipping a binary or shared library that was linked with clang coverage
+// mapping.  The unstripped file is needed by llvm-cov together with the .profraw files collected
+// on the device to produce coverage reports.  The zip also contains a manifest that maps the
+// module, its on-device install path and the name of its .profraw files to the unstripped file.
+func TransformCoverageMappingToZip(ctx android.ModuleContext,
+	unstrippedFile android.Path, installPath string, baseName string) android.OptionalPath {
+
+	outDir := android.PathForOutput(ctx)
+
+	manifest := android.PathForModuleOut(ctx, baseName+"-coverage-manifest.txt")
+	content := []string{
+		"module: " + ctx.ModuleName(),
+		"install_path: " + installPath,
+		"profraw: " + clangCoverageProfraw,
+		"binary: " + android.Rel(ctx, outDir.String(), unstrippedFile.String()),
+	}
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.WriteFile,
+		Description: "coverage manifest " + baseName,
+		Output:      manifest,
+		Args: map[string]string{
+			"content": strings.Join(content, "\\n"),
+		},
+	})
+
+	outputFile := android.PathForModuleOut(ctx, baseName+".zip")
+
+	rule := android.NewRuleBuilder()
+	rule.Command().
+		Tool(ctx.Config().HostToolPath(ctx, "soong_zip")).
+		FlagWithOutput("-o ", outputFile).
+		FlagWithArg("-C ", outDir.String()).
+		FlagWithInput("-f ", manifest).
+		FlagWithInput("-f ", unstrippedFile)
+	rule.Build(pctx, ctx, "coverage_zip", "zip "+outputFile.Base())
+
+	return android.OptionalPathForPath(outputFile)
+}
+
 func gccCmd(toolchain config.Toolchain, cmd string) string {
 	return filepath.Join(toolchain.GccRoot(), "bin", toolchain.GccTriple()+"-"+cmd)
 }
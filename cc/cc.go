@@ -51,6 +51,9 @@ func init() {
 		ctx.TopDown("hwasan_deps", sanitizerDepsMutator(hwasan))
 		ctx.BottomUp("hwasan", sanitizerMutator(hwasan)).Parallel()
 
+		ctx.TopDown("fuzzer_deps", sanitizerDepsMutator(fuzzer))
+		ctx.BottomUp("fuzzer", sanitizerMutator(fuzzer)).Parallel()
+
 		// cfi mutator shouldn't run before sanitizers that return true for
 		// incompatibleWithCfi()
 		ctx.TopDown("cfi_deps", sanitizerDepsMutator(cfi))
@@ -159,11 +162,18 @@ type Flags struct {
 	RequiredInstructionSet string
 	DynamicLinker          string
 
-	CFlagsDeps  android.Paths // Files depended on by compiler flags
-	LdFlagsDeps android.Paths // Files depended on by linker flags
+	CFlagsDeps     android.Paths         // Files depended on by compiler flags
+	LdFlagsDeps    android.Paths         // Files depended on by linker flags
+	LdFlagsOutputs android.WritablePaths // Files written by the linker because of linker flags
+
+	// If set, the wall time of the link in milliseconds is written to this file.
+	LinkTimeFile android.WritablePath
 
 	GroupStaticLibs bool
 
+	// The linked output contains clang coverage mapping.
+	ClangCoverage bool
+
 	proto            android.ProtoFlags
 	protoC           bool // Whether to use C instead of C++
 	protoOptionsFile bool // Whether to look for a .options file next to the .proto
@@ -242,6 +252,7 @@ type ModuleContextIntf interface {
 	static() bool
 	staticBinary() bool
 	header() bool
+	object() bool
 	toolchain() config.Toolchain
 	useSdk() bool
 	sdkVersion() string
@@ -255,6 +266,7 @@ type ModuleContextIntf interface {
 	isVndkExt() bool
 	inRecovery() bool
 	shouldCreateVndkSourceAbiDump() bool
+	shouldCreateSourceAbiDump() bool
 	selectedStl() string
 	baseModuleName() string
 	getVndkExtendsModuleName() string
@@ -312,6 +324,7 @@ type linker interface {
 	link(ctx ModuleContext, flags Flags, deps PathDeps, objs Objects) android.Path
 	appendLdflags([]string)
 	unstrippedOutputFilePath() android.Path
+	debugFilePath() android.OptionalPath
 
 	nativeCoverage() bool
 	coverageOutputFilePath() android.OptionalPath
@@ -336,6 +349,18 @@ type dependencyTag struct {
 	explicitlyVersioned bool
 }
 
+// Linkage implements android.LinkageDependencyTag.
+func (d dependencyTag) Linkage() android.Linkage {
+	switch d.name {
+	case "static", "late static", "whole static", "obj", "crtbegin", "crtend":
+		return android.StaticLinkage
+	case "shared", "early_shared", "late shared", "ndk stub", "ndk late stub":
+		return android.SharedLinkage
+	default:
+		return android.NoLinkage
+	}
+}
+
 var (
 	sharedDepTag          = dependencyTag{name: "shared", library: true}
 	sharedExportDepTag    = dependencyTag{name: "shared", library: true, reexportFlags: true}
@@ -678,6 +703,10 @@ func (ctx *moduleContextImpl) header() bool {
 	return ctx.mod.header()
 }
 
+func (ctx *moduleContextImpl) object() bool {
+	return ctx.mod.object()
+}
+
 func (ctx *moduleContextImpl) useSdk() bool {
 	if ctx.ctx.Device() && !ctx.useVndk() && !ctx.inRecovery() && !ctx.ctx.Fuchsia() {
 		return String(ctx.mod.Properties.Sdk_version) != ""
@@ -751,8 +780,48 @@ func (ctx *moduleContextImpl) inRecovery() bool {
 	return ctx.mod.inRecovery()
 }
 
-// Check whether ABI dumps should be created for this module.
+// Check whether ABI dumps should be created for this module, either because it is an NDK, LLNDK or
+// VNDK library or because it sets header_abi_checker.enabled.
+func (ctx *moduleContextImpl) shouldCreateSourceAbiDump() bool {
+	if library, ok := ctx.mod.linker.(*libraryDecorator); ok && library.Properties.Header_abi_checker.Enabled != nil {
+		if !Bool(library.Properties.Header_abi_checker.Enabled) || !ctx.canCreateSourceAbiDump() {
+			return false
+		}
+		if ctx.inRecovery() {
+			return false
+		}
+		// Libraries with both a core and a vendor variant are only checked once: VNDK libraries on
+		// their vendor variant, and other libraries on their core variant.
+		if ctx.mod.hasVendorVariant() && ctx.useVndk() != ctx.isVndk() {
+			return false
+		}
+		return true
+	}
+	return ctx.shouldCreateVndkSourceAbiDump()
+}
+
+// Check whether ABI dumps should be created for this module because it is an NDK, LLNDK or VNDK
+// library.
 func (ctx *moduleContextImpl) shouldCreateVndkSourceAbiDump() bool {
+	if !ctx.canCreateSourceAbiDump() {
+		return false
+	}
+	if ctx.isNdk() {
+		return true
+	}
+	if ctx.isLlndkPublic() {
+		return true
+	}
+	if ctx.useVndk() && ctx.isVndk() && !ctx.isVndkPrivate() {
+		// Return true if this is VNDK-core, VNDK-SP, or VNDK-Ext and this is not
+		// VNDK-private.
+		return true
+	}
+	return false
+}
+
+// Check whether this variant of the module can have ABI dumps at all.
+func (ctx *moduleContextImpl) canCreateSourceAbiDump() bool {
 	if ctx.ctx.Config().IsEnvTrue("SKIP_ABI_CHECKS") {
 		return false
 	}
@@ -774,18 +843,7 @@ func (ctx *moduleContextImpl) shouldCreateVndkSourceAbiDump() bool {
 		// APEX variants do not need ABI dumps.
 		return false
 	}
-	if ctx.isNdk() {
-		return true
-	}
-	if ctx.isLlndkPublic() {
-		return true
-	}
-	if ctx.useVndk() && ctx.isVndk() && !ctx.isVndkPrivate() {
-		// Return true if this is VNDK-core, VNDK-SP, or VNDK-Ext and this is not
-		// VNDK-private.
-		return true
-	}
-	return false
+	return true
 }
 
 func (ctx *moduleContextImpl) selectedStl() string {
@@ -867,6 +925,26 @@ func (c *Module) Name() string {
 	return name
 }
 
+// TestSuites returns the test suites of a test or benchmark module, for module-info.json.
+func (c *Module) TestSuites() []string {
+	if p, ok := c.installer.(interface {
+		testSuites() []string
+	}); ok {
+		return p.testSuites()
+	}
+	return nil
+}
+
+// TestConfig returns the test config of a test or benchmark module, for module-info.json.
+func (c *Module) TestConfig() android.Path {
+	if p, ok := c.installer.(interface {
+		testConfigPath() android.Path
+	}); ok {
+		return p.testConfigPath()
+	}
+	return nil
+}
+
 func (c *Module) Symlinks() []string {
 	if p, ok := c.installer.(interface {
 		symlinkList() []string
@@ -1016,6 +1094,10 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 		}
 		c.outputFile = android.OptionalPathForPath(outputFile)
 
+		if c.pgo != nil {
+			c.pgo.checkProfile(ctx, c.linker.unstrippedOutputFilePath())
+		}
+
 		// If a lib is directly included in any of the APEXes, unhide the stubs
 		// variant having the latest version gets visible to make. In addition,
 		// the non-stubs variant is renamed to <libname>.bootstrap. This is to
@@ -1037,6 +1119,12 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 			return
 		}
 	}
+
+	if flags.ClangCoverage && c.outputFile.Valid() {
+		if zipper, ok := c.linker.(coverageMappingZipper); ok {
+			zipper.zipCoverageMapping(ctx)
+		}
+	}
 }
 
 func (c *Module) toolchain(ctx android.BaseContext) config.Toolchain {
@@ -1950,6 +2038,11 @@ func (c *Module) header() bool {
 	return false
 }
 
+func (c *Module) object() bool {
+	_, ok := c.linker.(*objectLinker)
+	return ok
+}
+
 func (c *Module) getMakeLinkType() string {
 	if c.useVndk() {
 		if inList(c.Name(), vndkCoreLibraries) || inList(c.Name(), vndkSpLibraries) || inList(c.Name(), llndkLibraries) {
@@ -2065,8 +2158,19 @@ const (
 	vendorMode = "vendor"
 
 	recoveryMode = "recovery"
+
+	// CoreVariation is the image variation of coreMode, for use by modules in other packages
+	// that depend on cc modules.
+	CoreVariation = coreMode
 )
 
+// CoreImageModule is implemented by modules in other packages, such as Rust modules, that are
+// built for the core image and can depend on cc modules.
+type CoreImageModule interface {
+	android.Module
+	CoreImageOnly() bool
+}
+
 func squashVendorSrcs(m *Module) {
 	if lib, ok := m.compiler.(*libraryDecorator); ok {
 		lib.baseCompiler.Properties.Srcs = append(lib.baseCompiler.Properties.Srcs,
@@ -2139,6 +2243,11 @@ func ImageMutator(mctx android.BottomUpMutatorContext) {
 		}
 	}
 
+	if m, ok := mctx.Module().(CoreImageModule); ok && m.CoreImageOnly() {
+		mctx.CreateVariations(coreMode)
+		return
+	}
+
 	m, ok := mctx.Module().(*Module)
 	if !ok {
 		return
@@ -17,9 +17,11 @@ package cc
 import (
 	"android/soong/android"
 
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
@@ -2165,3 +2167,312 @@ func TestStaticDepsOrderWithStubs(t *testing.T) {
 		)
 	}
 }
+
+func TestMuslHostBinary(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	config.Targets[android.LinuxMusl] = []android.Target{
+		{android.LinuxMusl, android.Arch{ArchType: android.X86_64}},
+	}
+
+	ctx := testCcWithConfig(t, `
+		cc_binary_host {
+			name: "mybin",
+			srcs: ["foo.c"],
+			stl: "none",
+			target: {
+				musl: {
+					cflags: ["-DMUSL"],
+				},
+				linux_glibc: {
+					cflags: ["-DGLIBC"],
+				},
+			},
+		}`, config)
+
+	musl := ctx.ModuleForTests("mybin", "linux_musl_x86_64")
+	if cFlags := musl.Rule("cc").Args["cFlags"]; !strings.Contains(cFlags, "-DMUSL") ||
+		strings.Contains(cFlags, "-DGLIBC") {
+		t.Errorf("unexpected musl cflags %q", cFlags)
+	}
+	if ldFlags := musl.Rule("ld").Args["ldFlags"]; !strings.Contains(ldFlags, "-static") {
+		t.Errorf("musl host binary is not statically linked: %q", ldFlags)
+	}
+	installer := musl.Module().(*Module).installer.(*binaryDecorator).baseInstaller
+	if installPath := installer.path.String(); !strings.Contains(installPath, "/host/linux_musl-x86/") {
+		t.Errorf("unexpected musl install path %q", installPath)
+	}
+
+	glibc := ctx.ModuleForTests("mybin", "linux_glibc_x86_64")
+	if cFlags := glibc.Rule("cc").Args["cFlags"]; strings.Contains(cFlags, "-DMUSL") {
+		t.Errorf("musl cflags applied to glibc variant: %q", cFlags)
+	}
+}
+
+func TestNativeDebugSymbols(t *testing.T) {
+	bp := `
+		cc_binary {
+			name: "mybin",
+			srcs: ["foo.c"],
+			shared_libs: ["libfoo"],
+		}
+
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+		}
+
+		cc_binary {
+			name: "mybin_nostrip",
+			srcs: ["foo.c"],
+			strip: {
+				none: true,
+			},
+		}`
+
+	config := android.TestArchConfig(buildDir, nil)
+	ctx := createTestContext(t, config, bp, nil, android.Android)
+	ctx.RegisterSingletonType("native_debug_symbols", android.SingletonFactoryAdaptor(nativeDebugSymbolsSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	mybin := ctx.ModuleForTests("mybin", "android_arm64_armv8-a_core")
+	strip := mybin.Rule("strip")
+	debugFile := mybin.Output("debug/mybin.debug")
+	if debugFile.Rule != strip.Rule {
+		t.Errorf("debug file is not created by strip")
+	}
+	if args := strip.Args["args"]; !strings.Contains(args, "-g "+debugFile.Output.String()) {
+		t.Errorf("strip args %q missing -g %s", args, debugFile.Output)
+	}
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_shared")
+	libfoo.Output("debug/libfoo.so.debug")
+
+	nostrip := ctx.ModuleForTests("mybin_nostrip", "android_arm64_armv8-a_core")
+	if nostrip.MaybeOutput("debug/mybin_nostrip.debug").Rule != nil {
+		t.Errorf("unexpected debug file for module that is not stripped")
+	}
+
+	symbolsZip := ctx.SingletonForTests("native_debug_symbols").Output("native-debug-symbols.zip")
+	for _, expected := range []string{"debug/mybin.debug", "debug/libfoo.so.debug"} {
+		found := false
+		for _, input := range symbolsZip.Inputs {
+			if strings.HasSuffix(input.String(), expected) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("native debug symbols zip inputs %q missing %q", symbolsZip.Inputs.Strings(), expected)
+		}
+	}
+	for _, input := range symbolsZip.Inputs {
+		if strings.Contains(input.String(), "mybin_nostrip") {
+			t.Errorf("unexpected native debug symbols zip input %q", input)
+		}
+	}
+}
+
+func TestLtoReports(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libthin",
+			srcs: ["foo.c"],
+			lto: {
+				thin: true,
+			},
+		}
+
+		cc_library_shared {
+			name: "libnolto",
+			srcs: ["foo.c"],
+		}`
+
+	config := android.TestArchConfig(buildDir, map[string]string{
+		"LTO_REPORTS": "true",
+	})
+	ctx := createTestContext(t, config, bp, nil, android.Android)
+	ctx.RegisterSingletonType("lto_report", android.SingletonFactoryAdaptor(ltoReportSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	libthin := ctx.ModuleForTests("libthin", "android_arm64_armv8-a_core_shared")
+	ld := libthin.Rule("timedLd")
+	for _, flag := range []string{"-Wl,--thinlto-cache-dir=", "-Wl,--thinlto-cache-policy=" + defaultThinLTOCachePolicy,
+		"-Wl,--opt-remarks-filename=", "stats-file="} {
+		if !strings.Contains(ld.Args["ldFlags"], flag) {
+			t.Errorf("LTO link flags %q missing %q", ld.Args["ldFlags"], flag)
+		}
+	}
+	libthin.Output("lto/link_time_ms")
+	libthin.Output("lto/stats.json")
+
+	libnolto := ctx.ModuleForTests("libnolto", "android_arm64_armv8-a_core_shared")
+	if ld := libnolto.Rule("ld"); strings.Contains(ld.Args["ldFlags"], "thinlto-cache") {
+		t.Errorf("unexpected LTO flags for a module without LTO: %q", ld.Args["ldFlags"])
+	}
+	if libnolto.MaybeOutput("lto/link_time_ms").Rule != nil {
+		t.Errorf("unexpected LTO link time for a module without LTO")
+	}
+
+	report := ctx.SingletonForTests("lto_report").Output("lto-report.txt")
+	if len(report.Implicits) != 4 {
+		t.Errorf("expected the link time and statistics of 2 LTO links, got %q", report.Implicits.Strings())
+	}
+}
+
+func TestThinLTOCacheDisabled(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libthin",
+			srcs: ["foo.c"],
+			lto: {
+				thin: true,
+			},
+		}`
+
+	config := android.TestArchConfig(buildDir, map[string]string{
+		"DISABLE_THINLTO_CACHE": "true",
+	})
+	ctx := testCcWithConfig(t, bp, config)
+
+	ld := ctx.ModuleForTests("libthin", "android_arm64_armv8-a_core_shared").Rule("ld")
+	if strings.Contains(ld.Args["ldFlags"], "thinlto-cache") {
+		t.Errorf("unexpected ThinLTO cache flags with DISABLE_THINLTO_CACHE=true: %q", ld.Args["ldFlags"])
+	}
+}
+
+func TestPgoProfileCheck(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			pgo: {
+				instrumentation: true,
+				profile_file: "libfoo.profdata",
+				benchmarks: ["foo"],
+			},
+		}
+
+		cc_library_shared {
+			name: "libsampled",
+			srcs: ["foo.c"],
+			pgo: {
+				sampling: true,
+				profile_file: "libsampled.afdo",
+				benchmarks: ["foo"],
+			},
+		}
+
+		cc_library_shared {
+			name: "libbar",
+			srcs: ["foo.c"],
+		}`
+
+	config := android.TestArchConfig(buildDir, map[string]string{
+		"ANDROID_PGO_MIN_PROFILE_MATCH":   "90",
+		"ANDROID_PGO_CURRENT_PROFILE_DIR": "current-profiles",
+	})
+	ctx := createTestContext(t, config, bp, map[string][]byte{
+		"toolchain/pgo-profiles/libfoo.profdata": nil,
+		"toolchain/pgo-profiles/libsampled.afdo": nil,
+		"current-profiles/libfoo.profdata":       nil,
+	}, android.Android)
+	ctx.RegisterSingletonType("pgo_report", android.SingletonFactoryAdaptor(pgoReportSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_shared")
+	check := libfoo.Output("pgo/profile_check.tsv")
+	if check.Args["profile"] != "toolchain/pgo-profiles/libfoo.profdata" {
+		t.Errorf("expected profile toolchain/pgo-profiles/libfoo.profdata, got %q", check.Args["profile"])
+	}
+	if expected := "-c current-profiles/libfoo.profdata -t 90"; check.Args["extraArgs"] != expected {
+		t.Errorf("expected profile check args %q, got %q", expected, check.Args["extraArgs"])
+	}
+	if !libfoo.Module().(*Module).pgo.enforceProfileMatch {
+		t.Errorf("expected the profile check of libfoo to be enforced")
+	}
+
+	sampledCheck := ctx.ModuleForTests("libsampled", "android_arm64_armv8-a_core_shared").Output("pgo/profile_check.tsv")
+	if expected := "-s -t 90"; sampledCheck.Args["extraArgs"] != expected {
+		t.Errorf("expected sampling profile check args %q, got %q", expected, sampledCheck.Args["extraArgs"])
+	}
+
+	libbar := ctx.ModuleForTests("libbar", "android_arm64_armv8-a_core_shared")
+	if libbar.MaybeOutput("pgo/profile_check.tsv").Rule != nil {
+		t.Errorf("unexpected profile check for a module without a PGO profile")
+	}
+
+	report := ctx.SingletonForTests("pgo_report").Output("pgo-report.txt")
+	if len(report.Inputs) != 2 || !inList(check.Output.String(), report.Inputs.Strings()) ||
+		!inList(sampledCheck.Output.String(), report.Inputs.Strings()) {
+		t.Errorf("expected the PGO report to contain %q and %q, got %q", check.Output, sampledCheck.Output,
+			report.Inputs.Strings())
+	}
+}
+
+func TestModuleInfoJson(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			vendor_available: true,
+			srcs: ["foo.c"],
+		}`
+
+	config := android.TestArchConfig(buildDir, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+
+	ctx := createTestContext(t, config, bp, nil, android.Android)
+	ctx.RegisterSingletonType("module_info_json", android.SingletonFactoryAdaptor(android.ModuleInfoJsonSingleton))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	data, err := ioutil.ReadFile(filepath.Join(buildDir, "module-info.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modules map[string]struct {
+		Class     []string `json:"class"`
+		Installed []string `json:"installed"`
+	}
+	if err := json.Unmarshal(data, &modules); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"libfoo", "libfoo.vendor"} {
+		info, ok := modules[name]
+		if !ok {
+			t.Errorf("missing module-info.json entry for %q", name)
+			continue
+		}
+		if !inList("SHARED_LIBRARIES", info.Class) {
+			t.Errorf("expected class of %q to be SHARED_LIBRARIES, got %q", name, info.Class)
+		}
+		if len(info.Installed) == 0 {
+			t.Errorf("expected %q to be installed", name)
+		}
+	}
+
+	// In a build the androidmk singleton calls AndroidMk as well, which must not skip anything.
+	libfoo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_vendor_shared").Module().(*Module)
+	if data := libfoo.AndroidMk(); data.Class != "SHARED_LIBRARIES" || data.SubName != vendorSuffix {
+		t.Errorf("expected the second AndroidMk call to return class %q and sub name %q, got %q and %q",
+			"SHARED_LIBRARIES", vendorSuffix, data.Class, data.SubName)
+	}
+}
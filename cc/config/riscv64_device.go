@@ -0,0 +1,142 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	riscv64Cflags = []string{
+		// Implicitly declared functions return int, which truncates pointers on 64-bit only
+		// architectures like riscv64.
+		"-Werror=implicit-function-declaration",
+	}
+
+	riscv64ArchVariantCflags = map[string][]string{
+		"": []string{
+			"-march=rv64gc",
+		},
+		"rv64gc": []string{
+			"-march=rv64gc",
+		},
+		"rv64gcv": []string{
+			"-march=rv64gcv",
+		},
+	}
+
+	riscv64Ldflags = []string{
+		"-Wl,--hash-style=gnu",
+	}
+
+	// There is no gold linker for riscv64, so riscv64 is always linked with lld.
+	riscv64Lldflags = append(ClangFilterUnknownLldflags(riscv64Ldflags),
+		"-Wl,-z,max-page-size=4096")
+
+	riscv64Cppflags = []string{}
+)
+
+func init() {
+	pctx.StaticVariable("Riscv64Ldflags", strings.Join(riscv64Ldflags, " "))
+	pctx.StaticVariable("Riscv64Lldflags", strings.Join(riscv64Lldflags, " "))
+	pctx.StaticVariable("Riscv64IncludeFlags", bionicHeaders("riscv"))
+
+	pctx.StaticVariable("Riscv64ClangCflags", strings.Join(ClangFilterUnknownCflags(riscv64Cflags), " "))
+	pctx.StaticVariable("Riscv64ClangLdflags", strings.Join(ClangFilterUnknownCflags(riscv64Ldflags), " "))
+	pctx.StaticVariable("Riscv64ClangLldflags", strings.Join(ClangFilterUnknownCflags(riscv64Lldflags), " "))
+	pctx.StaticVariable("Riscv64ClangCppflags", strings.Join(ClangFilterUnknownCflags(riscv64Cppflags), " "))
+
+	// Architecture variant cflags
+	for variant, cflags := range riscv64ArchVariantCflags {
+		pctx.StaticVariable("Riscv64"+variant+"VariantClangCflags",
+			strings.Join(ClangFilterUnknownCflags(cflags), " "))
+	}
+}
+
+type toolchainRiscv64 struct {
+	toolchain64Bit
+
+	toolchainClangCflags string
+}
+
+func (t *toolchainRiscv64) Name() string {
+	return "riscv64"
+}
+
+// There is no GCC toolchain for riscv64, everything comes from the clang toolchain.
+func (t *toolchainRiscv64) GccRoot() string {
+	return "${config.ClangPath}"
+}
+
+func (t *toolchainRiscv64) GccTriple() string {
+	return "riscv64-linux-android"
+}
+
+func (t *toolchainRiscv64) GccVersion() string {
+	return ""
+}
+
+func (t *toolchainRiscv64) ToolPath() string {
+	return "${config.ClangBin}"
+}
+
+func (t *toolchainRiscv64) IncludeFlags() string {
+	return "${config.Riscv64IncludeFlags}"
+}
+
+func (t *toolchainRiscv64) ClangTriple() string {
+	return t.GccTriple()
+}
+
+func (t *toolchainRiscv64) ClangCflags() string {
+	return "${config.Riscv64ClangCflags}"
+}
+
+func (t *toolchainRiscv64) ClangCppflags() string {
+	return "${config.Riscv64ClangCppflags}"
+}
+
+func (t *toolchainRiscv64) ClangLdflags() string {
+	return "${config.Riscv64Ldflags}"
+}
+
+func (t *toolchainRiscv64) ClangLldflags() string {
+	return "${config.Riscv64Lldflags}"
+}
+
+func (t *toolchainRiscv64) ToolchainClangCflags() string {
+	return t.toolchainClangCflags
+}
+
+func (toolchainRiscv64) LibclangRuntimeLibraryArch() string {
+	return "riscv64"
+}
+
+func riscv64ToolchainFactory(arch android.Arch) Toolchain {
+	if _, ok := riscv64ArchVariantCflags[arch.ArchVariant]; !ok {
+		panic(fmt.Sprintf("Unknown RISC-V architecture version: %q", arch.ArchVariant))
+	}
+
+	return &toolchainRiscv64{
+		toolchainClangCflags: "${config.Riscv64" + arch.ArchVariant + "VariantClangCflags}",
+	}
+}
+
+func init() {
+	registerToolchainFactory(android.Android, android.Riscv64, riscv64ToolchainFactory)
+}
@@ -212,6 +212,10 @@ func ThreadSanitizerRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "tsan")
 }
 
+func LibFuzzerRuntimeLibrary(t Toolchain) string {
+	return LibclangRuntimeLibrary(t, "fuzzer")
+}
+
 func ProfileRuntimeLibrary(t Toolchain) string {
 	return LibclangRuntimeLibrary(t, "profile")
 }
@@ -0,0 +1,245 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	linuxMuslCflags = []string{
+		"-fdiagnostics-color",
+
+		"-Wa,--noexecstack",
+
+		"-fPIC",
+
+		"-U_FORTIFY_SOURCE",
+		"-D_FORTIFY_SOURCE=2",
+		"-fstack-protector-strong",
+
+		// Workaround differences in inttypes.h between host and target.
+		//See bug 12708004.
+		"-D__STDC_FORMAT_MACROS",
+		"-D__STDC_CONSTANT_MACROS",
+	}
+
+	linuxMuslLdflags = []string{
+		"-Wl,-z,noexecstack",
+		"-Wl,-z,relro",
+		"-Wl,-z,now",
+		"-Wl,--no-undefined-version",
+	}
+
+	linuxMuslLldflags = ClangFilterUnknownLldflags(linuxMuslLdflags)
+
+	// Extended cflags
+	linuxMuslX86Cflags = []string{
+		"-msse3",
+		"-mfpmath=sse",
+		"-m32",
+		"-march=prescott",
+		"-D_FILE_OFFSET_BITS=64",
+		"-D_LARGEFILE_SOURCE=1",
+	}
+
+	linuxMuslX8664Cflags = []string{
+		"-m64",
+	}
+
+	// Each musl toolchain is a separate cross toolchain with its own sysroot.
+	linuxMuslX86ClangCflags = append(ClangFilterUnknownCflags(linuxMuslX86Cflags), []string{
+		"--gcc-toolchain=${LinuxMuslX86GccRoot}",
+		"--sysroot ${LinuxMuslX86GccRoot}/i686-linux-musl",
+	}...)
+
+	linuxMuslX8664ClangCflags = append(ClangFilterUnknownCflags(linuxMuslX8664Cflags), []string{
+		"--gcc-toolchain=${LinuxMuslX8664GccRoot}",
+		"--sysroot ${LinuxMuslX8664GccRoot}/x86_64-linux-musl",
+	}...)
+
+	linuxMuslX86ClangLdflags = []string{
+		"-m32",
+		"--gcc-toolchain=${LinuxMuslX86GccRoot}",
+		"--sysroot ${LinuxMuslX86GccRoot}/i686-linux-musl",
+		"-B${LinuxMuslX86GccRoot}/lib/gcc/i686-linux-musl/${LinuxMuslGccVersion}",
+		"-L${LinuxMuslX86GccRoot}/lib/gcc/i686-linux-musl/${LinuxMuslGccVersion}",
+	}
+
+	linuxMuslX8664ClangLdflags = []string{
+		"-m64",
+		"--gcc-toolchain=${LinuxMuslX8664GccRoot}",
+		"--sysroot ${LinuxMuslX8664GccRoot}/x86_64-linux-musl",
+		"-B${LinuxMuslX8664GccRoot}/lib/gcc/x86_64-linux-musl/${LinuxMuslGccVersion}",
+		"-L${LinuxMuslX8664GccRoot}/lib/gcc/x86_64-linux-musl/${LinuxMuslGccVersion}",
+	}
+
+	// libdl, libpthread, librt, libresolv and libutil are empty in musl, everything is in libc.
+	linuxMuslAvailableLibraries = addPrefix([]string{
+		"c",
+		"dl",
+		"gcc",
+		"gcc_s",
+		"m",
+		"pthread",
+		"resolv",
+		"rt",
+		"util",
+	}, "-l")
+)
+
+const (
+	linuxMuslGccVersion = "9.2.0"
+)
+
+func init() {
+	pctx.StaticVariable("LinuxMuslGccVersion", linuxMuslGccVersion)
+
+	pctx.SourcePathVariable("LinuxMuslX86GccRoot",
+		"prebuilts/gcc/${HostPrebuiltTag}/host/i686-linux-musl-${LinuxMuslGccVersion}")
+	pctx.SourcePathVariable("LinuxMuslX8664GccRoot",
+		"prebuilts/gcc/${HostPrebuiltTag}/host/x86_64-linux-musl-${LinuxMuslGccVersion}")
+
+	pctx.StaticVariable("LinuxMuslClangCflags", strings.Join(ClangFilterUnknownCflags(linuxMuslCflags), " "))
+	pctx.StaticVariable("LinuxMuslClangLdflags", strings.Join(ClangFilterUnknownCflags(linuxMuslLdflags), " "))
+	pctx.StaticVariable("LinuxMuslClangLldflags", strings.Join(ClangFilterUnknownCflags(linuxMuslLldflags), " "))
+
+	pctx.StaticVariable("LinuxMuslX86ClangCflags", strings.Join(linuxMuslX86ClangCflags, " "))
+	pctx.StaticVariable("LinuxMuslX8664ClangCflags", strings.Join(linuxMuslX8664ClangCflags, " "))
+	pctx.StaticVariable("LinuxMuslX86ClangLdflags", strings.Join(linuxMuslX86ClangLdflags, " "))
+	pctx.StaticVariable("LinuxMuslX86ClangLldflags",
+		strings.Join(ClangFilterUnknownLldflags(linuxMuslX86ClangLdflags), " "))
+	pctx.StaticVariable("LinuxMuslX8664ClangLdflags", strings.Join(linuxMuslX8664ClangLdflags, " "))
+	pctx.StaticVariable("LinuxMuslX8664ClangLldflags",
+		strings.Join(ClangFilterUnknownLldflags(linuxMuslX8664ClangLdflags), " "))
+}
+
+type toolchainLinuxMusl struct{}
+
+type toolchainLinuxMuslX86 struct {
+	toolchain32Bit
+	toolchainLinuxMusl
+}
+
+type toolchainLinuxMuslX8664 struct {
+	toolchain64Bit
+	toolchainLinuxMusl
+}
+
+func (t *toolchainLinuxMuslX86) Name() string {
+	return "x86"
+}
+
+func (t *toolchainLinuxMuslX8664) Name() string {
+	return "x86_64"
+}
+
+func (t *toolchainLinuxMuslX86) GccRoot() string {
+	return "${config.LinuxMuslX86GccRoot}"
+}
+
+func (t *toolchainLinuxMuslX8664) GccRoot() string {
+	return "${config.LinuxMuslX8664GccRoot}"
+}
+
+func (t *toolchainLinuxMuslX86) GccTriple() string {
+	return "i686-linux-musl"
+}
+
+func (t *toolchainLinuxMuslX8664) GccTriple() string {
+	return "x86_64-linux-musl"
+}
+
+func (t *toolchainLinuxMusl) GccVersion() string {
+	return linuxMuslGccVersion
+}
+
+func (t *toolchainLinuxMusl) IncludeFlags() string {
+	return ""
+}
+
+func (t *toolchainLinuxMuslX86) ClangTriple() string {
+	return "i686-linux-musl"
+}
+
+func (t *toolchainLinuxMuslX86) ClangCflags() string {
+	return "${config.LinuxMuslClangCflags} ${config.LinuxMuslX86ClangCflags}"
+}
+
+func (t *toolchainLinuxMuslX86) ClangCppflags() string {
+	return ""
+}
+
+func (t *toolchainLinuxMuslX8664) ClangTriple() string {
+	return "x86_64-linux-musl"
+}
+
+func (t *toolchainLinuxMuslX8664) ClangCflags() string {
+	return "${config.LinuxMuslClangCflags} ${config.LinuxMuslX8664ClangCflags}"
+}
+
+func (t *toolchainLinuxMuslX8664) ClangCppflags() string {
+	return ""
+}
+
+func (t *toolchainLinuxMuslX86) ClangLdflags() string {
+	return "${config.LinuxMuslClangLdflags} ${config.LinuxMuslX86ClangLdflags}"
+}
+
+func (t *toolchainLinuxMuslX86) ClangLldflags() string {
+	return "${config.LinuxMuslClangLldflags} ${config.LinuxMuslX86ClangLldflags}"
+}
+
+func (t *toolchainLinuxMuslX8664) ClangLdflags() string {
+	return "${config.LinuxMuslClangLdflags} ${config.LinuxMuslX8664ClangLdflags}"
+}
+
+func (t *toolchainLinuxMuslX8664) ClangLldflags() string {
+	return "${config.LinuxMuslClangLldflags} ${config.LinuxMuslX8664ClangLldflags}"
+}
+
+func (t *toolchainLinuxMuslX86) YasmFlags() string {
+	return "${config.LinuxX86YasmFlags}"
+}
+
+func (t *toolchainLinuxMuslX8664) YasmFlags() string {
+	return "${config.LinuxX8664YasmFlags}"
+}
+
+func (t *toolchainLinuxMusl) AvailableLibraries() []string {
+	return linuxMuslAvailableLibraries
+}
+
+func (t *toolchainLinuxMusl) Bionic() bool {
+	return false
+}
+
+var toolchainLinuxMuslX86Singleton Toolchain = &toolchainLinuxMuslX86{}
+var toolchainLinuxMuslX8664Singleton Toolchain = &toolchainLinuxMuslX8664{}
+
+func linuxMuslX86ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainLinuxMuslX86Singleton
+}
+
+func linuxMuslX8664ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainLinuxMuslX8664Singleton
+}
+
+func init() {
+	registerToolchainFactory(android.LinuxMusl, android.X86, linuxMuslX86ToolchainFactory)
+	registerToolchainFactory(android.LinuxMusl, android.X86_64, linuxMuslX8664ToolchainFactory)
+}
@@ -35,7 +35,8 @@ type CoverageProperties struct {
 type coverage struct {
 	Properties CoverageProperties
 
-	// Whether binaries containing this module need --coverage added to their ldflags
+	// Whether binaries containing this module need --coverage or -fprofile-instr-generate added
+	// to their ldflags
 	linkCoverage bool
 }
 
@@ -43,21 +44,37 @@ func (cov *coverage) props() []interface{} {
 	return []interface{}{&cov.Properties}
 }
 
-func getProfileLibraryName(ctx ModuleContextIntf) string {
+// The .profraw files written by binaries and shared libraries built with clang coverage.  They are
+// written to a directory that can be pulled from the device, and named after the pid of the process
+// and the signature of the binary or shared library.
+const clangCoverageProfraw = "/data/misc/trace/clang-%p-%m.profraw"
+
+// coverageMappingZipper is implemented by linkers that zip their unstripped output for clang
+// coverage.  The zip records where the output is installed, so it is generated after install.
+type coverageMappingZipper interface {
+	zipCoverageMapping(ctx ModuleContext)
+}
+
+// getProfileLibraryName returns the library that hooks the coverage runtime, so that gcov data or
+// .profraw files are written to a location that can be pulled from the device.
+func getProfileLibraryName(ctx ModuleContextIntf, clangCoverage bool) string {
+	libName := "libprofile-extras"
+	if clangCoverage {
+		libName = "libprofile-clang-extras"
+	}
 	// This function should only ever be called for a cc.Module, so the
 	// following statement should always succeed.
 	if ctx.useSdk() {
-		return "libprofile-extras_ndk"
-	} else {
-		return "libprofile-extras"
+		libName += "_ndk"
 	}
+	return libName
 }
 
 func (cov *coverage) deps(ctx DepsContext, deps Deps) Deps {
 	if cov.Properties.NeedCoverageVariant {
 		ctx.AddVariationDependencies([]blueprint.Variation{
 			{Mutator: "link", Variation: "static"},
-		}, coverageDepTag, getProfileLibraryName(ctx))
+		}, coverageDepTag, getProfileLibraryName(ctx, ctx.DeviceConfig().ClangCoverageEnabled()))
 	}
 	return deps
 }
@@ -67,14 +84,23 @@ func (cov *coverage) flags(ctx ModuleContext, flags Flags, deps PathDeps) (Flags
 		return flags, deps
 	}
 
+	clangCoverage := ctx.DeviceConfig().ClangCoverageEnabled()
+
 	if cov.Properties.CoverageEnabled {
-		flags.Coverage = true
-		flags.GlobalFlags = append(flags.GlobalFlags, "--coverage", "-O0")
-		cov.linkCoverage = true
+		if clangCoverage {
+			// Source-based coverage is accurate with optimizations enabled, and writes the
+			// coverage mapping into the linked output instead of .gcno files.
+			flags.GlobalFlags = append(flags.GlobalFlags, "-fprofile-instr-generate="+clangCoverageProfraw,
+				"-fcoverage-mapping")
+		} else {
+			flags.Coverage = true
+			flags.GlobalFlags = append(flags.GlobalFlags, "--coverage", "-O0")
 
-		// Override -Wframe-larger-than and non-default optimization
-		// flags that the module may use.
-		flags.CFlags = append(flags.CFlags, "-Wno-frame-larger-than=", "-O0")
+			// Override -Wframe-larger-than and non-default optimization
+			// flags that the module may use.
+			flags.CFlags = append(flags.CFlags, "-Wno-frame-larger-than=", "-O0")
+		}
+		cov.linkCoverage = true
 	}
 
 	// Even if we don't have coverage enabled, if any of our object files were compiled
@@ -112,12 +138,19 @@ func (cov *coverage) flags(ctx ModuleContext, flags Flags, deps PathDeps) (Flags
 	}
 
 	if cov.linkCoverage {
-		flags.LdFlags = append(flags.LdFlags, "--coverage")
-
-		coverage := ctx.GetDirectDepWithTag(getProfileLibraryName(ctx), coverageDepTag).(*Module)
+		coverage := ctx.GetDirectDepWithTag(getProfileLibraryName(ctx, clangCoverage), coverageDepTag).(*Module)
 		deps.WholeStaticLibs = append(deps.WholeStaticLibs, coverage.OutputFile().Path())
 
-		flags.LdFlags = append(flags.LdFlags, "-Wl,--wrap,getenv")
+		if clangCoverage {
+			// The profile runtime writes the .profraw file when the process exits,
+			// libprofile-clang-extras also writes it when the process is sent a signal
+			// so that coverage can be collected from long running processes.
+			flags.LdFlags = append(flags.LdFlags, "-fprofile-instr-generate="+clangCoverageProfraw)
+			flags.ClangCoverage = true
+		} else {
+			flags.LdFlags = append(flags.LdFlags, "--coverage")
+			flags.LdFlags = append(flags.LdFlags, "-Wl,--wrap,getenv")
+		}
 	}
 
 	return flags, deps
@@ -0,0 +1,135 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestClangCoverage(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	config.TestProductVariables.ClangCoverage = BoolPtr(true)
+	config.TestProductVariables.CoveragePaths = []string{"*"}
+
+	ctx := createTestContext(t, config, `
+		cc_binary {
+			name: "mybin",
+			srcs: ["foo.c"],
+			shared_libs: ["libfoo"],
+			stl: "none",
+			system_shared_libs: [],
+		}
+
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["bar.c"],
+			stl: "none",
+			system_shared_libs: [],
+		}
+
+		cc_library_static {
+			name: "libprofile-clang-extras",
+			srcs: ["foo.c"],
+			stl: "none",
+			system_shared_libs: [],
+			native_coverage: false,
+		}`, nil, android.Android)
+	ctx.PostDepsMutators(func(ctx android.RegisterMutatorsContext) {
+		ctx.BottomUp("coverage", coverageMutator).Parallel()
+	})
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	tests := []struct {
+		name        string
+		variant     string
+		zip         string
+		manifest    string
+		installPath string
+	}{
+		{
+			name:        "mybin",
+			variant:     "android_arm64_armv8-a_core_cov",
+			zip:         "mybin.zip",
+			manifest:    "mybin-coverage-manifest.txt",
+			installPath: "/system/bin/mybin",
+		},
+		{
+			name:        "libfoo",
+			variant:     "android_arm64_armv8-a_core_shared_cov",
+			zip:         "libfoo.zip",
+			manifest:    "libfoo-coverage-manifest.txt",
+			installPath: "/system/lib64/libfoo.so",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module := ctx.ModuleForTests(test.name, test.variant)
+
+			cc := module.Rule("cc")
+			if !strings.Contains(cc.Args["cFlags"], "-fprofile-instr-generate="+clangCoverageProfraw+" -fcoverage-mapping") {
+				t.Errorf("cflags %q missing clang coverage flags", cc.Args["cFlags"])
+			}
+			if strings.Contains(cc.Args["cFlags"], "--coverage") {
+				t.Errorf("cflags %q contain gcov flags", cc.Args["cFlags"])
+			}
+			for _, output := range cc.ImplicitOutputs {
+				if output.Ext() == ".gcno" {
+					t.Errorf("unexpected gcno output %q", output)
+				}
+			}
+
+			ld := module.Rule("ld")
+			if !strings.Contains(ld.Args["ldFlags"], "-fprofile-instr-generate="+clangCoverageProfraw) {
+				t.Errorf("ldflags %q missing -fprofile-instr-generate", ld.Args["ldFlags"])
+			}
+			if !strings.Contains(ld.Args["libFlags"], "libprofile-clang-extras.a") {
+				t.Errorf("libflags %q missing libprofile-clang-extras", ld.Args["libFlags"])
+			}
+
+			m := module.Module().(*Module)
+			zip := module.Output(test.zip)
+			if !m.CoverageOutputFile().Valid() || m.CoverageOutputFile().Path() != zip.Output {
+				t.Errorf("expected coverage output file %q, got %q", zip.Output, m.CoverageOutputFile())
+			}
+			manifest := module.Output(test.manifest)
+			unstripped := m.linker.unstrippedOutputFilePath()
+			if !android.InList(unstripped.String(), zip.Implicits.Strings()) ||
+				!android.InList(manifest.Output.String(), zip.Implicits.Strings()) {
+				t.Errorf("expected coverage zip inputs %q and %q, got %q", unstripped, manifest.Output,
+					zip.Implicits)
+			}
+
+			for _, line := range []string{
+				"module: " + test.name,
+				"install_path: " + test.installPath,
+				"profraw: " + clangCoverageProfraw,
+				"binary: " + strings.TrimPrefix(unstripped.String(), buildDir+"/"),
+			} {
+				if !strings.Contains(manifest.Args["content"], line) {
+					t.Errorf("coverage manifest %q missing %q", manifest.Args["content"], line)
+				}
+			}
+		})
+	}
+}
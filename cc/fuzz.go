@@ -0,0 +1,334 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/cc/config"
+)
+
+type FuzzConfig struct {
+	// Email addresses of people to CC on bugs filed by the fuzzing infrastructure.
+	Cc []string `json:"cc,omitempty"`
+	// Component in the bug tracker that bugs should be filed against.
+	Componentid *int64 `json:"componentid,omitempty"`
+	// Hotlists in the bug tracker that bugs should be added to.
+	Hotlists []string `json:"hotlists,omitempty"`
+	// Extra options passed to libFuzzer when the fuzz target is run, for example "max_len=1024".
+	Libfuzzer_options []string `json:"libfuzzer_options,omitempty"`
+	// Set to false to stop the fuzzing infrastructure from running this fuzz target on devices.
+	Fuzz_on_device *bool `json:"fuzz_on_device,omitempty"`
+	// Set to false to stop the fuzzing infrastructure from running this fuzz target on hosts.
+	Fuzz_on_host *bool `json:"fuzz_on_host,omitempty"`
+}
+
+type FuzzProperties struct {
+	// list of files or filegroup modules that provide the seed corpus of the fuzz target.
+	Corpus []string `android:"path"`
+
+	// the libFuzzer dictionary for the fuzz target.  Must have a .dict extension.
+	Dictionary *string `android:"path"`
+
+	// configuration for the fuzzing infrastructure, written to config.json next to the fuzz
+	// target.
+	Fuzz_config *FuzzConfig
+}
+
+func init() {
+	android.RegisterModuleType("cc_fuzz", FuzzFactory)
+	android.RegisterSingletonType("cc_fuzz_packaging", fuzzPackagingFactory)
+}
+
+// cc_fuzz creates a libFuzzer fuzz target, which is a binary that defines
+// LLVMFuzzerTestOneInput and is linked against libFuzzer.  Fuzz targets and
+// everything they link against are built with ASan (or HWASan if it is enabled)
+// and the libFuzzer coverage instrumentation.  Fuzz targets are installed to
+// /data/fuzz/<arch>/<name>/ along with their corpus, dictionary and config, and
+// are packaged together with their shared library dependencies into
+// fuzz-<target|host>-<arch>.zip.
+func FuzzFactory() android.Module {
+	module := NewFuzz(android.HostAndDeviceSupported)
+	return module.Init()
+}
+
+func NewFuzzInstaller() *baseInstaller {
+	return NewBaseInstaller("fuzz", "fuzz", InstallInData)
+}
+
+type fuzzBinary struct {
+	*binaryDecorator
+	*baseCompiler
+
+	Properties FuzzProperties
+
+	corpus     android.Paths
+	dictionary android.Path
+	config     android.Path
+
+	// The shared libraries that the fuzz target needs at runtime, which are
+	// packaged into the lib directory next to it.
+	sharedLibraries android.Paths
+}
+
+func (fuzz *fuzzBinary) linkerProps() []interface{} {
+	props := fuzz.binaryDecorator.linkerProps()
+	props = append(props, &fuzz.Properties)
+	return props
+}
+
+func (fuzz *fuzzBinary) linkerDeps(ctx DepsContext, deps Deps) Deps {
+	// The clang driver links libFuzzer on hosts, but devices need the runtime
+	// from the prebuilts.
+	if ctx.Device() {
+		deps.StaticLibs = append(deps.StaticLibs, config.LibFuzzerRuntimeLibrary(ctx.toolchain()))
+	}
+	deps = fuzz.binaryDecorator.linkerDeps(ctx, deps)
+	return deps
+}
+
+func (fuzz *fuzzBinary) linkerFlags(ctx ModuleContext, flags Flags) Flags {
+	flags = fuzz.binaryDecorator.linkerFlags(ctx, flags)
+	if ctx.Host() {
+		flags.LdFlags = append(flags.LdFlags, "-fsanitize=fuzzer")
+	}
+	// Shared libraries are packaged into the lib directory next to the fuzz
+	// target, and installed fuzz targets use the libraries in ../lib.
+	flags.LdFlags = append(flags.LdFlags, `-Wl,-rpath,\$$ORIGIN/lib`)
+	flags.LdFlags = append(flags.LdFlags, `-Wl,-rpath,\$$ORIGIN/../lib`)
+	return flags
+}
+
+func (fuzz *fuzzBinary) install(ctx ModuleContext, file android.Path) {
+	// installDir already adds the architecture for the secondary architecture
+	// of devices.
+	if ctx.Host() || ctx.Arch().Native {
+		fuzz.binaryDecorator.baseInstaller.subDir = ctx.Arch().ArchType.String()
+	}
+	fuzz.binaryDecorator.baseInstaller.relative = ctx.ModuleName()
+	fuzz.binaryDecorator.baseInstaller.install(ctx, file)
+
+	installDir := fuzz.binaryDecorator.baseInstaller.installDir(ctx)
+
+	// The corpus is copied into a single directory so that Make can install it
+	// with LOCAL_TEST_DATA.
+	fuzz.corpus = nil
+	for _, path := range android.PathsForModuleSrc(ctx, fuzz.Properties.Corpus) {
+		corpusPath := android.PathForModuleOut(ctx, "corpus", path.Base())
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   android.Cp,
+			Input:  path,
+			Output: corpusPath,
+		})
+		fuzz.corpus = append(fuzz.corpus, corpusPath)
+		ctx.InstallFile(installDir.Join(ctx, "corpus"), corpusPath.Base(), corpusPath)
+	}
+
+	if fuzz.Properties.Dictionary != nil {
+		fuzz.dictionary = android.PathForModuleSrc(ctx, *fuzz.Properties.Dictionary)
+		if fuzz.dictionary.Ext() != ".dict" {
+			ctx.PropertyErrorf("dictionary", "fuzzer dictionary %q does not have a .dict extension",
+				fuzz.dictionary.String())
+		}
+		ctx.InstallFile(installDir, fuzz.dictionary.Base(), fuzz.dictionary)
+	}
+
+	if fuzz.Properties.Fuzz_config != nil {
+		content, err := json.Marshal(fuzz.Properties.Fuzz_config)
+		if err != nil {
+			ctx.PropertyErrorf("fuzz_config", "%s", err)
+			return
+		}
+		configPath := android.PathForModuleOut(ctx, "config", "config.json")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.WriteFile,
+			Description: "fuzz target config",
+			Output:      configPath,
+			Args: map[string]string{
+				"content": string(content),
+			},
+		})
+		fuzz.config = configPath
+		ctx.InstallFile(installDir, configPath.Base(), configPath)
+	}
+
+	fuzz.sharedLibraries = collectFuzzSharedLibraries(ctx)
+}
+
+// collectFuzzSharedLibraries returns the shared libraries that a fuzz target
+// loads at runtime, other than the bionic libraries that are always present on
+// devices.
+func collectFuzzSharedLibraries(ctx ModuleContext) android.Paths {
+	var sharedLibraries android.Paths
+	seen := make(map[android.Path]bool)
+
+	ctx.WalkDeps(func(child, parent android.Module) bool {
+		if tag, ok := ctx.OtherModuleDependencyTag(child).(dependencyTag); !ok || !tag.library {
+			return false
+		}
+		c, ok := child.(*Module)
+		if !ok {
+			return false
+		}
+		if lib, ok := c.linker.(libraryInterface); ok && !lib.static() && !c.header() &&
+			!c.IsStubs() && !isBionic(ctx.OtherModuleName(child)) && c.outputFile.Valid() {
+
+			if path := c.outputFile.Path(); !seen[path] {
+				seen[path] = true
+				sharedLibraries = append(sharedLibraries, path)
+			}
+		}
+		return true
+	})
+
+	return sharedLibraries
+}
+
+func NewFuzz(hod android.HostOrDeviceSupported) *Module {
+	module, binary := NewBinary(hod)
+	module.multilib = android.MultilibBoth
+
+	binary.baseInstaller = NewFuzzInstaller()
+	module.sanitize.SetSanitizer(fuzzer, true)
+
+	fuzz := &fuzzBinary{
+		binaryDecorator: binary,
+		baseCompiler:    NewBaseCompiler(),
+	}
+	module.compiler = fuzz
+	module.linker = fuzz
+	module.installer = fuzz
+
+	// libFuzzer is not available for Darwin, Windows or musl hosts.
+	android.AddLoadHook(module, func(ctx android.LoadHookContext) {
+		disableHosts := struct {
+			Target struct {
+				Darwin struct {
+					Enabled *bool
+				}
+				Linux_musl struct {
+					Enabled *bool
+				}
+				Windows struct {
+					Enabled *bool
+				}
+			}
+		}{}
+		disableHosts.Target.Darwin.Enabled = BoolPtr(false)
+		disableHosts.Target.Linux_musl.Enabled = BoolPtr(false)
+		disableHosts.Target.Windows.Enabled = BoolPtr(false)
+		ctx.AppendProperties(&disableHosts)
+	})
+
+	return module
+}
+
+func fuzzPackagingFactory() android.Singleton {
+	return &fuzzPackager{}
+}
+
+// fuzzPackager packages every fuzz target into fuzz-<target|host>-<arch>.zip,
+// with one directory per fuzz target that contains the fuzz target, its
+// corpus, dictionary and config, and its shared libraries in a lib directory.
+type fuzzPackager struct {
+	packages android.Paths
+}
+
+type fuzzPackageFile struct {
+	src    android.Path
+	prefix string
+}
+
+func (s *fuzzPackager) GenerateBuildActions(ctx android.SingletonContext) {
+	archFiles := make(map[string][]fuzzPackageFile)
+
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok {
+			return
+		}
+		fuzz, ok := c.compiler.(*fuzzBinary)
+		if !ok {
+			return
+		}
+		// The vendor and recovery variants are copies of the fuzz targets that
+		// are already packaged.
+		if !c.Enabled() || c.Properties.PreventInstall || c.useVndk() || c.inRecovery() ||
+			!c.outputFile.Valid() {
+			return
+		}
+
+		hostOrTarget := "target"
+		if c.Host() {
+			hostOrTarget = "host"
+		}
+		archName := hostOrTarget + "-" + c.Arch().ArchType.String()
+
+		name := ctx.ModuleName(module)
+		files := []fuzzPackageFile{{c.outputFile.Path(), name}}
+		for _, path := range fuzz.corpus {
+			files = append(files, fuzzPackageFile{path, filepath.Join(name, "corpus")})
+		}
+		if fuzz.dictionary != nil {
+			files = append(files, fuzzPackageFile{fuzz.dictionary, name})
+		}
+		if fuzz.config != nil {
+			files = append(files, fuzzPackageFile{fuzz.config, name})
+		}
+		for _, path := range fuzz.sharedLibraries {
+			files = append(files, fuzzPackageFile{path, filepath.Join(name, "lib")})
+		}
+
+		archFiles[archName] = append(archFiles[archName], files...)
+	})
+
+	var archNames []string
+	for archName := range archFiles {
+		archNames = append(archNames, archName)
+	}
+	sort.Strings(archNames)
+
+	for _, archName := range archNames {
+		outputFile := android.PathForOutput(ctx, "fuzz-"+archName+".zip")
+		s.packages = append(s.packages, outputFile)
+
+		rule := android.NewRuleBuilder()
+		cmd := rule.Command().
+			Tool(ctx.Config().HostToolPath(ctx, "soong_zip")).
+			Flag("-j").
+			FlagWithOutput("-o ", outputFile)
+		for _, file := range archFiles[archName] {
+			cmd.FlagWithArg("-P ", file.prefix).
+				FlagWithInput("-f ", file.src)
+		}
+		rule.Build(pctx, ctx, "fuzz-package-"+archName, "fuzz targets for "+archName)
+	}
+
+	if len(s.packages) > 0 {
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   android.Phony,
+			Output: android.PathForPhony(ctx, "fuzz-packages"),
+			Inputs: s.packages,
+		})
+	}
+}
+
+func (s *fuzzPackager) MakeVars(ctx android.MakeVarsContext) {
+	ctx.Strict("SOONG_FUZZ_PACKAGES", strings.Join(s.packages.Strings(), " "))
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+const fuzzTestDeps = `
+		cc_object {
+			name: "crtbegin_dynamic",
+			recovery_available: true,
+			vendor_available: true,
+		}
+
+		cc_library_static {
+			name: "libclang_rt.fuzzer-aarch64-android",
+			srcs: ["foo.c"],
+			stl: "none",
+			system_shared_libs: [],
+			sanitize: {
+				never: true,
+			},
+		}
+
+		cc_library_static {
+			name: "libclang_rt.fuzzer-arm-android",
+			srcs: ["foo.c"],
+			stl: "none",
+			system_shared_libs: [],
+			sanitize: {
+				never: true,
+			},
+		}
+
+		cc_library_shared {
+			name: "libclang_rt.asan-aarch64-android",
+			srcs: ["foo.c"],
+			stl: "none",
+			system_shared_libs: [],
+			nocrt: true,
+			sanitize: {
+				never: true,
+			},
+		}
+
+		cc_library_shared {
+			name: "libclang_rt.asan-arm-android",
+			srcs: ["foo.c"],
+			stl: "none",
+			system_shared_libs: [],
+			nocrt: true,
+			sanitize: {
+				never: true,
+			},
+		}
+`
+
+func testCcFuzz(t *testing.T, bp string) (*android.TestContext, []error) {
+	t.Helper()
+	config := android.TestArchConfig(buildDir, nil)
+
+	ctx := createTestContext(t, config, bp+fuzzTestDeps, map[string][]byte{
+		"corpus/seed1": nil,
+		"corpus/seed2": nil,
+		"foo.dict":     nil,
+		"foo.txt":      nil,
+	}, android.Android)
+	ctx.RegisterModuleType("cc_fuzz", android.ModuleFactoryAdaptor(FuzzFactory))
+	ctx.RegisterSingletonType("cc_fuzz_packaging", android.SingletonFactoryAdaptor(fuzzPackagingFactory))
+	ctx.PostDepsMutators(func(ctx android.RegisterMutatorsContext) {
+		ctx.TopDown("asan_deps", sanitizerDepsMutator(asan))
+		ctx.BottomUp("asan", sanitizerMutator(asan)).Parallel()
+		ctx.TopDown("fuzzer_deps", sanitizerDepsMutator(fuzzer))
+		ctx.BottomUp("fuzzer", sanitizerMutator(fuzzer)).Parallel()
+		ctx.TopDown("sanitize_runtime_deps", sanitizerRuntimeDepsMutator)
+		ctx.BottomUp("sanitize_runtime", sanitizerRuntimeMutator).Parallel()
+	})
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if len(errs) > 0 {
+		return ctx, errs
+	}
+	_, errs = ctx.PrepareBuildActions(config)
+	return ctx, errs
+}
+
+func TestFuzz(t *testing.T) {
+	ctx, errs := testCcFuzz(t, `
+		cc_fuzz {
+			name: "fuzz_foo",
+			srcs: ["foo.c"],
+			shared_libs: ["libfuzzdep"],
+			stl: "none",
+			system_shared_libs: [],
+			corpus: ["corpus/*"],
+			dictionary: "foo.dict",
+			fuzz_config: {
+				cc: ["someone@example.com"],
+				componentid: 1234,
+			},
+		}
+
+		cc_library_shared {
+			name: "libfuzzdep",
+			srcs: ["bar.c"],
+			stl: "none",
+			system_shared_libs: [],
+		}`)
+	android.FailIfErrored(t, errs)
+
+	const fuzzVariant = "android_arm64_armv8-a_core_asan_fuzzer"
+	fuzz := ctx.ModuleForTests("fuzz_foo", fuzzVariant)
+
+	cFlags := fuzz.Rule("cc").Args["cFlags"]
+	if !strings.Contains(cFlags, "-fsanitize=address,fuzzer-no-link") {
+		t.Errorf("fuzz target cflags %q missing -fsanitize=address,fuzzer-no-link", cFlags)
+	}
+
+	libFlags := fuzz.Rule("ld").Args["libFlags"]
+	if !strings.Contains(libFlags, "libclang_rt.fuzzer-aarch64-android.a") {
+		t.Errorf("fuzz target is not linked against libFuzzer: %q", libFlags)
+	}
+
+	depCFlags := ctx.ModuleForTests("libfuzzdep", "android_arm64_armv8-a_core_shared_asan_fuzzer").
+		Rule("cc").Args["cFlags"]
+	if !strings.Contains(depCFlags, "fuzzer-no-link") {
+		t.Errorf("fuzz target dependency cflags %q missing fuzzer-no-link", depCFlags)
+	}
+
+	fuzzBin := fuzz.Module().(*Module).installer.(*fuzzBinary)
+	if installPath := fuzzBin.binaryDecorator.baseInstaller.path.String(); !strings.HasSuffix(installPath,
+		"/data/fuzz/arm64/fuzz_foo/fuzz_foo") {
+		t.Errorf("unexpected fuzz target install path %q", installPath)
+	}
+
+	if len(fuzzBin.corpus) != 2 {
+		t.Errorf("expected 2 corpus files, got %q", fuzzBin.corpus)
+	}
+	if len(fuzzBin.sharedLibraries) != 1 || fuzzBin.sharedLibraries[0].Base() != "libfuzzdep.so" {
+		t.Errorf("expected shared libraries [libfuzzdep.so], got %q", fuzzBin.sharedLibraries)
+	}
+
+	config := fuzz.Output("config/config.json")
+	expectedConfig := `{"cc":["someone@example.com"],"componentid":1234}`
+	if config.Args["content"] != expectedConfig {
+		t.Errorf("expected fuzz config %q, got %q", expectedConfig, config.Args["content"])
+	}
+
+	pkg := ctx.SingletonForTests("cc_fuzz_packaging").Output("fuzz-target-arm64.zip")
+	for _, expected := range []string{
+		"-P fuzz_foo -f " + fuzz.Module().(*Module).outputFile.Path().String(),
+		"-P fuzz_foo/corpus -f ",
+		"-P fuzz_foo -f foo.dict",
+		"-P fuzz_foo/lib -f ",
+		"libfuzzdep.so",
+	} {
+		if !strings.Contains(pkg.RuleParams.Command, expected) {
+			t.Errorf("fuzz package command %q missing %q", pkg.RuleParams.Command, expected)
+		}
+	}
+}
+
+func TestFuzzDictionaryExtension(t *testing.T) {
+	_, errs := testCcFuzz(t, `
+		cc_fuzz {
+			name: "fuzz_foo",
+			srcs: ["foo.c"],
+			stl: "none",
+			system_shared_libs: [],
+			dictionary: "foo.txt",
+		}`)
+	android.FailIfNoMatchingErrors(t, `does not have a \.dict extension`, errs)
+}
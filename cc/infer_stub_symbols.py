@@ -0,0 +1,262 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2019 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Infers the symbols of a stub library from the library and its headers.
+
+The symbols that the library exports and that are declared in its exported
+headers are compared against the symbol file of the stubs. The differences are
+written as an API diff, and an updated symbol file that adds the missing
+symbols and drops the symbols the library no longer exports is written next to
+it.
+"""
+import argparse
+import io
+import json
+import os
+import re
+import sys
+
+import gen_stub_libs as gsl
+
+
+# Symbols that the linker defines in every shared library.
+LINKER_SYMBOLS = frozenset([
+    '__bss_start',
+    '_edata',
+    '_end',
+    '_fini',
+    '_init',
+])
+
+# llvm-nm symbol types of exported variables.
+VARIABLE_TYPES = frozenset(['B', 'D', 'R', 'V'])
+
+IDENTIFIER_RE = re.compile(r'[A-Za-z_][A-Za-z0-9_]*')
+COMMENT_RE = re.compile(r'//[^\n]*|/\*.*?\*/', re.DOTALL)
+
+
+def parse_library_symbols(nm_output):
+    """Parses llvm-nm --format=posix output of the library's dynamic symbols.
+
+    Returns: Dict of the names of the exported C symbols to whether they are
+    variables. C++ symbols are not supported by stub libraries and are skipped.
+    """
+    symbols = {}
+    for line in nm_output.splitlines():
+        fields = line.split()
+        if len(fields) < 2:
+            continue
+        name = fields[0].partition('@')[0]
+        sym_type = fields[1]
+        if name.startswith('_Z') or name in LINKER_SYMBOLS:
+            continue
+        symbols[name] = sym_type.upper() in VARIABLE_TYPES
+    return symbols
+
+
+def header_identifiers(header_contents):
+    """Returns the set of identifiers outside of comments in the headers."""
+    identifiers = set()
+    for contents in header_contents:
+        identifiers.update(IDENTIFIER_RE.findall(COMMENT_RE.sub(' ', contents)))
+    return identifiers
+
+
+def infer_symbols(library_symbols, identifiers):
+    """Returns the library symbols that are declared in the headers."""
+    return {name: is_var for name, is_var in library_symbols.items()
+            if name in identifiers}
+
+
+def symbol_file_symbols(versions, arch, api, vndk, apex):
+    """Returns the symbols of the symbol file.
+
+    Returns: Tuple of the set of every symbol named in the symbol file, and the
+    set of symbols that are in the stubs for the given arch, API level, VNDK
+    and APEX settings. C++ symbols are left out of the stubs, as they are not
+    inferred from the library.
+    """
+    named = set()
+    stubs = set()
+    for version in versions:
+        omit_version = gsl.should_omit_version(version, arch, api, vndk, apex)
+        for symbol in version.symbols:
+            named.add(symbol.name)
+            if omit_version or symbol.name.startswith('_Z'):
+                continue
+            if gsl.should_omit_symbol(symbol, arch, api, vndk, apex):
+                continue
+            stubs.add(symbol.name)
+    return named, stubs
+
+
+def diff_symbols(inferred, named, stubs):
+    """Returns the sorted lists of added and removed symbols."""
+    added = sorted(name for name in inferred if name not in named)
+    removed = sorted(name for name in stubs if name not in inferred)
+    return added, removed
+
+
+def write_diff(diff_file, added, removed):
+    """Writes the added and removed symbols as +symbol and -symbol lines."""
+    for name in added:
+        diff_file.write('+{}\n'.format(name))
+    for name in removed:
+        diff_file.write('-{}\n'.format(name))
+
+
+def update_symbol_file(lines, versions, added, removed, inferred, lib_name):
+    """Returns the lines of the symbol file updated with the symbol diff.
+
+    Removed symbols are dropped, and added symbols are appended to the global
+    section of the last public version, or to a new version if there is none.
+    """
+    removed = set(removed)
+    symbol_line_re = re.compile(r'^\s*([A-Za-z_][A-Za-z0-9_]*)\s*;')
+
+    public_versions = [v.name for v in versions
+                       if not gsl.version_is_private(v.name)]
+    target_version = public_versions[-1] if public_versions else None
+
+    def added_lines():
+        result = []
+        for name in added:
+            if inferred[name]:
+                result.append('    {}; # var\n'.format(name))
+            else:
+                result.append('    {};\n'.format(name))
+        return result
+
+    output = []
+    current_version = None
+    cpp_symbols = False
+    inserted = False
+    for line in lines:
+        stripped = line.partition('#')[0].strip()
+        if current_version is None and '{' in stripped:
+            current_version = stripped.split('{')[0].strip()
+        elif 'extern "C++" {' in line:
+            cpp_symbols = True
+        elif '}' in stripped:
+            if cpp_symbols:
+                cpp_symbols = False
+            else:
+                if current_version == target_version and not inserted:
+                    output.extend(added_lines())
+                    inserted = True
+                current_version = None
+        elif current_version is not None and not cpp_symbols:
+            if stripped.startswith('local:') and \
+                    current_version == target_version and not inserted:
+                output.extend(added_lines())
+                inserted = True
+            match = symbol_line_re.match(line)
+            if match and match.group(1) in removed:
+                continue
+        output.append(line)
+
+    if not inserted and added:
+        if output and not output[-1].endswith('\n'):
+            output[-1] += '\n'
+        output.append('\n')
+        output.append('{} {{\n'.format(lib_name.upper()))
+        output.append('  global:\n')
+        output.extend(added_lines())
+        output.append('  local:\n')
+        output.append('    *;\n')
+        output.append('};\n')
+
+    return output
+
+
+def parse_args():
+    """Parses and returns command line arguments."""
+    parser = argparse.ArgumentParser()
+
+    parser.add_argument(
+        '--arch', choices=gsl.ALL_ARCHITECTURES, required=True,
+        help='Architecture of the library.')
+    parser.add_argument(
+        '--api', default='current', help='API level being targeted.')
+    parser.add_argument(
+        '--vndk', action='store_true', help='Use the VNDK variant.')
+    parser.add_argument(
+        '--apex', action='store_true', help='Use the APEX variant.')
+    parser.add_argument(
+        '--api-map', type=os.path.realpath, required=True,
+        help='Path to the API level map JSON file.')
+    parser.add_argument(
+        '--name', required=True,
+        help='Name of the library, used for a new version section.')
+    parser.add_argument(
+        '--library-symbols', type=os.path.realpath, required=True,
+        help='Path to the llvm-nm --format=posix output of the dynamic '
+        'symbols of the library.')
+    parser.add_argument(
+        '--headers', type=os.path.realpath, required=True,
+        help='Path to a file that lists the exported headers.')
+
+    parser.add_argument(
+        'symbol_file', type=os.path.realpath, help='Path to symbol file.')
+    parser.add_argument(
+        'diff', type=os.path.realpath, help='Path to output symbol diff.')
+    parser.add_argument(
+        'updated_symbol_file', type=os.path.realpath,
+        help='Path to output updated symbol file.')
+
+    return parser.parse_args()
+
+
+def main():
+    """Program entry point."""
+    args = parse_args()
+
+    with open(args.api_map) as map_file:
+        api_map = json.load(map_file)
+    api = gsl.decode_api_level(args.api, api_map)
+
+    with open(args.library_symbols) as nm_file:
+        library_symbols = parse_library_symbols(nm_file.read())
+
+    with open(args.headers) as headers_file:
+        header_contents = []
+        for header in headers_file.read().split():
+            with open(header) as f:
+                header_contents.append(f.read())
+    inferred = infer_symbols(library_symbols, header_identifiers(header_contents))
+
+    with open(args.symbol_file) as symbol_file:
+        lines = symbol_file.readlines()
+    try:
+        versions = gsl.SymbolFileParser(io.StringIO(u''.join(lines)), api_map, args.arch,
+                                        api, args.vndk, args.apex).parse()
+    except (gsl.ParseError, gsl.MultiplyDefinedSymbolError) as ex:
+        sys.exit('{}: error: {}'.format(args.symbol_file, ex))
+
+    named, stubs = symbol_file_symbols(versions, args.arch, api, args.vndk,
+                                       args.apex)
+    added, removed = diff_symbols(inferred, named, stubs)
+
+    with open(args.diff, 'w') as diff_file:
+        write_diff(diff_file, added, removed)
+
+    with open(args.updated_symbol_file, 'w') as updated_file:
+        updated_file.writelines(update_symbol_file(
+            lines, versions, added, removed, inferred, args.name))
+
+
+if __name__ == '__main__':
+    main()
@@ -80,6 +80,15 @@ func (installer *baseInstaller) install(ctx ModuleContext, file android.Path) {
 	installer.path = ctx.InstallFile(installer.installDir(ctx), file.Base(), file)
 }
 
+// onDevicePath returns the path the module was installed to on the device, or an empty string if
+// it was not installed, e.g. because it is only used by APEXes.
+func (installer *baseInstaller) onDevicePath(ctx ModuleContext) string {
+	if installer.path.RelPathString() == "" {
+		return ""
+	}
+	return android.InstallPathToOnDevicePath(ctx, installer.path)
+}
+
 func (installer *baseInstaller) inData() bool {
 	return installer.location == InstallInData
 }
@@ -81,6 +81,12 @@ type LibraryProperties struct {
 
 		// List versions to generate stubs libs for.
 		Versions []string
+
+		// Headers that declare the API of the stubs.  When set, the C symbols that the library
+		// exports and that are declared in these headers are compared against symbol_file, and
+		// any difference is a build error.  m update-stub-symbols-<module> rewrites symbol_file
+		// with the symbols inferred from the library.
+		Exported_headers []string `android:"path"`
 	}
 
 	// set the name of the output
@@ -95,6 +101,21 @@ type LibraryProperties struct {
 
 	// Properties for ABI compatibility checker
 	Header_abi_checker struct {
+		// Enable ABI checks for a library that is not an NDK, LLNDK or VNDK library, or disable them
+		// for one that is.  The ABI of the library is compared against its reference ABI dump, and
+		// incompatible changes are build errors.  When enabled, a missing reference ABI dump is a
+		// build error too; create it with m update-abi-ref-dumps-<module>.
+		Enabled *bool
+
+		// Directories relative to the module directory that contain the reference ABI dumps of the
+		// library, as <dir>/<PlatformSdkVersion>/<binder bitness>/<arch>_<arch variant>/source-based/.
+		// Defaults to prebuilts/abi-dumps/ndk or prebuilts/abi-dumps/vndk for NDK, LLNDK and VNDK
+		// libraries, and to prebuilts/abi-dumps/platform for other libraries.
+		Ref_dump_dirs []string
+
+		// Extra flags to pass to header-abi-diff, for example -allow-unreferenced-changes.
+		Diff_flags []string
+
 		// Path to a symbol file that specifies the symbols to be included in the generated
 		// ABI dump file
 		Symbol_file *string `android:"path"`
@@ -284,6 +305,15 @@ type libraryDecorator struct {
 	// Source Abi Diff
 	sAbiDiff android.OptionalPath
 
+	// Output of the rule that copies the linked Source Abi Dump over the reference dump
+	sAbiRefDumpUpdate android.OptionalPath
+
+	// Result of comparing the symbol file of the stubs against the symbols inferred from the library
+	stubSymbolsCheck android.OptionalPath
+
+	// Output of the rule that copies the inferred stub symbols over the symbol file
+	stubSymbolsUpdate android.OptionalPath
+
 	// Location of the static library in the sysroot. Empty if the library is
 	// not included in the NDK.
 	ndkSysrootPath android.Path
@@ -291,6 +321,9 @@ type libraryDecorator struct {
 	// Location of the linked, unstripped library for shared libraries
 	unstrippedOutputFile android.Path
 
+	// Location of the debug info split out of the unstripped library for shared libraries
+	debugFile android.OptionalPath
+
 	// Location of the file that should be copied to dist dir when requested
 	distFile android.OptionalPath
 
@@ -448,7 +481,7 @@ func (library *libraryDecorator) compile(ctx ModuleContext, flags Flags, deps Pa
 		}
 		return Objects{}
 	}
-	if ctx.shouldCreateVndkSourceAbiDump() || library.sabi.Properties.CreateSAbiDumps {
+	if ctx.shouldCreateSourceAbiDump() || library.sabi.Properties.CreateSAbiDumps {
 		exportIncludeDirs := library.flagExporter.exportedIncludes(ctx)
 		var SourceAbiFlags []string
 		for _, dir := range exportIncludeDirs.Strings() {
@@ -708,7 +741,7 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 		}
 		strippedOutputFile := outputFile
 		outputFile = android.PathForModuleOut(ctx, "unstripped", fileName)
-		library.stripper.strip(ctx, outputFile, strippedOutputFile, builderFlags)
+		library.debugFile = library.stripper.stripAndSplitDebugInfo(ctx, outputFile, strippedOutputFile, builderFlags)
 	}
 
 	library.unstrippedOutputFile = outputFile
@@ -751,8 +784,11 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	objs.sAbiDumpFiles = append(objs.sAbiDumpFiles, deps.StaticLibObjs.sAbiDumpFiles...)
 	objs.sAbiDumpFiles = append(objs.sAbiDumpFiles, deps.WholeStaticLibObjs.sAbiDumpFiles...)
 
-	library.coverageOutputFile = TransformCoverageFilesToZip(ctx, objs, library.getLibName(ctx))
+	if !flags.ClangCoverage {
+		library.coverageOutputFile = TransformCoverageFilesToZip(ctx, objs, library.getLibName(ctx))
+	}
 	library.linkSAbiDumpFiles(ctx, objs, fileName, ret)
+	library.checkStubSymbols(ctx, library.unstrippedOutputFile)
 
 	return ret
 }
@@ -761,6 +797,10 @@ func (library *libraryDecorator) unstrippedOutputFilePath() android.Path {
 	return library.unstrippedOutputFile
 }
 
+func (library *libraryDecorator) debugFilePath() android.OptionalPath {
+	return library.debugFile
+}
+
 func (library *libraryDecorator) nativeCoverage() bool {
 	if library.header() || library.buildStubs() {
 		return false
@@ -772,33 +812,68 @@ func (library *libraryDecorator) coverageOutputFilePath() android.OptionalPath {
 	return library.coverageOutputFile
 }
 
-func getRefAbiDumpFile(ctx ModuleContext, vndkVersion, fileName string) android.Path {
-	isLlndk := inList(ctx.baseModuleName(), llndkLibraries) || inList(ctx.baseModuleName(), ndkMigratedLibs)
+func (library *libraryDecorator) zipCoverageMapping(ctx ModuleContext) {
+	if library.shared() {
+		library.coverageOutputFile = TransformCoverageMappingToZip(ctx, library.unstrippedOutputFile,
+			library.baseInstaller.onDevicePath(ctx), library.getLibName(ctx))
+	}
+}
 
-	refAbiDumpTextFile := android.PathForVndkRefAbiDump(ctx, vndkVersion, fileName, isLlndk, false)
-	refAbiDumpGzipFile := android.PathForVndkRefAbiDump(ctx, vndkVersion, fileName, isLlndk, true)
+// refAbiDumpDirs returns the directories that contain the reference ABI dumps of the library, and
+// the version subdirectory of them to use.
+func (library *libraryDecorator) refAbiDumpDirs(ctx ModuleContext) ([]string, string) {
+	if dirs := library.Properties.Header_abi_checker.Ref_dump_dirs; len(dirs) > 0 {
+		var ret []string
+		for _, dir := range dirs {
+			ret = append(ret, filepath.Join(ctx.ModuleDir(), dir))
+		}
+		return ret, ctx.Config().PlatformSdkVersion()
+	}
 
-	if refAbiDumpTextFile.Valid() {
-		if refAbiDumpGzipFile.Valid() {
-			ctx.ModuleErrorf(
-				"Two reference ABI dump files are found: %q and %q. Please delete the stale one.",
-				refAbiDumpTextFile, refAbiDumpGzipFile)
-			return nil
+	if ctx.shouldCreateVndkSourceAbiDump() {
+		vndkVersion := ctx.DeviceConfig().PlatformVndkVersion()
+		if ver := ctx.DeviceConfig().VndkVersion(); ver != "" && ver != "current" {
+			vndkVersion = ver
 		}
-		return refAbiDumpTextFile.Path()
+
+		dirName := "vndk"
+		if inList(ctx.baseModuleName(), llndkLibraries) || inList(ctx.baseModuleName(), ndkMigratedLibs) {
+			dirName = "ndk"
+		}
+		return []string{filepath.Join("prebuilts", "abi-dumps", dirName)}, vndkVersion
 	}
-	if refAbiDumpGzipFile.Valid() {
-		return UnzipRefDump(ctx, refAbiDumpGzipFile.Path(), fileName)
+
+	return []string{filepath.Join("prebuilts", "abi-dumps", "platform")}, ctx.Config().PlatformSdkVersion()
+}
+
+// getRefAbiDumpFile returns the reference ABI dump for fileName from the first of dirs that has one,
+// or nil if none of them do.
+func getRefAbiDumpFile(ctx ModuleContext, dirs []string, version, fileName string) android.Path {
+	for _, dir := range dirs {
+		refAbiDumpTextFile := android.ExistentPathForSource(ctx,
+			android.RefAbiDumpPath(ctx, dir, version, fileName+".lsdump"))
+		refAbiDumpGzipFile := android.ExistentPathForSource(ctx,
+			android.RefAbiDumpPath(ctx, dir, version, fileName+".lsdump.gz"))
+
+		if refAbiDumpTextFile.Valid() {
+			if refAbiDumpGzipFile.Valid() {
+				ctx.ModuleErrorf(
+					"Two reference ABI dump files are found: %q and %q. Please delete the stale one.",
+					refAbiDumpTextFile, refAbiDumpGzipFile)
+				return nil
+			}
+			return refAbiDumpTextFile.Path()
+		}
+		if refAbiDumpGzipFile.Valid() {
+			return UnzipRefDump(ctx, refAbiDumpGzipFile.Path(), fileName)
+		}
 	}
 	return nil
 }
 
 func (library *libraryDecorator) linkSAbiDumpFiles(ctx ModuleContext, objs Objects, fileName string, soFile android.Path) {
-	if len(objs.sAbiDumpFiles) > 0 && ctx.shouldCreateVndkSourceAbiDump() {
-		vndkVersion := ctx.DeviceConfig().PlatformVndkVersion()
-		if ver := ctx.DeviceConfig().VndkVersion(); ver != "" && ver != "current" {
-			vndkVersion = ver
-		}
+	if len(objs.sAbiDumpFiles) > 0 && ctx.shouldCreateSourceAbiDump() {
+		refAbiDumpDirs, version := library.refAbiDumpDirs(ctx)
 
 		exportIncludeDirs := library.flagExporter.exportedIncludes(ctx)
 		var SourceAbiFlags []string
@@ -814,11 +889,21 @@ func (library *libraryDecorator) linkSAbiDumpFiles(ctx ModuleContext, objs Objec
 			library.Properties.Header_abi_checker.Exclude_symbol_versions,
 			library.Properties.Header_abi_checker.Exclude_symbol_tags)
 
-		refAbiDumpFile := getRefAbiDumpFile(ctx, vndkVersion, fileName)
+		// New reference dumps are written to the first directory, which is searched first.
+		newRefAbiDump := android.RefAbiDumpPath(ctx, refAbiDumpDirs[0], version, fileName+".lsdump")
+
+		refAbiDumpFile := getRefAbiDumpFile(ctx, refAbiDumpDirs, version, fileName)
 		if refAbiDumpFile != nil {
 			library.sAbiDiff = SourceAbiDiff(ctx, library.sAbiOutputFile.Path(),
-				refAbiDumpFile, fileName, exportedHeaderFlags, ctx.isLlndk(), ctx.isVndkExt())
+				refAbiDumpFile, fileName, exportedHeaderFlags, library.Properties.Header_abi_checker.Diff_flags,
+				ctx.isLlndk(), ctx.isVndkExt())
+		} else if Bool(library.Properties.Header_abi_checker.Enabled) {
+			// Libraries that opted in are not allowed to go unchecked, while VNDK libraries may
+			// lack reference dumps for the versions that have not been snapshotted yet.
+			library.sAbiDiff = MissingRefAbiDump(ctx, library.sAbiOutputFile.Path(), fileName, newRefAbiDump)
 		}
+
+		library.sAbiRefDumpUpdate = UpdateRefAbiDump(ctx, library.sAbiOutputFile.Path(), newRefAbiDump)
 	}
 }
 
@@ -16,7 +16,10 @@ package cc
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+
+	"android/soong/android"
 )
 
 func TestLibraryReuse(t *testing.T) {
@@ -183,3 +186,126 @@ func TestLibraryReuse(t *testing.T) {
 		}
 	})
 }
+
+func TestHeaderAbiChecker(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libvendorabi",
+			vendor: true,
+			srcs: ["foo.c"],
+			export_include_dirs: ["my_include"],
+			header_abi_checker: {
+				enabled: true,
+				ref_dump_dirs: ["abi-dumps"],
+				diff_flags: ["-allow-unreferenced-changes"],
+			},
+		}`
+
+	config := android.TestArchConfig(buildDir, nil)
+	config.TestProductVariables.DeviceVndkVersion = StringPtr("current")
+	config.TestProductVariables.Platform_vndk_version = StringPtr("VER")
+
+	ctx := createTestContext(t, config, bp, map[string][]byte{
+		"abi-dumps/26/64/arm64_armv8-a/source-based/libvendorabi.so.lsdump": nil,
+	}, android.Android)
+	ctx.RegisterSingletonType("sabi_ref_dumps", android.SingletonFactoryAdaptor(sabiRefDumpsSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	arm64 := ctx.ModuleForTests("libvendorabi", "android_arm64_armv8-a_vendor_shared")
+	abiDiff := arm64.Output("libvendorabi.so.abidiff")
+	if ref := abiDiff.Args["referenceDump"]; !strings.HasSuffix(ref,
+		"abi-dumps/26/64/arm64_armv8-a/source-based/libvendorabi.so.lsdump") {
+		t.Errorf("unexpected reference dump %q", ref)
+	}
+	if flags := abiDiff.Args["allowFlags"]; !strings.Contains(flags, "-allow-unreferenced-changes") {
+		t.Errorf("header-abi-diff flags %q missing diff_flags", flags)
+	}
+	if target := abiDiff.Args["updateTarget"]; target != "update-abi-ref-dumps-libvendorabi" {
+		t.Errorf("unexpected update target %q", target)
+	}
+
+	// There is no reference dump for arm, so its ABI check fails until the dump is created.
+	arm := ctx.ModuleForTests("libvendorabi", "android_arm_armv7-a-neon_vendor_shared")
+	missing := arm.Output("libvendorabi.so.abidiff")
+	if missing.Rule != sAbiMissingRefDump {
+		t.Errorf("expected a failing ABI check without a reference dump, got rule %v", missing.Rule)
+	}
+	if target := missing.Args["updateTarget"]; target != "update-abi-ref-dumps-libvendorabi" {
+		t.Errorf("unexpected update target %q", target)
+	}
+	update := arm.Output("update_ref_abi_dump/libvendorabi.so.lsdump")
+	if ref := update.Args["refDump"]; ref != "abi-dumps/26/64/arm_armv7-a-neon/source-based/libvendorabi.so.lsdump" {
+		t.Errorf("unexpected updated reference dump %q", ref)
+	}
+
+	updates := ctx.SingletonForTests("sabi_ref_dumps").Output("update-abi-ref-dumps-libvendorabi")
+	if len(updates.Inputs) != 2 {
+		t.Errorf("expected reference dump updates for 2 architectures, got %q", updates.Inputs.Strings())
+	}
+}
+
+func TestStubSymbols(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			stubs: {
+				symbol_file: "libfoo.map.txt",
+				versions: ["1"],
+				exported_headers: ["include/foo.h"],
+			},
+		}`
+
+	config := android.TestArchConfig(buildDir, nil)
+	ctx := createTestContext(t, config, bp, map[string][]byte{
+		"libfoo.map.txt": nil,
+		"include/foo.h":  nil,
+	}, android.Android)
+	ctx.RegisterSingletonType("stub_symbols", android.SingletonFactoryAdaptor(stubSymbolsSingletonFactory))
+	ctx.Register()
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	arm64 := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_shared")
+	infer := arm64.Output("stub_symbols/api.diff")
+	if infer.Args["headers"] != "include/foo.h" {
+		t.Errorf("expected exported headers %q, got %q", "include/foo.h", infer.Args["headers"])
+	}
+	if infer.Args["symbolFile"] != "libfoo.map.txt" {
+		t.Errorf("expected symbol file %q, got %q", "libfoo.map.txt", infer.Args["symbolFile"])
+	}
+	check := arm64.Output("stub_symbols/api.diff.check")
+	if target := check.Args["updateTarget"]; target != "update-stub-symbols-libfoo" {
+		t.Errorf("unexpected update target %q", target)
+	}
+	update := arm64.Output("stub_symbols/update_symbol_file")
+	if update.Args["symbolFile"] != "libfoo.map.txt" {
+		t.Errorf("expected the update to write %q, got %q", "libfoo.map.txt", update.Args["symbolFile"])
+	}
+
+	// The secondary architecture is checked, but does not update the shared symbol file.
+	arm := ctx.ModuleForTests("libfoo", "android_arm_armv7-a-neon_core_shared")
+	arm.Output("stub_symbols/api.diff.check")
+	if arm.MaybeOutput("stub_symbols/update_symbol_file").Rule != nil {
+		t.Errorf("unexpected symbol file update for the secondary architecture")
+	}
+
+	// The stubs variants are not checked.
+	stubs := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_core_shared_1")
+	if stubs.MaybeOutput("stub_symbols/api.diff").Rule != nil {
+		t.Errorf("unexpected stub symbols check for a stubs variant")
+	}
+
+	updates := ctx.SingletonForTests("stub_symbols").Output("update-stub-symbols-libfoo")
+	if updates.Input.String() != update.Output.String() {
+		t.Errorf("expected update target input %q, got %q", update.Output, updates.Input)
+	}
+}
@@ -225,12 +225,18 @@ func (linker *baseLinker) linkerDeps(ctx DepsContext, deps Deps) Deps {
 	}
 
 	if ctx.toolchain().Bionic() {
+		// There is no libgcc or libatomic for riscv64, libclang_rt.builtins provides the compiler
+		// runtime and the atomics.
+		hasLibgcc := ctx.Arch().ArchType != android.Riscv64
+
 		// libclang_rt.builtins, libgcc and libatomic have to be last on the command line
 		if !Bool(linker.Properties.No_libcrt) {
 			deps.LateStaticLibs = append(deps.LateStaticLibs, config.BuiltinsRuntimeLibrary(ctx.toolchain()))
-			deps.LateStaticLibs = append(deps.LateStaticLibs, "libatomic")
-			deps.LateStaticLibs = append(deps.LateStaticLibs, "libgcc_stripped")
-		} else if !Bool(linker.Properties.No_libgcc) {
+			if hasLibgcc {
+				deps.LateStaticLibs = append(deps.LateStaticLibs, "libatomic")
+				deps.LateStaticLibs = append(deps.LateStaticLibs, "libgcc_stripped")
+			}
+		} else if !Bool(linker.Properties.No_libgcc) && hasLibgcc {
 			deps.LateStaticLibs = append(deps.LateStaticLibs, "libatomic")
 			deps.LateStaticLibs = append(deps.LateStaticLibs, "libgcc")
 		}
@@ -305,6 +311,10 @@ func (linker *baseLinker) useClangLld(ctx ModuleContext) bool {
 	if ctx.Windows() {
 		return false
 	}
+	// There is no gold linker for riscv64.
+	if ctx.Arch().ArchType == android.Riscv64 {
+		return true
+	}
 	if linker.Properties.Use_clang_lld != nil {
 		return Bool(linker.Properties.Use_clang_lld)
 	}
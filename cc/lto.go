@@ -15,6 +15,11 @@
 package cc
 
 import (
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+
 	"android/soong/android"
 )
 
@@ -36,6 +41,20 @@ import (
 // This file adds support to soong to automatically propogate LTO options to a
 // new variant of all static dependencies for each module with LTO enabled.
 
+func init() {
+	android.RegisterSingletonType("lto_report", ltoReportSingletonFactory)
+}
+
+var (
+	_ = pctx.SourcePathVariable("ltoReportCmd", "build/soong/scripts/lto_report.sh")
+
+	ltoReport = pctx.AndroidStaticRule("ltoReport",
+		blueprint.RuleParams{
+			Command:     "$ltoReportCmd -i $in -o $out",
+			CommandDeps: []string{"$ltoReportCmd"},
+		})
+)
+
 type LTOProperties struct {
 	// Lto must violate capitialization style for acronyms so that it can be
 	// referred to in blueprint files as "lto"
@@ -54,8 +73,16 @@ type LTOProperties struct {
 	Use_clang_lld *bool
 }
 
+// The ThinLTO cache pruning policy, which can be overridden with THINLTO_CACHE_POLICY.
+const defaultThinLTOCachePolicy = "cache_size=10%:cache_size_bytes=10g:prune_after=168h"
+
 type lto struct {
 	Properties LTOProperties
+
+	// Outputs of the LTO link when LTO_REPORTS=true.
+	remarksFile  android.WritablePath
+	statsFile    android.WritablePath
+	linkTimeFile android.WritablePath
 }
 
 func (lto *lto) props() []interface{} {
@@ -79,7 +106,7 @@ func (lto *lto) useClangLld(ctx BaseModuleContext) bool {
 	return true
 }
 
-func (lto *lto) flags(ctx BaseModuleContext, flags Flags) Flags {
+func (lto *lto) flags(ctx ModuleContext, flags Flags) Flags {
 	if lto.LTO() {
 		var ltoFlag string
 		if Bool(lto.Properties.Lto.Thin) {
@@ -91,19 +118,35 @@ func (lto *lto) flags(ctx BaseModuleContext, flags Flags) Flags {
 		flags.CFlags = append(flags.CFlags, ltoFlag)
 		flags.LdFlags = append(flags.LdFlags, ltoFlag)
 
-		if ctx.Config().IsEnvTrue("USE_THINLTO_CACHE") && Bool(lto.Properties.Lto.Thin) && lto.useClangLld(ctx) {
-			// Set appropriate ThinLTO cache policy
+		if !ctx.Config().IsEnvTrue("DISABLE_THINLTO_CACHE") && Bool(lto.Properties.Lto.Thin) && lto.useClangLld(ctx) {
+			// Cache the results of ThinLTO codegen so that relinking a module only
+			// redoes the codegen of the modules whose summaries changed. The cache
+			// can be turned off with DISABLE_THINLTO_CACHE=true.
 			cacheDirFormat := "-Wl,--thinlto-cache-dir="
 			cacheDir := android.PathForOutput(ctx, "thinlto-cache").String()
 			flags.LdFlags = append(flags.LdFlags, cacheDirFormat+cacheDir)
 
-			// Limit the size of the ThinLTO cache to the lesser of 10% of available
-			// disk space and 10GB.
+			// By default, limit the size of the ThinLTO cache to the lesser of 10% of
+			// available disk space and 10GB, and remove files that have not been used
+			// for a week.
 			cachePolicyFormat := "-Wl,--thinlto-cache-policy="
-			policy := "cache_size=10%:cache_size_bytes=10g"
+			policy := ctx.Config().GetenvWithDefault("THINLTO_CACHE_POLICY", defaultThinLTOCachePolicy)
 			flags.LdFlags = append(flags.LdFlags, cachePolicyFormat+policy)
 		}
 
+		if ctx.Device() && lto.useClangLld(ctx) && lto.linksModule(ctx) && ctx.Config().IsEnvTrue("LTO_REPORTS") {
+			// Write the optimization remarks and statistics of the LTO link, and
+			// record how long the link takes for the LTO summary report.
+			lto.remarksFile = android.PathForModuleOut(ctx, "lto", "remarks.yaml")
+			lto.statsFile = android.PathForModuleOut(ctx, "lto", "stats.json")
+			lto.linkTimeFile = android.PathForModuleOut(ctx, "lto", "link_time_ms")
+
+			flags.LdFlags = append(flags.LdFlags, "-Wl,--opt-remarks-filename="+lto.remarksFile.String())
+			flags.LdFlags = append(flags.LdFlags, "-Wl,-plugin-opt,stats-file="+lto.statsFile.String())
+			flags.LdFlagsOutputs = append(flags.LdFlagsOutputs, lto.remarksFile, lto.statsFile)
+			flags.LinkTimeFile = lto.linkTimeFile
+		}
+
 		// If the module does not have a profile, be conservative and do not inline
 		// or unroll loops during LTO, in order to prevent significant size bloat.
 		if !ctx.isPgoCompile() {
@@ -114,6 +157,15 @@ func (lto *lto) flags(ctx BaseModuleContext, flags Flags) Flags {
 	return flags
 }
 
+// Whether the module is linked into a binary or shared library, rather than only compiled to
+// bitcode for the LTO links of other modules.
+func (lto *lto) linksModule(ctx ModuleContext) bool {
+	if ctx.header() || ctx.object() {
+		return false
+	}
+	return !ctx.static() || ctx.staticBinary()
+}
+
 // Can be called with a null receiver
 func (lto *lto) LTO() bool {
 	if lto == nil || lto.Disabled() {
@@ -213,3 +265,53 @@ func ltoMutator(mctx android.BottomUpMutatorContext) {
 		}
 	}
 }
+
+func ltoReportSingletonFactory() android.Singleton {
+	return &ltoReportSingleton{android.NewSingletonOutput("lto-report", "SOONG_LTO_REPORT")}
+}
+
+// ltoReportSingleton writes lto-report.txt when LTO_REPORTS=true, which lists the link time and
+// the statistics file of every LTO link in the build, sorted by link time.
+type ltoReportSingleton struct {
+	android.SingletonOutput
+}
+
+func (s *ltoReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var links []string
+	var deps android.Paths
+
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || !c.Enabled() || c.lto == nil || c.lto.linkTimeFile == nil {
+			return
+		}
+		links = append(links, strings.Join([]string{ctx.ModuleName(module), ctx.ModuleSubDir(module),
+			c.lto.linkTimeFile.String(), c.lto.statsFile.String()}, " "))
+		deps = append(deps, c.lto.linkTimeFile, c.lto.statsFile)
+	})
+
+	if len(links) == 0 {
+		return
+	}
+	sort.Strings(links)
+
+	linksFile := android.PathForOutput(ctx, "lto-report", "links.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.WriteFile,
+		Description: "LTO links",
+		Output:      linksFile,
+		Args: map[string]string{
+			"content": strings.Join(links, "\\n"),
+		},
+	})
+
+	report := android.PathForOutput(ctx, "lto-report.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        ltoReport,
+		Description: "LTO report",
+		Output:      report,
+		Input:       linksFile,
+		Implicits:   deps,
+	})
+	s.SetOutput(ctx, report)
+}
@@ -130,8 +130,10 @@ func normalizeNdkApiLevel(ctx android.BaseContext, apiLevel string,
 		android.Arm64:  21,
 		android.Mips:   minVersion,
 		android.Mips64: 21,
-		android.X86:    minVersion,
-		android.X86_64: 21,
+		// riscv64 is not in any released NDK yet.
+		android.Riscv64: android.FutureApiLevel,
+		android.X86:     minVersion,
+		android.X86_64:  21,
 	}
 
 	firstArchVersion, ok := firstArchVersions[arch.ArchType]
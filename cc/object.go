@@ -115,6 +115,10 @@ func (object *objectLinker) unstrippedOutputFilePath() android.Path {
 	return nil
 }
 
+func (object *objectLinker) debugFilePath() android.OptionalPath {
+	return android.OptionalPath{}
+}
+
 func (object *objectLinker) nativeCoverage() bool {
 	return true
 }
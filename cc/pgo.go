@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
@@ -41,6 +42,31 @@ var (
 
 var pgoProfileProjectsConfigKey = android.NewOnceKey("PgoProfileProjects")
 
+func init() {
+	android.RegisterSingletonType("pgo_report", pgoReportSingletonFactory)
+}
+
+var (
+	_ = pctx.SourcePathVariable("pgoProfileCheckCmd", "build/soong/scripts/pgo_profile_check.sh")
+
+	pgoProfileCheck = pctx.AndroidStaticRule("pgoProfileCheck",
+		blueprint.RuleParams{
+			Command: "CLANG_BIN=${config.ClangBin} $pgoProfileCheckCmd -m ${module} -p ${profile} -b $in " +
+				"-o $out ${extraArgs}",
+			CommandDeps: []string{"$pgoProfileCheckCmd", "${config.ClangBin}/llvm-nm",
+				"${config.ClangBin}/llvm-profdata"},
+		},
+		"module", "profile", "extraArgs")
+
+	pgoReport = pctx.AndroidStaticRule("pgoReport",
+		blueprint.RuleParams{
+			Command: "(echo -e 'module\\tprofile\\tmodule functions\\tprofile functions\\tmatched functions\\t" +
+				"coverage\\tmismatch\\tsimilarity' && xargs cat < $out.rsp) > $out",
+			Rspfile:        "$out.rsp",
+			RspfileContent: "$in",
+		})
+)
+
 const profileInstrumentFlag = "-fprofile-generate=/data/local/tmp"
 const profileSamplingFlag = "-gline-tables-only"
 const profileUseInstrumentFormat = "-fprofile-use=%s"
@@ -75,6 +101,15 @@ type PgoProperties struct {
 
 type pgo struct {
 	Properties PgoProperties
+
+	// The profile the module is built with, if any.
+	profileFile android.OptionalPath
+
+	// The result of comparing profileFile against the module.
+	profileCheck android.OptionalPath
+
+	// Whether profileCheck fails when the profile does not match the module well enough.
+	enforceProfileMatch bool
 }
 
 func (props *PgoProperties) isInstrumentation() bool {
@@ -160,25 +195,16 @@ func (props *PgoProperties) profileUseFlags(ctx ModuleContext, file string) []st
 	return flags
 }
 
-func (props *PgoProperties) addProfileUseFlags(ctx ModuleContext, flags Flags) Flags {
-	// Return if 'pgo' property is not present in this module.
-	if !props.PgoPresent {
-		return flags
-	}
-
-	if props.PgoCompile {
-		profileFile := props.getPgoProfileFile(ctx)
-		profileFilePath := profileFile.Path()
-		profileUseFlags := props.profileUseFlags(ctx, profileFilePath.String())
+func (props *PgoProperties) addProfileUseFlags(ctx ModuleContext, flags Flags, profileFilePath android.Path) Flags {
+	profileUseFlags := props.profileUseFlags(ctx, profileFilePath.String())
 
-		flags.CFlags = append(flags.CFlags, profileUseFlags...)
-		flags.LdFlags = append(flags.LdFlags, profileUseFlags...)
+	flags.CFlags = append(flags.CFlags, profileUseFlags...)
+	flags.LdFlags = append(flags.LdFlags, profileUseFlags...)
 
-		// Update CFlagsDeps and LdFlagsDeps so the module is rebuilt
-		// if profileFile gets updated
-		flags.CFlagsDeps = append(flags.CFlagsDeps, profileFilePath)
-		flags.LdFlagsDeps = append(flags.LdFlagsDeps, profileFilePath)
-	}
+	// Update CFlagsDeps and LdFlagsDeps so the module is rebuilt
+	// if profileFile gets updated
+	flags.CFlagsDeps = append(flags.CFlagsDeps, profileFilePath)
+	flags.LdFlagsDeps = append(flags.LdFlagsDeps, profileFilePath)
 	return flags
 }
 
@@ -211,11 +237,6 @@ func (props *PgoProperties) isPGO(ctx BaseModuleContext) bool {
 		ctx.ModuleErrorf("PGO specification is missing properties: " + missingProps)
 	}
 
-	// Sampling not supported yet
-	if isSampling {
-		ctx.PropertyErrorf("pgo.sampling", "\"sampling\" is not supported yet)")
-	}
-
 	if isSampling && isInstrumentation {
 		ctx.PropertyErrorf("pgo", "Exactly one of \"instrumentation\" and \"sampling\" properties must be set")
 	}
@@ -286,9 +307,89 @@ func (pgo *pgo) flags(ctx ModuleContext, flags Flags) Flags {
 		return props.addProfileGatherFlags(ctx, flags)
 	}
 
-	if !ctx.Config().IsEnvTrue("ANDROID_PGO_NO_PROFILE_USE") {
-		return props.addProfileUseFlags(ctx, flags)
+	// Use the profile if the 'pgo' property is present in this module and the profile exists.
+	if !ctx.Config().IsEnvTrue("ANDROID_PGO_NO_PROFILE_USE") && props.PgoPresent && props.PgoCompile {
+		pgo.profileFile = props.getPgoProfileFile(ctx)
+		return props.addProfileUseFlags(ctx, flags, pgo.profileFile.Path())
 	}
 
 	return flags
 }
+
+// checkProfile compares the profile the module is built with against the linked module, and
+// writes the profile coverage and mismatch percentage of the module for the PGO report.  If
+// ANDROID_PGO_MIN_PROFILE_MATCH is set, the check fails when a smaller percentage of the functions
+// in the profile are found in the module.  If ANDROID_PGO_CURRENT_PROFILE_DIR is set to a
+// directory that contains profiles collected from an instrumented build of the current source,
+// with the same names as the profiles in the PGO profile projects, the profile is also compared
+// against the current profile with llvm-profdata overlap.
+func (pgo *pgo) checkProfile(ctx ModuleContext, unstrippedOutputFile android.Path) {
+	if !pgo.profileFile.Valid() || unstrippedOutputFile == nil {
+		return
+	}
+
+	var extraArgs []string
+	var implicits android.Paths
+	if pgo.Properties.isSampling() {
+		extraArgs = append(extraArgs, "-s")
+	}
+	if dir := ctx.Config().Getenv("ANDROID_PGO_CURRENT_PROFILE_DIR"); dir != "" {
+		currentProfile := android.ExistentPathForSource(ctx, dir, *pgo.Properties.Pgo.Profile_file)
+		if currentProfile.Valid() {
+			extraArgs = append(extraArgs, "-c "+currentProfile.String())
+			implicits = append(implicits, currentProfile.Path())
+		}
+	}
+	if minMatch := ctx.Config().Getenv("ANDROID_PGO_MIN_PROFILE_MATCH"); minMatch != "" {
+		extraArgs = append(extraArgs, "-t "+minMatch)
+		pgo.enforceProfileMatch = true
+	}
+
+	outputFile := android.PathForModuleOut(ctx, "pgo", "profile_check.tsv")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        pgoProfileCheck,
+		Description: "check PGO profile " + pgo.profileFile.Path().Base(),
+		Output:      outputFile,
+		Input:       unstrippedOutputFile,
+		Implicits:   append(implicits, pgo.profileFile.Path()),
+		Args: map[string]string{
+			"module":    ctx.ModuleName(),
+			"profile":   pgo.profileFile.String(),
+			"extraArgs": strings.Join(extraArgs, " "),
+		},
+	})
+	pgo.profileCheck = android.OptionalPathForPath(outputFile)
+}
+
+func pgoReportSingletonFactory() android.Singleton {
+	return &pgoReportSingleton{android.NewSingletonOutput("pgo-report", "SOONG_PGO_REPORT")}
+}
+
+// pgoReportSingleton writes pgo-report.txt, which lists the profile coverage and mismatch
+// percentage of every module that is built with a PGO profile.
+type pgoReportSingleton struct {
+	android.SingletonOutput
+}
+
+func (s *pgoReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var checks android.Paths
+
+	ctx.VisitAllModules(func(module android.Module) {
+		if c, ok := module.(*Module); ok && c.Enabled() && c.pgo != nil && c.pgo.profileCheck.Valid() {
+			checks = append(checks, c.pgo.profileCheck.Path())
+		}
+	})
+
+	if len(checks) == 0 {
+		return
+	}
+
+	report := android.PathForOutput(ctx, "pgo-report.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        pgoReport,
+		Description: "PGO report",
+		Output:      report,
+		Inputs:      checks,
+	})
+	s.SetOutput(ctx, report)
+}
@@ -27,6 +27,10 @@ var (
 	sabiLock    sync.Mutex
 )
 
+func init() {
+	android.RegisterSingletonType("sabi_ref_dumps", sabiRefDumpsSingletonFactory)
+}
+
 type SAbiProperties struct {
 	CreateSAbiDumps        bool `blueprint:"mutated"`
 	ReexportedIncludeFlags []string
@@ -79,7 +83,7 @@ func (sabimod *sabi) flags(ctx ModuleContext, flags Flags) Flags {
 func sabiDepsMutator(mctx android.TopDownMutatorContext) {
 	if c, ok := mctx.Module().(*Module); ok &&
 		((c.isVndk() && c.useVndk()) || inList(c.Name(), llndkLibraries) ||
-			(c.sabi != nil && c.sabi.Properties.CreateSAbiDumps)) {
+			(c.sabi != nil && c.sabi.Properties.CreateSAbiDumps) || c.headerAbiCheckerEnabled()) {
 		mctx.VisitDirectDeps(func(m android.Module) {
 			tag := mctx.OtherModuleDependencyTag(m)
 			switch tag {
@@ -94,3 +98,43 @@ func sabiDepsMutator(mctx android.TopDownMutatorContext) {
 		})
 	}
 }
+
+func (c *Module) headerAbiCheckerEnabled() bool {
+	if library, ok := c.linker.(*libraryDecorator); ok {
+		return Bool(library.Properties.Header_abi_checker.Enabled)
+	}
+	return false
+}
+
+const updateRefAbiDumpsGoal = "update-abi-ref-dumps"
+
+func updateRefAbiDumpsTarget(moduleName string) string {
+	return updateRefAbiDumpsGoal + "-" + moduleName
+}
+
+func sabiRefDumpsSingletonFactory() android.Singleton {
+	return &sabiRefDumpsSingleton{}
+}
+
+// sabiRefDumpsSingleton creates an update-abi-ref-dumps-<module> phony target for every library that
+// has ABI checks, which replaces the reference ABI dumps of the library for all architectures with
+// dumps of the library as it is built now, and an update-abi-ref-dumps phony target that does the
+// same for all of them.
+type sabiRefDumpsSingleton struct{}
+
+func (s *sabiRefDumpsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	moduleUpdates := make(map[string]android.Paths)
+
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || !c.Enabled() {
+			return
+		}
+		if library, ok := c.linker.(*libraryDecorator); ok && library.sAbiRefDumpUpdate.Valid() {
+			name := ctx.ModuleName(module)
+			moduleUpdates[name] = append(moduleUpdates[name], library.sAbiRefDumpUpdate.Path())
+		}
+	})
+
+	buildUpdateGoals(ctx, updateRefAbiDumpsGoal, moduleUpdates)
+}
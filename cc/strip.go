@@ -15,11 +15,28 @@
 package cc
 
 import (
+	"sort"
 	"strings"
 
+	"github.com/google/blueprint"
+
 	"android/soong/android"
 )
 
+func init() {
+	pctx.HostBinToolVariable("symbolsZipCmd", "symbols_zip")
+
+	android.RegisterSingletonType("native_debug_symbols", nativeDebugSymbolsSingletonFactory)
+}
+
+var symbolsZip = pctx.AndroidStaticRule("symbolsZip",
+	blueprint.RuleParams{
+		Command:        "${symbolsZipCmd} -o ${out} -l ${out}.rsp",
+		CommandDeps:    []string{"${symbolsZipCmd}"},
+		Rspfile:        "${out}.rsp",
+		RspfileContent: "${in}",
+	})
+
 type StripProperties struct {
 	Strip struct {
 		None              *bool    `android:"arch_variant"`
@@ -54,9 +71,73 @@ func (stripper *stripper) strip(ctx ModuleContext, in android.Path, out android.
 		if Bool(stripper.StripProperties.Strip.Use_gnu_strip) {
 			flags.stripUseGnuStrip = true
 		}
-		if ctx.Config().Debuggable() && !flags.stripKeepMiniDebugInfo {
+		if ctx.Config().Debuggable() && !flags.stripKeepMiniDebugInfo && flags.stripDebugFile == nil {
 			flags.stripAddGnuDebuglink = true
 		}
 		TransformStrip(ctx, in, out, flags)
 	}
 }
+
+// stripAndSplitDebugInfo strips in into out like strip, and for bionic modules also moves the debug
+// info of in into debug/<name>.debug, which keeps the GNU build-id of in and which out points to
+// with a GNU debuglink.  It returns the .debug file, which is packaged by build-id into the native
+// debug symbols zip.
+func (stripper *stripper) stripAndSplitDebugInfo(ctx ModuleContext, in android.Path, out android.ModuleOutPath,
+	flags builderFlags) android.OptionalPath {
+
+	var debugFile android.OptionalPath
+	// Only bionic modules are always linked with a build-id.
+	if ctx.toolchain().Bionic() {
+		debugPath := android.PathForModuleOut(ctx, "debug", out.Base()+".debug")
+		flags.stripDebugFile = debugPath
+		debugFile = android.OptionalPathForPath(debugPath)
+	}
+	stripper.strip(ctx, in, out, flags)
+	return debugFile
+}
+
+func nativeDebugSymbolsSingletonFactory() android.Singleton {
+	return &nativeDebugSymbolsSingleton{
+		android.NewSingletonOutput("native-debug-symbols", "SOONG_NATIVE_DEBUG_SYMBOLS_ZIP"),
+	}
+}
+
+// nativeDebugSymbolsSingleton packages the .debug files of all installed native modules into
+// native-debug-symbols.zip, indexed by build-id as .build-id/ab/cdef.debug, so that crash triage
+// tools can find the symbols of a binary from its build-id.
+type nativeDebugSymbolsSingleton struct {
+	android.SingletonOutput
+}
+
+func (s *nativeDebugSymbolsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var debugFiles android.Paths
+
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || c.linker == nil {
+			return
+		}
+		if !c.Enabled() || c.Properties.PreventInstall || c.IsStubs() {
+			return
+		}
+		if debugFile := c.linker.debugFilePath(); debugFile.Valid() {
+			debugFiles = append(debugFiles, debugFile.Path())
+		}
+	})
+
+	if len(debugFiles) == 0 {
+		return
+	}
+	sort.Slice(debugFiles, func(i, j int) bool {
+		return debugFiles[i].String() < debugFiles[j].String()
+	})
+
+	outputFile := android.PathForOutput(ctx, "native-debug-symbols.zip")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        symbolsZip,
+		Description: "native debug symbols zip",
+		Output:      outputFile,
+		Inputs:      debugFiles,
+	})
+	s.SetOutput(ctx, outputFile)
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+func init() {
+	android.RegisterSingletonType("stub_symbols", stubSymbolsSingletonFactory)
+}
+
+var (
+	inferStubSymbolsCmd = pctx.SourcePathVariable("inferStubSymbolsCmd", "build/soong/cc/infer_stub_symbols.py")
+
+	inferStubSymbols = pctx.AndroidStaticRule("inferStubSymbols",
+		blueprint.RuleParams{
+			Command: "${config.ClangBin}/llvm-nm -D --defined-only --extern-only --format=posix $in > ${librarySymbols} && " +
+				"$inferStubSymbolsCmd --arch $arch --api-map $apiMap $flags --name $libName " +
+				"--library-symbols ${librarySymbols} --headers $out.rsp $symbolFile $out $updatedSymbolFile",
+			CommandDeps:    []string{"$inferStubSymbolsCmd", "$toolPath", "${config.ClangBin}/llvm-nm"},
+			Rspfile:        "$out.rsp",
+			RspfileContent: "$headers",
+		}, "arch", "apiMap", "flags", "libName", "librarySymbols", "headers", "symbolFile",
+		"updatedSymbolFile")
+
+	stubSymbolsCheck = pctx.AndroidStaticRule("stubSymbolsCheck",
+		blueprint.RuleParams{
+			Command: "if [ -s $in ]; then " +
+				"echo 'error: The API of ${libName} (${arch}) differs from ${symbolFile}:' && cat $in && " +
+				"echo 'error: If the API change is intended, update the symbol file with: m ${updateTarget}' && " +
+				"exit 1; fi && touch $out",
+		}, "libName", "arch", "symbolFile", "updateTarget")
+
+	// Copies the inferred symbol file over the checked-in one.  Nothing writes $out, so ninja reruns
+	// the copy whenever an update-stub-symbols goal is built.
+	updateStubSymbols = pctx.AndroidStaticRule("updateStubSymbols",
+		blueprint.RuleParams{
+			Command: "cp -f $in ${symbolFile}",
+		}, "symbolFile")
+)
+
+const updateStubSymbolsGoal = "update-stub-symbols"
+
+func updateStubSymbolsTarget(moduleName string) string {
+	return updateStubSymbolsGoal + "-" + moduleName
+}
+
+func (library *libraryDecorator) shouldCheckStubSymbols(ctx ModuleContext) bool {
+	return ctx.Device() && library.shared() && !library.buildStubs() && ctx.hasStubsVariants() &&
+		!ctx.useVndk() && !ctx.inRecovery() &&
+		library.Properties.Stubs.Symbol_file != nil && len(library.Properties.Stubs.Exported_headers) > 0
+}
+
+// checkStubSymbols compares the symbol file of the stubs against the symbols inferred from the
+// linked, unstripped library and the exported headers of the stubs.
+func (library *libraryDecorator) checkStubSymbols(ctx ModuleContext, unstrippedOutputFile android.Path) {
+	if !library.shouldCheckStubSymbols(ctx) {
+		return
+	}
+
+	arch := ctx.Arch().ArchType.String()
+	libName := ctx.baseModuleName()
+	symbolFile := android.PathForModuleSrc(ctx, String(library.Properties.Stubs.Symbol_file))
+	headers := android.PathsForModuleSrc(ctx, library.Properties.Stubs.Exported_headers)
+	apiLevelsJson := android.GetApiLevelsJson(ctx)
+
+	diffFile := android.PathForModuleOut(ctx, "stub_symbols", "api.diff")
+	librarySymbols := android.PathForModuleOut(ctx, "stub_symbols", "library_symbols.txt")
+	updatedSymbolFile := android.PathForModuleOut(ctx, "stub_symbols", symbolFile.Base())
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:            inferStubSymbols,
+		Description:     "infer stub symbols " + libName,
+		Output:          diffFile,
+		ImplicitOutputs: android.WritablePaths{librarySymbols, updatedSymbolFile},
+		Input:           unstrippedOutputFile,
+		Implicits:       append(android.Paths{symbolFile, apiLevelsJson}, headers...),
+		Args: map[string]string{
+			"arch":              arch,
+			"apiMap":            apiLevelsJson.String(),
+			"flags":             "--apex",
+			"libName":           libName,
+			"librarySymbols":    librarySymbols.String(),
+			"headers":           strings.Join(headers.Strings(), " "),
+			"symbolFile":        symbolFile.String(),
+			"updatedSymbolFile": updatedSymbolFile.String(),
+		},
+	})
+
+	checkFile := android.PathForModuleOut(ctx, "stub_symbols", "api.diff.check")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        stubSymbolsCheck,
+		Description: "check stub symbols " + libName,
+		Output:      checkFile,
+		Input:       diffFile,
+		Args: map[string]string{
+			"libName":      libName,
+			"arch":         arch,
+			"symbolFile":   symbolFile.String(),
+			"updateTarget": updateStubSymbolsTarget(libName),
+		},
+	})
+	library.stubSymbolsCheck = android.OptionalPathForPath(checkFile)
+
+	// The symbol file is shared by all the architectures and APEXes, so only the platform variant
+	// of the primary architecture updates it.
+	if ctx.PrimaryArch() && ctx.apexName() == "" {
+		updateFile := android.PathForModuleOut(ctx, "stub_symbols", "update_symbol_file")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        updateStubSymbols,
+			Description: "update stub symbols " + symbolFile.String(),
+			Output:      updateFile,
+			Input:       updatedSymbolFile,
+			Args: map[string]string{
+				"symbolFile": symbolFile.String(),
+			},
+		})
+		library.stubSymbolsUpdate = android.OptionalPathForPath(updateFile)
+	}
+}
+
+func stubSymbolsSingletonFactory() android.Singleton {
+	return &stubSymbolsSingleton{}
+}
+
+// stubSymbolsSingleton creates the update-stub-symbols-<module> goals of the libraries that check
+// their stub symbols, and an update-stub-symbols goal for all of them.  Only one variant of each
+// library writes its symbol file, see checkStubSymbols.
+type stubSymbolsSingleton struct{}
+
+func (s *stubSymbolsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	moduleUpdates := make(map[string]android.Paths)
+
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || !c.Enabled() {
+			return
+		}
+		if library, ok := c.linker.(*libraryDecorator); ok && library.stubSymbolsUpdate.Valid() {
+			moduleUpdates[ctx.ModuleName(module)] = android.Paths{library.stubSymbolsUpdate.Path()}
+		}
+	})
+
+	buildUpdateGoals(ctx, updateStubSymbolsGoal, moduleUpdates)
+}
@@ -192,7 +192,7 @@ func (test *testDecorator) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 		switch ctx.Os() {
 		case android.Windows:
 			flags.CFlags = append(flags.CFlags, "-DGTEST_OS_WINDOWS")
-		case android.Linux:
+		case android.Linux, android.LinuxMusl:
 			flags.CFlags = append(flags.CFlags, "-DGTEST_OS_LINUX")
 		case android.Darwin:
 			flags.CFlags = append(flags.CFlags, "-DGTEST_OS_MAC")
@@ -275,6 +275,14 @@ func (test *testBinary) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 	return flags
 }
 
+func (test *testBinary) testSuites() []string {
+	return test.Properties.Test_suites
+}
+
+func (test *testBinary) testConfigPath() android.Path {
+	return test.testConfig
+}
+
 func (test *testBinary) install(ctx ModuleContext, file android.Path) {
 	test.data = android.PathsForModuleSrc(ctx, test.Properties.Data)
 	var configs []tradefed.Config
@@ -410,6 +418,14 @@ func (benchmark *benchmarkDecorator) linkerDeps(ctx DepsContext, deps Deps) Deps
 	return deps
 }
 
+func (benchmark *benchmarkDecorator) testSuites() []string {
+	return benchmark.Properties.Test_suites
+}
+
+func (benchmark *benchmarkDecorator) testConfigPath() android.Path {
+	return benchmark.testConfig
+}
+
 func (benchmark *benchmarkDecorator) install(ctx ModuleContext, file android.Path) {
 	benchmark.data = android.PathsForModuleSrc(ctx, benchmark.Properties.Data)
 	var configs []tradefed.Config
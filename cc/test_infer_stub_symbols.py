@@ -0,0 +1,181 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2019 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Tests for infer_stub_symbols.py."""
+import io
+import textwrap
+import unittest
+
+import gen_stub_libs as gsl
+import infer_stub_symbols as iss
+
+
+# pylint: disable=missing-docstring
+
+
+def parse_symbol_file(contents):
+    return gsl.SymbolFileParser(io.StringIO(contents), {}, 'arm64',
+                                gsl.FUTURE_API_LEVEL, False, True).parse()
+
+
+class ParseLibrarySymbolsTest(unittest.TestCase):
+    def test_parse_library_symbols(self):
+        nm_output = textwrap.dedent("""\
+            _ZN3foo3barEv T 1000 10
+            __bss_start B 2000
+            foo_bar T 1010 10
+            foo_baz@@LIBFOO W 1020 10
+            foo_count D 2010 4
+        """)
+        self.assertEqual({
+            'foo_bar': False,
+            'foo_baz': False,
+            'foo_count': True,
+        }, iss.parse_library_symbols(nm_output))
+
+
+class InferSymbolsTest(unittest.TestCase):
+    def test_header_identifiers_skip_comments(self):
+        identifiers = iss.header_identifiers([textwrap.dedent("""\
+            // foo_old is deprecated
+            /* foo_older
+               is gone */
+            int foo_bar(void);
+        """)])
+        self.assertIn('foo_bar', identifiers)
+        self.assertNotIn('foo_old', identifiers)
+        self.assertNotIn('foo_older', identifiers)
+
+    def test_infer_symbols(self):
+        inferred = iss.infer_symbols(
+            {'foo_bar': False, 'foo_internal': False, 'foo_count': True},
+            {'int', 'foo_bar', 'foo_count'})
+        self.assertEqual({'foo_bar': False, 'foo_count': True}, inferred)
+
+
+class DiffSymbolsTest(unittest.TestCase):
+    def test_diff_symbols(self):
+        versions = parse_symbol_file(textwrap.dedent(u"""\
+            LIBFOO {
+              global:
+                foo_bar;
+                foo_removed;
+                foo_x86; # x86
+              local:
+                *;
+            };
+
+            LIBFOO_PRIVATE {
+              global:
+                foo_private;
+            };
+        """))
+        named, stubs = iss.symbol_file_symbols(versions, 'arm64',
+                                               gsl.FUTURE_API_LEVEL, False,
+                                               True)
+        self.assertEqual({'foo_bar', 'foo_removed'}, stubs)
+
+        inferred = {'foo_bar': False, 'foo_new': True, 'foo_private': False}
+        added, removed = iss.diff_symbols(inferred, named, stubs)
+        self.assertEqual(['foo_new'], added)
+        self.assertEqual(['foo_removed'], removed)
+
+
+    def test_diff_symbols_keeps_cpp_symbols(self):
+        versions = parse_symbol_file(textwrap.dedent(u"""\
+            LIBFOO {
+              global:
+                foo_bar;
+                _ZN3foo3barEv;
+              local:
+                *;
+            };
+        """))
+        named, stubs = iss.symbol_file_symbols(versions, 'arm64',
+                                               gsl.FUTURE_API_LEVEL, False,
+                                               True)
+        self.assertEqual({'foo_bar'}, stubs)
+
+        library_symbols = iss.parse_library_symbols(textwrap.dedent("""\
+            _ZN3foo3barEv T 1000 10
+            foo_bar T 1010 10
+        """))
+        inferred = iss.infer_symbols(library_symbols, {'foo_bar'})
+        added, removed = iss.diff_symbols(inferred, named, stubs)
+        self.assertEqual([], added)
+        self.assertEqual([], removed)
+
+
+class UpdateSymbolFileTest(unittest.TestCase):
+    def test_update_symbol_file(self):
+        contents = textwrap.dedent(u"""\
+            LIBFOO {
+              global:
+                foo_bar;
+                foo_removed;
+              local:
+                *;
+            };
+        """)
+        versions = parse_symbol_file(contents)
+        lines = contents.splitlines(True)
+        updated = iss.update_symbol_file(
+            lines, versions, ['foo_count', 'foo_new'], ['foo_removed'],
+            {'foo_bar': False, 'foo_count': True, 'foo_new': False}, 'libfoo')
+        self.assertEqual(textwrap.dedent("""\
+            LIBFOO {
+              global:
+                foo_bar;
+                foo_count; # var
+                foo_new;
+              local:
+                *;
+            };
+        """), ''.join(updated))
+
+    def test_update_symbol_file_without_public_version(self):
+        contents = textwrap.dedent(u"""\
+            LIBFOO_PRIVATE {
+              global:
+                foo_private;
+            };
+        """)
+        versions = parse_symbol_file(contents)
+        updated = iss.update_symbol_file(
+            contents.splitlines(True), versions, ['foo_new'], [],
+            {'foo_new': False}, 'libfoo')
+        self.assertEqual(textwrap.dedent("""\
+            LIBFOO_PRIVATE {
+              global:
+                foo_private;
+            };
+
+            LIBFOO {
+              global:
+                foo_new;
+              local:
+                *;
+            };
+        """), ''.join(updated))
+
+
+def main():
+    suite = unittest.TestLoader().loadTestsFromName(__name__)
+    unittest.TextTestRunner(verbosity=3).run(suite)
+
+
+if __name__ == '__main__':
+    main()
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"android/soong/android"
@@ -84,6 +85,9 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 
 		groupStaticLibs: in.GroupStaticLibs,
 
+		ldFlagsOutputs: in.LdFlagsOutputs,
+		linkTimeFile:   in.LinkTimeFile,
+
 		proto:            in.proto,
 		protoC:           in.protoC,
 		protoOptionsFile: in.protoOptionsFile,
@@ -136,3 +140,31 @@ func makeSymlinkCmd(linkDirOnDevice string, linkName string, target string) stri
 	return "mkdir -p " + dir + " && " +
 		"ln -sf " + target + " " + filepath.Join(dir, linkName)
 }
+
+// buildUpdateGoals creates a <goal>-<module> phony goal for every module in moduleUpdates that
+// builds the update rules of the module, and a <goal> phony goal that builds all of them.
+func buildUpdateGoals(ctx android.SingletonContext, goal string, moduleUpdates map[string]android.Paths) {
+	var names []string
+	for name := range moduleUpdates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var allUpdates android.Paths
+	for _, name := range names {
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   android.Phony,
+			Output: android.PathForPhony(ctx, goal+"-"+name),
+			Inputs: moduleUpdates[name],
+		})
+		allUpdates = append(allUpdates, moduleUpdates[name]...)
+	}
+
+	if len(allUpdates) > 0 {
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   android.Phony,
+			Output: android.PathForPhony(ctx, goal),
+			Inputs: allUpdates,
+		})
+	}
+}
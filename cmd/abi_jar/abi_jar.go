@@ -0,0 +1,114 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// abi_jar copies a jar of header classes, removing everything from the classes that other classes cannot be
+// compiled against: private fields and methods, static initializers, method bodies and debug information.  The
+// constant pools of the classes are rebuilt in a canonical order, so the output only changes when the ABI of the
+// classes changes.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+)
+
+var output = flag.String("o", "", "output jar")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: abi_jar -o <output jar> <input jar>")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *output == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := abiJar(*output, flag.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func abiJar(outputFile, inputFile string) error {
+	reader, err := zip.OpenReader(inputFile)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := zip.NewWriter(out)
+
+	for _, f := range reader.File {
+		if !strings.HasSuffix(f.Name, ".class") || path.Base(f.Name) == "module-info.class" {
+			if err := writer.CopyFrom(f, f.Name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := readFile(f)
+		if err != nil {
+			return err
+		}
+
+		data, err = abiClass(data)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %s", inputFile, f.Name, err)
+		}
+
+		fh := &zip.FileHeader{
+			Name:   f.Name,
+			Method: zip.Deflate,
+		}
+		fh.SetMode(0600)
+		fh.SetModTime(jar.DefaultTime)
+
+		w, err := writer.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func readFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,554 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Constant pool tags from the JVM specification, section 4.4.
+const (
+	constantUtf8               = 1
+	constantInteger            = 3
+	constantFloat              = 4
+	constantLong               = 5
+	constantDouble             = 6
+	constantClass              = 7
+	constantString             = 8
+	constantFieldref           = 9
+	constantMethodref          = 10
+	constantInterfaceMethodref = 11
+	constantNameAndType        = 12
+	constantMethodHandle       = 15
+	constantMethodType         = 16
+	constantDynamic            = 17
+	constantInvokeDynamic      = 18
+	constantModule             = 19
+	constantPackage            = 20
+)
+
+const (
+	classMagic = 0xCAFEBABE
+	accPrivate = 0x0002
+)
+
+// errUnsupported is returned when a class contains something that abiClass does not know how to rewrite.  The
+// class is copied unchanged.
+var errUnsupported = errors.New("unsupported class file contents")
+
+type constant struct {
+	tag  byte
+	data []byte
+}
+
+type attribute struct {
+	name uint16
+	data []byte
+}
+
+type member struct {
+	access     uint16
+	name       uint16
+	descriptor uint16
+	attributes []attribute
+}
+
+type classFile struct {
+	minor, major uint16
+	pool         []constant
+	access       uint16
+	thisClass    uint16
+	superClass   uint16
+	interfaces   []uint16
+	fields       []member
+	methods      []member
+	attributes   []attribute
+}
+
+// abiClass returns the ABI of a class file: the class without private fields and methods, static initializers,
+// method bodies and debug information, with a constant pool that only contains the constants that are still
+// referenced.  Classes compiled from Kotlin are returned unchanged, as kotlinc needs the bodies of inline functions
+// from the classpath.
+func abiClass(data []byte) ([]byte, error) {
+	cf, err := parseClass(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if cf.isKotlin() {
+		return data, nil
+	}
+
+	out, err := cf.writeABI()
+	if err == errUnsupported {
+		return data, nil
+	}
+	return out, err
+}
+
+func (cf *classFile) utf8(index uint16) string {
+	if int(index) < len(cf.pool) && cf.pool[index].tag == constantUtf8 {
+		return string(cf.pool[index].data)
+	}
+	return ""
+}
+
+func (cf *classFile) isKotlin() bool {
+	for _, c := range cf.pool {
+		if c.tag == constantUtf8 && string(c.data) == "Lkotlin/Metadata;" {
+			return true
+		}
+	}
+	return false
+}
+
+// classReader reads big endian values from a class file, recording the first error.
+type classReader struct {
+	b   []byte
+	err error
+}
+
+func (r *classReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.b) {
+		r.err = errors.New("truncated class file")
+		return nil
+	}
+	ret := r.b[:n]
+	r.b = r.b[n:]
+	return ret
+}
+
+func (r *classReader) u1() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *classReader) u2() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *classReader) u4() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func parseClass(data []byte) (*classFile, error) {
+	r := &classReader{b: data}
+	cf := &classFile{}
+
+	if r.u4() != classMagic {
+		return nil, errors.New("not a class file")
+	}
+	cf.minor = r.u2()
+	cf.major = r.u2()
+
+	count := int(r.u2())
+	cf.pool = make([]constant, count)
+	for i := 1; i < count && r.err == nil; i++ {
+		tag := r.u1()
+		var size int
+		switch tag {
+		case constantUtf8:
+			size = int(r.u2())
+		case constantClass, constantString, constantMethodType, constantModule, constantPackage:
+			size = 2
+		case constantMethodHandle:
+			size = 3
+		case constantInteger, constantFloat, constantFieldref, constantMethodref, constantInterfaceMethodref,
+			constantNameAndType, constantDynamic, constantInvokeDynamic:
+			size = 4
+		case constantLong, constantDouble:
+			size = 8
+		default:
+			return nil, fmt.Errorf("unknown constant pool tag %d", tag)
+		}
+		cf.pool[i] = constant{tag: tag, data: r.bytes(size)}
+		if tag == constantLong || tag == constantDouble {
+			// 8-byte constants take up two entries in the constant pool.
+			i++
+		}
+	}
+
+	cf.access = r.u2()
+	cf.thisClass = r.u2()
+	cf.superClass = r.u2()
+	cf.interfaces = make([]uint16, r.u2())
+	for i := range cf.interfaces {
+		cf.interfaces[i] = r.u2()
+	}
+	cf.fields = parseMembers(r)
+	cf.methods = parseMembers(r)
+	cf.attributes = parseAttributes(r)
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return cf, nil
+}
+
+func parseMembers(r *classReader) []member {
+	members := make([]member, r.u2())
+	for i := range members {
+		members[i].access = r.u2()
+		members[i].name = r.u2()
+		members[i].descriptor = r.u2()
+		members[i].attributes = parseAttributes(r)
+	}
+	return members
+}
+
+func parseAttributes(r *classReader) []attribute {
+	attributes := make([]attribute, r.u2())
+	for i := range attributes {
+		attributes[i].name = r.u2()
+		attributes[i].data = r.bytes(int(r.u4()))
+	}
+	return attributes
+}
+
+// poolWriter builds a new constant pool out of the constants of a class file in the order they are referenced.
+type poolWriter struct {
+	cf      *classFile
+	buf     bytes.Buffer
+	count   uint16
+	indexes map[string]uint16
+}
+
+func newPoolWriter(cf *classFile) *poolWriter {
+	return &poolWriter{
+		cf:      cf,
+		count:   1,
+		indexes: make(map[string]uint16),
+	}
+}
+
+// ref copies the constant at index in the original constant pool and the constants it refers to into the new
+// constant pool, and returns its index in the new constant pool.
+func (p *poolWriter) ref(index uint16) (uint16, error) {
+	if index == 0 || int(index) >= len(p.cf.pool) || p.cf.pool[index].data == nil {
+		return 0, fmt.Errorf("invalid constant pool index %d", index)
+	}
+	c := p.cf.pool[index]
+
+	data := append([]byte(nil), c.data...)
+	switch c.tag {
+	case constantUtf8:
+		data = append([]byte{0, 0}, data...)
+		binary.BigEndian.PutUint16(data, uint16(len(c.data)))
+	case constantInteger, constantFloat, constantLong, constantDouble:
+	case constantClass, constantString, constantMethodType, constantModule, constantPackage:
+		if err := p.refAt(data, 0); err != nil {
+			return 0, err
+		}
+	case constantFieldref, constantMethodref, constantInterfaceMethodref, constantNameAndType:
+		if err := p.refAt(data, 0); err != nil {
+			return 0, err
+		}
+		if err := p.refAt(data, 2); err != nil {
+			return 0, err
+		}
+	case constantMethodHandle:
+		if err := p.refAt(data, 1); err != nil {
+			return 0, err
+		}
+	default:
+		// CONSTANT_Dynamic and CONSTANT_InvokeDynamic refer to bootstrap methods, which are removed with the
+		// method bodies.
+		return 0, errUnsupported
+	}
+
+	key := string(append([]byte{c.tag}, data...))
+	if i, ok := p.indexes[key]; ok {
+		return i, nil
+	}
+
+	i := p.count
+	p.indexes[key] = i
+	p.buf.WriteByte(c.tag)
+	p.buf.Write(data)
+	p.count++
+	if c.tag == constantLong || c.tag == constantDouble {
+		p.count++
+	}
+	return i, nil
+}
+
+// refAt replaces the constant pool index at offset in b with its index in the new constant pool.
+func (p *poolWriter) refAt(b []byte, offset int) error {
+	i, err := p.ref(binary.BigEndian.Uint16(b[offset:]))
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(b[offset:], i)
+	return nil
+}
+
+// attributeWriter copies the contents of an attribute, replacing constant pool indexes with their indexes in the
+// new constant pool.
+type attributeWriter struct {
+	pool *poolWriter
+	r    *classReader
+	w    bytes.Buffer
+	err  error
+}
+
+func (a *attributeWriter) u1() byte {
+	b := a.r.u1()
+	a.w.WriteByte(b)
+	return b
+}
+
+func (a *attributeWriter) u2() uint16 {
+	v := a.r.u2()
+	binary.Write(&a.w, binary.BigEndian, v)
+	return v
+}
+
+func (a *attributeWriter) bytes(n int) {
+	a.w.Write(a.r.bytes(n))
+}
+
+func (a *attributeWriter) ref() {
+	i := a.r.u2()
+	if a.err == nil && a.r.err == nil {
+		i, a.err = a.pool.ref(i)
+	}
+	binary.Write(&a.w, binary.BigEndian, i)
+}
+
+// optionalRef copies a constant pool index that may be 0.
+func (a *attributeWriter) optionalRef() {
+	if len(a.r.b) >= 2 && binary.BigEndian.Uint16(a.r.b) == 0 {
+		a.u2()
+	} else {
+		a.ref()
+	}
+}
+
+func (a *attributeWriter) refs() {
+	for n := a.u2(); n > 0; n-- {
+		a.ref()
+	}
+}
+
+func (a *attributeWriter) annotations() {
+	for n := a.u2(); n > 0; n-- {
+		a.annotation()
+	}
+}
+
+func (a *attributeWriter) annotation() {
+	a.ref()
+	for n := a.u2(); n > 0 && a.r.err == nil; n-- {
+		a.ref()
+		a.elementValue()
+	}
+}
+
+func (a *attributeWriter) elementValue() {
+	switch tag := a.u1(); tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's', 'c':
+		a.ref()
+	case 'e':
+		a.ref()
+		a.ref()
+	case '@':
+		a.annotation()
+	case '[':
+		for n := a.u2(); n > 0 && a.r.err == nil; n-- {
+			a.elementValue()
+		}
+	default:
+		a.err = errUnsupported
+	}
+}
+
+func (a *attributeWriter) typeAnnotations() {
+	for n := a.u2(); n > 0 && a.r.err == nil; n-- {
+		switch target := a.u1(); target {
+		case 0x00, 0x01, 0x16:
+			a.bytes(1)
+		case 0x10, 0x11, 0x12, 0x17:
+			a.bytes(2)
+		case 0x13, 0x14, 0x15:
+		default:
+			// Type annotations in method bodies, which are removed with the Code attribute.
+			a.err = errUnsupported
+			return
+		}
+		a.bytes(2 * int(a.u1()))
+		a.annotation()
+	}
+}
+
+// writeAttributes writes the attributes that are part of the ABI, and drops the rest.  Attributes that are not
+// known to be safe to copy or drop make the whole class unsupported.
+func (cf *classFile) writeAttributes(pool *poolWriter, out *bytes.Buffer, attributes []attribute) error {
+	var kept []attribute
+	for _, attr := range attributes {
+		a := &attributeWriter{pool: pool, r: &classReader{b: attr.data}}
+
+		switch cf.utf8(attr.name) {
+		case "Code", "SourceFile", "SourceDebugExtension", "BootstrapMethods":
+			continue
+		case "Deprecated", "Synthetic":
+		case "ConstantValue", "Signature", "NestHost":
+			a.ref()
+		case "Exceptions", "NestMembers":
+			a.refs()
+		case "EnclosingMethod":
+			a.ref()
+			a.optionalRef()
+		case "InnerClasses":
+			for n := a.u2(); n > 0 && a.r.err == nil; n-- {
+				a.ref()
+				a.optionalRef()
+				a.optionalRef()
+				a.u2()
+			}
+		case "MethodParameters":
+			for n := a.u1(); n > 0 && a.r.err == nil; n-- {
+				a.optionalRef()
+				a.u2()
+			}
+		case "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+			a.annotations()
+		case "RuntimeVisibleParameterAnnotations", "RuntimeInvisibleParameterAnnotations":
+			for n := a.u1(); n > 0 && a.r.err == nil; n-- {
+				a.annotations()
+			}
+		case "RuntimeVisibleTypeAnnotations", "RuntimeInvisibleTypeAnnotations":
+			a.typeAnnotations()
+		case "AnnotationDefault":
+			a.elementValue()
+		default:
+			return errUnsupported
+		}
+
+		if a.err != nil {
+			return a.err
+		}
+		if a.r.err != nil {
+			return a.r.err
+		}
+		if len(a.r.b) != 0 {
+			return fmt.Errorf("attribute %s has %d extra bytes", cf.utf8(attr.name), len(a.r.b))
+		}
+
+		name, err := pool.ref(attr.name)
+		if err != nil {
+			return err
+		}
+		kept = append(kept, attribute{name: name, data: a.w.Bytes()})
+	}
+
+	binary.Write(out, binary.BigEndian, uint16(len(kept)))
+	for _, attr := range kept {
+		binary.Write(out, binary.BigEndian, attr.name)
+		binary.Write(out, binary.BigEndian, uint32(len(attr.data)))
+		out.Write(attr.data)
+	}
+	return nil
+}
+
+func (cf *classFile) writeMembers(pool *poolWriter, out *bytes.Buffer, members []member) error {
+	var kept []member
+	for _, m := range members {
+		if m.access&accPrivate != 0 || cf.utf8(m.name) == "<clinit>" {
+			continue
+		}
+		kept = append(kept, m)
+	}
+
+	binary.Write(out, binary.BigEndian, uint16(len(kept)))
+	for _, m := range kept {
+		name, err := pool.ref(m.name)
+		if err != nil {
+			return err
+		}
+		descriptor, err := pool.ref(m.descriptor)
+		if err != nil {
+			return err
+		}
+		binary.Write(out, binary.BigEndian, m.access)
+		binary.Write(out, binary.BigEndian, name)
+		binary.Write(out, binary.BigEndian, descriptor)
+		if err := cf.writeAttributes(pool, out, m.attributes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeABI writes the ABI of the class file.
+func (cf *classFile) writeABI() ([]byte, error) {
+	pool := newPoolWriter(cf)
+	var body bytes.Buffer
+
+	thisClass, err := pool.ref(cf.thisClass)
+	if err != nil {
+		return nil, err
+	}
+	var superClass uint16
+	if cf.superClass != 0 {
+		if superClass, err = pool.ref(cf.superClass); err != nil {
+			return nil, err
+		}
+	}
+	binary.Write(&body, binary.BigEndian, cf.access)
+	binary.Write(&body, binary.BigEndian, thisClass)
+	binary.Write(&body, binary.BigEndian, superClass)
+
+	binary.Write(&body, binary.BigEndian, uint16(len(cf.interfaces)))
+	for _, iface := range cf.interfaces {
+		i, err := pool.ref(iface)
+		if err != nil {
+			return nil, err
+		}
+		binary.Write(&body, binary.BigEndian, i)
+	}
+
+	if err := cf.writeMembers(pool, &body, cf.fields); err != nil {
+		return nil, err
+	}
+	if err := cf.writeMembers(pool, &body, cf.methods); err != nil {
+		return nil, err
+	}
+	if err := cf.writeAttributes(pool, &body, cf.attributes); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.BigEndian, uint32(classMagic))
+	binary.Write(&out, binary.BigEndian, cf.minor)
+	binary.Write(&out, binary.BigEndian, cf.major)
+	binary.Write(&out, binary.BigEndian, pool.count)
+	out.Write(pool.buf.Bytes())
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// testClass builds a class file for tests.
+type testClass struct {
+	pool    bytes.Buffer
+	count   uint16
+	fields  []testMember
+	methods []testMember
+	attrs   []testAttribute
+}
+
+type testMember struct {
+	access     uint16
+	name       string
+	descriptor string
+	attrs      []testAttribute
+}
+
+type testAttribute struct {
+	name string
+	data []byte
+}
+
+func newTestClass() *testClass {
+	return &testClass{count: 1}
+}
+
+func (c *testClass) constant(tag byte, data ...byte) uint16 {
+	c.pool.WriteByte(tag)
+	c.pool.Write(data)
+	c.count++
+	return c.count - 1
+}
+
+func (c *testClass) utf8(s string) uint16 {
+	return c.constant(constantUtf8, append(u2(uint16(len(s))), s...)...)
+}
+
+func (c *testClass) class(name string) uint16 {
+	return c.constant(constantClass, u2(c.utf8(name))...)
+}
+
+func u2(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func (c *testClass) writeAttributes(out *bytes.Buffer, attrs []testAttribute) {
+	out.Write(u2(uint16(len(attrs))))
+	for _, attr := range attrs {
+		out.Write(u2(c.utf8(attr.name)))
+		binary.Write(out, binary.BigEndian, uint32(len(attr.data)))
+		out.Write(attr.data)
+	}
+}
+
+func (c *testClass) writeMembers(out *bytes.Buffer, members []testMember) {
+	out.Write(u2(uint16(len(members))))
+	for _, m := range members {
+		out.Write(u2(m.access))
+		out.Write(u2(c.utf8(m.name)))
+		out.Write(u2(c.utf8(m.descriptor)))
+		c.writeAttributes(out, m.attrs)
+	}
+}
+
+func (c *testClass) bytes() []byte {
+	var body bytes.Buffer
+	body.Write(u2(0x0021))
+	body.Write(u2(c.class("com/example/Foo")))
+	body.Write(u2(c.class("java/lang/Object")))
+	body.Write(u2(0))
+	c.writeMembers(&body, c.fields)
+	c.writeMembers(&body, c.methods)
+	c.writeAttributes(&body, c.attrs)
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.BigEndian, uint32(classMagic))
+	out.Write(u2(0))
+	out.Write(u2(52))
+	out.Write(u2(c.count))
+	out.Write(c.pool.Bytes())
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func memberNames(cf *classFile, members []member) []string {
+	var names []string
+	for _, m := range members {
+		names = append(names, cf.utf8(m.name))
+	}
+	return names
+}
+
+func attributeNames(cf *classFile, attrs []attribute) []string {
+	var names []string
+	for _, attr := range attrs {
+		names = append(names, cf.utf8(attr.name))
+	}
+	return names
+}
+
+func fooClass(privateName string) []byte {
+	c := newTestClass()
+	c.fields = []testMember{
+		{access: 0x0019, name: "CONSTANT", descriptor: "I",
+			attrs: []testAttribute{{"ConstantValue", u2(c.constant(constantInteger, 0, 0, 0, 1))}}},
+		{access: 0x0002, name: privateName, descriptor: "Ljava/lang/String;"},
+	}
+	code := []testAttribute{{"Code", []byte{0, 1, 0, 1, 0, 0, 0, 1, 0xb1, 0, 0, 0, 0}}}
+	c.methods = []testMember{
+		{access: 0x0001, name: "foo", descriptor: "()V", attrs: code},
+		{access: 0x0002, name: privateName, descriptor: "()V", attrs: code},
+		{access: 0x0008, name: "<clinit>", descriptor: "()V", attrs: code},
+	}
+	c.attrs = []testAttribute{
+		{"SourceFile", u2(c.utf8("Foo.java"))},
+		{"Deprecated", nil},
+	}
+	return c.bytes()
+}
+
+func TestAbiClass(t *testing.T) {
+	out, err := abiClass(fooClass("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := parseClass(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, w := memberNames(cf, cf.fields), []string{"CONSTANT"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want fields %q, got %q", w, g)
+	}
+	if g, w := attributeNames(cf, cf.fields[0].attributes), []string{"ConstantValue"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want field attributes %q, got %q", w, g)
+	}
+	if g, w := memberNames(cf, cf.methods), []string{"foo"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want methods %q, got %q", w, g)
+	}
+	if len(cf.methods) > 0 && len(cf.methods[0].attributes) != 0 {
+		t.Errorf("want no method attributes, got %q", attributeNames(cf, cf.methods[0].attributes))
+	}
+	if g, w := attributeNames(cf, cf.attributes), []string{"Deprecated"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want class attributes %q, got %q", w, g)
+	}
+
+	for _, c := range cf.pool {
+		if c.tag == constantUtf8 {
+			switch s := string(c.data); s {
+			case "secret", "Code", "SourceFile", "Foo.java", "<clinit>":
+				t.Errorf("want no %q in the constant pool", s)
+			}
+		}
+	}
+}
+
+func TestAbiClassDeterministic(t *testing.T) {
+	a, err := abiClass(fooClass("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := abiClass(fooClass("somethingElse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("want classes that only differ in private members to have the same ABI")
+	}
+}
+
+func TestAbiClassUnchanged(t *testing.T) {
+	testCases := []struct {
+		name  string
+		attrs func(c *testClass) []testAttribute
+	}{
+		{
+			name: "kotlin",
+			attrs: func(c *testClass) []testAttribute {
+				// RuntimeVisibleAnnotations with a single @kotlin.Metadata annotation without elements
+				data := append(u2(1), u2(c.utf8("Lkotlin/Metadata;"))...)
+				data = append(data, u2(0)...)
+				return []testAttribute{{"RuntimeVisibleAnnotations", data}}
+			},
+		},
+		{
+			name: "unknown attribute",
+			attrs: func(c *testClass) []testAttribute {
+				return []testAttribute{{"com.example.Unknown", []byte{1, 2, 3}}}
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := newTestClass()
+			c.methods = []testMember{{access: 0x0002, name: "bar", descriptor: "()V"}}
+			c.attrs = testCase.attrs(c)
+			in := c.bytes()
+
+			out, err := abiClass(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(in, out) {
+				t.Errorf("want class to be unchanged")
+			}
+		})
+	}
+}
+
+func TestAbiClassErrors(t *testing.T) {
+	if _, err := abiClass([]byte{0xca, 0xfe}); err == nil {
+		t.Errorf("want error for truncated class file")
+	}
+	if _, err := abiClass([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}); err == nil {
+		t.Errorf("want error for a file that is not a class file")
+	}
+}
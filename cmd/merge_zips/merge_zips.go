@@ -25,11 +25,13 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/google/blueprint/pathtools"
 
 	"android/soong/jar"
 	"android/soong/third_party/zip"
+	soongZip "android/soong/zip"
 )
 
 type fileList []string
@@ -79,6 +81,7 @@ func init() {
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintln(os.Stderr, "usage: merge_zips [-jpsD] [-m manifest] [--prefix script] [-pm __main__.py] output [inputs...]")
+		fmt.Fprintln(os.Stderr, "  inputs of the form @<file> are replaced with the list of inputs in <file>")
 		flag.PrintDefaults()
 	}
 
@@ -90,10 +93,23 @@ func main() {
 		os.Exit(1)
 	}
 	outputPath := args[0]
-	inputs := args[1:]
 
 	log.SetFlags(log.Lshortfile)
 
+	// expand response files, which are used when there are too many inputs for the command line
+	var inputs []string
+	for _, input := range args[1:] {
+		if strings.HasPrefix(input, "@") {
+			data, err := ioutil.ReadFile(strings.TrimPrefix(input, "@"))
+			if err != nil {
+				log.Fatal(err)
+			}
+			inputs = append(inputs, soongZip.ReadRespFile(data)...)
+		} else {
+			inputs = append(inputs, input)
+		}
+	}
+
 	// make writer
 	output, err := os.Create(outputPath)
 	if err != nil {
@@ -0,0 +1,297 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// actionCache is a local cache of the outputs of sbox commands, keyed by a hash of the command
+// line, the outputs and the contents of the declared inputs, which include the tools used by
+// the command.  Entries are stored in <dir>/entries/<first two characters of key>/<key>, with
+// the outputs named by their index in the list of outputs.  The modification time of an entry is
+// updated when it is used, so that the least recently used entries can be evicted when the cache
+// grows larger than maxSize.
+type actionCache struct {
+	dir       string
+	maxSize   int64
+	statsFile string
+}
+
+// actionCacheVersion is included in every key, and must be changed when the layout of the cache or
+// the way keys are computed changes.
+const actionCacheVersion = "sbox action cache 1"
+
+// key returns the cache key for a command, or an empty string if the command can't be cached
+// because one of its inputs is not a regular file.
+func (c *actionCache) key(command, outputRoot string, outputs, inputs []string) string {
+	h := sha256.New()
+	writeField := func(s string) {
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+
+	writeField(actionCacheVersion)
+	writeField(command)
+	writeField(outputRoot)
+	writeField(strconv.FormatBool(sandboxInputs))
+	for _, output := range outputs {
+		writeField(output)
+	}
+	writeField("")
+
+	inputs = append([]string(nil), inputs...)
+	sort.Strings(inputs)
+	for i, input := range inputs {
+		if i > 0 && input == inputs[i-1] {
+			continue
+		}
+		contentHash, err := hashFile(input)
+		if err != nil {
+			return ""
+		}
+		writeField(input)
+		writeField(contentHash)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%q is not a regular file", path)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *actionCache) entriesDir() string {
+	return filepath.Join(c.dir, "entries")
+}
+
+func (c *actionCache) entryDir(key string) string {
+	return filepath.Join(c.entriesDir(), key[:2], key)
+}
+
+// restore copies the outputs stored for key to their destinations, and returns false if there is
+// no entry for key.
+func (c *actionCache) restore(key string, outputs []string) bool {
+	entry := c.entryDir(key)
+
+	var size int64
+	for i, output := range outputs {
+		n, err := copyFile(filepath.Join(entry, strconv.Itoa(i)), output)
+		if err != nil {
+			// The entry doesn't exist, or was evicted while it was being restored.
+			return false
+		}
+		size += n
+	}
+
+	now := time.Now()
+	os.Chtimes(entry, now, now)
+
+	c.recordStats("hit %d", size)
+	return true
+}
+
+// store adds the outputs of a command to the cache, and then evicts the least recently used entries
+// if the cache is larger than maxSize.
+func (c *actionCache) store(key string, outputs []string) error {
+	c.recordStats("miss")
+
+	tmpDir := filepath.Join(c.dir, "tmp")
+	if err := os.MkdirAll(tmpDir, 0777); err != nil {
+		return err
+	}
+	staging, err := ioutil.TempDir(tmpDir, key[:8])
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	for i, output := range outputs {
+		if _, err := copyFile(output, filepath.Join(staging, strconv.Itoa(i))); err != nil {
+			return err
+		}
+	}
+
+	entry := c.entryDir(key)
+	if err := os.MkdirAll(filepath.Dir(entry), 0777); err != nil {
+		return err
+	}
+	// Another sbox process may have stored the same entry concurrently, in which case the rename
+	// fails and the existing entry is kept.
+	if err := os.Rename(staging, entry); err != nil {
+		if _, statErr := os.Stat(entry); statErr != nil {
+			return err
+		}
+	}
+
+	if c.maxSize > 0 {
+		return c.evict(key)
+	}
+	return nil
+}
+
+type actionCacheEntry struct {
+	dir     string
+	size    int64
+	modTime time.Time
+}
+
+// evict removes the least recently used entries, other than the entry for key, until the size of
+// the cache is not larger than maxSize.
+func (c *actionCache) evict(key string) error {
+	dirs, err := filepath.Glob(filepath.Join(c.entriesDir(), "*", "*"))
+	if err != nil {
+		return err
+	}
+
+	var entries []actionCacheEntry
+	var total int64
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		entry := actionCacheEntry{dir: dir, modTime: info.ModTime()}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			entry.size += file.Size()
+		}
+		total += entry.size
+		if filepath.Base(dir) != key {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	evicted := 0
+	var evictedSize int64
+	for _, entry := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.RemoveAll(entry.dir); err != nil {
+			return err
+		}
+		total -= entry.size
+		evicted++
+		evictedSize += entry.size
+	}
+
+	if evicted > 0 {
+		c.recordStats("evict %d %d", evicted, evictedSize)
+	}
+	return nil
+}
+
+// recordStats appends a line to the stats file, which is read by soong_ui to report the hit rate of
+// the cache after the build.  Lines are short enough that concurrent appends from multiple sbox
+// processes don't interleave.
+func (c *actionCache) recordStats(format string, args ...interface{}) {
+	if c.statsFile == "" {
+		return
+	}
+	f, err := os.OpenFile(c.statsFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write([]byte(fmt.Sprintf(format, args...) + "\n"))
+}
+
+// copyFile copies a regular file, including its permissions, and returns the number of bytes copied.
+func copyFile(from, to string) (int64, error) {
+	in, err := os.Open(from)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return 0, err
+	}
+	// Remove the destination first in case it is a read-only file or a symlink.
+	os.Remove(to)
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// parseSize parses a size in bytes with an optional K, M or G suffix.
+func parseSize(s string) (int64, error) {
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
@@ -19,11 +19,13 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -37,8 +39,26 @@ var (
 	keepOutDir    bool
 	copyAllOutput bool
 	depfileOut    string
+	sandboxInputs bool
+	inputs        stringList
+	inputLists    stringList
+
+	actionCacheDir     string
+	actionCacheMaxSize string
+	actionCacheStats   string
 )
 
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
 func init() {
 	flag.StringVar(&sandboxesRoot, "sandbox-path", "",
 		"root of temp directory to put the sandbox into")
@@ -54,6 +74,24 @@ func init() {
 	flag.StringVar(&depfileOut, "depfile-out", "",
 		"file path of the depfile to generate. This value will replace '__SBOX_DEPFILE__' in the command and will be treated as an output but won't be added to __SBOX_OUT_FILES__")
 
+	flag.BoolVar(&sandboxInputs, "sandbox-inputs", false,
+		"run the command in a directory that only contains the files given by --input and --input-list, "+
+			"and allow outputs that are relative to the top of the source tree instead of __SBOX_OUT_DIR__")
+	flag.Var(&inputs, "input",
+		"file to make available to the command when --sandbox-inputs is set, may be repeated")
+	flag.Var(&inputLists, "input-list",
+		"file containing a whitespace separated list of files to make available to the command when "+
+			"--sandbox-inputs is set, may be repeated. The lists are read before <outputRoot> is deleted, "+
+			"so they may be written into it")
+
+	flag.StringVar(&actionCacheDir, "action-cache", "",
+		"directory of a local cache of outputs, keyed by the command and the contents of the files given by "+
+			"--input and --input-list. Requires --sandbox-inputs. Commands with a depfile are not cached")
+	flag.StringVar(&actionCacheMaxSize, "action-cache-max-size", "",
+		"evict the least recently used outputs when the action cache is larger than this size in bytes, "+
+			"with an optional K, M or G suffix")
+	flag.StringVar(&actionCacheStats, "action-cache-stats", "",
+		"file to append action cache hits, misses and evictions to")
 }
 
 func usageViolation(violation string) {
@@ -62,7 +100,10 @@ func usageViolation(violation string) {
 	}
 
 	fmt.Fprintf(os.Stderr,
-		"Usage: sbox -c <commandToRun> --sandbox-path <sandboxPath> --output-root <outputRoot> [--depfile-out depFile] <outputFile> [<outputFile>...]\n"+
+		"Usage: sbox -c <commandToRun> --sandbox-path <sandboxPath> --output-root <outputRoot> [--depfile-out depFile] "+
+			"[--sandbox-inputs] [--input <inputFile>] [--input-list <inputListFile>] "+
+			"[--action-cache <cacheDir> [--action-cache-max-size <size>] [--action-cache-stats <statsFile>]] "+
+			"<outputFile> [<outputFile>...]\n"+
 			"\n"+
 			"Deletes <outputRoot>,"+
 			"runs <commandToRun>,"+
@@ -114,9 +155,28 @@ func run() error {
 		// and by passing it as a parameter we don't need to duplicate its value
 		usageViolation("--sandbox-path <sandboxPath> is required and must be non-empty")
 	}
-	if len(outputRoot) == 0 {
+	if len(outputRoot) == 0 && !sandboxInputs {
 		usageViolation("--output-root <outputRoot> is required and must be non-empty")
 	}
+	if (len(inputs) > 0 || len(inputLists) > 0) && !sandboxInputs {
+		usageViolation("--input and --input-list require --sandbox-inputs")
+	}
+	if actionCacheDir != "" && !sandboxInputs {
+		// Commands that can read undeclared inputs would be restored from the cache when those inputs change.
+		usageViolation("--action-cache requires --sandbox-inputs")
+	}
+
+	var cache *actionCache
+	if actionCacheDir != "" {
+		cache = &actionCache{dir: actionCacheDir, statsFile: actionCacheStats}
+		if actionCacheMaxSize != "" {
+			maxSize, err := parseSize(actionCacheMaxSize)
+			if err != nil {
+				usageViolation("--action-cache-max-size: " + err.Error())
+			}
+			cache.maxSize = maxSize
+		}
+	}
 
 	// the contents of the __SBOX_OUT_FILES__ variable
 	outputsVarEntries := flag.Args()
@@ -127,27 +187,65 @@ func run() error {
 	// all outputs
 	var allOutputs []string
 
+	// outputs that are relative to the top of the source tree, only allowed with --sandbox-inputs
+	var inPlaceOutputs []string
+
+	// The input lists have to be read before the output root is deleted, as they may be in it.
+	var declaredInputs []string
+	if sandboxInputs {
+		var err error
+		declaredInputs, err = readInputs()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The cache key is computed from the command before the placeholders in it are replaced with
+	// the randomly named sandbox directory.  Commands with a depfile aren't cached, as the depfile
+	// may list inputs that are not declared.
+	var cacheKey string
+	if cache != nil && depfileOut == "" && !copyAllOutput {
+		cacheKey = cache.key(rawCommand, outputRoot, outputsVarEntries, declaredInputs)
+	}
+
 	// setup directories
 	err := os.MkdirAll(sandboxesRoot, 0777)
 	if err != nil {
 		return err
 	}
-	err = os.RemoveAll(outputRoot)
-	if err != nil {
-		return err
-	}
-	err = os.MkdirAll(outputRoot, 0777)
-	if err != nil {
-		return err
+	if outputRoot != "" {
+		err = os.RemoveAll(outputRoot)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(outputRoot, 0777)
+		if err != nil {
+			return err
+		}
 	}
 
 	tempDir, err := ioutil.TempDir(sandboxesRoot, "sbox")
 
-	for i, filePath := range outputsVarEntries {
-		if !strings.HasPrefix(filePath, "__SBOX_OUT_DIR__/") {
+	var sboxOutputs []string
+	for _, filePath := range outputsVarEntries {
+		if strings.HasPrefix(filePath, "__SBOX_OUT_DIR__/") {
+			sboxOutputs = append(sboxOutputs, strings.TrimPrefix(filePath, "__SBOX_OUT_DIR__/"))
+		} else if sandboxInputs && !filepath.IsAbs(filePath) {
+			inPlaceOutputs = append(inPlaceOutputs, filepath.Clean(filePath))
+		} else if sandboxInputs {
+			return fmt.Errorf("output files must start with `__SBOX_OUT_DIR__/` or be relative to the top of the source tree")
+		} else {
 			return fmt.Errorf("output files must start with `__SBOX_OUT_DIR__/`")
 		}
-		outputsVarEntries[i] = strings.TrimPrefix(filePath, "__SBOX_OUT_DIR__/")
+	}
+	outputsVarEntries = sboxOutputs
+	if len(outputsVarEntries) > 0 && outputRoot == "" {
+		usageViolation("--output-root <outputRoot> is required for outputs in `__SBOX_OUT_DIR__/`")
+	}
+
+	if err == nil && sandboxInputs {
+		// The command runs in a different directory, so it needs an absolute path to the sandbox.
+		tempDir, err = filepath.Abs(tempDir)
 	}
 
 	allOutputs = append([]string(nil), outputsVarEntries...)
@@ -176,6 +274,44 @@ func run() error {
 		}
 	}()
 
+	// the final locations of the outputs, which are stored in and restored from the action cache
+	var outputDests []string
+	for _, filePath := range outputsVarEntries {
+		outputDests = append(outputDests, filepath.Join(outputRoot, filePath))
+	}
+	outputDests = append(outputDests, inPlaceOutputs...)
+
+	if cacheKey != "" && cache.restore(cacheKey, outputDests) {
+		return nil
+	}
+
+	// With --sandbox-inputs the command runs in a directory that mirrors the source tree but only
+	// contains the declared inputs, and writes its in-place outputs there.
+	var inputsDir string
+	if sandboxInputs {
+		inputsDir, err = ioutil.TempDir(sandboxesRoot, "sbox-inputs")
+		if err != nil {
+			return fmt.Errorf("Failed to create temp dir: %s", err)
+		}
+		defer func() {
+			if !keepOutDir {
+				os.RemoveAll(inputsDir)
+			}
+		}()
+
+		err = materializeInputs(inputsDir, declaredInputs)
+		if err != nil {
+			return err
+		}
+
+		for _, filePath := range inPlaceOutputs {
+			err = os.MkdirAll(filepath.Join(inputsDir, filepath.Dir(filePath)), 0777)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	if strings.Contains(rawCommand, "__SBOX_OUT_DIR__") {
 		rawCommand = strings.Replace(rawCommand, "__SBOX_OUT_DIR__", tempDir, -1)
 	}
@@ -187,6 +323,7 @@ func run() error {
 			tempOutPath := path.Join(tempDir, outputPath)
 			tempOutPaths = append(tempOutPaths, tempOutPath)
 		}
+		tempOutPaths = append(tempOutPaths, inPlaceOutputs...)
 		pathsText := strings.Join(tempOutPaths, " ")
 		rawCommand = strings.Replace(rawCommand, "__SBOX_OUT_FILES__", pathsText, -1)
 	}
@@ -201,14 +338,31 @@ func run() error {
 
 	commandDescription := rawCommand
 
+	// Keep a copy of the output of the command to look for undeclared inputs if it fails.
+	commandOutput := &bytes.Buffer{}
+
 	cmd := exec.Command("bash", "-c", rawCommand)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if sandboxInputs {
+		cmd.Dir = inputsDir
+		cmd.Stdout = io.MultiWriter(os.Stdout, commandOutput)
+		cmd.Stderr = io.MultiWriter(os.Stderr, commandOutput)
+	}
 	err = cmd.Run()
 
 	if exit, ok := err.(*exec.ExitError); ok && !exit.Success() {
-		return fmt.Errorf("sbox command (%s) failed with err %#v\n", commandDescription, err.Error())
+		errorMessage := fmt.Sprintf("sbox command (%s) failed with err %#v\n", commandDescription, err.Error())
+		if sandboxInputs {
+			if undeclared := findUndeclaredInputs(commandOutput.String(), inputsDir); len(undeclared) > 0 {
+				errorMessage += "the command may have tried to read files that are not declared as inputs:\n"
+				for _, file := range undeclared {
+					errorMessage += "  " + file + "\n"
+				}
+			}
+		}
+		return errors.New(errorMessage)
 	} else if err != nil {
 		return err
 	}
@@ -226,6 +380,16 @@ func run() error {
 			missingOutputErrors = append(missingOutputErrors, fmt.Sprintf("%s: not a file", filePath))
 		}
 	}
+	for _, filePath := range inPlaceOutputs {
+		fileInfo, err := os.Lstat(filepath.Join(inputsDir, filePath))
+		if err != nil {
+			missingOutputErrors = append(missingOutputErrors, fmt.Sprintf("%s: does not exist", filePath))
+			continue
+		}
+		if !fileInfo.Mode().IsRegular() {
+			missingOutputErrors = append(missingOutputErrors, fmt.Sprintf("%s: not a file", filePath))
+		}
+	}
 	if !copyAllOutput && len(missingOutputErrors) > 0 {
 		// find all created files for making a more informative error message
 		createdFiles := findAllFilesUnder(tempDir)
@@ -271,22 +435,22 @@ func run() error {
 		if len(outputRoot) != 0 {
 			destPath = filepath.Join(outputRoot, filePath)
 		}
-		err := os.MkdirAll(filepath.Dir(destPath), 0777)
+		err := moveOutput(tempPath, destPath)
 		if err != nil {
 			return err
 		}
-
-		// Update the timestamp of the output file in case the tool wrote an old timestamp (for example, tar can extract
-		// files with old timestamps).
-		now := time.Now()
-		err = os.Chtimes(tempPath, now, now)
+	}
+	for _, filePath := range inPlaceOutputs {
+		err := moveOutput(filepath.Join(inputsDir, filePath), filePath)
 		if err != nil {
 			return err
 		}
+	}
 
-		err = os.Rename(tempPath, destPath)
-		if err != nil {
-			return err
+	if cacheKey != "" {
+		// Failing to store the outputs doesn't fail the command, it only means that it will be run again.
+		if err := cache.store(cacheKey, outputDests); err != nil {
+			fmt.Fprintf(os.Stderr, "sbox: failed to store outputs in action cache: %s\n", err)
 		}
 	}
 
@@ -313,3 +477,130 @@ func run() error {
 	// TODO(jeffrygaston) if a process creates more output files than it declares, should there be a warning?
 	return nil
 }
+
+func moveOutput(tempPath, destPath string) error {
+	err := os.MkdirAll(filepath.Dir(destPath), 0777)
+	if err != nil {
+		return err
+	}
+
+	// Update the timestamp of the output file in case the tool wrote an old timestamp (for example, tar can extract
+	// files with old timestamps).
+	now := time.Now()
+	err = os.Chtimes(tempPath, now, now)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, destPath)
+}
+
+// readInputs returns the files passed with --input and listed in the files passed with --input-list.
+func readInputs() ([]string, error) {
+	ret := append([]string(nil), inputs...)
+	for _, list := range inputLists {
+		data, err := ioutil.ReadFile(list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input list: %s", err)
+		}
+		ret = append(ret, strings.Fields(string(data))...)
+	}
+	return ret, nil
+}
+
+// materializeInputs creates a symlink in dir to each input at the same path relative to dir as
+// the input is relative to the current directory.  Absolute paths are rejected, as the command
+// would reach them outside of the sandbox.
+func materializeInputs(dir string, inputs []string) error {
+	sort.Strings(inputs)
+	for _, input := range inputs {
+		input = filepath.Clean(input)
+		if filepath.IsAbs(input) {
+			return fmt.Errorf("input %q is an absolute path, which cannot be sandboxed", input)
+		}
+		if input == "." || input == ".." || strings.HasPrefix(input, "../") {
+			return fmt.Errorf("input %q is not under the top of the source tree", input)
+		}
+
+		if underSymlink(dir, input) {
+			// A directory containing the input is already a symlink to the real directory.
+			continue
+		}
+
+		src, err := filepath.Abs(input)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(src); err != nil {
+			return fmt.Errorf("declared input %q does not exist", input)
+		}
+
+		dest := filepath.Join(dir, input)
+		if err := os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+			return err
+		}
+		if err := os.Symlink(src, dest); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// underSymlink returns true if any existing prefix of rel in dir, including rel itself, is a
+// symlink.
+func underSymlink(dir, rel string) bool {
+	p := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if err != nil {
+			return false
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// findUndeclaredInputs returns the files named in the output of a failed command that exist in
+// the source tree but not in the sandbox, which are likely to be files that the command tried to
+// read without them being declared as inputs.
+func findUndeclaredInputs(output string, sandboxDir string) []string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	isSeparator := func(r rune) bool {
+		return strings.ContainsRune(" \t\r\n'\"`:,;=()[]{}<>@", r)
+	}
+
+	seen := make(map[string]bool)
+	var undeclared []string
+	for _, word := range strings.FieldsFunc(output, isSeparator) {
+		file := filepath.Clean(word)
+		if filepath.IsAbs(file) {
+			rel, err := filepath.Rel(cwd, file)
+			if err != nil {
+				continue
+			}
+			file = rel
+		}
+		if file == "." || file == ".." || strings.HasPrefix(file, "../") || seen[file] {
+			continue
+		}
+		seen[file] = true
+
+		if _, err := os.Lstat(filepath.Join(sandboxDir, file)); err == nil {
+			continue
+		}
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			continue
+		}
+		undeclared = append(undeclared, file)
+	}
+
+	sort.Strings(undeclared)
+	return undeclared
+}
@@ -26,11 +26,16 @@ import (
 )
 
 var (
-	docFile string
+	docFile     string
+	query       string
+	queryFormat string
 )
 
 func init() {
 	flag.StringVar(&docFile, "soong_docs", "", "build documentation file to output")
+	flag.StringVar(&query, "soong_query", "", "query the module graph instead of writing build.ninja")
+	flag.StringVar(&queryFormat, "soong_query_format", android.QueryFormatText,
+		"output format of --soong_query, one of text, json or graph")
 }
 
 func newNameResolver(config android.Config) *android.NameResolver {
@@ -64,7 +69,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	if docFile != "" {
+	if docFile != "" || query != "" {
 		configuration.SetStopBefore(bootstrap.StopBeforePrepareBuildActions)
 	}
 
@@ -80,4 +85,11 @@ func main() {
 			os.Exit(1)
 		}
 	}
+
+	if query != "" {
+		if err := android.RunQuery(ctx, query, queryFormat, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	}
 }
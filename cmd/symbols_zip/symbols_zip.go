@@ -0,0 +1,196 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This tool packages the .debug files of native modules into a zip file indexed by their GNU
+// build-ids, as .build-id/<first two hex digits>/<remaining hex digits>.debug.  The extracted zip
+// file can be used as the --debug-file-directory of llvm-symbolizer, or served by a symbol server.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"android/soong/jar"
+)
+
+// NT_GNU_BUILD_ID is the type of the ELF note that contains the build-id.
+const ntGnuBuildId = 3
+
+var (
+	outputFile = flag.String("o", "", "output zip file")
+	listFile   = flag.String("l", "", "file containing a whitespace separated list of .debug files")
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: symbols_zip -o <output zip> [-l <list file>] [<.debug file>...]")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if *outputFile == "" {
+		usage()
+	}
+
+	files := flag.Args()
+	if *listFile != "" {
+		list, err := ioutil.ReadFile(*listFile)
+		if err != nil {
+			log.Fatalf("error reading %q: %v", *listFile, err)
+		}
+		files = append(files, strings.Fields(string(list))...)
+	}
+
+	if err := writeSymbolsZip(*outputFile, files); err != nil {
+		os.Remove(*outputFile)
+		log.Fatal(err)
+	}
+}
+
+func writeSymbolsZip(output string, files []string) error {
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	seen := make(map[string]bool)
+
+	for _, file := range files {
+		buildId, err := readBuildId(file)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		// A module may be built more than once with the same contents, for example for the
+		// platform and for an APEX, in which case the first copy is used.
+		if seen[buildId] {
+			continue
+		}
+		seen[buildId] = true
+
+		if err := addFile(w, buildIdPath(buildId), file); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+func addFile(w *zip.Writer, name, file string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fh := &zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+	}
+	fh.SetModTime(jar.DefaultTime)
+
+	out, err := w.CreateHeader(fh)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// buildIdPath returns the path of the .debug file for a hex encoded build-id, in the layout used by
+// --debug-file-directory.
+func buildIdPath(buildId string) string {
+	return filepath.Join(".build-id", buildId[:2], buildId[2:]+".debug")
+}
+
+// readBuildId returns the hex encoded GNU build-id of an ELF file.
+func readBuildId(file string) (string, error) {
+	ef, err := elf.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer ef.Close()
+
+	for _, section := range ef.Sections {
+		if section.Type != elf.SHT_NOTE {
+			continue
+		}
+		data, err := section.Data()
+		if err != nil {
+			return "", err
+		}
+		buildId, err := findBuildIdNote(data, ef.ByteOrder)
+		if err != nil {
+			return "", fmt.Errorf("section %s: %v", section.Name, err)
+		}
+		if buildId != nil {
+			return hex.EncodeToString(buildId), nil
+		}
+	}
+
+	return "", fmt.Errorf("no GNU build-id note")
+}
+
+// findBuildIdNote returns the description of the GNU build-id note in the contents of an ELF note
+// section, or nil if there is none.
+func findBuildIdNote(data []byte, byteOrder binary.ByteOrder) ([]byte, error) {
+	align4 := func(n uint32) uint32 {
+		return (n + 3) &^ 3
+	}
+
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, fmt.Errorf("truncated note header")
+		}
+		nameSize := byteOrder.Uint32(data[0:4])
+		descSize := byteOrder.Uint32(data[4:8])
+		noteType := byteOrder.Uint32(data[8:12])
+		data = data[12:]
+
+		if uint64(len(data)) < uint64(align4(nameSize))+uint64(descSize) {
+			return nil, fmt.Errorf("truncated note")
+		}
+		name := data[:nameSize]
+		data = data[align4(nameSize):]
+		desc := data[:descSize]
+		if uint64(len(data)) > uint64(align4(descSize)) {
+			data = data[align4(descSize):]
+		} else {
+			data = nil
+		}
+
+		if noteType == ntGnuBuildId && bytes.Equal(name, []byte("GNU\x00")) {
+			if len(desc) < 2 {
+				return nil, fmt.Errorf("build-id is too short")
+			}
+			return desc, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func note(name string, noteType uint32, desc []byte) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint32(len(name)))
+	binary.Write(buf, binary.LittleEndian, uint32(len(desc)))
+	binary.Write(buf, binary.LittleEndian, noteType)
+	buf.WriteString(name)
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+	buf.Write(desc)
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func TestFindBuildIdNote(t *testing.T) {
+	buildId := []byte{0xab, 0xcd, 0xef, 0x01, 0x23}
+
+	testCases := []struct {
+		name string
+		in   []byte
+		out  []byte
+		err  bool
+	}{
+		{
+			name: "empty",
+			in:   nil,
+			out:  nil,
+		},
+		{
+			name: "build-id",
+			in:   note("GNU\x00", ntGnuBuildId, buildId),
+			out:  buildId,
+		},
+		{
+			name: "after other notes",
+			in: append(append(note("Android\x00", 1, []byte{29, 0, 0, 0}),
+				note("GNU\x00", 1, []byte{0, 0, 0, 0})...),
+				note("GNU\x00", ntGnuBuildId, buildId)...),
+			out: buildId,
+		},
+		{
+			name: "other notes only",
+			in:   note("Android\x00", ntGnuBuildId, []byte{29, 0, 0, 0}),
+			out:  nil,
+		},
+		{
+			name: "truncated",
+			in:   note("GNU\x00", ntGnuBuildId, buildId)[:14],
+			err:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			out, err := findBuildIdNote(testCase.in, binary.LittleEndian)
+			if testCase.err {
+				if err == nil {
+					t.Errorf("expected error, got %x", out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(out, testCase.out) {
+				t.Errorf("expected %x, got %x", testCase.out, out)
+			}
+		})
+	}
+}
+
+func TestBuildIdPath(t *testing.T) {
+	if got, want := buildIdPath("abcdef0123"), ".build-id/ab/cdef0123.debug"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
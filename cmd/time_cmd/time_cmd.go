@@ -0,0 +1,69 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// time_cmd runs a command and, if it succeeds, writes its wall time in milliseconds to a file.
+// It is used instead of date, whose nanosecond format is not supported on Darwin.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+func main() {
+	exitCode, err := Main(os.Args[0], os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+	os.Exit(exitCode)
+}
+
+func Main(name string, args []string) (int, error) {
+	if len(args) < 3 || args[0] != "-o" {
+		return 1, fmt.Errorf("usage: %s -o <output file> command ...", name)
+	}
+	outputFile := args[1]
+	args = args[2:]
+
+	start := time.Now()
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				if status.Exited() {
+					return status.ExitStatus(), nil
+				} else if status.Signaled() {
+					return 128 + int(status.Signal()), nil
+				}
+			}
+		}
+		return 1, err
+	}
+
+	elapsed := time.Since(start) / time.Millisecond
+	err := ioutil.WriteFile(outputFile, []byte(fmt.Sprintf("%d\n", elapsed)), 0666)
+	if err != nil {
+		return 1, err
+	}
+
+	return 0, nil
+}
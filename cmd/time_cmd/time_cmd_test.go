@@ -0,0 +1,57 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestTimeCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "time_cmd_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outputFile := filepath.Join(dir, "time_ms")
+
+	exitCode, err := Main("time_cmd", []string{"-o", outputFile, "sh", "-c", "exit 3"})
+	if err != nil || exitCode != 3 {
+		t.Errorf("expected exit code 3, got %d (%v)", exitCode, err)
+	}
+	if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+		t.Errorf("expected no output file for a failed command")
+	}
+
+	exitCode, err = Main("time_cmd", []string{"-o", outputFile, "true"})
+	if err != nil || exitCode != 0 {
+		t.Fatalf("expected exit code 0, got %d (%v)", exitCode, err)
+	}
+	output, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9]+\n$`).Match(output) {
+		t.Errorf("expected a time in milliseconds, got %q", output)
+	}
+
+	if exitCode, err := Main("time_cmd", []string{"true"}); exitCode == 0 || err == nil {
+		t.Errorf("expected a usage error without an output file")
+	}
+}
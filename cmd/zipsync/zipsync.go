@@ -29,9 +29,20 @@ import (
 var (
 	outputDir  = flag.String("d", "", "output dir")
 	outputFile = flag.String("l", "", "output list file")
-	filter     = flag.String("f", "", "optional filter pattern")
+	filters    = newMultiString("f", "optional filter pattern, may be repeated to match any of the patterns")
 )
 
+func newMultiString(name, usage string) *multiString {
+	var f multiString
+	flag.Var(&f, name, usage)
+	return &f
+}
+
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
 func must(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -54,7 +65,7 @@ func writeFile(filename string, in io.Reader, perm os.FileMode) error {
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, "usage: zipsync -d <output dir> [-l <output file>] [-f <pattern>] [zip]...")
+		fmt.Fprintln(os.Stderr, "usage: zipsync -d <output dir> [-l <output file>] [-f <pattern>]... [zip]...")
 		flag.PrintDefaults()
 	}
 
@@ -85,8 +96,8 @@ func main() {
 		defer reader.Close()
 
 		for _, f := range reader.File {
-			if *filter != "" {
-				if match, err := filepath.Match(*filter, filepath.Base(f.Name)); err != nil {
+			if len(*filters) > 0 {
+				if match, err := matchAny(*filters, filepath.Base(f.Name)); err != nil {
 					log.Fatal(err)
 				} else if !match {
 					continue
@@ -125,3 +136,13 @@ func main() {
 		must(ioutil.WriteFile(*outputFile, []byte(data), 0666))
 	}
 }
+
+// matchAny returns true if name matches any of the patterns.
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		if match, err := filepath.Match(pattern, name); err != nil || match {
+			return match, err
+		}
+	}
+	return false, nil
+}
@@ -107,6 +107,10 @@ type generatorProperties struct {
 
 	// input files to exclude
 	Exclude_srcs []string `android:"path,arch_variant"`
+
+	// Run the command in a sandbox that only contains srcs, tool_files and the outputs of tools,
+	// and fail if it reads any other file from the source or output directories.
+	Sandbox_inputs *bool
 }
 
 type Module struct {
@@ -324,6 +328,10 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 				}
 				return locationLabels[firstLabel][0], nil
 			case "in":
+				if Bool(g.properties.Sandbox_inputs) {
+					// RuleBuilder doesn't use ${in}, the inputs are written into the command.
+					return strings.Join(task.in.Strings(), " "), nil
+				}
 				return "${in}", nil
 			case "out":
 				return "__SBOX_OUT_FILES__", nil
@@ -386,6 +394,9 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 			depfilePlaceholder = "$depfileArgs"
 		}
 
+		// RuleBuilder escapes the command for ninja and the shell itself.
+		ruleBuilderCommand := strings.Replace(rawCommand, "$$", "$", -1)
+
 		// Escape the command for the shell
 		rawCommand = "'" + strings.Replace(rawCommand, "'", `'\''`, -1) + "'"
 		g.rawCommands = append(g.rawCommands, rawCommand)
@@ -405,9 +416,13 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		if task.shards > 1 {
 			name += strconv.Itoa(task.shard)
 		}
-		rule := ctx.Rule(pctx, name, ruleParams, args...)
 
-		g.generateSourceFile(ctx, task, rule)
+		if Bool(g.properties.Sandbox_inputs) {
+			g.generateSandboxedSourceFile(ctx, task, ruleBuilderCommand, name)
+		} else {
+			rule := ctx.Rule(pctx, name, ruleParams, args...)
+			g.generateSourceFile(ctx, task, rule)
+		}
 
 		if len(task.copyTo) > 0 {
 			outputFiles = append(outputFiles, task.copyTo...)
@@ -438,25 +453,29 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 }
 
-func (g *Module) generateSourceFile(ctx android.ModuleContext, task generateTask, rule blueprint.Rule) {
+func generateDescription(task generateTask) string {
 	desc := "generate"
+	if len(task.out) == 1 {
+		desc += " " + task.out[0].Base()
+	}
+	if task.shards > 1 {
+		desc += " " + strconv.Itoa(task.shard)
+	}
+	return desc
+}
+
+func (g *Module) generateSourceFile(ctx android.ModuleContext, task generateTask, rule blueprint.Rule) {
 	if len(task.out) == 0 {
 		ctx.ModuleErrorf("must have at least one output file")
 		return
 	}
-	if len(task.out) == 1 {
-		desc += " " + task.out[0].Base()
-	}
+	desc := generateDescription(task)
 
 	var depFile android.ModuleGenPath
 	if Bool(g.properties.Depfile) {
 		depFile = android.PathForModuleGen(ctx, task.out[0].Rel()+".d")
 	}
 
-	if task.shards > 1 {
-		desc += " " + strconv.Itoa(task.shard)
-	}
-
 	params := android.BuildParams{
 		Rule:            rule,
 		Description:     desc,
@@ -476,6 +495,27 @@ func (g *Module) generateSourceFile(ctx android.ModuleContext, task generateTask
 	ctx.Build(pctx, params)
 }
 
+// generateSandboxedSourceFile runs the command of task with sbox in a sandbox that only contains the srcs,
+// tool_files and tools of the module.
+func (g *Module) generateSandboxedSourceFile(ctx android.ModuleContext, task generateTask, command, name string) {
+	if len(task.out) == 0 {
+		ctx.ModuleErrorf("must have at least one output file")
+		return
+	}
+
+	rule := android.NewRuleBuilder().Sbox(task.genDir).SandboxInputs()
+	cmd := rule.Command().
+		Text(command).
+		Implicits(task.in).
+		Implicits(g.deps).
+		ImplicitOutputs(task.out)
+	if Bool(g.properties.Depfile) {
+		cmd.ImplicitDepFile(android.PathForModuleGen(ctx, task.out[0].Rel()+".d"))
+	}
+
+	rule.Build(pctx, ctx, name, generateDescription(task))
+}
+
 // Collect information for opening IDE project files in java/jdeps.go.
 func (g *Module) IDEInfo(dpInfo *android.IdeInfo) {
 	dpInfo.Srcs = append(dpInfo.Srcs, g.Srcs().Strings()...)
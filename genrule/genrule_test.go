@@ -634,6 +634,88 @@ func TestGenruleDefaults(t *testing.T) {
 	}
 }
 
+func TestGenruleSandboxInputs(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	bp := `
+				genrule {
+					name: "gen",
+					tools: ["tool"],
+					tool_files: ["tool_file1"],
+					srcs: ["in1"],
+					out: ["out"],
+					cmd: "$(location tool) $(location tool_file1) $(in) > $(out)",
+					sandbox_inputs: true,
+				}
+			`
+	ctx := testContext(config, bp, nil)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if errs == nil {
+		_, errs = ctx.PrepareBuildActions(config)
+	}
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	params := ctx.ModuleForTests("gen", "").Rule("generator")
+
+	rspFile := buildDir + "/.intermediates/gen/gen/sbox_inputs.rsp"
+	if !strings.Contains(params.RuleParams.Command, " -c 'out/tool tool_file1 in1 > __SBOX_OUT_FILES__' ") {
+		t.Errorf("expected command to contain the expanded cmd, got %q", params.RuleParams.Command)
+	}
+	if !strings.Contains(params.RuleParams.Command, " --sandbox-inputs --input-list "+rspFile+" ") {
+		t.Errorf("expected command to sandbox inputs with %q, got %q", rspFile, params.RuleParams.Command)
+	}
+	if params.RuleParams.Rspfile != rspFile {
+		t.Errorf("expected Rspfile %q, got %q", rspFile, params.RuleParams.Rspfile)
+	}
+	if g, w := params.RuleParams.RspfileContent, "in1 out/tool tool_file1"; g != w {
+		t.Errorf("expected RspfileContent %q, got %q", w, g)
+	}
+}
+
+func TestGenruleActionCache(t *testing.T) {
+	config := android.TestArchConfig(buildDir, map[string]string{
+		"SOONG_ACTION_CACHE_DIR": "/action_cache",
+	})
+	bp := `
+				genrule {
+					name: "gen",
+					tool_files: ["tool_file1"],
+					srcs: ["in1"],
+					out: ["out"],
+					cmd: "$(location tool_file1) $(in) > $(out)",
+					sandbox_inputs: true,
+				}
+
+				genrule {
+					name: "gen_not_sandboxed",
+					tool_files: ["tool_file1"],
+					srcs: ["in1"],
+					out: ["out"],
+					cmd: "$(location tool_file1) $(in) > $(out)",
+				}
+			`
+	ctx := testContext(config, bp, nil)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if errs == nil {
+		_, errs = ctx.PrepareBuildActions(config)
+	}
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	gen := ctx.ModuleForTests("gen", "").Rule("generator")
+	if !strings.Contains(gen.RuleParams.Command, " --action-cache /action_cache ") {
+		t.Errorf("expected a genrule with sandboxed inputs to use the action cache, got %q", gen.RuleParams.Command)
+	}
+
+	notSandboxed := ctx.ModuleForTests("gen_not_sandboxed", "").Rule("generator")
+	if strings.Contains(notSandboxed.RuleParams.Command, "--action-cache") {
+		t.Errorf("expected a genrule without sandboxed inputs not to use the action cache, got %q",
+			notSandboxed.RuleParams.Command)
+	}
+}
+
 type testTool struct {
 	android.ModuleBase
 	outputFile android.Path
@@ -165,10 +165,62 @@ func aapt2Link(ctx android.ModuleContext,
 
 	genDir := android.PathForModuleGen(ctx, "aapt2", "R")
 
-	var inFlags []string
+	inFlags, inDeps := aapt2LinkInputs(ctx, "aapt2", compiledRes, compiledOverlay)
+	deps = append(deps, inDeps...)
+
+	implicitOutputs := append(splitPackages, proguardOptions, genJar, rTxt, extraPackages)
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:            aapt2LinkRule,
+		Description:     "aapt2 link",
+		Implicits:       deps,
+		Output:          packageRes,
+		ImplicitOutputs: implicitOutputs,
+		Args: map[string]string{
+			"flags":           strings.Join(flags, " "),
+			"inFlags":         strings.Join(inFlags, " "),
+			"proguardOptions": proguardOptions.String(),
+			"genDir":          genDir.String(),
+			"genJar":          genJar.String(),
+			"rTxt":            rTxt.String(),
+			"extraPackages":   extraPackages.String(),
+		},
+	})
+}
+
+var aapt2LinkProtoRule = pctx.AndroidStaticRule("aapt2LinkProto",
+	blueprint.RuleParams{
+		Command:     `${config.Aapt2Cmd} link --proto-format -o $out $flags $inFlags`,
+		CommandDeps: []string{"${config.Aapt2Cmd}"},
+	},
+	"flags", "inFlags")
+
+// aapt2LinkProto links the resources into a package in the protobuf format used by app bundles.
+func aapt2LinkProto(ctx android.ModuleContext, packageRes android.WritablePath, flags []string, deps android.Paths,
+	compiledRes, compiledOverlay android.Paths) {
+
+	inFlags, inDeps := aapt2LinkInputs(ctx, "aapt2-proto", compiledRes, compiledOverlay)
+	deps = append(deps, inDeps...)
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        aapt2LinkProtoRule,
+		Description: "aapt2 link proto",
+		Implicits:   deps,
+		Output:      packageRes,
+		Args: map[string]string{
+			"flags":   strings.Join(flags, " "),
+			"inFlags": strings.Join(inFlags, " "),
+		},
+	})
+}
+
+// aapt2LinkInputs writes the lists of compiled resources and overlays into files in dir and returns the aapt2 link
+// flags that reference them.
+func aapt2LinkInputs(ctx android.ModuleContext, dir string,
+	compiledRes, compiledOverlay android.Paths) (inFlags []string, deps android.Paths) {
 
 	if len(compiledRes) > 0 {
-		resFileList := android.PathForModuleOut(ctx, "aapt2", "res.list")
+		resFileList := android.PathForModuleOut(ctx, dir, "res.list")
 		// Write out file lists to files
 		ctx.Build(pctx, android.BuildParams{
 			Rule:        fileListToFileRule,
@@ -183,7 +235,7 @@ func aapt2Link(ctx android.ModuleContext,
 	}
 
 	if len(compiledOverlay) > 0 {
-		overlayFileList := android.PathForModuleOut(ctx, "aapt2", "overlay.list")
+		overlayFileList := android.PathForModuleOut(ctx, dir, "overlay.list")
 		ctx.Build(pctx, android.BuildParams{
 			Rule:        fileListToFileRule,
 			Description: "overlay resource file list",
@@ -196,37 +248,51 @@ func aapt2Link(ctx android.ModuleContext,
 		inFlags = append(inFlags, "-R", "@"+overlayFileList.String())
 	}
 
-	implicitOutputs := append(splitPackages, proguardOptions, genJar, rTxt, extraPackages)
+	return inFlags, deps
+}
+
+var aapt2ConvertRule = pctx.AndroidStaticRule("aapt2Convert",
+	blueprint.RuleParams{
+		Command:     `${config.Aapt2Cmd} convert --output-format $format $in -o $out`,
+		CommandDeps: []string{"${config.Aapt2Cmd}"},
+	},
+	"format")
 
+// aapt2Convert converts a resource package to format, which is either "proto" or "binary".
+func aapt2Convert(ctx android.ModuleContext, out android.WritablePath, in android.Path, format string) {
 	ctx.Build(pctx, android.BuildParams{
-		Rule:            aapt2LinkRule,
-		Description:     "aapt2 link",
-		Implicits:       deps,
-		Output:          packageRes,
-		ImplicitOutputs: implicitOutputs,
+		Rule:        aapt2ConvertRule,
+		Input:       in,
+		Output:      out,
+		Description: "convert to " + format,
 		Args: map[string]string{
-			"flags":           strings.Join(flags, " "),
-			"inFlags":         strings.Join(inFlags, " "),
-			"proguardOptions": proguardOptions.String(),
-			"genDir":          genDir.String(),
-			"genJar":          genJar.String(),
-			"rTxt":            rTxt.String(),
-			"extraPackages":   extraPackages.String(),
+			"format": format,
 		},
 	})
 }
 
-var aapt2ConvertRule = pctx.AndroidStaticRule("aapt2Convert",
+var resourceShrinkerReportRule = pctx.AndroidStaticRule("resourceShrinkerReport",
 	blueprint.RuleParams{
-		Command:     `${config.Aapt2Cmd} convert --output-format proto $in -o $out`,
+		Command: `${config.Aapt2Cmd} dump resources $in | awk '$$1 == "resource" { print $$3 }' | sort -u > $out.all && ` +
+			`${config.Aapt2Cmd} dump resources $shrunk | awk '$$1 == "resource" { print $$3 }' | sort -u > $out.kept && ` +
+			`(comm -23 $out.all $out.kept | sed 's/^/removed: /' && ` +
+			`comm -12 $out.all $out.kept | sed 's/^/kept: /') > $out && ` +
+			`rm -f $out.all $out.kept`,
 		CommandDeps: []string{"${config.Aapt2Cmd}"},
-	})
+	},
+	"shrunk")
 
-func aapt2Convert(ctx android.ModuleContext, out android.WritablePath, in android.Path) {
+// resourceShrinkerReport writes a report listing the resources that were removed from a resource package by the
+// resource shrinker and the resources that were kept.
+func resourceShrinkerReport(ctx android.ModuleContext, out android.WritablePath, packageRes, shrunkPackageRes android.Path) {
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        aapt2ConvertRule,
-		Input:       in,
+		Rule:        resourceShrinkerReportRule,
+		Description: "resource shrinker report",
+		Input:       packageRes,
+		Implicit:    shrunkPackageRes,
 		Output:      out,
-		Description: "convert to proto",
+		Args: map[string]string{
+			"shrunk": shrunkPackageRes.String(),
+		},
 	})
 }
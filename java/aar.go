@@ -81,6 +81,7 @@ type aapt struct {
 	transitiveManifestPaths android.Paths
 	proguardOptionsFile     android.Path
 	rroDirs                 []rroDir
+	resourceFiles           android.Paths
 	rTxt                    android.Path
 	extraAaptPackagesFile   android.Path
 	mergedManifestFile      android.Path
@@ -90,6 +91,11 @@ type aapt struct {
 	useEmbeddedDex          bool
 	usesNonSdkApis          bool
 
+	// If protoFormat is set the resources are also linked in the protobuf format used by app bundles into
+	// protoExportPackage.
+	protoFormat        bool
+	protoExportPackage android.Path
+
 	splitNames []string
 	splits     []split
 
@@ -252,8 +258,10 @@ func (a *aapt) buildActions(ctx android.ModuleContext, sdkContext sdkContext, ex
 	extraPackages := android.PathForModuleOut(ctx, "extra_packages")
 
 	var compiledResDirs []android.Paths
+	var resourceFiles android.Paths
 	for _, dir := range resDirs {
 		compiledResDirs = append(compiledResDirs, aapt2Compile(ctx, dir.dir, dir.files).Paths())
+		resourceFiles = append(resourceFiles, dir.files...)
 	}
 
 	for i, zip := range resZips {
@@ -290,6 +298,9 @@ func (a *aapt) buildActions(ctx android.ModuleContext, sdkContext sdkContext, ex
 		compiledOverlay = append(compiledOverlay, aapt2Compile(ctx, dir.dir, dir.files).Paths()...)
 	}
 
+	// Splits are generated by bundletool from the protobuf package, don't pass --split to it.
+	protoLinkFlags := append([]string(nil), linkFlags...)
+
 	var splitPackages android.WritablePaths
 	var splits []split
 
@@ -308,11 +319,18 @@ func (a *aapt) buildActions(ctx android.ModuleContext, sdkContext sdkContext, ex
 	aapt2Link(ctx, packageRes, srcJar, proguardOptionsFile, rTxt, extraPackages,
 		linkFlags, linkDeps, compiledRes, compiledOverlay, splitPackages)
 
+	if a.protoFormat {
+		protoPackageRes := android.PathForModuleOut(ctx, "package-res.pb.apk")
+		aapt2LinkProto(ctx, protoPackageRes, protoLinkFlags, linkDeps, compiledRes, compiledOverlay)
+		a.protoExportPackage = protoPackageRes
+	}
+
 	a.aaptSrcJar = srcJar
 	a.exportPackage = packageRes
 	a.manifestPath = manifestPath
 	a.proguardOptionsFile = proguardOptionsFile
 	a.rroDirs = rroDirs
+	a.resourceFiles = resourceFiles
 	a.extraAaptPackagesFile = extraPackages
 	a.rTxt = rTxt
 	a.splits = splits
@@ -422,6 +440,10 @@ func (a *AndroidLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext)
 	a.Module.extraProguardFlagFiles = append(a.Module.extraProguardFlagFiles,
 		a.proguardOptionsFile)
 
+	a.Module.linter.manifest = a.manifestPath
+	a.Module.linter.resources = a.resourceFiles
+	a.Module.linter.library = true
+
 	a.Module.compile(ctx, a.aaptSrcJar)
 
 	a.aarFile = android.PathForModuleOut(ctx, ctx.ModuleName()+".aar")
@@ -458,9 +480,11 @@ func AndroidLibraryFactory() android.Module {
 		&module.Module.dexpreoptProperties,
 		&module.Module.protoProperties,
 		&module.aaptProperties,
-		&module.androidLibraryProperties)
+		&module.androidLibraryProperties,
+		&module.Module.linter.properties)
 
 	module.androidLibraryProperties.BuildAAR = true
+	module.Module.linter.properties.Lint.EnabledByDefault = true
 
 	InitJavaModule(module, android.DeviceSupported)
 	return module
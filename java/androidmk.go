@@ -86,6 +86,10 @@ func (library *Library) AndroidMk() android.AndroidMkData {
 					fmt.Fprintln(w, "LOCAL_ADDITIONAL_CHECKED_MODULE +=", strings.Join(library.additionalCheckedModules.Strings(), " "))
 				}
 
+				if library.linter.outputs.check != nil {
+					fmt.Fprintln(w, "LOCAL_ADDITIONAL_DEPENDENCIES +=", library.linter.outputs.check.String())
+				}
+
 				// Temporary hack: export sources used to compile framework.jar to Make
 				// to be used for droiddoc
 				// TODO(ccross): remove this once droiddoc is in soong
@@ -264,12 +268,22 @@ func (app *AndroidApp) AndroidMk() android.AndroidMkData {
 				if app.bundleFile != nil {
 					fmt.Fprintln(w, "LOCAL_SOONG_BUNDLE :=", app.bundleFile.String())
 				}
+				if app.resourceShrinkerReport != nil {
+					fmt.Fprintln(w, "LOCAL_ADDITIONAL_DEPENDENCIES +=", app.resourceShrinkerReport.String())
+				}
+				if app.aabFile != nil {
+					fmt.Fprintln(w, "LOCAL_ADDITIONAL_DEPENDENCIES +=", app.aabFile.String(),
+						app.apkSetFile.String(), app.universalApkFile.String())
+				}
 				if app.jacocoReportClassesFile != nil {
 					fmt.Fprintln(w, "LOCAL_SOONG_JACOCO_REPORT_CLASSES_JAR :=", app.jacocoReportClassesFile.String())
 				}
 				if app.proguardDictionary != nil {
 					fmt.Fprintln(w, "LOCAL_SOONG_PROGUARD_DICT :=", app.proguardDictionary.String())
 				}
+				if app.linter.outputs.check != nil {
+					fmt.Fprintln(w, "LOCAL_ADDITIONAL_DEPENDENCIES +=", app.linter.outputs.check.String())
+				}
 
 				if app.Name() == "framework-res" || app.Name() == "org.lineageos.platform-res" {
 					fmt.Fprintln(w, "LOCAL_MODULE_PATH := $(TARGET_OUT_JAVA_LIBRARIES)")
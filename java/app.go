@@ -89,6 +89,21 @@ type appProperties struct {
 	// If set, find and merge all NOTICE files that this module and its dependencies have and store
 	// it in the APK as an asset.
 	Embed_notices *bool
+
+	// Properties for building an Android App Bundle (.aab).
+	Bundle struct {
+		// If set to true, link the resources in the protobuf format and build an app bundle from the dex files,
+		// resources, assets and native libraries of the app, along with the split APKs and a universal APK
+		// generated from it.
+		Enabled *bool
+
+		// Path to a JSON device spec, as produced by `bundletool get-device-spec`.  If set, only the split APKs
+		// that would be installed on that device are generated.
+		Device_spec *string `android:"path"`
+
+		// Path to a BundleConfig JSON file that is passed to `bundletool build-bundle`.
+		Config *string `android:"path"`
+	}
 }
 
 // android_app properties that can be overridden by override_android_app
@@ -116,6 +131,14 @@ type AndroidApp struct {
 
 	bundleFile android.Path
 
+	// The app bundle and the APKs generated from it when bundle.enabled is set.
+	aabFile          android.Path
+	apkSetFile       android.Path
+	universalApkFile android.Path
+
+	// The report of the resources removed by optimize.shrink_resources.
+	resourceShrinkerReport android.Path
+
 	// the install APK name is normally the same as the module name, but can be overridden with PRODUCT_PACKAGE_NAME_OVERRIDES.
 	installApkName string
 
@@ -251,11 +274,15 @@ func (a *AndroidApp) aaptBuildActions(ctx android.ModuleContext) {
 	aaptLinkFlags = append(aaptLinkFlags, a.additionalAaptFlags...)
 
 	a.aapt.splitNames = a.appProperties.Package_splits
+	a.aapt.protoFormat = a.bundleEnabled()
 
 	a.aapt.buildActions(ctx, sdkContext(a), aaptLinkFlags...)
 
 	// apps manifests are handled by aapt, don't let Module see them
 	a.properties.Manifest = nil
+
+	a.linter.manifest = a.aapt.manifestPath
+	a.linter.resources = a.aapt.resourceFiles
 }
 
 func (a *AndroidApp) proguardBuildActions(ctx android.ModuleContext) {
@@ -420,10 +447,31 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	// Process all building blocks, from AAPT to certificates.
 	a.aaptBuildActions(ctx)
 
+	// App bundles and R8 resource shrinking use the resources in the protobuf format.
+	protoPackageFile := a.aapt.protoExportPackage
+	if protoPackageFile == nil {
+		convertedPackageFile := android.PathForModuleOut(ctx, "package-res.pb.apk")
+		aapt2Convert(ctx, convertedPackageFile, a.exportPackage, "proto")
+		protoPackageFile = convertedPackageFile
+	}
+	if Bool(a.deviceProperties.Optimize.Shrink_resources) {
+		// R8 only shrinks the resources of the base APK, the split APKs would keep their unused resources.
+		if len(a.appProperties.Package_splits) > 0 {
+			ctx.PropertyErrorf("optimize.shrink_resources", "cannot be used with package_splits")
+		}
+		a.Module.resourcesInput = android.OptionalPathForPath(protoPackageFile)
+	}
+
 	a.proguardBuildActions(ctx)
 
 	dexJarFile := a.dexBuildActions(ctx)
 
+	packageResources := a.exportPackage
+	if a.Module.resourcesOutput.Valid() {
+		protoPackageFile = a.Module.resourcesOutput.Path()
+		packageResources = a.shrinkResourcesBuildActions(ctx, protoPackageFile)
+	}
+
 	jniLibs, certificateDeps := collectAppDeps(ctx)
 	jniJarFile := a.jniBuildActions(jniLibs, ctx)
 
@@ -437,7 +485,7 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	// Build a final signed app package.
 	// TODO(jungjw): Consider changing this to installApkName.
 	packageFile := android.PathForModuleOut(ctx, ctx.ModuleName()+".apk")
-	CreateAndSignAppPackage(ctx, packageFile, a.exportPackage, jniJarFile, dexJarFile, certificates)
+	CreateAndSignAppPackage(ctx, packageFile, packageResources, jniJarFile, dexJarFile, certificates)
 	a.outputFile = packageFile
 
 	for _, split := range a.aapt.splits {
@@ -448,10 +496,21 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 
 	// Build an app bundle.
+	var bundleJniJarFile android.Path = jniJarFile
+	if a.bundleEnabled() && jniJarFile == nil && len(jniLibs) > 0 {
+		// The native libraries are installed outside the APK, but the app bundle must contain them.
+		bundleJniJar := android.PathForModuleOut(ctx, "bundle", "jnilibs.zip")
+		TransformJniLibsToJar(ctx, bundleJniJar, jniLibs, a.shouldUncompressJNI(ctx))
+		bundleJniJarFile = bundleJniJar
+	}
 	bundleFile := android.PathForModuleOut(ctx, "base.zip")
-	BuildBundleModule(ctx, bundleFile, a.exportPackage, jniJarFile, dexJarFile)
+	BuildBundleModule(ctx, bundleFile, protoPackageFile, bundleJniJarFile, dexJarFile)
 	a.bundleFile = bundleFile
 
+	if a.bundleEnabled() {
+		a.bundleBuildActions(ctx, bundleFile, certificates)
+	}
+
 	// Install the app package.
 	ctx.InstallFile(a.installDir, a.installApkName+".apk", a.outputFile)
 	for _, split := range a.aapt.splits {
@@ -459,6 +518,47 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 }
 
+// shrinkResourcesBuildActions converts the resources shrunk by R8 back to the binary format used in APKs, and writes
+// a report of the removed and kept resources.
+func (a *AndroidApp) shrinkResourcesBuildActions(ctx android.ModuleContext, shrunkProtoPackage android.Path) android.Path {
+	shrunkPackage := android.PathForModuleOut(ctx, "package-res-shrunk.apk")
+	aapt2Convert(ctx, shrunkPackage, shrunkProtoPackage, "binary")
+
+	report := android.PathForModuleOut(ctx, "resource-shrinker-report.txt")
+	resourceShrinkerReport(ctx, report, a.exportPackage, shrunkPackage)
+	a.resourceShrinkerReport = report
+
+	return shrunkPackage
+}
+
+func (a *AndroidApp) bundleEnabled() bool {
+	return Bool(a.appProperties.Bundle.Enabled)
+}
+
+// bundleBuildActions builds an app bundle from the bundle module, and generates the signed split APKs and a signed
+// universal APK from it.
+func (a *AndroidApp) bundleBuildActions(ctx android.ModuleContext, bundleModule android.Path,
+	certificates []Certificate) {
+
+	aabFile := android.PathForModuleOut(ctx, ctx.ModuleName()+".aab")
+	BuildAppBundle(ctx, aabFile, bundleModule,
+		android.OptionalPathForModuleSrc(ctx, a.appProperties.Bundle.Config))
+	a.aabFile = aabFile
+
+	apkSetFile := android.PathForModuleOut(ctx, ctx.ModuleName()+".apks")
+	BuildApksFromBundle(ctx, apkSetFile, aabFile,
+		android.OptionalPathForModuleSrc(ctx, a.appProperties.Bundle.Device_spec), false, certificates)
+	a.apkSetFile = apkSetFile
+
+	universalApkSetFile := android.PathForModuleOut(ctx, ctx.ModuleName()+"_universal.apks")
+	BuildApksFromBundle(ctx, universalApkSetFile, aabFile, android.OptionalPath{}, true, certificates)
+	universalApkFile := android.PathForModuleOut(ctx, ctx.ModuleName()+"_universal.apk")
+	ExtractUniversalApk(ctx, universalApkFile, universalApkSetFile)
+	a.universalApkFile = universalApkFile
+
+	a.extraOutputFiles = append(a.extraOutputFiles, aabFile, apkSetFile, universalApkFile)
+}
+
 func collectAppDeps(ctx android.ModuleContext) ([]jniLib, []Certificate) {
 	var jniLibs []jniLib
 	var certificates []Certificate
@@ -519,7 +619,10 @@ func AndroidAppFactory() android.Module {
 		&module.Module.protoProperties,
 		&module.aaptProperties,
 		&module.appProperties,
-		&module.overridableAppProperties)
+		&module.overridableAppProperties,
+		&module.Module.linter.properties)
+
+	module.Module.linter.properties.Lint.EnabledByDefault = true
 
 	module.Prefer32(func(ctx android.BaseModuleContext, base *android.ModuleBase, class android.OsClass) bool {
 		return class == android.Device && ctx.Config().DevicePrefer32BitApps()
@@ -547,6 +650,14 @@ type AndroidTest struct {
 	data       android.Paths
 }
 
+func (a *AndroidTest) TestSuites() []string {
+	return a.testProperties.Test_suites
+}
+
+func (a *AndroidTest) TestConfig() android.Path {
+	return a.testConfig
+}
+
 func (a *AndroidTest) InstallInTestcases() bool {
 	return true
 }
@@ -616,6 +727,14 @@ type AndroidTestHelperApp struct {
 	appTestHelperAppProperties appTestHelperAppProperties
 }
 
+func (a *AndroidTestHelperApp) TestSuites() []string {
+	return a.appTestHelperAppProperties.Test_suites
+}
+
+func (a *AndroidTestHelperApp) TestConfig() android.Path {
+	return nil
+}
+
 // android_test_helper_app compiles sources and Android resources into an Android application package `.apk` file that
 // will be used by tests, but does not produce an `AndroidTest.xml` file so the module will not be run directly as a
 // test.
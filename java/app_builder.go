@@ -163,12 +163,10 @@ var bundleMungeDexJar = pctx.AndroidStaticRule("bundleMungeDexJar",
 		CommandDeps: []string{"${config.Zip2ZipCmd}"},
 	}, "resJar")
 
-// Builds an app into a module suitable for input to bundletool
+// Builds an app into a module suitable for input to bundletool.  protoResJarFile is the resource package of the
+// app in the protobuf format.
 func BuildBundleModule(ctx android.ModuleContext, outputFile android.WritablePath,
-	packageFile, jniJarFile, dexJarFile android.Path) {
-
-	protoResJarFile := android.PathForModuleOut(ctx, "package-res.pb.apk")
-	aapt2Convert(ctx, protoResJarFile, packageFile)
+	protoResJarFile, jniJarFile, dexJarFile android.Path) {
 
 	var zips android.Paths
 
@@ -208,6 +206,118 @@ func BuildBundleModule(ctx android.ModuleContext, outputFile android.WritablePat
 	})
 }
 
+var bundletoolBuildBundle = pctx.AndroidStaticRule("bundletoolBuildBundle",
+	blueprint.RuleParams{
+		Command: `rm -f $out && ` +
+			`${config.JavaCmd} -jar ${config.BundletoolJar} build-bundle --modules=$in --output=$out $flags`,
+		CommandDeps: []string{"${config.BundletoolJar}"},
+	},
+	"flags")
+
+// BuildAppBundle builds an Android App Bundle (.aab) from a bundle module built by BuildBundleModule.
+func BuildAppBundle(ctx android.ModuleContext, outputFile android.WritablePath, bundleModule android.Path,
+	bundleConfig android.OptionalPath) {
+
+	var flags []string
+	var deps android.Paths
+	if bundleConfig.Valid() {
+		flags = append(flags, "--config="+bundleConfig.String())
+		deps = append(deps, bundleConfig.Path())
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        bundletoolBuildBundle,
+		Description: "app bundle",
+		Input:       bundleModule,
+		Implicits:   deps,
+		Output:      outputFile,
+		Args: map[string]string{
+			"flags": strings.Join(flags, " "),
+		},
+	})
+}
+
+// bundletoolBuildApks generates an APK set from an app bundle, signs all of the APKs in it with signapk, and
+// writes the signed APKs into a new APK set.  Without --ks bundletool signs the APKs with the debug keystore
+// in the home directory of the user if there is one, so it is run with an empty home directory to make it
+// leave the APKs unsigned regardless of the host.
+var bundletoolBuildApks = pctx.AndroidStaticRule("bundletoolBuildApks",
+	blueprint.RuleParams{
+		Command: `rm -rf $outDir && mkdir -p $outDir/home && ` +
+			`HOME=$outDir/home ANDROID_SDK_HOME=$outDir/home ` +
+			`${config.JavaCmd} -Duser.home=$outDir/home -jar ${config.BundletoolJar} ` +
+			`build-apks --bundle=$in --output=$outDir/unsigned.apks $flags && ` +
+			`${config.ZipSyncCmd} -d $outDir/apks -l $outDir/apks.list $outDir/unsigned.apks && ` +
+			`for f in $$(grep '\.apk$$' $outDir/apks.list); do ` +
+			`${config.JavaCmd} -Djava.library.path=$$(dirname $signapkJniLibrary) ` +
+			`-jar $signapkCmd $certificates $$f $$f.signed && mv $$f.signed $$f || exit 1; done && ` +
+			`${config.SoongZipCmd} -L 0 -o $out -C $outDir/apks -l $outDir/apks.list`,
+		CommandDeps: []string{
+			"${config.BundletoolJar}",
+			"${config.ZipSyncCmd}",
+			"${config.SoongZipCmd}",
+			"$signapkCmd",
+			"$signapkJniLibrary",
+		},
+	},
+	"flags", "certificates", "outDir")
+
+// BuildApksFromBundle generates the signed split APKs for an app bundle into an APK set (.apks).  If deviceSpec
+// is set only the APKs that would be installed on that device are generated.  If universal is set the APK set
+// contains a single universal APK that supports all devices instead.
+func BuildApksFromBundle(ctx android.ModuleContext, outputFile android.WritablePath, bundle android.Path,
+	deviceSpec android.OptionalPath, universal bool, certificates []Certificate) {
+
+	var flags []string
+	var deps android.Paths
+	if universal {
+		flags = append(flags, "--mode=universal")
+	} else if deviceSpec.Valid() {
+		flags = append(flags, "--device-spec="+deviceSpec.String())
+		deps = append(deps, deviceSpec.Path())
+	}
+
+	var certificateArgs []string
+	for _, c := range certificates {
+		certificateArgs = append(certificateArgs, c.Pem.String(), c.Key.String())
+		deps = append(deps, c.Pem, c.Key)
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        bundletoolBuildApks,
+		Description: "bundle apks",
+		Input:       bundle,
+		Implicits:   deps,
+		Output:      outputFile,
+		Args: map[string]string{
+			"flags":        strings.Join(flags, " "),
+			"certificates": strings.Join(certificateArgs, " "),
+			"outDir":       android.PathForModuleOut(ctx, "bundle", strings.TrimSuffix(outputFile.Base(), ".apks")).String(),
+		},
+	})
+}
+
+var extractApk = pctx.AndroidStaticRule("extractApk",
+	blueprint.RuleParams{
+		Command:     `${config.ZipSyncCmd} -d $outDir -f $apk $in && cp -f $outDir/$apk $out`,
+		CommandDeps: []string{"${config.ZipSyncCmd}"},
+	},
+	"apk", "outDir")
+
+// ExtractUniversalApk extracts the universal APK from an APK set built by BuildApksFromBundle with universal set.
+func ExtractUniversalApk(ctx android.ModuleContext, outputFile android.WritablePath, apkSet android.Path) {
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        extractApk,
+		Description: "extract universal apk",
+		Input:       apkSet,
+		Output:      outputFile,
+		Args: map[string]string{
+			"apk":    "universal.apk",
+			"outDir": android.PathForModuleOut(ctx, "bundle", "universal").String(),
+		},
+	})
+}
+
 func TransformJniLibsToJar(ctx android.ModuleContext, outputFile android.WritablePath,
 	jniLibs []jniLib, uncompressJNI bool) {
 
@@ -142,6 +142,179 @@ func TestAppSplits(t *testing.T) {
 	}
 }
 
+func TestAppBundle(t *testing.T) {
+	bp := cc.GatherRequiredDepsForTest(android.Android) + `
+		cc_library {
+			name: "libjni",
+			system_shared_libs: [],
+			stl: "none",
+		}
+
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			jni_libs: ["libjni"],
+			package_splits: ["v4"],
+			bundle: {
+				enabled: true,
+				device_spec: "device_spec.json",
+				config: "bundle_config.json",
+			},
+		}
+
+		android_app {
+			name: "bar",
+			srcs: ["a.java"],
+		}
+	`
+
+	config := testConfig(nil)
+	ctx := testAppContext(config, bp, map[string][]byte{
+		"device_spec.json":   nil,
+		"bundle_config.json": nil,
+	})
+	run(t, ctx, config)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+
+	protoLink := foo.Rule("aapt2LinkProto")
+	if g, w := protoLink.Output.Rel(), "package-res.pb.apk"; g != w {
+		t.Errorf("want proto link output %q, got %q", w, g)
+	}
+	if strings.Contains(protoLink.Args["flags"], "--split") {
+		t.Errorf("want proto link flags without --split, got %q", protoLink.Args["flags"])
+	}
+	if foo.MaybeRule("aapt2Convert").Rule != nil {
+		t.Errorf("want no aapt2 convert for app with proto resources")
+	}
+
+	jniLibZip := foo.Output("bundle/jnilibs.zip")
+	bundleModule := foo.Output("base.zip")
+	if !inList(jniLibZip.Output.String(), bundleModule.Inputs.Strings()) {
+		t.Errorf("want bundle module inputs %q to contain %q", bundleModule.Inputs.Strings(), jniLibZip.Output.String())
+	}
+
+	aab := foo.Output("foo.aab")
+	if g, w := aab.Input.String(), bundleModule.Output.String(); g != w {
+		t.Errorf("want app bundle input %q, got %q", w, g)
+	}
+	if g, w := aab.Args["flags"], "--config=bundle_config.json"; g != w {
+		t.Errorf("want app bundle flags %q, got %q", w, g)
+	}
+
+	apks := foo.Output("foo.apks")
+	if g, w := apks.Input.String(), aab.Output.String(); g != w {
+		t.Errorf("want apks input %q, got %q", w, g)
+	}
+	if g, w := apks.Args["flags"], "--device-spec=device_spec.json"; g != w {
+		t.Errorf("want apks flags %q, got %q", w, g)
+	}
+	if w := "build/make/target/product/security/testkey.x509.pem"; !strings.Contains(apks.Args["certificates"], w) {
+		t.Errorf("want apks certificates %q to contain %q", apks.Args["certificates"], w)
+	}
+
+	universalApks := foo.Output("foo_universal.apks")
+	if g, w := universalApks.Args["flags"], "--mode=universal"; g != w {
+		t.Errorf("want universal apks flags %q, got %q", w, g)
+	}
+	universalApk := foo.Output("foo_universal.apk")
+	if g, w := universalApk.Input.String(), universalApks.Output.String(); g != w {
+		t.Errorf("want universal apk input %q, got %q", w, g)
+	}
+
+	bar := ctx.ModuleForTests("bar", "android_common")
+	if bar.MaybeRule("aapt2LinkProto").Rule != nil {
+		t.Errorf("want no proto link for app without bundle enabled")
+	}
+	bar.Rule("aapt2Convert")
+	if bar.MaybeOutput("bar.aab").Rule != nil {
+		t.Errorf("want no app bundle for app without bundle enabled")
+	}
+}
+
+func TestShrinkResources(t *testing.T) {
+	ctx := testApp(t, `
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			optimize: {
+				shrink_resources: true,
+			},
+		}
+
+		android_app {
+			name: "bar",
+			srcs: ["a.java"],
+		}
+	`)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+
+	protoRes := foo.Output("package-res.pb.apk")
+	r8 := foo.Rule("r8")
+	shrunkProtoRes := foo.Output("package-res-shrunk.pb.apk")
+	if shrunkProtoRes.Rule != r8.Rule {
+		t.Errorf("want shrunk resources to be written by r8")
+	}
+	for _, w := range []string{
+		"--resource-input " + protoRes.Output.String(),
+		"--resource-output " + shrunkProtoRes.ImplicitOutputs[1].String(),
+	} {
+		if !strings.Contains(r8.Args["r8Flags"], w) {
+			t.Errorf("want r8 flags to contain %q, got %q", w, r8.Args["r8Flags"])
+		}
+	}
+
+	shrunkRes := foo.Output("package-res-shrunk.apk")
+	if g, w := shrunkRes.Args["format"], "binary"; g != w {
+		t.Errorf("want shrunk resources converted to %q, got %q", w, g)
+	}
+	unsignedApk := foo.Output("foo-unsigned.apk")
+	if !inList(shrunkRes.Output.String(), unsignedApk.Inputs.Strings()) {
+		t.Errorf("want apk inputs %q to contain %q", unsignedApk.Inputs.Strings(), shrunkRes.Output.String())
+	}
+
+	report := foo.Output("resource-shrinker-report.txt")
+	if g, w := report.Input.String(), foo.Output("package-res.apk").Output.String(); g != w {
+		t.Errorf("want resource shrinker report input %q, got %q", w, g)
+	}
+	if g, w := report.Implicit.String(), shrunkRes.Output.String(); g != w {
+		t.Errorf("want resource shrinker report implicit %q, got %q", w, g)
+	}
+
+	bar := ctx.ModuleForTests("bar", "android_common")
+	if strings.Contains(bar.Rule("r8").Args["r8Flags"], "--resource-input") {
+		t.Errorf("want no resource shrinking for app without shrink_resources")
+	}
+	if bar.MaybeOutput("package-res-shrunk.apk").Rule != nil {
+		t.Errorf("want no shrunk resources for app without shrink_resources")
+	}
+}
+
+func TestShrinkResourcesErrors(t *testing.T) {
+	testJavaError(t, `shrink_resources: is only supported by android_app modules`, `
+		android_test {
+			name: "foo",
+			srcs: ["a.java"],
+			optimize: {
+				enabled: true,
+				shrink_resources: true,
+			},
+		}
+	`)
+
+	testJavaError(t, `shrink_resources: cannot be used with package_splits`, `
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			package_splits: ["v4"],
+			optimize: {
+				shrink_resources: true,
+			},
+		}
+	`)
+}
+
 func TestResourceDirs(t *testing.T) {
 	testCases := []struct {
 		name      string
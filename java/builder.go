@@ -47,7 +47,8 @@ var (
 				`$processorpath $processor $javacFlags $bootClasspath $classpath ` +
 				`-source $javaVersion -target $javaVersion ` +
 				`-d $outDir -s $annoDir @$out.rsp @$srcJarDir/list ; fi ) && ` +
-				`${config.SoongZipCmd} -jar -o $out -C $outDir -D $outDir && ` +
+				`${config.SoongZipCmd} -jar -o $out.tmp -C $outDir -D $outDir && ` +
+				`(if cmp -s $out.tmp $out ; then rm $out.tmp ; else mv $out.tmp $out ; fi ) && ` +
 				`rm -rf "$srcJarDir"`,
 			CommandDeps: []string{
 				"${config.JavacCmd}",
@@ -57,6 +58,7 @@ var (
 			CommandOrderOnly: []string{"${config.SoongJavacWrapper}"},
 			Rspfile:          "$out.rsp",
 			RspfileContent:   "$in",
+			Restat:           true,
 		},
 		"javacFlags", "bootClasspath", "classpath", "processorpath", "processor", "srcJars", "srcJarDir",
 		"outDir", "annoDir", "javaVersion")
@@ -81,6 +83,17 @@ var (
 		},
 		"javacFlags", "bootClasspath", "classpath", "srcJars", "outDir", "javaVersion")
 
+	// abiJar strips everything that dependents cannot compile against from a header jar.  The output is
+	// only rewritten when the ABI changes, so changes to private members or method bodies don't cause
+	// dependents to be recompiled.
+	abiJar = pctx.AndroidStaticRule("abiJar",
+		blueprint.RuleParams{
+			Command: `${config.AbiJarCmd} -o $out.tmp $in && ` +
+				`(if cmp -s $out.tmp $out ; then rm $out.tmp ; else mv $out.tmp $out ; fi )`,
+			CommandDeps: []string{"${config.AbiJarCmd}"},
+			Restat:      true,
+		})
+
 	jar = pctx.AndroidStaticRule("jar",
 		blueprint.RuleParams{
 			Command:        `${config.SoongZipCmd} -jar -o $out @$out.rsp`,
@@ -99,13 +112,6 @@ var (
 		},
 		"jarArgs")
 
-	combineJar = pctx.AndroidStaticRule("combineJar",
-		blueprint.RuleParams{
-			Command:     `${config.MergeZipsCmd} --ignore-duplicates -j $jarArgs $out $in`,
-			CommandDeps: []string{"${config.MergeZipsCmd}"},
-		},
-		"jarArgs")
-
 	jarjar = pctx.AndroidStaticRule("jarjar",
 		blueprint.RuleParams{
 			Command:     "${config.JavaCmd} -jar ${config.JarjarCmd} process $rulesFile $in $out",
@@ -162,6 +168,7 @@ type javaBuilderFlags struct {
 
 	kotlincFlags     string
 	kotlincClasspath classpath
+	kotlinPlugins    []kotlinPlugin
 
 	proto android.ProtoFlags
 }
@@ -298,6 +305,17 @@ func transformJavaToClasses(ctx android.ModuleContext, outputFile android.Writab
 	})
 }
 
+// TransformJarToAbiJar strips the private members, static initializers, method bodies and debug information
+// from the classes in a header jar.
+func TransformJarToAbiJar(ctx android.ModuleContext, outputFile android.WritablePath, inputFile android.Path) {
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        abiJar,
+		Description: "abi jar",
+		Output:      outputFile,
+		Input:       inputFile,
+	})
+}
+
 func TransformResourcesToJar(ctx android.ModuleContext, outputFile android.WritablePath,
 	jarArgs []string, deps android.Paths) {
 
@@ -316,40 +334,37 @@ func TransformJarsToJar(ctx android.ModuleContext, outputFile android.WritablePa
 	jars android.Paths, manifest android.OptionalPath, stripDirEntries bool, filesToStrip []string,
 	dirsToStrip []string) {
 
-	var deps android.Paths
+	rule := android.NewRuleBuilder()
+	cmd := rule.Command().
+		Tool(ctx.Config().HostToolPath(ctx, "merge_zips")).
+		Flag("--ignore-duplicates").
+		Flag("-j")
 
-	var jarArgs []string
 	if manifest.Valid() {
-		jarArgs = append(jarArgs, "-m ", manifest.String())
-		deps = append(deps, manifest.Path())
+		cmd.FlagWithInput("-m ", manifest.Path())
 	}
 
 	for _, dir := range dirsToStrip {
-		jarArgs = append(jarArgs, "-stripDir ", dir)
+		cmd.FlagWithArg("-stripDir ", dir)
 	}
 
 	for _, file := range filesToStrip {
-		jarArgs = append(jarArgs, "-stripFile ", file)
+		cmd.FlagWithArg("-stripFile ", file)
 	}
 
 	// Remove any module-info.class files that may have come from prebuilt jars, they cause problems
 	// for downstream tools like desugar.
-	jarArgs = append(jarArgs, "-stripFile module-info.class")
+	cmd.FlagWithArg("-stripFile ", "module-info.class")
 
 	if stripDirEntries {
-		jarArgs = append(jarArgs, "-D")
+		cmd.Flag("-D")
 	}
 
-	ctx.Build(pctx, android.BuildParams{
-		Rule:        combineJar,
-		Description: desc,
-		Output:      outputFile,
-		Inputs:      jars,
-		Implicits:   deps,
-		Args: map[string]string{
-			"jarArgs": strings.Join(jarArgs, " "),
-		},
-	})
+	// Modules with many static libraries may have too many jars for the command line.
+	cmd.Output(outputFile).
+		FlagWithRspFileInputList("@", android.PathForModuleOut(ctx, outputFile.Rel()+".rsp"), jars)
+
+	rule.Build(pctx, ctx, "combineJar_"+outputFile.Rel(), desc)
 }
 
 func TransformJarJar(ctx android.ModuleContext, outputFile android.WritablePath,
@@ -96,6 +96,7 @@ func init() {
 	pctx.HostBinToolVariable("ExtractJarPackagesCmd", "extract_jar_packages")
 	pctx.HostBinToolVariable("SoongZipCmd", "soong_zip")
 	pctx.HostBinToolVariable("MergeZipsCmd", "merge_zips")
+	pctx.HostBinToolVariable("AbiJarCmd", "abi_jar")
 	pctx.HostBinToolVariable("Zip2ZipCmd", "zip2zip")
 	pctx.HostBinToolVariable("ZipSyncCmd", "zipsync")
 	pctx.HostBinToolVariable("ApiCheckCmd", "apicheck")
@@ -118,6 +119,7 @@ func init() {
 	pctx.HostJavaToolVariable("MetalavaJar", "metalava.jar")
 	pctx.HostJavaToolVariable("DokkaJar", "dokka.jar")
 	pctx.HostJavaToolVariable("JetifierJar", "jetifier.jar")
+	pctx.HostJavaToolVariable("BundletoolJar", "bundletool.jar")
 
 	pctx.HostBinToolVariable("SoongJavacWrapper", "soong_javac_wrapper")
 	pctx.HostBinToolVariable("DexpreoptGen", "dexpreopt_gen")
@@ -147,8 +149,25 @@ func init() {
 
 	pctx.HostBinToolVariable("ManifestMergerCmd", "manifest-merger")
 
+	pctx.SourcePathVariable("LintProjectXmlCmd", "build/soong/scripts/lint_project_xml.py")
+	pctx.HostBinToolVariable("LintCmd", "lint")
+
 	pctx.HostBinToolVariable("ZipAlign", "zipalign")
 
 	pctx.HostBinToolVariable("Class2Greylist", "class2greylist")
 	pctx.HostBinToolVariable("HiddenAPI", "hiddenapi")
 }
+
+// JavaCmd returns the path to the java binary of the JDK, for commands built with android.RuleBuilder, which can't
+// use ${config.JavaCmd}.
+func JavaCmd(ctx android.PathContext) android.SourcePath {
+	// ANDROID_JAVA_HOME is set up and guaranteed by soong_ui
+	return android.PathForSource(ctx, ctx.Config().Getenv("ANDROID_JAVA_HOME"), "bin", "java")
+}
+
+// JavadocCmd returns the path to the javadoc binary of the JDK, for commands built with android.RuleBuilder, which
+// can't use ${config.JavadocCmd}.
+func JavadocCmd(ctx android.PathContext) android.SourcePath {
+	// ANDROID_JAVA_HOME is set up and guaranteed by soong_ui
+	return android.PathForSource(ctx, ctx.Config().Getenv("ANDROID_JAVA_HOME"), "bin", "javadoc")
+}
@@ -165,6 +165,12 @@ func (j *Module) r8Flags(ctx android.ModuleContext, flags javaBuilderFlags) (r8F
 		r8Flags = append(r8Flags, "--debug")
 	}
 
+	if j.resourcesInput.Valid() {
+		r8Flags = append(r8Flags, "--resource-input", j.resourcesInput.String())
+		r8Flags = append(r8Flags, "--resource-output", j.resourcesOutput.String())
+		r8Deps = append(r8Deps, j.resourcesInput.Path())
+	}
+
 	return r8Flags, r8Deps
 }
 
@@ -173,6 +179,14 @@ func (j *Module) compileDex(ctx android.ModuleContext, flags javaBuilderFlags,
 
 	useR8 := j.deviceProperties.EffectiveOptimizeEnabled()
 
+	if Bool(j.deviceProperties.Optimize.Shrink_resources) {
+		if ctx.ModuleType() != "android_app" {
+			ctx.PropertyErrorf("optimize.shrink_resources", "is only supported by android_app modules")
+		} else if !useR8 || !Bool(j.deviceProperties.Optimize.Shrink) {
+			ctx.PropertyErrorf("optimize.shrink_resources", "requires optimize.enabled and optimize.shrink")
+		}
+	}
+
 	// Compile classes.jar into classes.dex and then javalib.jar
 	javalibJar := android.PathForModuleOut(ctx, "dex", jarName)
 	outDir := android.PathForModuleOut(ctx, "dex")
@@ -185,14 +199,20 @@ func (j *Module) compileDex(ctx android.ModuleContext, flags javaBuilderFlags,
 	if useR8 {
 		proguardDictionary := android.PathForModuleOut(ctx, "proguard_dictionary")
 		j.proguardDictionary = proguardDictionary
+		implicitOutputs := android.WritablePaths{proguardDictionary}
+		if j.resourcesInput.Valid() {
+			resourcesOutput := android.PathForModuleOut(ctx, "package-res-shrunk.pb.apk")
+			j.resourcesOutput = android.OptionalPathForPath(resourcesOutput)
+			implicitOutputs = append(implicitOutputs, resourcesOutput)
+		}
 		r8Flags, r8Deps := j.r8Flags(ctx, flags)
 		ctx.Build(pctx, android.BuildParams{
-			Rule:           r8,
-			Description:    "r8",
-			Output:         javalibJar,
-			ImplicitOutput: proguardDictionary,
-			Input:          classesJar,
-			Implicits:      r8Deps,
+			Rule:            r8,
+			Description:     "r8",
+			Output:          javalibJar,
+			ImplicitOutputs: implicitOutputs,
+			Input:           classesJar,
+			Implicits:       r8Deps,
 			Args: map[string]string{
 				"r8Flags":  strings.Join(r8Flags, " "),
 				"zipFlags": zipFlags,
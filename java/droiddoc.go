@@ -23,33 +23,10 @@ import (
 	"strings"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 )
 
 var (
-	javadoc = pctx.AndroidStaticRule("javadoc",
-		blueprint.RuleParams{
-			Command: `rm -rf "$outDir" "$srcJarDir" "$stubsDir" && mkdir -p "$outDir" "$srcJarDir" "$stubsDir" && ` +
-				`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
-				`${config.SoongJavacWrapper} ${config.JavadocCmd} -encoding UTF-8 @$out.rsp @$srcJarDir/list ` +
-				`$opts $bootclasspathArgs $classpathArgs $sourcepathArgs ` +
-				`-d $outDir -quiet  && ` +
-				`${config.SoongZipCmd} -write_if_changed -d -o $docZip -C $outDir -D $outDir && ` +
-				`${config.SoongZipCmd} -write_if_changed -jar -o $out -C $stubsDir -D $stubsDir $postDoclavaCmds && ` +
-				`rm -rf "$srcJarDir"`,
-
-			CommandDeps: []string{
-				"${config.ZipSyncCmd}",
-				"${config.JavadocCmd}",
-				"${config.SoongZipCmd}",
-			},
-			CommandOrderOnly: []string{"${config.SoongJavacWrapper}"},
-			Rspfile:          "$out.rsp",
-			RspfileContent:   "$in",
-			Restat:           true,
-		},
-		"outDir", "srcJarDir", "stubsDir", "srcJars", "opts",
-		"bootclasspathArgs", "classpathArgs", "sourcepathArgs", "docZip", "postDoclavaCmds")
-
 	apiCheck = pctx.AndroidStaticRule("apiCheck",
 		blueprint.RuleParams{
 			Command: `( ${config.ApiCheckCmd} -JXmx1024m -J"classpath $classpath" $opts ` +
@@ -68,74 +45,11 @@ var (
 		},
 		"srcApiFile", "destApiFile", "srcRemovedApiFile", "destRemovedApiFile")
 
-	metalava = pctx.AndroidStaticRule("metalava",
-		blueprint.RuleParams{
-			Command: `rm -rf "$outDir" "$srcJarDir" "$stubsDir" && ` +
-				`mkdir -p "$outDir" "$srcJarDir" "$stubsDir" && ` +
-				`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
-				`${config.JavaCmd} -jar ${config.MetalavaJar} -encoding UTF-8 -source $javaVersion @$out.rsp @$srcJarDir/list ` +
-				`$bootclasspathArgs $classpathArgs $sourcepathArgs --no-banner --color --quiet --format=v2 ` +
-				`$opts && ` +
-				`${config.SoongZipCmd} -write_if_changed -jar -o $out -C $stubsDir -D $stubsDir && ` +
-				`(if $writeSdkValues; then ${config.SoongZipCmd} -write_if_changed -d -o $metadataZip ` +
-				`-C $metadataDir -D $metadataDir; fi) && ` +
-				`rm -rf "$srcJarDir"`,
-			CommandDeps: []string{
-				"${config.ZipSyncCmd}",
-				"${config.JavaCmd}",
-				"${config.MetalavaJar}",
-				"${config.SoongZipCmd}",
-			},
-			Rspfile:        "$out.rsp",
-			RspfileContent: "$in",
-			Restat:         true,
-		},
-		"outDir", "srcJarDir", "stubsDir", "srcJars", "javaVersion", "bootclasspathArgs",
-		"classpathArgs", "sourcepathArgs", "opts", "writeSdkValues", "metadataZip", "metadataDir")
-
-	metalavaApiCheck = pctx.AndroidStaticRule("metalavaApiCheck",
-		blueprint.RuleParams{
-			Command: `( rm -rf "$srcJarDir" && mkdir -p "$srcJarDir" && ` +
-				`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
-				`${config.JavaCmd} -jar ${config.MetalavaJar} -encoding UTF-8 -source $javaVersion @$out.rsp @$srcJarDir/list ` +
-				`$bootclasspathArgs $classpathArgs $sourcepathArgs --no-banner --color --quiet --format=v2 ` +
-				`$opts && touch $out && rm -rf "$srcJarDir") || ` +
-				`( echo -e "$msg" ; exit 38 )`,
-			CommandDeps: []string{
-				"${config.ZipSyncCmd}",
-				"${config.JavaCmd}",
-				"${config.MetalavaJar}",
-			},
-			Rspfile:        "$out.rsp",
-			RspfileContent: "$in",
-		},
-		"srcJarDir", "srcJars", "javaVersion", "bootclasspathArgs", "classpathArgs", "sourcepathArgs", "opts", "msg")
-
 	nullabilityWarningsCheck = pctx.AndroidStaticRule("nullabilityWarningsCheck",
 		blueprint.RuleParams{
 			Command: `( diff $expected $actual && touch $out ) || ( echo -e "$msg" ; exit 38 )`,
 		},
 		"expected", "actual", "msg")
-
-	dokka = pctx.AndroidStaticRule("dokka",
-		blueprint.RuleParams{
-			Command: `rm -rf "$outDir" "$srcJarDir" "$stubsDir" && ` +
-				`mkdir -p "$outDir" "$srcJarDir" "$stubsDir" && ` +
-				`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
-				`${config.JavaCmd} -jar ${config.DokkaJar} $srcJarDir ` +
-				`$classpathArgs -format dac -dacRoot /reference/kotlin -output $outDir $opts && ` +
-				`${config.SoongZipCmd} -write_if_changed -d -o $docZip -C $outDir -D $outDir && ` +
-				`${config.SoongZipCmd} -write_if_changed -jar -o $out -C $stubsDir -D $stubsDir && ` +
-				`rm -rf "$srcJarDir"`,
-			CommandDeps: []string{
-				"${config.ZipSyncCmd}",
-				"${config.DokkaJar}",
-				"${config.MetalavaJar}",
-				"${config.SoongZipCmd}",
-			},
-			Restat: true,
-		},
-		"outDir", "srcJarDir", "stubsDir", "srcJars", "classpathArgs", "opts", "docZip")
 )
 
 func init() {
@@ -217,6 +131,10 @@ type JavadocProperties struct {
 
 	// names of the output files used in args that will be generated
 	Out []string
+
+	// If set to true, run javadoc, doclava, dokka or metalava in a sandbox that only contains the
+	// declared inputs of the module, and fail if they read any other file.
+	Sandbox_inputs *bool
 }
 
 type ApiToCheck struct {
@@ -758,6 +676,84 @@ func (j *Javadoc) collectDeps(ctx android.ModuleContext) deps {
 	return deps
 }
 
+// docRuleBuilder returns a RuleBuilder for the javadoc, doclava, dokka or metalava command of the module.  If the
+// module sets sandbox_inputs the command runs with sbox in a sandbox that only contains its declared inputs, and
+// writes its outputs in place.
+func (j *Javadoc) docRuleBuilder(ctx android.ModuleContext, name string) *android.RuleBuilder {
+	rule := android.NewRuleBuilder()
+	if Bool(j.properties.Sandbox_inputs) {
+		// All outputs of the command are written in place, the sbox output directory stays empty.
+		rule.Sbox(android.PathForModuleOut(ctx, name+"-sbox")).SandboxInputs()
+	} else {
+		rule.Restat()
+	}
+	return rule
+}
+
+// docCommand adds commands to rule that clear outDir, srcJarDir and stubsDir and extract the java files in the
+// srcjars of the module into srcJarDir, and returns a new command for the documentation tool.
+func (j *Javadoc) docCommand(ctx android.ModuleContext, rule *android.RuleBuilder,
+	outDir, srcJarDir, stubsDir android.ModuleOutPath) *android.RuleBuilderCommand {
+
+	dirs := []string{outDir.String(), srcJarDir.String(), stubsDir.String()}
+	rule.Command().Text("rm -rf").Flags(dirs)
+	rule.Command().Text("mkdir -p").Flags(dirs)
+	rule.Command().
+		Tool(pctx.HostBinToolPath(ctx, "zipsync")).
+		FlagWithArg("-d ", srcJarDir.String()).
+		FlagWithArg("-l ", srcJarDir.Join(ctx, "list").String()).
+		FlagWithArg("-f ", `"*.java"`).
+		Inputs(j.srcJars)
+	return rule.Command()
+}
+
+// zipDocDir adds a command to rule that zips dir into out, and only updates out if its contents changed.
+func zipDocDir(ctx android.ModuleContext, rule *android.RuleBuilder, flag string, out android.WritablePath,
+	dir android.Path) *android.RuleBuilderCommand {
+
+	return rule.Command().
+		Tool(pctx.HostBinToolPath(ctx, "soong_zip")).
+		Flag("-write_if_changed").
+		Flag(flag).
+		FlagWithOutput("-o ", out).
+		FlagWithArg("-C ", dir.String()).
+		FlagWithArg("-D ", dir.String())
+}
+
+// transformJavadoc adds a rule that runs javadoc, with the doclet selected by opts, to write the docs to docZip and
+// the stubs to stubsSrcJar.  The command uses the out, srcjars and stubsDir directories of the module, prefixed by
+// dirPrefix.
+func (j *Javadoc) transformJavadoc(ctx android.ModuleContext, name, desc, dirPrefix string,
+	stubsSrcJar, docZip android.WritablePath, implicits android.Paths, implicitOutputs android.WritablePaths,
+	bootclasspathArgs, classpathArgs, sourcepathArgs, opts, postDoclavaCmds string) {
+
+	outDir := android.PathForModuleOut(ctx, dirPrefix+"out")
+	srcJarDir := android.PathForModuleOut(ctx, dirPrefix+"srcjars")
+	stubsDir := android.PathForModuleOut(ctx, dirPrefix+"stubsDir")
+
+	rule := j.docRuleBuilder(ctx, name)
+	j.docCommand(ctx, rule, outDir, srcJarDir, stubsDir).
+		Tool(pctx.HostBinToolPath(ctx, "soong_javac_wrapper")).
+		Tool(config.JavadocCmd(ctx)).
+		Flag("-encoding UTF-8").
+		FlagWithRspFileInputList("@", android.PathForModuleOut(ctx, name+".rsp"), j.srcFiles).
+		FlagWithArg("@", srcJarDir.Join(ctx, "list").String()).
+		Text(opts).
+		Text(bootclasspathArgs).
+		Text(classpathArgs).
+		Text(sourcepathArgs).
+		FlagWithArg("-d ", outDir.String()).
+		Flag("-quiet").
+		Implicits(implicits).
+		ImplicitOutputs(implicitOutputs)
+
+	zipDocDir(ctx, rule, "-d", docZip, outDir)
+	zipDocDir(ctx, rule, "-jar", stubsSrcJar, stubsDir).Text(postDoclavaCmds)
+	rule.Command().Text("rm -rf").Text(srcJarDir.String())
+
+	rule.Build(pctx, ctx, name, desc)
+}
+
 func (j *Javadoc) DepsMutator(ctx android.BottomUpMutatorContext) {
 	j.addDeps(ctx)
 }
@@ -791,25 +787,8 @@ func (j *Javadoc) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	sourcepathArgs = "-sourcepath " + strings.Join(j.sourcepaths.Strings(), ":")
 
-	ctx.Build(pctx, android.BuildParams{
-		Rule:           javadoc,
-		Description:    "Javadoc",
-		Output:         j.stubsSrcJar,
-		ImplicitOutput: j.docZip,
-		Inputs:         j.srcFiles,
-		Implicits:      implicits,
-		Args: map[string]string{
-			"outDir":            android.PathForModuleOut(ctx, "out").String(),
-			"srcJarDir":         android.PathForModuleOut(ctx, "srcjars").String(),
-			"stubsDir":          android.PathForModuleOut(ctx, "stubsDir").String(),
-			"srcJars":           strings.Join(j.srcJars.Strings(), " "),
-			"opts":              opts,
-			"bootclasspathArgs": bootClasspathArgs,
-			"classpathArgs":     classpathArgs,
-			"sourcepathArgs":    sourcepathArgs,
-			"docZip":            j.docZip.String(),
-		},
-	})
+	j.transformJavadoc(ctx, "javadoc", "Javadoc", "", j.stubsSrcJar, j.docZip, implicits, nil,
+		bootClasspathArgs, classpathArgs, sourcepathArgs, opts, "")
 }
 
 //
@@ -1088,26 +1067,8 @@ func (d *Droiddoc) getPostDoclavaCmds(ctx android.ModuleContext, implicits *andr
 func (d *Droiddoc) transformDoclava(ctx android.ModuleContext, implicits android.Paths,
 	implicitOutputs android.WritablePaths,
 	bootclasspathArgs, classpathArgs, sourcepathArgs, opts, postDoclavaCmds string) {
-	ctx.Build(pctx, android.BuildParams{
-		Rule:            javadoc,
-		Description:     "Doclava",
-		Output:          d.Javadoc.stubsSrcJar,
-		Inputs:          d.Javadoc.srcFiles,
-		Implicits:       implicits,
-		ImplicitOutputs: implicitOutputs,
-		Args: map[string]string{
-			"outDir":            android.PathForModuleOut(ctx, "out").String(),
-			"srcJarDir":         android.PathForModuleOut(ctx, "srcjars").String(),
-			"stubsDir":          android.PathForModuleOut(ctx, "stubsDir").String(),
-			"srcJars":           strings.Join(d.Javadoc.srcJars.Strings(), " "),
-			"opts":              opts,
-			"bootclasspathArgs": bootclasspathArgs,
-			"classpathArgs":     classpathArgs,
-			"sourcepathArgs":    sourcepathArgs,
-			"docZip":            d.Javadoc.docZip.String(),
-			"postDoclavaCmds":   postDoclavaCmds,
-		},
-	})
+	d.Javadoc.transformJavadoc(ctx, "javadoc", "Doclava", "", d.Javadoc.stubsSrcJar, d.Javadoc.docZip,
+		implicits, implicitOutputs, bootclasspathArgs, classpathArgs, sourcepathArgs, opts, postDoclavaCmds)
 }
 
 func (d *Droiddoc) transformCheckApi(ctx android.ModuleContext, apiFile, removedApiFile android.Path,
@@ -1133,22 +1094,28 @@ func (d *Droiddoc) transformCheckApi(ctx android.ModuleContext, apiFile, removed
 
 func (d *Droiddoc) transformDokka(ctx android.ModuleContext, implicits android.Paths,
 	classpathArgs, opts string) {
-	ctx.Build(pctx, android.BuildParams{
-		Rule:        dokka,
-		Description: "Dokka",
-		Output:      d.Javadoc.stubsSrcJar,
-		Inputs:      d.Javadoc.srcFiles,
-		Implicits:   implicits,
-		Args: map[string]string{
-			"outDir":        android.PathForModuleOut(ctx, "dokka-out").String(),
-			"srcJarDir":     android.PathForModuleOut(ctx, "dokka-srcjars").String(),
-			"stubsDir":      android.PathForModuleOut(ctx, "dokka-stubsDir").String(),
-			"srcJars":       strings.Join(d.Javadoc.srcJars.Strings(), " "),
-			"classpathArgs": classpathArgs,
-			"opts":          opts,
-			"docZip":        d.Javadoc.docZip.String(),
-		},
-	})
+
+	outDir := android.PathForModuleOut(ctx, "dokka-out")
+	srcJarDir := android.PathForModuleOut(ctx, "dokka-srcjars")
+	stubsDir := android.PathForModuleOut(ctx, "dokka-stubsDir")
+
+	rule := d.Javadoc.docRuleBuilder(ctx, "dokka")
+	d.Javadoc.docCommand(ctx, rule, outDir, srcJarDir, stubsDir).
+		Tool(config.JavaCmd(ctx)).
+		Flag("-jar").Tool(pctx.HostJavaToolPath(ctx, "dokka.jar")).
+		Text(srcJarDir.String()).
+		Text(classpathArgs).
+		Flag("-format dac -dacRoot /reference/kotlin").
+		FlagWithArg("-output ", outDir.String()).
+		Text(opts).
+		Implicits(d.Javadoc.srcFiles).
+		Implicits(implicits)
+
+	zipDocDir(ctx, rule, "-d", d.Javadoc.docZip, outDir)
+	zipDocDir(ctx, rule, "-jar", d.Javadoc.stubsSrcJar, stubsDir)
+	rule.Command().Text("rm -rf").Text(srcJarDir.String())
+
+	rule.Build(pctx, ctx, "dokka", "Dokka")
 }
 
 func (d *Droiddoc) GenerateAndroidBuildActions(ctx android.ModuleContext) {
@@ -1551,41 +1518,34 @@ func (d *Droidstubs) transformMetalava(ctx android.ModuleContext, implicits andr
 	implicitOutputs android.WritablePaths, javaVersion,
 	bootclasspathArgs, classpathArgs, sourcepathArgs, opts string) {
 
-	var writeSdkValues, metadataZip, metadataDir string
+	outDir := android.PathForModuleOut(ctx, "out")
+	srcJarDir := android.PathForModuleOut(ctx, "srcjars")
+	stubsDir := android.PathForModuleOut(ctx, "stubsDir")
+
+	rule := d.Javadoc.docRuleBuilder(ctx, "metalava")
+	d.Javadoc.docCommand(ctx, rule, outDir, srcJarDir, stubsDir).
+		Tool(config.JavaCmd(ctx)).
+		Flag("-jar").Tool(pctx.HostJavaToolPath(ctx, "metalava.jar")).
+		Flag("-encoding UTF-8").
+		FlagWithArg("-source ", javaVersion).
+		FlagWithRspFileInputList("@", android.PathForModuleOut(ctx, "metalava.rsp"), d.Javadoc.srcFiles).
+		FlagWithArg("@", srcJarDir.Join(ctx, "list").String()).
+		Text(bootclasspathArgs).
+		Text(classpathArgs).
+		Text(sourcepathArgs).
+		Flag("--no-banner --color --quiet --format=v2").
+		Text(opts).
+		Implicits(implicits).
+		ImplicitOutputs(implicitOutputs)
+
+	zipDocDir(ctx, rule, "-jar", d.Javadoc.stubsSrcJar, stubsDir)
 	if Bool(d.properties.Write_sdk_values) {
-		writeSdkValues = "true"
 		d.metadataZip = android.PathForModuleOut(ctx, ctx.ModuleName()+"-metadata.zip")
-		metadataZip = d.metadataZip.String()
-		metadataDir = d.metadataDir.String()
-		implicitOutputs = append(implicitOutputs, d.metadataZip)
-	} else {
-		writeSdkValues = "false"
-		metadataZip = ""
-		metadataDir = ""
+		zipDocDir(ctx, rule, "-d", d.metadataZip, d.metadataDir)
 	}
+	rule.Command().Text("rm -rf").Text(srcJarDir.String())
 
-	ctx.Build(pctx, android.BuildParams{
-		Rule:            metalava,
-		Description:     "Metalava",
-		Output:          d.Javadoc.stubsSrcJar,
-		Inputs:          d.Javadoc.srcFiles,
-		Implicits:       implicits,
-		ImplicitOutputs: implicitOutputs,
-		Args: map[string]string{
-			"outDir":            android.PathForModuleOut(ctx, "out").String(),
-			"srcJarDir":         android.PathForModuleOut(ctx, "srcjars").String(),
-			"stubsDir":          android.PathForModuleOut(ctx, "stubsDir").String(),
-			"srcJars":           strings.Join(d.Javadoc.srcJars.Strings(), " "),
-			"javaVersion":       javaVersion,
-			"bootclasspathArgs": bootclasspathArgs,
-			"classpathArgs":     classpathArgs,
-			"sourcepathArgs":    sourcepathArgs,
-			"opts":              opts,
-			"writeSdkValues":    writeSdkValues,
-			"metadataZip":       metadataZip,
-			"metadataDir":       metadataDir,
-		},
-	})
+	rule.Build(pctx, ctx, "metalava", "Metalava")
 }
 
 func (d *Droidstubs) transformCheckApi(ctx android.ModuleContext,
@@ -1594,55 +1554,49 @@ func (d *Droidstubs) transformCheckApi(ctx android.ModuleContext,
 	output android.WritablePath) {
 
 	implicits = append(android.Paths{apiFile, removedApiFile, d.apiFile, d.removedApiFile}, implicits...)
-	var implicitOutputs android.WritablePaths
+
+	srcJarDir := android.PathForModuleOut(ctx, subdir, "srcjars")
+	srcJarList := srcJarDir.Join(ctx, "list")
+
+	rule := android.NewRuleBuilder()
+	cmd := rule.Command().
+		Text("( rm -rf").Text(srcJarDir.String()).
+		Text("&& mkdir -p").Text(srcJarDir.String()).
+		Text("&&").Tool(pctx.HostBinToolPath(ctx, "zipsync")).
+		FlagWithArg("-d ", srcJarDir.String()).
+		FlagWithArg("-l ", srcJarList.String()).
+		FlagWithArg("-f ", `"*.java"`).
+		Inputs(d.Javadoc.srcJars).
+		Text("&&").Tool(config.JavaCmd(ctx)).
+		Flag("-jar").Tool(pctx.HostJavaToolPath(ctx, "metalava.jar")).
+		Flag("-encoding UTF-8").
+		FlagWithArg("-source ", javaVersion).
+		// The sources of large modules don't fit on the command line.
+		FlagWithRspFileInputList("@", android.PathForModuleOut(ctx, subdir, "metalava.rsp"), d.Javadoc.srcFiles).
+		FlagWithArg("@", srcJarList.String()).
+		Text(bootclasspathArgs).
+		Text(classpathArgs).
+		Text(sourcepathArgs).
+		Flag("--no-banner --color --quiet --format=v2").
+		Text(opts).
+		Implicits(implicits)
 
 	if baselineFile.Valid() {
-		implicits = append(implicits, baselineFile.Path())
-		implicitOutputs = append(implicitOutputs, updatedBaselineOut)
+		cmd.Implicit(baselineFile.Path()).ImplicitOutput(updatedBaselineOut)
 	}
 
-	ctx.Build(pctx, android.BuildParams{
-		Rule:            metalavaApiCheck,
-		Description:     "Metalava Check API",
-		Output:          output,
-		Inputs:          d.Javadoc.srcFiles,
-		Implicits:       implicits,
-		ImplicitOutputs: implicitOutputs,
-		Args: map[string]string{
-			"srcJarDir":         android.PathForModuleOut(ctx, subdir, "srcjars").String(),
-			"srcJars":           strings.Join(d.Javadoc.srcJars.Strings(), " "),
-			"javaVersion":       javaVersion,
-			"bootclasspathArgs": bootclasspathArgs,
-			"classpathArgs":     classpathArgs,
-			"sourcepathArgs":    sourcepathArgs,
-			"opts":              opts,
-			"msg":               msg,
-		},
-	})
+	cmd.Text("&& touch").Output(output).
+		Text("&& rm -rf").Text(srcJarDir.String()).
+		Text(") || ( echo -e").Text(proptools.ShellEscape(msg)).Text("; exit 38 )")
+
+	rule.Build(pctx, ctx, "metalavaApiCheck_"+subdir, "Metalava Check API")
 }
 
 func (d *Droidstubs) transformJdiff(ctx android.ModuleContext, implicits android.Paths,
 	implicitOutputs android.WritablePaths,
 	bootclasspathArgs, classpathArgs, sourcepathArgs, opts string) {
-	ctx.Build(pctx, android.BuildParams{
-		Rule:            javadoc,
-		Description:     "Jdiff",
-		Output:          d.jdiffStubsSrcJar,
-		Inputs:          d.Javadoc.srcFiles,
-		Implicits:       implicits,
-		ImplicitOutputs: implicitOutputs,
-		Args: map[string]string{
-			"outDir":            android.PathForModuleOut(ctx, "jdiff-out").String(),
-			"srcJarDir":         android.PathForModuleOut(ctx, "jdiff-srcjars").String(),
-			"stubsDir":          android.PathForModuleOut(ctx, "jdiff-stubsDir").String(),
-			"srcJars":           strings.Join(d.Javadoc.srcJars.Strings(), " "),
-			"opts":              opts,
-			"bootclasspathArgs": bootclasspathArgs,
-			"classpathArgs":     classpathArgs,
-			"sourcepathArgs":    sourcepathArgs,
-			"docZip":            d.jdiffDocZip.String(),
-		},
-	})
+	d.Javadoc.transformJavadoc(ctx, "jdiff", "Jdiff", "jdiff-", d.jdiffStubsSrcJar, d.jdiffDocZip,
+		implicits, implicitOutputs, bootclasspathArgs, classpathArgs, sourcepathArgs, opts, "")
 }
 
 func (d *Droidstubs) GenerateAndroidBuildActions(ctx android.ModuleContext) {
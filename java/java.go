@@ -21,6 +21,7 @@ package java
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -95,6 +96,13 @@ type CompilerProperties struct {
 	// list of module-specific flags that will be used for kotlinc compiles
 	Kotlincflags []string `android:"arch_variant"`
 
+	// If set, the Kotlin language version passed to kotlinc as -language-version, for example "1.3".
+	Kotlin_lang_version *string
+
+	// If set, the Kotlin API version passed to kotlinc as -api-version, for example "1.3".  Must not be newer than
+	// kotlin_lang_version.
+	Kotlin_api_version *string
+
 	// list of of java libraries that will be in the classpath
 	Libs []string `android:"arch_variant"`
 
@@ -124,6 +132,9 @@ type CompilerProperties struct {
 	// List of modules to use as annotation processors
 	Plugins []string
 
+	// List of kotlin_plugin modules to use as Kotlin compiler plugins or symbol processors
+	Kotlin_plugins []string
+
 	// The number of Java source entries each Javac instance can process
 	Javac_shard_size *int64
 
@@ -242,6 +253,11 @@ type CompilerDeviceProperties struct {
 		// false for libraries and tests.
 		Shrink *bool
 
+		// If true, remove the resources that are not referenced from the code or the other resources of the app
+		// from the APK, and write a report of the removed and kept resources.  Requires shrink.  Only supported
+		// by android_app modules.  Defaults to false.
+		Shrink_resources *bool
+
 		// If true, optimize bytecode.  Defaults to false.
 		Optimize *bool
 
@@ -306,6 +322,10 @@ type Module struct {
 	// output file containing mapping of obfuscated names
 	proguardDictionary android.Path
 
+	// resource package in the protobuf format to shrink with R8, and the shrunk resource package written by R8
+	resourcesInput  android.OptionalPath
+	resourcesOutput android.OptionalPath
+
 	// output file of the module, which may be a classes jar or a dex jar
 	outputFile       android.Path
 	extraOutputFiles android.Paths
@@ -342,6 +362,7 @@ type Module struct {
 
 	hiddenAPI
 	dexpreopter
+	linter
 }
 
 func (j *Module) Srcs() android.Paths {
@@ -394,6 +415,18 @@ type dependencyTag struct {
 	name string
 }
 
+// Linkage implements android.LinkageDependencyTag.
+func (d dependencyTag) Linkage() android.Linkage {
+	switch d {
+	case staticLibTag:
+		return android.StaticLinkage
+	case libTag:
+		return android.SharedLinkage
+	default:
+		return android.NoLinkage
+	}
+}
+
 type jniDependencyTag struct {
 	blueprint.BaseDependencyTag
 	target android.Target
@@ -410,9 +443,11 @@ var (
 	frameworkApkTag       = dependencyTag{name: "framework-apk"}
 	kotlinStdlibTag       = dependencyTag{name: "kotlin-stdlib"}
 	kotlinAnnotationsTag  = dependencyTag{name: "kotlin-annotations"}
+	kotlinPluginTag       = dependencyTag{name: "kotlin-plugin"}
 	proguardRaiseTag      = dependencyTag{name: "proguard-raise"}
 	certificateTag        = dependencyTag{name: "certificate"}
 	instrumentationForTag = dependencyTag{name: "instrumentation_for"}
+	extraLintCheckTag     = dependencyTag{name: "extra-lint-check"}
 )
 
 type sdkDep struct {
@@ -511,6 +546,11 @@ func (j *Module) deps(ctx android.BottomUpMutatorContext) {
 	ctx.AddFarVariationDependencies([]blueprint.Variation{
 		{Mutator: "arch", Variation: ctx.Config().BuildOsCommonVariant},
 	}, pluginTag, j.properties.Plugins...)
+	ctx.AddFarVariationDependencies([]blueprint.Variation{
+		{Mutator: "arch", Variation: ctx.Config().BuildOsCommonVariant},
+	}, kotlinPluginTag, j.properties.Kotlin_plugins...)
+
+	j.linter.deps(ctx)
 
 	android.ProtoDeps(ctx, &j.protoProperties)
 	if j.hasSrcExt(".proto") {
@@ -603,6 +643,7 @@ type deps struct {
 	aidlPreprocess     android.OptionalPath
 	kotlinStdlib       android.Paths
 	kotlinAnnotations  android.Paths
+	kotlinPlugins      []kotlinPlugin
 
 	disableTurbine bool
 }
@@ -765,6 +806,17 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 				} else {
 					ctx.PropertyErrorf("plugins", "%q is not a java_plugin module", otherName)
 				}
+			case kotlinPluginTag:
+				if plugin, ok := dep.(*KotlinPlugin); ok {
+					deps.kotlinPlugins = append(deps.kotlinPlugins, kotlinPlugin{
+						id:               String(plugin.kotlinPluginProperties.Plugin_id),
+						jars:             dep.ImplementationAndResourcesJars(),
+						options:          plugin.kotlinPluginProperties.Plugin_options,
+						generatesSources: Bool(plugin.kotlinPluginProperties.Generates_sources),
+					})
+				} else {
+					ctx.PropertyErrorf("kotlin_plugins", "%q is not a kotlin_plugin module", otherName)
+				}
 			case frameworkResTag:
 				if (ctx.ModuleName() == "framework") || (ctx.ModuleName() == "framework-annotation-proc") {
 					// framework.jar has a one-off dependency on the R.java and Manifest.java files
@@ -1008,6 +1060,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars ...android.Path
 	}
 
 	var kotlinJars android.Paths
+	var kotlinHeaderJars android.Paths
 
 	if srcFiles.HasExt(".kt") {
 		// user defined kotlin flags.
@@ -1021,6 +1074,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars ...android.Path
 		if ctx.Device() {
 			kotlincFlags = append(kotlincFlags, "-no-jdk")
 		}
+		kotlincFlags = append(kotlincFlags, j.kotlinVersionFlags(ctx)...)
 		if len(kotlincFlags) > 0 {
 			// optimization.
 			ctx.Variable(pctx, "kotlincFlags", strings.Join(kotlincFlags, " "))
@@ -1037,6 +1091,22 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars ...android.Path
 		flags.kotlincClasspath = append(flags.kotlincClasspath, flags.bootClasspath...)
 		flags.kotlincClasspath = append(flags.kotlincClasspath, flags.classpath...)
 
+		var symbolProcessors []kotlinPlugin
+		for _, plugin := range deps.kotlinPlugins {
+			if plugin.generatesSources {
+				symbolProcessors = append(symbolProcessors, plugin)
+			} else {
+				flags.kotlinPlugins = append(flags.kotlinPlugins, plugin)
+			}
+		}
+
+		if len(symbolProcessors) > 0 {
+			// Run the symbol processors before kapt and kotlinc so that they see the generated sources
+			symbolsSrcJar := android.PathForModuleOut(ctx, "kotlin-symbols", "kotlin-symbols-sources.jar")
+			kotlinProcessSymbols(ctx, symbolsSrcJar, kotlinSrcFiles, srcJars, symbolProcessors, flags)
+			srcJars = append(srcJars, symbolsSrcJar)
+		}
+
 		if len(flags.processorPath) > 0 {
 			// Use kapt for annotation processing
 			kaptSrcJar := android.PathForModuleOut(ctx, "kapt", "kapt-sources.jar")
@@ -1048,7 +1118,13 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars ...android.Path
 		}
 
 		kotlinJar := android.PathForModuleOut(ctx, "kotlin", jarName)
-		kotlinCompile(ctx, kotlinJar, kotlinSrcFiles, srcJars, flags)
+		var kotlinAbiJar android.WritablePath
+		if ctx.Device() && ctx.Config().IsEnvTrue("KOTLINC_INCREMENTAL") {
+			// Modules that depend on this one compile against the ABI of the kotlin classes, so they
+			// are only recompiled when the ABI changes
+			kotlinAbiJar = android.PathForModuleOut(ctx, "kotlin-abi", jarName)
+		}
+		kotlinCompile(ctx, kotlinJar, kotlinAbiJar, kotlinSrcFiles, srcJars, flags)
 		if ctx.Failed() {
 			return
 		}
@@ -1059,6 +1135,13 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars ...android.Path
 		// Jar kotlin classes into the final jar after javac
 		kotlinJars = append(kotlinJars, kotlinJar)
 		kotlinJars = append(kotlinJars, deps.kotlinStdlib...)
+
+		if kotlinAbiJar != nil {
+			kotlinHeaderJars = append(kotlinHeaderJars, kotlinAbiJar)
+		} else {
+			kotlinHeaderJars = append(kotlinHeaderJars, kotlinJar)
+		}
+		kotlinHeaderJars = append(kotlinHeaderJars, deps.kotlinStdlib...)
 	}
 
 	jars := append(android.Paths(nil), kotlinJars...)
@@ -1077,7 +1160,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars ...android.Path
 			// allow for the use of annotation processors that do function correctly
 			// with sharding enabled. See: b/77284273.
 		}
-		j.headerJarFile = j.compileJavaHeader(ctx, uniqueSrcFiles, srcJars, deps, flags, jarName, kotlinJars)
+		j.headerJarFile = j.compileJavaHeader(ctx, uniqueSrcFiles, srcJars, deps, flags, jarName, kotlinHeaderJars)
 		if ctx.Failed() {
 			return
 		}
@@ -1252,6 +1335,12 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars ...android.Path
 		j.headerJarFile = j.implementationJarFile
 	}
 
+	j.linter.name = ctx.ModuleName()
+	j.linter.srcs = append(append(android.Paths(nil), uniqueSrcFiles...), srcFiles.FilterByExt(".kt")...)
+	j.linter.classpath = append(flags.bootClasspath.Paths(), flags.classpath.Paths()...)
+	j.linter.classes = j.implementationJarFile
+	j.linter.lint(ctx)
+
 	if ctx.Config().IsEnvTrue("EMMA_INSTRUMENT_FRAMEWORK") {
 		if inList(ctx.ModuleName(), config.InstrumentFrameworkModules) {
 			j.properties.Instrument = true
@@ -1348,6 +1437,52 @@ func CheckKotlincFlags(ctx android.ModuleContext, flags []string) {
 	}
 }
 
+// kotlinVersionFlags returns the kotlinc flags that set the Kotlin language and API versions of the module.
+func (j *Module) kotlinVersionFlags(ctx android.ModuleContext) []string {
+	var flags []string
+	if v := j.properties.Kotlin_lang_version; v != nil {
+		if !kotlinVersionRegexp.MatchString(*v) {
+			ctx.PropertyErrorf("kotlin_lang_version", "version %q must be in the form <major>.<minor>", *v)
+		}
+		flags = append(flags, "-language-version", *v)
+	}
+	if v := j.properties.Kotlin_api_version; v != nil {
+		if !kotlinVersionRegexp.MatchString(*v) {
+			ctx.PropertyErrorf("kotlin_api_version", "version %q must be in the form <major>.<minor>", *v)
+		}
+		flags = append(flags, "-api-version", *v)
+	}
+	if lang, api := j.properties.Kotlin_lang_version, j.properties.Kotlin_api_version; lang != nil && api != nil {
+		if kotlinVersionNewer(*api, *lang) {
+			ctx.PropertyErrorf("kotlin_api_version", "version %q must not be newer than kotlin_lang_version %q",
+				*api, *lang)
+		}
+	}
+	return flags
+}
+
+var kotlinVersionRegexp = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// kotlinVersionNewer returns true if Kotlin version a is newer than Kotlin version b.  Versions that are not in the
+// form <major>.<minor> are never newer.
+func kotlinVersionNewer(a, b string) bool {
+	parse := func(v string) (int, int, bool) {
+		if !kotlinVersionRegexp.MatchString(v) {
+			return 0, 0, false
+		}
+		parts := strings.Split(v, ".")
+		major, _ := strconv.Atoi(parts[0])
+		minor, _ := strconv.Atoi(parts[1])
+		return major, minor, true
+	}
+	aMajor, aMinor, aOk := parse(a)
+	bMajor, bMinor, bOk := parse(b)
+	if !aOk || !bOk {
+		return false
+	}
+	return aMajor > bMajor || (aMajor == bMajor && aMinor > bMinor)
+}
+
 func (j *Module) compileJavaHeader(ctx android.ModuleContext, srcFiles, srcJars android.Paths,
 	deps deps, flags javaBuilderFlags, jarName string, extraJars android.Paths) android.Path {
 
@@ -1359,7 +1494,12 @@ func (j *Module) compileJavaHeader(ctx android.ModuleContext, srcFiles, srcJars
 		if ctx.Failed() {
 			return nil
 		}
-		jars = append(jars, turbineJar)
+
+		// Strip the header classes down to their ABI, so that dependents are only recompiled when
+		// the ABI changes.
+		abiJar := android.PathForModuleOut(ctx, "turbine-abi", jarName)
+		TransformJarToAbiJar(ctx, abiJar, turbineJar)
+		jars = append(jars, abiJar)
 	}
 
 	jars = append(jars, extraJars...)
@@ -1503,6 +1643,7 @@ func (j *Library) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	j.dexpreopter.isInstallable = Bool(j.properties.Installable)
 	j.dexpreopter.uncompressedDex = shouldUncompressDex(ctx, &j.dexpreopter)
 	j.deviceProperties.UncompressDex = j.dexpreopter.uncompressedDex
+	j.linter.library = true
 	j.compile(ctx)
 
 	if (Bool(j.properties.Installable) || ctx.Host()) && !android.DirectlyInAnyApex(ctx, ctx.ModuleName()) {
@@ -1533,7 +1674,10 @@ func LibraryFactory() android.Module {
 		&module.Module.properties,
 		&module.Module.deviceProperties,
 		&module.Module.dexpreoptProperties,
-		&module.Module.protoProperties)
+		&module.Module.protoProperties,
+		&module.Module.linter.properties)
+
+	module.Module.linter.properties.Lint.EnabledByDefault = true
 
 	InitJavaModule(module, android.HostAndDeviceSupported)
 	return module
@@ -1615,6 +1759,22 @@ func (j *TestHelperLibrary) GenerateAndroidBuildActions(ctx android.ModuleContex
 	j.Library.GenerateAndroidBuildActions(ctx)
 }
 
+func (j *Test) TestSuites() []string {
+	return j.testProperties.Test_suites
+}
+
+func (j *Test) TestConfig() android.Path {
+	return j.testConfig
+}
+
+func (j *TestHelperLibrary) TestSuites() []string {
+	return j.testHelperLibraryProperties.Test_suites
+}
+
+func (j *TestHelperLibrary) TestConfig() android.Path {
+	return nil
+}
+
 // java_test builds a and links sources into a `.jar` file for the device, and possibly for the host as well, and
 // creates an `AndroidTest.xml` file to allow running the test with `atest` or a `TEST_MAPPING` file.
 //
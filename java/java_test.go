@@ -80,6 +80,7 @@ func testContext(config android.Config, bp string,
 	ctx.RegisterModuleType("java_system_modules", android.ModuleFactoryAdaptor(SystemModulesFactory))
 	ctx.RegisterModuleType("java_genrule", android.ModuleFactoryAdaptor(genRuleFactory))
 	ctx.RegisterModuleType("java_plugin", android.ModuleFactoryAdaptor(PluginFactory))
+	ctx.RegisterModuleType("kotlin_plugin", android.ModuleFactoryAdaptor(KotlinPluginFactory))
 	ctx.RegisterModuleType("dex_import", android.ModuleFactoryAdaptor(DexImportFactory))
 	ctx.RegisterModuleType("filegroup", android.ModuleFactoryAdaptor(android.FileGroupFactory))
 	ctx.RegisterModuleType("genrule", android.ModuleFactoryAdaptor(genrule.GenRuleFactory))
@@ -228,6 +229,29 @@ func testJava(t *testing.T, bp string) *android.TestContext {
 	return ctx
 }
 
+func testJavaError(t *testing.T, pattern string, bp string) {
+	t.Helper()
+	config := testConfig(nil)
+	ctx := testContext(config, bp, nil)
+
+	pathCtx := android.PathContextForTesting(config, nil)
+	setDexpreoptTestGlobalConfig(config, dexpreopt.GlobalConfigForTests(pathCtx))
+
+	ctx.Register()
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp", "prebuilts/sdk/Android.bp"})
+	if len(errs) > 0 {
+		android.FailIfNoMatchingErrors(t, pattern, errs)
+		return
+	}
+	_, errs = ctx.PrepareBuildActions(config)
+	if len(errs) > 0 {
+		android.FailIfNoMatchingErrors(t, pattern, errs)
+		return
+	}
+
+	t.Fatalf("missing expected error %q (0 errors are returned)", pattern)
+}
+
 func moduleToPath(name string) string {
 	switch {
 	case name == `""`:
@@ -281,6 +305,10 @@ func TestSimple(t *testing.T) {
 	if len(combineJar.Inputs) != 2 || combineJar.Inputs[1].String() != baz {
 		t.Errorf("foo combineJar inputs %v does not contain %q", combineJar.Inputs, baz)
 	}
+
+	if combineJar.RuleParams.Rspfile == "" || combineJar.RuleParams.RspfileContent != "$in" {
+		t.Errorf("expected foo combineJar to pass its inputs in an RSP file, got %q", combineJar.RuleParams.Command)
+	}
 }
 
 func TestArchSpecific(t *testing.T) {
@@ -613,6 +641,7 @@ func TestTurbine(t *testing.T) {
 	barTurbine := ctx.ModuleForTests("bar", "android_common").Rule("turbine")
 	barJavac := ctx.ModuleForTests("bar", "android_common").Rule("javac")
 	barTurbineCombined := ctx.ModuleForTests("bar", "android_common").Description("for turbine")
+	barAbiJar := ctx.ModuleForTests("bar", "android_common").Rule("abiJar")
 	bazJavac := ctx.ModuleForTests("baz", "android_common").Rule("javac")
 
 	if len(fooTurbine.Inputs) != 1 || fooTurbine.Inputs[0].String() != "a.java" {
@@ -629,6 +658,12 @@ func TestTurbine(t *testing.T) {
 	if len(barTurbineCombined.Inputs) != 2 || barTurbineCombined.Inputs[1].String() != fooHeaderJar {
 		t.Errorf("bar turbine combineJar inputs %v does not contain %q", barTurbineCombined.Inputs, fooHeaderJar)
 	}
+	if barAbiJar.Input.String() != barTurbine.Output.String() {
+		t.Errorf("bar abi jar input %q is not the turbine output %q", barAbiJar.Input.String(), barTurbine.Output.String())
+	}
+	if len(barTurbineCombined.Inputs) != 2 || barTurbineCombined.Inputs[0].String() != barAbiJar.Output.String() {
+		t.Errorf("bar turbine combineJar inputs %v does not contain %q", barTurbineCombined.Inputs, barAbiJar.Output.String())
+	}
 	if !strings.Contains(bazJavac.Args["classpath"], "prebuilts/sdk/14/public/android.jar") {
 		t.Errorf("baz javac classpath %v does not contain %q", bazJavac.Args["classpath"],
 			"prebuilts/sdk/14/public/android.jar")
@@ -683,8 +718,9 @@ func TestDroiddoc(t *testing.T) {
 
 	stubsJar := filepath.Join(buildDir, ".intermediates", "bar-doc", "android_common", "bar-doc-stubs.srcjar")
 	barDoc := ctx.ModuleForTests("bar-doc", "android_common").Output("bar-doc-stubs.srcjar")
-	if stubsJar != barDoc.Output.String() {
-		t.Errorf("expected stubs Jar [%q], got %q", stubsJar, barDoc.Output.String())
+	barDocOutputs := append(android.WritablePaths{barDoc.Output}, barDoc.ImplicitOutputs...)
+	if !inList(stubsJar, barDocOutputs.Strings()) {
+		t.Errorf("expected stubs Jar [%q], got %q", stubsJar, barDocOutputs.Strings())
 	}
 	inputs := ctx.ModuleForTests("bar-doc", "android_common").Rule("javadoc").Inputs
 	var javaSrcs []string
@@ -696,6 +732,39 @@ func TestDroiddoc(t *testing.T) {
 	}
 }
 
+func TestDroiddocSandboxInputs(t *testing.T) {
+	ctx := testJava(t, `
+		droiddoc_template {
+		    name: "droiddoc-templates-sdk",
+		    path: ".",
+		}
+		droiddoc {
+		    name: "bar-doc",
+		    srcs: ["bar-doc/a.java"],
+		    custom_template: "droiddoc-templates-sdk",
+		    knowntags: ["bar-doc/known_oj_tags.txt"],
+		    sandbox_inputs: true,
+		}
+		`)
+
+	doclava := ctx.ModuleForTests("bar-doc", "android_common").Rule("javadoc")
+
+	if !strings.Contains(doclava.RuleParams.Command, " --sandbox-inputs ") {
+		t.Errorf("expected doclava to run with sandboxed inputs, got %q", doclava.RuleParams.Command)
+	}
+
+	knownTags := "--input bar-doc/known_oj_tags.txt"
+	if !strings.Contains(doclava.RuleParams.Command, knownTags) {
+		t.Errorf("expected command to contain %q, got %q", knownTags, doclava.RuleParams.Command)
+	}
+
+	// The outputs are written in place in the sandbox.
+	docZip := filepath.Join(buildDir, ".intermediates", "bar-doc", "android_common", "bar-doc-docs.zip")
+	if !strings.Contains(doclava.RuleParams.Command, " -o "+docZip+" ") {
+		t.Errorf("expected command to write %q in place, got %q", docZip, doclava.RuleParams.Command)
+	}
+}
+
 func TestJarGenrules(t *testing.T) {
 	ctx := testJava(t, `
 		java_library {
@@ -28,46 +28,154 @@ import (
 var kotlinc = pctx.AndroidGomaStaticRule("kotlinc",
 	blueprint.RuleParams{
 		Command: `rm -rf "$classesDir" "$srcJarDir" "$kotlinBuildFile" && mkdir -p "$classesDir" "$srcJarDir" && ` +
-			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" -f "*.kt" $srcJars && ` +
+			`${config.GenKotlinBuildFileCmd} $classpath $classesDir $out.rsp $srcJarDir/list > $kotlinBuildFile &&` +
+			`${config.KotlincCmd} ${config.JavacHeapFlags} $kotlincFlags $kotlincPluginFlags ` +
+			`-jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile && ` +
+			`${config.SoongZipCmd} -jar -o $out -C $classesDir -D $classesDir && ` +
+			`rm -rf "$srcJarDir"`,
+		CommandDeps: []string{
+			"${config.KotlincCmd}",
+			"${config.KotlinCompilerJar}",
+			"${config.GenKotlinBuildFileCmd}",
+			"${config.SoongZipCmd}",
+			"${config.ZipSyncCmd}",
+		},
+		Rspfile:        "$out.rsp",
+		RspfileContent: `$in`,
+	},
+	"kotlincFlags", "kotlincPluginFlags", "classpath", "srcJars", "srcJarDir", "classesDir", "kotlinJvmTarget",
+	"kotlinBuildFile")
+
+// kotlincAbi is kotlinc that also writes a jar of the ABI of the compiled classes with the jvm-abi-gen plugin.  The
+// ABI jar is only replaced if its contents change, so modules that only depend on the ABI are not recompiled when
+// the implementation of the module changes.
+var kotlincAbi = pctx.AndroidGomaStaticRule("kotlincAbi",
+	blueprint.RuleParams{
+		Command: `rm -rf "$classesDir" "$abiClassesDir" "$srcJarDir" "$kotlinBuildFile" && ` +
+			`mkdir -p "$classesDir" "$abiClassesDir" "$srcJarDir" && ` +
+			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" -f "*.kt" $srcJars && ` +
 			`${config.GenKotlinBuildFileCmd} $classpath $classesDir $out.rsp $srcJarDir/list > $kotlinBuildFile &&` +
-			`${config.KotlincCmd} ${config.JavacHeapFlags} $kotlincFlags ` +
+			`${config.KotlincCmd} ${config.JavacHeapFlags} $kotlincFlags $kotlincPluginFlags ` +
+			`-Xplugin=${config.KotlinAbiGenPluginJar} ` +
+			`-P plugin:org.jetbrains.kotlin.jvm.abi:outputDir=$abiClassesDir ` +
 			`-jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile && ` +
 			`${config.SoongZipCmd} -jar -o $out -C $classesDir -D $classesDir && ` +
+			`${config.SoongZipCmd} -jar -o $abiJar.tmp -C $abiClassesDir -D $abiClassesDir && ` +
+			`(if cmp -s $abiJar.tmp $abiJar ; then rm $abiJar.tmp ; else mv $abiJar.tmp $abiJar ; fi ) && ` +
 			`rm -rf "$srcJarDir"`,
 		CommandDeps: []string{
 			"${config.KotlincCmd}",
 			"${config.KotlinCompilerJar}",
+			"${config.KotlinAbiGenPluginJar}",
 			"${config.GenKotlinBuildFileCmd}",
 			"${config.SoongZipCmd}",
 			"${config.ZipSyncCmd}",
 		},
 		Rspfile:        "$out.rsp",
 		RspfileContent: `$in`,
+		Restat:         true,
 	},
-	"kotlincFlags", "classpath", "srcJars", "srcJarDir", "classesDir", "kotlinJvmTarget", "kotlinBuildFile")
+	"kotlincFlags", "kotlincPluginFlags", "classpath", "srcJars", "srcJarDir", "classesDir", "abiClassesDir",
+	"abiJar", "kotlinJvmTarget", "kotlinBuildFile")
 
 // kotlinCompile takes .java and .kt sources and srcJars, and compiles the .kt sources into a classes jar in outputFile.
-func kotlinCompile(ctx android.ModuleContext, outputFile android.WritablePath,
+// If abiJar is not nil, it also writes a jar of the ABI of the classes to abiJar.
+func kotlinCompile(ctx android.ModuleContext, outputFile, abiJar android.WritablePath,
 	srcFiles, srcJars android.Paths,
 	flags javaBuilderFlags) {
 
 	var deps android.Paths
 	deps = append(deps, flags.kotlincClasspath...)
 	deps = append(deps, srcJars...)
+	deps = append(deps, kotlinPluginJars(flags.kotlinPlugins)...)
+
+	args := map[string]string{
+		"classpath":          flags.kotlincClasspath.FormJavaClassPath("-classpath"),
+		"kotlincFlags":       flags.kotlincFlags,
+		"kotlincPluginFlags": kotlinPluginFlags(flags.kotlinPlugins, ""),
+		"srcJars":            strings.Join(srcJars.Strings(), " "),
+		"classesDir":         android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
+		"srcJarDir":          android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
+		"kotlinBuildFile":    android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
+		// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
+		"kotlinJvmTarget": "1.8",
+	}
+
+	rule := kotlinc
+	var implicitOutputs android.WritablePaths
+	if abiJar != nil {
+		rule = kotlincAbi
+		implicitOutputs = append(implicitOutputs, abiJar)
+		args["abiClassesDir"] = android.PathForModuleOut(ctx, "kotlinc", "abi-classes").String()
+		args["abiJar"] = abiJar.String()
+	}
 
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        kotlinc,
-		Description: "kotlinc",
+		Rule:            rule,
+		Description:     "kotlinc",
+		Output:          outputFile,
+		ImplicitOutputs: implicitOutputs,
+		Inputs:          srcFiles,
+		Implicits:       deps,
+		Args:            args,
+	})
+}
+
+var kotlinSymbolProcessing = pctx.AndroidGomaStaticRule("kotlinSymbolProcessing",
+	blueprint.RuleParams{
+		Command: `rm -rf "$classesDir" "$srcJarDir" "$kotlinBuildFile" "$genDir" && ` +
+			`mkdir -p "$classesDir" "$srcJarDir" "$genDir" && ` +
+			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" -f "*.kt" $srcJars && ` +
+			`${config.GenKotlinBuildFileCmd} $classpath $classesDir $out.rsp $srcJarDir/list > $kotlinBuildFile &&` +
+			`${config.KotlincCmd} ${config.KotlincSuppressJDK9Warnings} ${config.JavacHeapFlags} $kotlincFlags ` +
+			`$kotlincPluginFlags ` +
+			`-jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile && ` +
+			`${config.SoongZipCmd} -jar -o $out -C $genDir -D $genDir && ` +
+			`rm -rf "$classesDir" "$srcJarDir"`,
+		CommandDeps: []string{
+			"${config.KotlincCmd}",
+			"${config.KotlinCompilerJar}",
+			"${config.GenKotlinBuildFileCmd}",
+			"${config.SoongZipCmd}",
+			"${config.ZipSyncCmd}",
+		},
+		Rspfile:        "$out.rsp",
+		RspfileContent: `$in`,
+	},
+	"kotlincFlags", "kotlincPluginFlags", "classpath", "srcJars", "srcJarDir", "classesDir", "genDir",
+	"kotlinJvmTarget", "kotlinBuildFile")
+
+// kotlinProcessSymbols runs the kotlin_plugin symbol processors over .kt and .java sources and srcjars, producing a
+// srcjar of generated code in outputFile.  The srcjar should be added as an additional input to kotlinc and javac
+// rules.  The classes that kotlinc compiles while running the processors are written to a scratch directory and
+// discarded.
+func kotlinProcessSymbols(ctx android.ModuleContext, outputFile android.WritablePath,
+	srcFiles, srcJars android.Paths, processors []kotlinPlugin,
+	flags javaBuilderFlags) {
+
+	var deps android.Paths
+	deps = append(deps, flags.kotlincClasspath...)
+	deps = append(deps, srcJars...)
+	deps = append(deps, kotlinPluginJars(processors)...)
+
+	genDir := android.PathForModuleOut(ctx, "kotlin-symbols", "gen")
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        kotlinSymbolProcessing,
+		Description: "kotlin symbol processing",
 		Output:      outputFile,
 		Inputs:      srcFiles,
 		Implicits:   deps,
 		Args: map[string]string{
-			"classpath":       flags.kotlincClasspath.FormJavaClassPath("-classpath"),
-			"kotlincFlags":    flags.kotlincFlags,
-			"srcJars":         strings.Join(srcJars.Strings(), " "),
-			"classesDir":      android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
-			"srcJarDir":       android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
-			"kotlinBuildFile": android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
+			"classpath":          flags.kotlincClasspath.FormJavaClassPath("-classpath"),
+			"kotlincFlags":       flags.kotlincFlags,
+			"kotlincPluginFlags": kotlinPluginFlags(processors, genDir.String()),
+			"srcJars":            strings.Join(srcJars.Strings(), " "),
+			"srcJarDir":          android.PathForModuleOut(ctx, "kotlin-symbols", "srcJars").String(),
+			"classesDir":         android.PathForModuleOut(ctx, "kotlin-symbols", "classes").String(),
+			"kotlinBuildFile":    android.PathForModuleOut(ctx, "kotlin-symbols", "build.xml").String(),
+			"genDir":             genDir.String(),
 			// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
 			"kotlinJvmTarget": "1.8",
 		},
@@ -176,3 +284,34 @@ func kaptEncodeFlags(options [][2]string) string {
 
 	return base64.StdEncoding.EncodeToString(append(header.Bytes(), buf.Bytes()...))
 }
+
+// kotlinPlugin is a kotlin_plugin dependency of a module that is passed to kotlinc.
+type kotlinPlugin struct {
+	id               string
+	jars             android.Paths
+	options          []string
+	generatesSources bool
+}
+
+// kotlinPluginFlags returns the kotlinc flags that load the plugins and pass their options, with $(genDir) replaced
+// by genDir.
+func kotlinPluginFlags(plugins []kotlinPlugin, genDir string) string {
+	var flags []string
+	for _, plugin := range plugins {
+		for _, jar := range plugin.jars {
+			flags = append(flags, "-Xplugin="+jar.String())
+		}
+		for _, option := range plugin.options {
+			flags = append(flags, "-P plugin:"+plugin.id+":"+strings.Replace(option, "$(genDir)", genDir, -1))
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+func kotlinPluginJars(plugins []kotlinPlugin) android.Paths {
+	var jars android.Paths
+	for _, plugin := range plugins {
+		jars = append(jars, plugin.jars...)
+	}
+	return jars
+}
@@ -154,6 +154,140 @@ func TestKapt(t *testing.T) {
 	}
 }
 
+func TestKotlinPlugins(t *testing.T) {
+	ctx := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+			kotlin_plugins: ["compose", "symbols"],
+		}
+
+		kotlin_plugin {
+			name: "compose",
+			srcs: ["b.java"],
+			plugin_id: "androidx.compose",
+			plugin_options: ["sourceInformation=true"],
+		}
+
+		kotlin_plugin {
+			name: "symbols",
+			srcs: ["c.java"],
+			plugin_id: "com.symbols",
+			plugin_options: ["outputDir=$(genDir)"],
+			generates_sources: true,
+		}
+		`)
+
+	buildOS := android.BuildOs.String()
+
+	symbols := ctx.ModuleForTests("foo", "android_common").Rule("kotlinSymbolProcessing")
+	kotlinc := ctx.ModuleForTests("foo", "android_common").Rule("kotlinc")
+	javac := ctx.ModuleForTests("foo", "android_common").Rule("javac")
+
+	compose := ctx.ModuleForTests("compose", buildOS+"_common").Rule("javac").Output.String()
+	processor := ctx.ModuleForTests("symbols", buildOS+"_common").Rule("javac").Output.String()
+
+	// Test that the compiler plugin is only passed to kotlinc
+	expectedKotlincPluginFlags := "-Xplugin=" + compose + " -P plugin:androidx.compose:sourceInformation=true"
+	if kotlinc.Args["kotlincPluginFlags"] != expectedKotlincPluginFlags {
+		t.Errorf("expected kotlincPluginFlags %q, got %q", expectedKotlincPluginFlags, kotlinc.Args["kotlincPluginFlags"])
+	}
+	if !inList(compose, kotlinc.Implicits.Strings()) {
+		t.Errorf("expected %q in kotlinc implicits %v", compose, kotlinc.Implicits.Strings())
+	}
+
+	// Test that the symbol processor runs in its own pass with $(genDir) expanded
+	expectedSymbolsPluginFlags := "-Xplugin=" + processor + " -P plugin:com.symbols:outputDir=" + symbols.Args["genDir"]
+	if symbols.Args["kotlincPluginFlags"] != expectedSymbolsPluginFlags {
+		t.Errorf("expected symbol processing kotlincPluginFlags %q, got %q", expectedSymbolsPluginFlags,
+			symbols.Args["kotlincPluginFlags"])
+	}
+	if !strings.Contains(symbols.Args["classesDir"], "/kotlin-symbols/") {
+		t.Errorf("expected symbol processing classesDir in kotlin-symbols, got %q", symbols.Args["classesDir"])
+	}
+	if len(symbols.Inputs) != 2 || symbols.Inputs[0].String() != "a.java" || symbols.Inputs[1].String() != "b.kt" {
+		t.Errorf(`foo symbol processing inputs %v != ["a.java", "b.kt"]`, symbols.Inputs)
+	}
+
+	// Test that the generated sources are compiled by kotlinc and javac
+	if kotlinc.Args["srcJars"] != symbols.Output.String() {
+		t.Errorf("expected %q in kotlinc srcjars %v", symbols.Output.String(), kotlinc.Args["srcJars"])
+	}
+	if javac.Args["srcJars"] != symbols.Output.String() {
+		t.Errorf("expected %q in javac srcjars %v", symbols.Output.String(), javac.Args["srcJars"])
+	}
+}
+
+func TestKotlinVersions(t *testing.T) {
+	ctx := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["b.kt"],
+			kotlin_lang_version: "1.3",
+			kotlin_api_version: "1.2",
+		}
+		`)
+
+	kotlincFlags := ctx.ModuleForTests("foo", "android_common").Module().VariablesForTests()["kotlincFlags"]
+	if !strings.Contains(kotlincFlags, "-language-version 1.3 -api-version 1.2") {
+		t.Errorf("expected kotlin versions in kotlincFlags, got %q", kotlincFlags)
+	}
+
+	testJavaError(t, `kotlin_api_version: version "1.10" must not be newer than kotlin_lang_version "1.3"`, `
+		java_library {
+			name: "foo",
+			srcs: ["b.kt"],
+			kotlin_lang_version: "1.3",
+			kotlin_api_version: "1.10",
+		}
+		`)
+}
+
+func TestKotlinIncremental(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["b.kt"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["a.java"],
+			libs: ["foo"],
+		}
+		`
+
+	config := testConfig(map[string]string{"KOTLINC_INCREMENTAL": "true"})
+	ctx := testContext(config, bp, nil)
+	run(t, ctx, config)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	fooKotlinc := foo.Rule("kotlincAbi")
+	fooHeaderJar := foo.Output("turbine-combined/foo.jar")
+	fooJar := foo.Output("combined/foo.jar")
+
+	if len(fooKotlinc.ImplicitOutputs) != 1 || fooKotlinc.ImplicitOutputs[0].Rel() != "kotlin-abi/foo.jar" {
+		t.Fatalf(`foo kotlinc implicit outputs %v != ["kotlin-abi/foo.jar"]`, fooKotlinc.ImplicitOutputs)
+	}
+	fooAbiJar := fooKotlinc.ImplicitOutputs[0].String()
+
+	// Test that the header jar contains the ABI of the kotlin classes and the jar contains the classes
+	if !inList(fooAbiJar, fooHeaderJar.Inputs.Strings()) {
+		t.Errorf("foo header jar inputs %v does not contain %q", fooHeaderJar.Inputs.Strings(), fooAbiJar)
+	}
+	if inList(fooKotlinc.Output.String(), fooHeaderJar.Inputs.Strings()) {
+		t.Errorf("foo header jar inputs %v contains %q", fooHeaderJar.Inputs.Strings(), fooKotlinc.Output.String())
+	}
+	if !inList(fooKotlinc.Output.String(), fooJar.Inputs.Strings()) {
+		t.Errorf("foo jar inputs %v does not contain %q", fooJar.Inputs.Strings(), fooKotlinc.Output.String())
+	}
+
+	barJavac := ctx.ModuleForTests("bar", "android_common").Rule("javac")
+	if !strings.Contains(barJavac.Args["classpath"], fooHeaderJar.Output.String()) {
+		t.Errorf("bar classpath %v does not contain %q", barJavac.Args["classpath"], fooHeaderJar.Output.String())
+	}
+}
+
 func TestKaptEncodeFlags(t *testing.T) {
 	// Compares the kaptEncodeFlags against the results of the example implementation at
 	// https://kotlinlang.org/docs/reference/kapt.html#apjavac-options-encoding
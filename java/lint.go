@@ -0,0 +1,287 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+func init() {
+	android.RegisterSingletonType("lint", lintSingletonFactory)
+}
+
+var (
+	lintProjectXml = pctx.AndroidStaticRule("lintProjectXml",
+		blueprint.RuleParams{
+			Command: `${config.LintProjectXmlCmd} --name ${name} --srcs $out.rsp ${args} ` +
+				`--project_out $out --config_out ${configXml}`,
+			CommandDeps:    []string{"${config.LintProjectXmlCmd}"},
+			Rspfile:        "$out.rsp",
+			RspfileContent: "$in",
+		},
+		"name", "args", "configXml")
+
+	lint = pctx.AndroidStaticRule("lint",
+		blueprint.RuleParams{
+			Command: `rm -rf ${homeDir} && mkdir -p ${homeDir} && ` +
+				`ANDROID_SDK_HOME=${homeDir} ${config.LintCmd} --quiet --project ${project} --config ${configXml} ` +
+				`${args} --html $out --xml ${xml} --text ${text}`,
+			CommandDeps: []string{"${config.LintCmd}"},
+		},
+		"homeDir", "project", "configXml", "args", "xml", "text")
+
+	lintCheck = pctx.AndroidStaticRule("lintCheck",
+		blueprint.RuleParams{
+			Command: `if grep -q 'severity="Fatal"' $in; then ` +
+				`echo 'error: Android Lint found fatal issues in ${name}:' && cat ${text} && exit 1; ` +
+				`fi && touch $out`,
+		},
+		"name", "text")
+)
+
+type LintProperties struct {
+	// Controls for running Android Lint on the module.
+	Lint struct {
+		// If false, don't run Android Lint on the module.  Defaults to true for java_library,
+		// android_library and android_app modules built for the device.  The reports are built
+		// by m lint-check.
+		Enabled *bool
+
+		// True if the module type runs lint by default.
+		EnabledByDefault bool `blueprint:"mutated"`
+
+		// Path to a baseline file of known issues, which are not reported.
+		Baseline *string `android:"path"`
+
+		// Host java_library or java_plugin modules that provide extra lint checks.
+		Extra_check_modules []string
+
+		// Lint checks that are disabled.
+		Disabled_checks []string
+
+		// Lint checks whose issues are fatal.  The module fails to build if lint finds any of them.
+		Fatal_checks []string
+	}
+}
+
+type linter struct {
+	name      string
+	manifest  android.Path
+	srcs      android.Paths
+	resources android.Paths
+	classes   android.Path
+	classpath android.Paths
+	library   bool
+
+	properties LintProperties
+
+	outputs lintOutputs
+}
+
+type lintOutputs struct {
+	html  android.Path
+	xml   android.Path
+	text  android.Path
+	check android.Path
+}
+
+func (l *linter) enabled(ctx android.BaseContext) bool {
+	return ctx.Device() && BoolDefault(l.properties.Lint.Enabled, l.properties.Lint.EnabledByDefault)
+}
+
+func (l *linter) deps(ctx android.BottomUpMutatorContext) {
+	if !l.enabled(ctx) {
+		return
+	}
+
+	ctx.AddFarVariationDependencies([]blueprint.Variation{
+		{Mutator: "arch", Variation: ctx.Config().BuildOsCommonVariant},
+	}, extraLintCheckTag, l.properties.Lint.Extra_check_modules...)
+}
+
+func (l *linter) lintOutputs() *lintOutputs {
+	return &l.outputs
+}
+
+// lint writes the lint project and configuration of the module and runs Android Lint on it, which
+// produces HTML, XML and text reports.  If the module has fatal checks, it also creates a rule that
+// fails if lint reported any fatal issues.
+func (l *linter) lint(ctx android.ModuleContext) {
+	if !l.enabled(ctx) {
+		return
+	}
+
+	var extraLintCheckJars android.Paths
+	ctx.VisitDirectDepsWithTag(extraLintCheckTag, func(module android.Module) {
+		if dep, ok := module.(Dependency); ok {
+			extraLintCheckJars = append(extraLintCheckJars, dep.ImplementationAndResourcesJars()...)
+		} else {
+			ctx.PropertyErrorf("lint.extra_check_modules",
+				"%s is not a java module", ctx.OtherModuleName(module))
+		}
+	})
+
+	var args []string
+	var deps android.Paths
+	if l.library {
+		args = append(args, "--library")
+	}
+	if l.manifest != nil {
+		args = append(args, "--manifest "+l.manifest.String())
+		deps = append(deps, l.manifest)
+	}
+	for _, resource := range l.resources {
+		args = append(args, "--resource "+resource.String())
+	}
+	deps = append(deps, l.resources...)
+	if l.classes != nil {
+		args = append(args, "--classes "+l.classes.String())
+		deps = append(deps, l.classes)
+	}
+	for _, jar := range l.classpath {
+		args = append(args, "--classpath "+jar.String())
+	}
+	deps = append(deps, l.classpath...)
+	for _, jar := range extraLintCheckJars {
+		args = append(args, "--extra_checks_jar "+jar.String())
+	}
+	deps = append(deps, extraLintCheckJars...)
+	for _, check := range l.properties.Lint.Disabled_checks {
+		args = append(args, "--disable_check "+check)
+	}
+	for _, check := range l.properties.Lint.Fatal_checks {
+		args = append(args, "--fatal_check "+check)
+	}
+
+	projectXml := android.PathForModuleOut(ctx, "lint", "project.xml")
+	configXml := android.PathForModuleOut(ctx, "lint", "lint.xml")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:           lintProjectXml,
+		Description:    "lint project",
+		Output:         projectXml,
+		ImplicitOutput: configXml,
+		Inputs:         l.srcs,
+		Args: map[string]string{
+			"name":      l.name,
+			"args":      strings.Join(args, " "),
+			"configXml": configXml.String(),
+		},
+	})
+
+	var lintArgs []string
+	if l.properties.Lint.Baseline != nil {
+		baseline := android.PathForModuleSrc(ctx, *l.properties.Lint.Baseline)
+		lintArgs = append(lintArgs, "--baseline "+baseline.String())
+		deps = append(deps, baseline)
+	}
+
+	html := android.PathForModuleOut(ctx, "lint", "lint-report.html")
+	xml := android.PathForModuleOut(ctx, "lint", "lint-report.xml")
+	text := android.PathForModuleOut(ctx, "lint", "lint-report.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:            lint,
+		Description:     "lint",
+		Output:          html,
+		ImplicitOutputs: android.WritablePaths{xml, text},
+		Implicits:       append(android.Paths{projectXml, configXml}, append(deps, l.srcs...)...),
+		Args: map[string]string{
+			"homeDir":   android.PathForModuleOut(ctx, "lint", "home").String(),
+			"project":   projectXml.String(),
+			"configXml": configXml.String(),
+			"args":      strings.Join(lintArgs, " "),
+			"xml":       xml.String(),
+			"text":      text.String(),
+		},
+	})
+	l.outputs = lintOutputs{
+		html: html,
+		xml:  xml,
+		text: text,
+	}
+
+	if len(l.properties.Lint.Fatal_checks) > 0 {
+		check := android.PathForModuleOut(ctx, "lint", "lint-check.stamp")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        lintCheck,
+			Description: "lint check",
+			Output:      check,
+			Input:       xml,
+			Implicit:    text,
+			Args: map[string]string{
+				"name": l.name,
+				"text": text.String(),
+			},
+		})
+		l.outputs.check = check
+	}
+}
+
+type lintOutputsIntf interface {
+	lintOutputs() *lintOutputs
+}
+
+var _ lintOutputsIntf = (*linter)(nil)
+
+func lintSingletonFactory() android.Singleton {
+	return &lintSingleton{android.NewSingletonOutput("lint-check", "SOONG_LINT_REPORTS")}
+}
+
+// lintSingleton packages the lint reports of all modules into lint-reports.zip, with one directory
+// per module, and creates a lint-check phony target that builds it.
+type lintSingleton struct {
+	android.SingletonOutput
+}
+
+func (l *lintSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	outputs := make(map[string]*lintOutputs)
+
+	ctx.VisitAllModules(func(module android.Module) {
+		if m, ok := module.(lintOutputsIntf); ok && module.Enabled() && m.lintOutputs().html != nil {
+			outputs[ctx.ModuleName(module)] = m.lintOutputs()
+		}
+	})
+
+	if len(outputs) == 0 {
+		return
+	}
+
+	var names []string
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reportsZip := android.PathForOutput(ctx, "lint-reports.zip")
+
+	rule := android.NewRuleBuilder()
+	cmd := rule.Command().
+		Tool(ctx.Config().HostToolPath(ctx, "soong_zip")).
+		Flag("-j").
+		FlagWithOutput("-o ", reportsZip)
+	for _, name := range names {
+		cmd.FlagWithArg("-P ", name).
+			FlagWithInput("-f ", outputs[name].html).
+			FlagWithInput("-f ", outputs[name].xml).
+			FlagWithInput("-f ", outputs[name].text)
+	}
+	rule.Build(pctx, ctx, "lint_reports", "lint reports")
+
+	l.SetOutput(ctx, reportsZip)
+}
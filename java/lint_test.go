@@ -0,0 +1,121 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestLint(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+			lint: {
+				baseline: "lint-baseline.xml",
+				extra_check_modules: ["checks"],
+				disabled_checks: ["NewApi"],
+				fatal_checks: ["MissingPermission"],
+			},
+		}
+
+		android_app {
+			name: "app",
+			srcs: ["c.java"],
+			sdk_version: "current",
+		}
+
+		android_app {
+			name: "nolint",
+			srcs: ["c.java"],
+			sdk_version: "current",
+			lint: {
+				enabled: false,
+			},
+		}
+
+		java_library_host {
+			name: "checks",
+			srcs: ["b.java"],
+		}
+	`
+
+	config := testConfig(nil)
+	ctx := testContext(config, bp, map[string][]byte{
+		"lint-baseline.xml": nil,
+	})
+	ctx.RegisterSingletonType("lint", android.SingletonFactoryAdaptor(lintSingletonFactory))
+	run(t, ctx, config)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+
+	projectXml := foo.Output("lint/project.xml")
+	if g, w := projectXml.Inputs.Strings(), []string{"a.java", "b.kt"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want lint srcs %q, got %q", w, g)
+	}
+	checksJar := ctx.ModuleForTests("checks", config.BuildOsCommonVariant).Module().(*Library).ImplementationAndResourcesJars()
+	for _, w := range []string{"--library", "--extra_checks_jar " + checksJar[0].String(), "--disable_check NewApi",
+		"--fatal_check MissingPermission"} {
+		if !strings.Contains(projectXml.Args["args"], w) {
+			t.Errorf("want lint project args to contain %q, got %q", w, projectXml.Args["args"])
+		}
+	}
+
+	lintReport := foo.Output("lint/lint-report.html")
+	if w := "--baseline lint-baseline.xml"; lintReport.Args["args"] != w {
+		t.Errorf("want lint args %q, got %q", w, lintReport.Args["args"])
+	}
+
+	check := foo.Output("lint/lint-check.stamp")
+	if g, w := check.Input.String(), filepath.Join(buildDir, ".intermediates", "foo", "android_common", "lint",
+		"lint-report.xml"); g != w {
+		t.Errorf("want lint check input %q, got %q", w, g)
+	}
+
+	app := ctx.ModuleForTests("app", "android_common")
+	appProjectXml := app.Output("lint/project.xml")
+	if strings.Contains(appProjectXml.Args["args"], "--library") {
+		t.Errorf("want lint project args of app not to contain --library, got %q", appProjectXml.Args["args"])
+	}
+	if !strings.Contains(appProjectXml.Args["args"], "--manifest ") {
+		t.Errorf("want lint project args of app to contain --manifest, got %q", appProjectXml.Args["args"])
+	}
+	if app.MaybeOutput("lint/lint-check.stamp").Rule != nil {
+		t.Errorf("want no lint check for app without fatal checks")
+	}
+
+	if ctx.ModuleForTests("nolint", "android_common").MaybeOutput("lint/lint-report.html").Rule != nil {
+		t.Errorf("want no lint for module with lint disabled")
+	}
+
+	reports := ctx.SingletonForTests("lint").Output("lint-reports.zip")
+	for _, w := range []string{
+		"-P app ",
+		"-P foo ",
+		"-f " + lintReport.Output.String(),
+	} {
+		if !strings.Contains(reports.RuleParams.Command, w) {
+			t.Errorf("want lint reports command to contain %q, got %q", w, reports.RuleParams.Command)
+		}
+	}
+	if strings.Contains(reports.RuleParams.Command, "-P nolint ") {
+		t.Errorf("want lint reports command not to contain nolint, got %q", reports.RuleParams.Command)
+	}
+}
@@ -14,10 +14,15 @@
 
 package java
 
-import "android/soong/android"
+import (
+	"strings"
+
+	"android/soong/android"
+)
 
 func init() {
 	android.RegisterModuleType("java_plugin", PluginFactory)
+	android.RegisterModuleType("kotlin_plugin", KotlinPluginFactory)
 }
 
 // A java_plugin module describes a host java library that will be used by javac as an annotation processor.
@@ -48,3 +53,53 @@ type PluginProperties struct {
 	// parallelism and cause more recompilation for modules that depend on modules that use this plugin.
 	Generates_api *bool
 }
+
+// A kotlin_plugin module describes a host java library that will be used by kotlinc as a compiler plugin, for
+// example the Compose compiler or the serialization plugin, or as a symbol processor that generates sources.
+func KotlinPluginFactory() android.Module {
+	module := &KotlinPlugin{}
+
+	module.AddProperties(
+		&module.Module.properties,
+		&module.Module.protoProperties,
+		&module.kotlinPluginProperties)
+
+	InitJavaModule(module, android.HostSupported)
+	return module
+}
+
+type KotlinPlugin struct {
+	Library
+
+	kotlinPluginProperties KotlinPluginProperties
+}
+
+type KotlinPluginProperties struct {
+	// The id of the compiler plugin, used to pass options to it with -P plugin:<id>:<option>.
+	Plugin_id *string
+
+	// Options to pass to the compiler plugin, in the form <key>=<value>.  $(genDir) is replaced with the directory
+	// that a symbol processor writes generated sources to.
+	Plugin_options []string
+
+	// If true, the plugin is a symbol processor that generates .java or .kt sources into $(genDir) instead of
+	// changing the compiled code.  Symbol processors run in a separate kotlinc pass before the sources of the
+	// module are compiled, and the generated sources are compiled with the module.
+	Generates_sources *bool
+}
+
+func (p *KotlinPlugin) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	if len(p.kotlinPluginProperties.Plugin_options) > 0 && p.kotlinPluginProperties.Plugin_id == nil {
+		ctx.PropertyErrorf("plugin_options", "plugin_id must be set to pass options to the plugin")
+	}
+	for _, option := range p.kotlinPluginProperties.Plugin_options {
+		if !strings.Contains(option, "=") {
+			ctx.PropertyErrorf("plugin_options", "option %q must be in the form <key>=<value>", option)
+		}
+	}
+	if Bool(p.kotlinPluginProperties.Generates_sources) && p.kotlinPluginProperties.Plugin_id == nil {
+		ctx.PropertyErrorf("plugin_id", "must be set for a plugin that generates sources")
+	}
+
+	p.Library.GenerateAndroidBuildActions(ctx)
+}
@@ -251,6 +251,25 @@ func (p *Module) HostToolPath() android.OptionalPath {
 	return android.OptionalPathForPath(p.installer.(*binaryDecorator).path)
 }
 
+// TestSuites returns the test suites of a binary or test module, for module-info.json.
+func (p *Module) TestSuites() []string {
+	switch installer := p.installer.(type) {
+	case *binaryDecorator:
+		return installer.binaryProperties.Test_suites
+	case *testDecorator:
+		return installer.binaryDecorator.binaryProperties.Test_suites
+	}
+	return nil
+}
+
+// TestConfig returns the test config of a test module, for module-info.json.
+func (p *Module) TestConfig() android.Path {
+	if test, ok := p.installer.(*testDecorator); ok {
+		return test.testConfig
+	}
+	return nil
+}
+
 func (p *Module) isEmbeddedLauncherEnabled(actual_version string) bool {
 	switch actual_version {
 	case pyVersion2:
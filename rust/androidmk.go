@@ -0,0 +1,108 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"android/soong/android"
+)
+
+type subAndroidMkProvider interface {
+	AndroidMk(*Module, *android.AndroidMkEntries)
+}
+
+func (mod *Module) subAndroidMk(entries *android.AndroidMkEntries, obj interface{}) {
+	if mod.subAndroidMkOnce == nil {
+		mod.subAndroidMkOnce = make(map[subAndroidMkProvider]bool)
+	}
+	if androidmk, ok := obj.(subAndroidMkProvider); ok {
+		if !mod.subAndroidMkOnce[androidmk] {
+			mod.subAndroidMkOnce[androidmk] = true
+			androidmk.AndroidMk(mod, entries)
+		}
+	}
+}
+
+func (mod *Module) AndroidMkEntries() android.AndroidMkEntries {
+	// AndroidMkEntries is called by both the androidmk and the module_info_json singletons, so the
+	// sub-providers that ran for a previous call must run again.
+	mod.subAndroidMkOnce = nil
+
+	entries := android.AndroidMkEntries{
+		OutputFile: mod.outputFile,
+		Include:    "$(BUILD_SYSTEM)/soong_rust_prebuilt.mk",
+		ExtraEntries: []android.AndroidMkExtraEntriesFunc{
+			func(entries *android.AndroidMkEntries) {
+				entries.AddStrings("LOCAL_RLIB_LIBRARIES", mod.Properties.AndroidMkRlibs...)
+				entries.AddStrings("LOCAL_DYLIB_LIBRARIES", mod.Properties.AndroidMkDylibs...)
+				entries.AddStrings("LOCAL_PROC_MACRO_LIBRARIES", mod.Properties.AndroidMkProcMacroLibs...)
+				entries.AddStrings("LOCAL_SHARED_LIBRARIES", mod.Properties.AndroidMkSharedLibs...)
+				entries.AddStrings("LOCAL_STATIC_LIBRARIES", mod.Properties.AndroidMkStaticLibs...)
+				if mod.outputFile.Valid() {
+					entries.SetString("LOCAL_INSTALLED_MODULE_STEM", mod.outputFile.Path().Base())
+				}
+			},
+		},
+	}
+
+	if mod.compiler != nil {
+		mod.subAndroidMk(&entries, mod.compiler)
+	}
+
+	return entries
+}
+
+func (compiler *baseCompiler) AndroidMk(mod *Module, entries *android.AndroidMkEntries) {
+	entries.ExtraEntries = append(entries.ExtraEntries, func(entries *android.AndroidMkEntries) {
+		if relPath := compiler.relativeInstallPath(); relPath != "" {
+			entries.SetString("LOCAL_MODULE_RELATIVE_PATH", relPath)
+		}
+	})
+}
+
+func (binary *binaryDecorator) AndroidMk(mod *Module, entries *android.AndroidMkEntries) {
+	mod.subAndroidMk(entries, binary.baseCompiler)
+
+	entries.Class = "EXECUTABLES"
+}
+
+func (test *testDecorator) AndroidMk(mod *Module, entries *android.AndroidMkEntries) {
+	mod.subAndroidMk(entries, test.binaryDecorator)
+
+	entries.Class = "NATIVE_TESTS"
+	entries.ExtraEntries = append(entries.ExtraEntries, func(entries *android.AndroidMkEntries) {
+		entries.AddStrings("LOCAL_COMPATIBILITY_SUITE", test.Properties.Test_suites...)
+		if test.testConfig.Valid() {
+			entries.SetString("LOCAL_FULL_TEST_CONFIG", test.testConfig.String())
+		}
+	})
+}
+
+func (library *libraryDecorator) AndroidMk(mod *Module, entries *android.AndroidMkEntries) {
+	mod.subAndroidMk(entries, library.baseCompiler)
+
+	if library.rlib() {
+		entries.Class = "RLIB_LIBRARIES"
+	} else if library.dylib() {
+		entries.Class = "DYLIB_LIBRARIES"
+	} else if library.static() {
+		entries.Class = "STATIC_LIBRARIES"
+	}
+}
+
+func (procMacro *procMacroDecorator) AndroidMk(mod *Module, entries *android.AndroidMkEntries) {
+	mod.subAndroidMk(entries, procMacro.baseCompiler)
+
+	entries.Class = "PROC_MACRO_LIBRARIES"
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	arm64RustFlags = []string{}
+)
+
+func init() {
+	registerToolchainFactory(android.Android, android.Arm64, arm64ToolchainFactory)
+
+	pctx.StaticVariable("Arm64ToolchainRustFlags", strings.Join(arm64RustFlags, " "))
+	pctx.StaticVariable("Arm64ToolchainLinkFlags", "${ccConfig.Arm64ClangLldflags}")
+}
+
+type toolchainArm64 struct {
+	toolchain64Bit
+}
+
+func (toolchainArm64) RustTriple() string {
+	return "aarch64-linux-android"
+}
+
+func (toolchainArm64) ClangTriple() string {
+	return "aarch64-linux-android"
+}
+
+func (toolchainArm64) ToolchainRustFlags() string {
+	return "${config.DeviceGlobalRustFlags} ${config.Arm64ToolchainRustFlags}"
+}
+
+func (toolchainArm64) ToolchainLinkFlags() string {
+	return "${config.DeviceGlobalLinkFlags} ${config.Arm64ToolchainLinkFlags}"
+}
+
+func arm64ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainArm64{}
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	armRustFlags = []string{}
+)
+
+func init() {
+	registerToolchainFactory(android.Android, android.Arm, armToolchainFactory)
+
+	pctx.StaticVariable("ArmToolchainRustFlags", strings.Join(armRustFlags, " "))
+	pctx.StaticVariable("ArmToolchainLinkFlags", "${ccConfig.ArmClangLldflags}")
+}
+
+type toolchainArm struct {
+	toolchain32Bit
+}
+
+func (toolchainArm) RustTriple() string {
+	return "armv7-linux-androideabi"
+}
+
+func (toolchainArm) ClangTriple() string {
+	return "armv7a-linux-androideabi"
+}
+
+func (toolchainArm) ToolchainRustFlags() string {
+	return "${config.DeviceGlobalRustFlags} ${config.ArmToolchainRustFlags}"
+}
+
+func (toolchainArm) ToolchainLinkFlags() string {
+	return "${config.DeviceGlobalLinkFlags} ${config.ArmToolchainLinkFlags}"
+}
+
+func armToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainArm{}
+}
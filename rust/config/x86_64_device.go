@@ -0,0 +1,56 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	x86_64RustFlags = []string{}
+)
+
+func init() {
+	registerToolchainFactory(android.Android, android.X86_64, x86_64ToolchainFactory)
+
+	pctx.StaticVariable("X86_64ToolchainRustFlags", strings.Join(x86_64RustFlags, " "))
+	pctx.StaticVariable("X86_64ToolchainLinkFlags", "${ccConfig.X86_64ClangLldflags}")
+}
+
+type toolchainX86_64 struct {
+	toolchain64Bit
+}
+
+func (toolchainX86_64) RustTriple() string {
+	return "x86_64-linux-android"
+}
+
+func (toolchainX86_64) ClangTriple() string {
+	return "x86_64-linux-android"
+}
+
+func (toolchainX86_64) ToolchainRustFlags() string {
+	return "${config.DeviceGlobalRustFlags} ${config.X86_64ToolchainRustFlags}"
+}
+
+func (toolchainX86_64) ToolchainLinkFlags() string {
+	return "${config.DeviceGlobalLinkFlags} ${config.X86_64ToolchainLinkFlags}"
+}
+
+func x86_64ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainX86_64{}
+}
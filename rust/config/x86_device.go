@@ -0,0 +1,56 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	x86RustFlags = []string{}
+)
+
+func init() {
+	registerToolchainFactory(android.Android, android.X86, x86ToolchainFactory)
+
+	pctx.StaticVariable("X86ToolchainRustFlags", strings.Join(x86RustFlags, " "))
+	pctx.StaticVariable("X86ToolchainLinkFlags", "${ccConfig.X86ClangLldflags}")
+}
+
+type toolchainX86 struct {
+	toolchain32Bit
+}
+
+func (toolchainX86) RustTriple() string {
+	return "i686-linux-android"
+}
+
+func (toolchainX86) ClangTriple() string {
+	return "i686-linux-android"
+}
+
+func (toolchainX86) ToolchainRustFlags() string {
+	return "${config.DeviceGlobalRustFlags} ${config.X86ToolchainRustFlags}"
+}
+
+func (toolchainX86) ToolchainLinkFlags() string {
+	return "${config.DeviceGlobalLinkFlags} ${config.X86ToolchainLinkFlags}"
+}
+
+func x86ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainX86{}
+}
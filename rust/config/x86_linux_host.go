@@ -0,0 +1,100 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	linuxRustFlags     = []string{}
+	linuxRustLinkFlags = []string{}
+)
+
+func init() {
+	registerToolchainFactory(android.Linux, android.X86_64, linuxX8664ToolchainFactory)
+	registerToolchainFactory(android.Linux, android.X86, linuxX86ToolchainFactory)
+
+	pctx.StaticVariable("LinuxToolchainRustFlags", strings.Join(linuxRustFlags, " "))
+	pctx.StaticVariable("LinuxToolchainLinkFlags", strings.Join(linuxRustLinkFlags, " "))
+	pctx.StaticVariable("LinuxToolchainX86LinkFlags",
+		"${ccConfig.LinuxClangLldflags} ${ccConfig.LinuxX86ClangLldflags}")
+	pctx.StaticVariable("LinuxToolchainX8664LinkFlags",
+		"${ccConfig.LinuxClangLldflags} ${ccConfig.LinuxX8664ClangLldflags}")
+}
+
+type toolchainLinux struct {
+	toolchainBase
+}
+
+func (toolchainLinux) Bionic() bool {
+	return false
+}
+
+func (toolchainLinux) ToolchainRustFlags() string {
+	return "${config.LinuxToolchainRustFlags}"
+}
+
+type toolchainLinuxX86 struct {
+	toolchainLinux
+}
+
+func (toolchainLinuxX86) RustTriple() string {
+	return "i686-unknown-linux-gnu"
+}
+
+func (toolchainLinuxX86) ClangTriple() string {
+	return "i686-linux-gnu"
+}
+
+func (toolchainLinuxX86) Is64Bit() bool {
+	return false
+}
+
+func (toolchainLinuxX86) ToolchainLinkFlags() string {
+	return "${config.HostGlobalLinkFlags} ${config.LinuxToolchainLinkFlags} " +
+		"${config.LinuxToolchainX86LinkFlags}"
+}
+
+type toolchainLinuxX8664 struct {
+	toolchainLinux
+}
+
+func (toolchainLinuxX8664) RustTriple() string {
+	return "x86_64-unknown-linux-gnu"
+}
+
+func (toolchainLinuxX8664) ClangTriple() string {
+	return "x86_64-linux-gnu"
+}
+
+func (toolchainLinuxX8664) Is64Bit() bool {
+	return true
+}
+
+func (toolchainLinuxX8664) ToolchainLinkFlags() string {
+	return "${config.HostGlobalLinkFlags} ${config.LinuxToolchainLinkFlags} " +
+		"${config.LinuxToolchainX8664LinkFlags}"
+}
+
+func linuxX8664ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainLinuxX8664{}
+}
+
+func linuxX86ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainLinuxX86{}
+}
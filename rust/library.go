@@ -0,0 +1,306 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+func init() {
+	android.RegisterModuleType("rust_library", RustLibraryFactory)
+	android.RegisterModuleType("rust_library_dylib", RustLibraryDylibFactory)
+	android.RegisterModuleType("rust_library_rlib", RustLibraryRlibFactory)
+	android.RegisterModuleType("rust_library_static", RustLibraryStaticFactory)
+	android.RegisterModuleType("rust_library_host", RustLibraryHostFactory)
+	android.RegisterModuleType("rust_library_host_dylib", RustLibraryDylibHostFactory)
+	android.RegisterModuleType("rust_library_host_rlib", RustLibraryRlibHostFactory)
+}
+
+type VariantLibraryProperties struct {
+	Enabled *bool `android:"arch_variant"`
+}
+
+type LibraryCompilerProperties struct {
+	Rlib  VariantLibraryProperties `android:"arch_variant"`
+	Dylib VariantLibraryProperties `android:"arch_variant"`
+}
+
+type LibraryMutatedProperties struct {
+	// Build an rlib variant
+	BuildRlib bool `blueprint:"mutated"`
+	// Build a dylib variant
+	BuildDylib bool `blueprint:"mutated"`
+	// Build a staticlib variant
+	BuildStatic bool `blueprint:"mutated"`
+
+	// This variant is an rlib
+	VariantIsRlib bool `blueprint:"mutated"`
+	// This variant is a dylib
+	VariantIsDylib bool `blueprint:"mutated"`
+	// This variant is a staticlib
+	VariantIsStatic bool `blueprint:"mutated"`
+}
+
+type libraryDecorator struct {
+	*baseCompiler
+
+	Properties        LibraryCompilerProperties
+	MutatedProperties LibraryMutatedProperties
+
+	// The directories that rustc searches for the dependencies of this library, which it also
+	// needs to find when it compiles the crates that depend on this library.
+	linkDirs []string
+}
+
+type libraryInterface interface {
+	rlib() bool
+	dylib() bool
+	static() bool
+
+	// Returns true if the build options for the module have selected a particular build type
+	buildRlib() bool
+	buildDylib() bool
+	buildStatic() bool
+
+	// Sets a particular variant type
+	setRlib()
+	setDylib()
+	setStatic()
+
+	exportedLinkDirs() []string
+}
+
+var _ compiler = (*libraryDecorator)(nil)
+var _ libraryInterface = (*libraryDecorator)(nil)
+
+func (library *libraryDecorator) exportedLinkDirs() []string {
+	return library.linkDirs
+}
+
+func (library *libraryDecorator) rlib() bool {
+	return library.MutatedProperties.VariantIsRlib
+}
+
+func (library *libraryDecorator) dylib() bool {
+	return library.MutatedProperties.VariantIsDylib
+}
+
+func (library *libraryDecorator) static() bool {
+	return library.MutatedProperties.VariantIsStatic
+}
+
+func (library *libraryDecorator) buildRlib() bool {
+	return library.MutatedProperties.BuildRlib && BoolDefault(library.Properties.Rlib.Enabled, true)
+}
+
+func (library *libraryDecorator) buildDylib() bool {
+	return library.MutatedProperties.BuildDylib && BoolDefault(library.Properties.Dylib.Enabled, true)
+}
+
+func (library *libraryDecorator) buildStatic() bool {
+	return library.MutatedProperties.BuildStatic
+}
+
+func (library *libraryDecorator) setRlib() {
+	library.MutatedProperties.VariantIsRlib = true
+	library.MutatedProperties.VariantIsDylib = false
+	library.MutatedProperties.VariantIsStatic = false
+}
+
+func (library *libraryDecorator) setDylib() {
+	library.MutatedProperties.VariantIsRlib = false
+	library.MutatedProperties.VariantIsDylib = true
+	library.MutatedProperties.VariantIsStatic = false
+}
+
+func (library *libraryDecorator) setStatic() {
+	library.MutatedProperties.VariantIsRlib = false
+	library.MutatedProperties.VariantIsDylib = false
+	library.MutatedProperties.VariantIsStatic = true
+}
+
+// rust_library produces all rust variants.
+func RustLibraryFactory() android.Module {
+	module, _ := NewRustLibrary(android.HostAndDeviceSupported)
+	return module.Init()
+}
+
+// rust_library_dylib produces a dylib.
+func RustLibraryDylibFactory() android.Module {
+	module, library := NewRustLibrary(android.HostAndDeviceSupported)
+	library.BuildOnlyDylib()
+	return module.Init()
+}
+
+// rust_library_rlib produces an rlib.
+func RustLibraryRlibFactory() android.Module {
+	module, library := NewRustLibrary(android.HostAndDeviceSupported)
+	library.BuildOnlyRlib()
+	return module.Init()
+}
+
+// rust_library_static produces a static library with a C ABI that can be linked into cc modules.
+func RustLibraryStaticFactory() android.Module {
+	module, library := NewRustLibrary(android.HostAndDeviceSupported)
+	library.BuildOnlyStatic()
+	return module.Init()
+}
+
+// rust_library_host produces all rust variants.
+func RustLibraryHostFactory() android.Module {
+	module, _ := NewRustLibrary(android.HostSupported)
+	return module.Init()
+}
+
+// rust_library_host_dylib produces a dylib.
+func RustLibraryDylibHostFactory() android.Module {
+	module, library := NewRustLibrary(android.HostSupported)
+	library.BuildOnlyDylib()
+	return module.Init()
+}
+
+// rust_library_host_rlib produces an rlib.
+func RustLibraryRlibHostFactory() android.Module {
+	module, library := NewRustLibrary(android.HostSupported)
+	library.BuildOnlyRlib()
+	return module.Init()
+}
+
+func (library *libraryDecorator) BuildOnlyDylib() {
+	library.MutatedProperties.BuildRlib = false
+	library.MutatedProperties.BuildDylib = true
+	library.MutatedProperties.BuildStatic = false
+}
+
+func (library *libraryDecorator) BuildOnlyRlib() {
+	library.MutatedProperties.BuildRlib = true
+	library.MutatedProperties.BuildDylib = false
+	library.MutatedProperties.BuildStatic = false
+}
+
+func (library *libraryDecorator) BuildOnlyStatic() {
+	library.MutatedProperties.BuildRlib = false
+	library.MutatedProperties.BuildDylib = false
+	library.MutatedProperties.BuildStatic = true
+}
+
+func NewRustLibrary(hod android.HostOrDeviceSupported) (*Module, *libraryDecorator) {
+	module := newModule(hod, android.MultilibBoth)
+
+	library := &libraryDecorator{
+		MutatedProperties: LibraryMutatedProperties{
+			BuildDylib: true,
+			BuildRlib:  true,
+		},
+		baseCompiler: NewBaseCompiler("lib", "lib64"),
+	}
+
+	module.compiler = library
+
+	return module, library
+}
+
+func (library *libraryDecorator) compilerProps() []interface{} {
+	return append(library.baseCompiler.compilerProps(),
+		&library.Properties,
+		&library.MutatedProperties)
+}
+
+func (library *libraryDecorator) compilerDeps(ctx DepsContext, deps Deps) Deps {
+	deps = library.baseCompiler.compilerDeps(ctx, deps)
+
+	if ctx.Device() && library.dylib() {
+		deps.CrtBegin = "crtbegin_so"
+		deps.CrtEnd = "crtend_so"
+	}
+
+	return deps
+}
+
+func (library *libraryDecorator) compile(ctx ModuleContext, flags Flags, deps PathDeps) android.Path {
+	var outputFile android.WritablePath
+
+	// rustc looks for the crates passed with --extern by their file name, which must start with
+	// lib.
+	stem := library.getStem(ctx)
+	if !strings.HasPrefix(stem, "lib") {
+		ctx.PropertyErrorf("stem", "library file name %q must start with lib", stem)
+		return nil
+	}
+
+	srcPath := crateRootPath(ctx, library.baseCompiler.Properties.Srcs)
+	if srcPath == nil {
+		return nil
+	}
+
+	if library.rlib() {
+		outputFile = android.PathForModuleOut(ctx, stem+ctx.toolchain().RlibSuffix())
+		TransformSrctoRlib(ctx, srcPath, deps, flags, outputFile)
+	} else if library.dylib() {
+		outputFile = android.PathForModuleOut(ctx, stem+ctx.toolchain().DylibSuffix())
+		TransformSrctoDylib(ctx, srcPath, deps, flags, outputFile)
+	} else if library.static() {
+		outputFile = android.PathForModuleOut(ctx, stem+ctx.toolchain().StaticLibSuffix())
+		TransformSrctoStatic(ctx, srcPath, deps, flags, outputFile)
+	}
+
+	library.linkDirs = deps.linkDirs
+
+	return outputFile
+}
+
+func (library *libraryDecorator) install(ctx ModuleContext, file android.Path) {
+	// rlibs and static libraries are linked into the modules that depend on them, so only dylibs
+	// are needed at runtime.
+	if library.dylib() {
+		library.baseCompiler.install(ctx, file)
+	}
+}
+
+func LibraryMutator(mctx android.BottomUpMutatorContext) {
+	if m, ok := mctx.Module().(*Module); ok && m.compiler != nil {
+		if library, ok := m.compiler.(libraryInterface); ok {
+			var variations []string
+			if library.buildRlib() {
+				variations = append(variations, "rlib")
+			}
+			if library.buildDylib() {
+				variations = append(variations, "dylib")
+			}
+			if library.buildStatic() {
+				variations = append(variations, "static")
+			}
+			if len(variations) == 0 {
+				mctx.ModuleErrorf("library has no enabled variants")
+				return
+			}
+
+			modules := mctx.CreateLocalVariations(variations...)
+			for i, variation := range variations {
+				lib := modules[i].(*Module).compiler.(libraryInterface)
+				switch variation {
+				case "rlib":
+					lib.setRlib()
+				case "dylib":
+					lib.setDylib()
+				case "static":
+					lib.setStatic()
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test that variants are being generated correctly, and that crate-types are correct.
+func TestLibraryVariants(t *testing.T) {
+	ctx := testRust(t, `
+		rust_library_host {
+			name: "libfoo",
+			srcs: ["foo.rs"],
+			crate_name: "foo",
+		}`)
+
+	libfooRlib := ctx.ModuleForTests("libfoo", "linux_glibc_x86_64_rlib").Output("libfoo.rlib")
+	libfooDylib := ctx.ModuleForTests("libfoo", "linux_glibc_x86_64_dylib").Output("libfoo.dylib.so")
+
+	if !strings.Contains(libfooRlib.Args["rustcFlags"], "crate-type=rlib") {
+		t.Errorf("missing crate-type for libfoo rlib, expecting %#v, rustcFlags: %#v",
+			"rlib", libfooRlib.Args["rustcFlags"])
+	}
+
+	if !strings.Contains(libfooDylib.Args["rustcFlags"], "crate-type=dylib") {
+		t.Errorf("missing crate-type for libfoo dylib, expecting %#v, rustcFlags: %#v",
+			"dylib", libfooDylib.Args["rustcFlags"])
+	}
+}
+
+// Test that the variants of a library can be disabled, and that static libraries only have a
+// static variant.
+func TestLibraryVariantsEnabled(t *testing.T) {
+	ctx := testRust(t, `
+		rust_library_host {
+			name: "libfoo",
+			srcs: ["foo.rs"],
+			dylib: {
+				enabled: false,
+			},
+		}
+		rust_library_static {
+			name: "libbar",
+			srcs: ["foo.rs"],
+			host_supported: true,
+		}`)
+
+	variants := ctx.ModuleVariantsForTests("libfoo")
+	for _, variant := range variants {
+		if strings.HasSuffix(variant, "_dylib") {
+			t.Errorf("unexpected dylib variant %q of libfoo", variant)
+		}
+	}
+
+	libbar := ctx.ModuleForTests("libbar", "linux_glibc_x86_64_static").Output("libbar.a")
+	if !strings.Contains(libbar.Args["rustcFlags"], "crate-type=staticlib") {
+		t.Errorf("missing crate-type for libbar, expecting %#v, rustcFlags: %#v",
+			"staticlib", libbar.Args["rustcFlags"])
+	}
+}
+
+// Test that the file names of libraries start with lib, which rustc requires for --extern.
+func TestValidateLibraryStem(t *testing.T) {
+	testRustError(t, "must start with lib", `
+		rust_library_host {
+			name: "libfoo",
+			srcs: ["foo.rs"],
+			stem: "foo",
+		}`)
+}
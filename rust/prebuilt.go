@@ -0,0 +1,69 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"android/soong/android"
+)
+
+func init() {
+	android.RegisterModuleType("rust_prebuilt_dylib", PrebuiltDylibFactory)
+	android.RegisterModuleType("rust_prebuilt_rlib", PrebuiltRlibFactory)
+}
+
+type prebuiltLibraryDecorator struct {
+	*libraryDecorator
+}
+
+var _ compiler = (*prebuiltLibraryDecorator)(nil)
+
+// rust_prebuilt_dylib imports a prebuilt dylib, for example the standard libraries that are
+// installed on devices.  srcs must contain exactly one file.
+func PrebuiltDylibFactory() android.Module {
+	module, library := NewPrebuiltLibrary(android.HostAndDeviceSupported)
+	library.BuildOnlyDylib()
+	return module.Init()
+}
+
+// rust_prebuilt_rlib imports a prebuilt rlib.  srcs must contain exactly one file.
+func PrebuiltRlibFactory() android.Module {
+	module, library := NewPrebuiltLibrary(android.HostAndDeviceSupported)
+	library.BuildOnlyRlib()
+	return module.Init()
+}
+
+func NewPrebuiltLibrary(hod android.HostOrDeviceSupported) (*Module, *prebuiltLibraryDecorator) {
+	module, library := NewRustLibrary(hod)
+	prebuilt := &prebuiltLibraryDecorator{
+		libraryDecorator: library,
+	}
+	module.compiler = prebuilt
+	return module, prebuilt
+}
+
+func (prebuilt *prebuiltLibraryDecorator) compile(ctx ModuleContext, flags Flags, deps PathDeps) android.Path {
+	srcs := android.PathsForModuleSrc(ctx, prebuilt.baseCompiler.Properties.Srcs)
+	if len(srcs) != 1 {
+		ctx.PropertyErrorf("srcs", "prebuilt libraries must have exactly one src, found %d", len(srcs))
+		return nil
+	}
+	return srcs[0]
+}
+
+func (prebuilt *prebuiltLibraryDecorator) compilerDeps(ctx DepsContext, deps Deps) Deps {
+	// Prebuilts don't link against the standard libraries or bionic, they were linked when they
+	// were built.
+	return deps
+}
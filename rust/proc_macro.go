@@ -0,0 +1,66 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"android/soong/android"
+)
+
+func init() {
+	android.RegisterModuleType("rust_proc_macro", ProcMacroFactory)
+}
+
+type procMacroDecorator struct {
+	*baseCompiler
+}
+
+var _ compiler = (*procMacroDecorator)(nil)
+
+// rust_proc_macro produces a procedural macro crate, which is a compiler plugin that is always
+// built for the build host, even when the modules that use it are built for devices.
+func ProcMacroFactory() android.Module {
+	module, _ := NewProcMacro(android.HostSupportedNoCross)
+	return module.Init()
+}
+
+func NewProcMacro(hod android.HostOrDeviceSupported) (*Module, *procMacroDecorator) {
+	module := newModule(hod, android.MultilibFirst)
+
+	procMacro := &procMacroDecorator{
+		baseCompiler: NewBaseCompiler("lib", "lib64"),
+	}
+
+	module.compiler = procMacro
+
+	return module, procMacro
+}
+
+func (procMacro *procMacroDecorator) compile(ctx ModuleContext, flags Flags, deps PathDeps) android.Path {
+	fileName := procMacro.getStem(ctx) + ctx.toolchain().ProcMacroSuffix()
+	outputFile := android.PathForModuleOut(ctx, fileName)
+
+	srcPath := crateRootPath(ctx, procMacro.Properties.Srcs)
+	if srcPath == nil {
+		return outputFile
+	}
+
+	TransformSrctoProcMacro(ctx, srcPath, deps, flags, outputFile)
+
+	return outputFile
+}
+
+func (procMacro *procMacroDecorator) install(ctx ModuleContext, file android.Path) {
+	// Proc macros are only loaded by rustc, so they are not installed.
+}
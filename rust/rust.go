@@ -0,0 +1,466 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+	"android/soong/cc"
+	"android/soong/rust/config"
+)
+
+var pctx = android.NewPackageContext("android/soong/rust")
+
+func init() {
+	android.RegisterModuleType("rust_defaults", defaultsFactory)
+	android.PreDepsMutators(func(ctx android.RegisterMutatorsContext) {
+		ctx.BottomUp("rust_libraries", LibraryMutator).Parallel()
+	})
+	pctx.Import("android/soong/rust/config")
+}
+
+type Flags struct {
+	GlobalRustFlags []string // Flags that apply globally to rust
+	GlobalLinkFlags []string // Flags that apply globally to linker
+	RustFlags       []string // Flags that apply to rust
+	LinkFlags       []string // Flags that apply to linker
+	Toolchain       config.Toolchain
+}
+
+type BaseProperties struct {
+	AndroidMkRlibs         []string `blueprint:"mutated"`
+	AndroidMkDylibs        []string `blueprint:"mutated"`
+	AndroidMkProcMacroLibs []string `blueprint:"mutated"`
+	AndroidMkSharedLibs    []string `blueprint:"mutated"`
+	AndroidMkStaticLibs    []string `blueprint:"mutated"`
+}
+
+type Module struct {
+	android.ModuleBase
+	android.DefaultableModuleBase
+
+	Properties BaseProperties
+
+	hod      android.HostOrDeviceSupported
+	multilib android.Multilib
+
+	compiler         compiler
+	cachedToolchain  config.Toolchain
+	subAndroidMkOnce map[subAndroidMkProvider]bool
+	outputFile       android.OptionalPath
+}
+
+var _ cc.CoreImageModule = (*Module)(nil)
+
+// CoreImageOnly returns true because Rust modules are only built for the core image.
+func (mod *Module) CoreImageOnly() bool {
+	return true
+}
+
+func (mod *Module) OutputFile() android.OptionalPath {
+	return mod.outputFile
+}
+
+func (mod *Module) Init() android.Module {
+	mod.AddProperties(&mod.Properties)
+
+	if mod.compiler != nil {
+		mod.AddProperties(mod.compiler.compilerProps()...)
+	}
+	android.InitAndroidArchModule(mod, mod.hod, mod.multilib)
+
+	android.InitDefaultableModule(mod)
+
+	// There are no Rust toolchains for riscv64 or musl hosts yet, see rust/config.
+	android.AddLoadHook(mod, func(ctx android.LoadHookContext) {
+		disableTargets := struct {
+			Arch struct {
+				Riscv64 struct {
+					Enabled *bool
+				}
+			}
+			Target struct {
+				Linux_musl struct {
+					Enabled *bool
+				}
+			}
+		}{}
+		disableTargets.Arch.Riscv64.Enabled = proptools.BoolPtr(false)
+		disableTargets.Target.Linux_musl.Enabled = proptools.BoolPtr(false)
+		ctx.AppendProperties(&disableTargets)
+	})
+
+	return mod
+}
+
+func newModule(hod android.HostOrDeviceSupported, multilib android.Multilib) *Module {
+	return &Module{
+		hod:      hod,
+		multilib: multilib,
+	}
+}
+
+type Deps struct {
+	Dylibs     []string
+	Rlibs      []string
+	ProcMacros []string
+	SharedLibs []string
+	StaticLibs []string
+
+	CrtBegin, CrtEnd string
+}
+
+type PathDeps struct {
+	DyLibs     RustLibraries
+	RLibs      RustLibraries
+	SharedLibs android.Paths
+	StaticLibs android.Paths
+	ProcMacros RustLibraries
+
+	// directories that rustc searches for the dependencies of the crate, and the native
+	// libraries that the crate links against
+	linkDirs []string
+	depFlags []string
+
+	CrtBegin android.OptionalPath
+	CrtEnd   android.OptionalPath
+}
+
+type RustLibraries []RustLibrary
+
+type RustLibrary struct {
+	Path      android.Path
+	CrateName string
+}
+
+type compiler interface {
+	compilerFlags(ctx ModuleContext, flags Flags) Flags
+	linkerFlags(ctx ModuleContext, flags Flags) Flags
+	compilerProps() []interface{}
+	compilerDeps(ctx DepsContext, deps Deps) Deps
+	compile(ctx ModuleContext, flags Flags, deps PathDeps) android.Path
+	crateName() string
+
+	install(ctx ModuleContext, path android.Path)
+	relativeInstallPath() string
+}
+
+type Defaults struct {
+	android.ModuleBase
+	android.DefaultsModuleBase
+}
+
+func (*Defaults) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+}
+
+// rust_defaults provides a set of properties that can be inherited by other
+// rust modules.
+func defaultsFactory() android.Module {
+	return DefaultsFactory()
+}
+
+func DefaultsFactory(props ...interface{}) android.Module {
+	module := &Defaults{}
+
+	module.AddProperties(props...)
+	module.AddProperties(
+		&BaseProperties{},
+		&BaseCompilerProperties{},
+		&LibraryCompilerProperties{},
+		&TestProperties{},
+	)
+
+	android.InitDefaultsModule(module)
+	return module
+}
+
+type ModuleContext interface {
+	android.ModuleContext
+	ModuleContextIntf
+}
+
+type BaseModuleContext interface {
+	android.BaseContext
+	ModuleContextIntf
+}
+
+type DepsContext interface {
+	android.BottomUpMutatorContext
+	ModuleContextIntf
+}
+
+type ModuleContextIntf interface {
+	toolchain() config.Toolchain
+	baseModuleName() string
+	CrateName() string
+}
+
+type depsContext struct {
+	android.BottomUpMutatorContext
+	moduleContextImpl
+}
+
+type moduleContext struct {
+	android.ModuleContext
+	moduleContextImpl
+}
+
+type moduleContextImpl struct {
+	mod *Module
+	ctx BaseModuleContext
+}
+
+func (ctx *moduleContextImpl) toolchain() config.Toolchain {
+	return ctx.mod.toolchain(ctx.ctx)
+}
+
+func (ctx *moduleContextImpl) baseModuleName() string {
+	return ctx.mod.ModuleBase.BaseModuleName()
+}
+
+func (ctx *moduleContextImpl) CrateName() string {
+	return ctx.mod.CrateName()
+}
+
+func (mod *Module) toolchain(ctx android.BaseContext) config.Toolchain {
+	if mod.cachedToolchain == nil {
+		mod.cachedToolchain = config.FindToolchain(ctx.Os(), ctx.Arch())
+	}
+	return mod.cachedToolchain
+}
+
+func (mod *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
+	ctx := &moduleContext{
+		ModuleContext: actx,
+		moduleContextImpl: moduleContextImpl{
+			mod: mod,
+		},
+	}
+	ctx.ctx = ctx
+
+	if !config.HasToolchain(ctx.Os(), ctx.Arch()) {
+		ctx.ModuleErrorf("Rust is not supported for %s %s", ctx.Os().String(), ctx.Arch().String())
+		return
+	}
+
+	toolchain := mod.toolchain(ctx)
+
+	flags := Flags{
+		Toolchain: toolchain,
+	}
+
+	if mod.compiler != nil {
+		flags = mod.compiler.compilerFlags(ctx, flags)
+		flags = mod.compiler.linkerFlags(ctx, flags)
+
+		deps := mod.depsToPaths(ctx)
+		if ctx.Failed() {
+			return
+		}
+
+		outputFile := mod.compiler.compile(ctx, flags, deps)
+		if ctx.Failed() {
+			return
+		}
+		mod.outputFile = android.OptionalPathForPath(outputFile)
+		mod.compiler.install(ctx, mod.outputFile.Path())
+	}
+}
+
+func (mod *Module) deps(ctx DepsContext) Deps {
+	deps := Deps{}
+
+	if mod.compiler != nil {
+		deps = mod.compiler.compilerDeps(ctx, deps)
+	}
+
+	deps.Rlibs = android.LastUniqueStrings(deps.Rlibs)
+	deps.Dylibs = android.LastUniqueStrings(deps.Dylibs)
+	deps.ProcMacros = android.LastUniqueStrings(deps.ProcMacros)
+	deps.SharedLibs = android.LastUniqueStrings(deps.SharedLibs)
+	deps.StaticLibs = android.LastUniqueStrings(deps.StaticLibs)
+
+	return deps
+}
+
+type dependencyTag struct {
+	blueprint.BaseDependencyTag
+	name    string
+	library bool
+}
+
+var (
+	rlibDepTag      = dependencyTag{name: "rlib", library: true}
+	dylibDepTag     = dependencyTag{name: "dylib", library: true}
+	procMacroDepTag = dependencyTag{name: "procMacro"}
+	sharedLibDepTag = dependencyTag{name: "sharedLib", library: true}
+	staticLibDepTag = dependencyTag{name: "staticLib", library: true}
+	crtBeginDepTag  = dependencyTag{name: "crtbegin"}
+	crtEndDepTag    = dependencyTag{name: "crtend"}
+)
+
+func (mod *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
+	var depPaths PathDeps
+
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		depName := ctx.OtherModuleName(dep)
+		depTag := ctx.OtherModuleDependencyTag(dep)
+
+		if rustDep, ok := dep.(*Module); ok {
+			linkFile := rustDep.outputFile
+			if !linkFile.Valid() {
+				ctx.ModuleErrorf("Invalid output file when adding dep %q to %q", depName, ctx.ModuleName())
+				return
+			}
+			lib := RustLibrary{Path: linkFile.Path(), CrateName: rustDep.CrateName()}
+
+			switch depTag {
+			case dylibDepTag:
+				dylib, ok := rustDep.compiler.(libraryInterface)
+				if !ok || !dylib.dylib() {
+					ctx.ModuleErrorf("mod %q not a dylib library", depName)
+					return
+				}
+				depPaths.DyLibs = append(depPaths.DyLibs, lib)
+				mod.Properties.AndroidMkDylibs = append(mod.Properties.AndroidMkDylibs, depName)
+			case rlibDepTag:
+				rlib, ok := rustDep.compiler.(libraryInterface)
+				if !ok || !rlib.rlib() {
+					ctx.ModuleErrorf("mod %q not an rlib library", depName)
+					return
+				}
+				depPaths.RLibs = append(depPaths.RLibs, lib)
+				mod.Properties.AndroidMkRlibs = append(mod.Properties.AndroidMkRlibs, depName)
+			case procMacroDepTag:
+				if _, ok := rustDep.compiler.(*procMacroDecorator); !ok {
+					ctx.ModuleErrorf("mod %q not a proc_macro", depName)
+					return
+				}
+				depPaths.ProcMacros = append(depPaths.ProcMacros, lib)
+				mod.Properties.AndroidMkProcMacroLibs = append(mod.Properties.AndroidMkProcMacroLibs, depName)
+			}
+
+			// rustc needs to find the dependencies of the crates that this module depends on.
+			depPaths.linkDirs = append(depPaths.linkDirs, linkPathFromFilePath(linkFile.Path()))
+			if lib, ok := rustDep.compiler.(libraryInterface); ok {
+				depPaths.linkDirs = append(depPaths.linkDirs, lib.exportedLinkDirs()...)
+			}
+		} else if ccDep, ok := dep.(*cc.Module); ok {
+			linkFile := ccDep.OutputFile()
+			if !linkFile.Valid() {
+				ctx.ModuleErrorf("Invalid output file when adding dep %q to %q", depName, ctx.ModuleName())
+				return
+			}
+			linkDir := linkPathFromFilePath(linkFile.Path())
+			libName := libNameFromFilePath(linkFile.Path())
+
+			switch depTag {
+			case staticLibDepTag:
+				depPaths.StaticLibs = append(depPaths.StaticLibs, linkFile.Path())
+				depPaths.linkDirs = append(depPaths.linkDirs, linkDir)
+				depPaths.depFlags = append(depPaths.depFlags, "-l static="+libName)
+				mod.Properties.AndroidMkStaticLibs = append(mod.Properties.AndroidMkStaticLibs, depName)
+			case sharedLibDepTag:
+				depPaths.SharedLibs = append(depPaths.SharedLibs, linkFile.Path())
+				depPaths.linkDirs = append(depPaths.linkDirs, linkDir)
+				depPaths.depFlags = append(depPaths.depFlags, "-l dylib="+libName)
+				mod.Properties.AndroidMkSharedLibs = append(mod.Properties.AndroidMkSharedLibs, depName)
+			case crtBeginDepTag:
+				depPaths.CrtBegin = linkFile
+			case crtEndDepTag:
+				depPaths.CrtEnd = linkFile
+			}
+		}
+	})
+
+	depPaths.linkDirs = android.FirstUniqueStrings(depPaths.linkDirs)
+	depPaths.depFlags = android.FirstUniqueStrings(depPaths.depFlags)
+
+	return depPaths
+}
+
+func linkPathFromFilePath(path android.Path) string {
+	return filepath.Dir(path.String())
+}
+
+// libNameFromFilePath returns the name that is passed to the linker with -l for a native library.
+func libNameFromFilePath(path android.Path) string {
+	return strings.TrimPrefix(strings.TrimSuffix(path.Base(), path.Ext()), "lib")
+}
+
+func (mod *Module) DepsMutator(actx android.BottomUpMutatorContext) {
+	ctx := &depsContext{
+		BottomUpMutatorContext: actx,
+		moduleContextImpl: moduleContextImpl{
+			mod: mod,
+		},
+	}
+	ctx.ctx = ctx
+
+	deps := mod.deps(ctx)
+
+	actx.AddVariationDependencies([]blueprint.Variation{
+		{Mutator: "rust_libraries", Variation: "rlib"},
+	}, rlibDepTag, deps.Rlibs...)
+	actx.AddVariationDependencies([]blueprint.Variation{
+		{Mutator: "rust_libraries", Variation: "dylib"},
+	}, dylibDepTag, deps.Dylibs...)
+
+	// cc libraries only have an image variation on devices.
+	ccDepVariations := []blueprint.Variation{
+		{Mutator: "arch", Variation: ctx.Target().String()},
+	}
+	if ctx.Os() == android.Android {
+		ccDepVariations = append(ccDepVariations, blueprint.Variation{Mutator: "image", Variation: cc.CoreVariation})
+	}
+	actx.AddFarVariationDependencies(append(ccDepVariations,
+		blueprint.Variation{Mutator: "link", Variation: "shared"}), sharedLibDepTag, deps.SharedLibs...)
+	actx.AddFarVariationDependencies(append(ccDepVariations,
+		blueprint.Variation{Mutator: "link", Variation: "static"}), staticLibDepTag, deps.StaticLibs...)
+
+	if deps.CrtBegin != "" {
+		actx.AddFarVariationDependencies(ccDepVariations, crtBeginDepTag, deps.CrtBegin)
+	}
+	if deps.CrtEnd != "" {
+		actx.AddFarVariationDependencies(ccDepVariations, crtEndDepTag, deps.CrtEnd)
+	}
+
+	// proc_macros are compiler plugins, so they are always built for the build host.
+	actx.AddFarVariationDependencies([]blueprint.Variation{
+		{Mutator: "arch", Variation: ctx.Config().BuildOsVariant},
+	}, procMacroDepTag, deps.ProcMacros...)
+}
+
+// CrateName returns the name of the crate that the module compiles, which is the name passed to
+// --extern by the modules that depend on it.
+func (mod *Module) CrateName() string {
+	if mod.compiler != nil {
+		if name := mod.compiler.crateName(); name != "" {
+			return name
+		}
+	}
+	// Default the crate name to the module name, without any lib prefix.
+	return strings.Replace(strings.TrimPrefix(mod.BaseModuleName(), "lib"), "-", "_", -1)
+}
+
+var Bool = proptools.Bool
+var BoolDefault = proptools.BoolDefault
+var String = proptools.String
+var StringPtr = proptools.StringPtr
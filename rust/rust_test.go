@@ -0,0 +1,303 @@
+// Copyright 2019 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+var buildDir string
+
+func setUp() {
+	var err error
+	buildDir, err = ioutil.TempDir("", "soong_rust_test")
+	if err != nil {
+		panic(err)
+	}
+}
+
+func tearDown() {
+	os.RemoveAll(buildDir)
+}
+
+func TestMain(m *testing.M) {
+	run := func() int {
+		setUp()
+		defer tearDown()
+
+		return m.Run()
+	}
+
+	os.Exit(run())
+}
+
+func testRust(t *testing.T, bp string) *android.TestContext {
+	t.Helper()
+	config := android.TestArchConfig(buildDir, nil)
+
+	ctx := CreateTestContext(bp)
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	return ctx
+}
+
+func testRustError(t *testing.T, pattern string, bp string) {
+	t.Helper()
+	config := android.TestArchConfig(buildDir, nil)
+
+	ctx := CreateTestContext(bp)
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if len(errs) > 0 {
+		android.FailIfNoMatchingErrors(t, pattern, errs)
+		return
+	}
+
+	_, errs = ctx.PrepareBuildActions(config)
+	if len(errs) > 0 {
+		android.FailIfNoMatchingErrors(t, pattern, errs)
+		return
+	}
+
+	t.Fatalf("missing expected error %q (0 errors are returned)", pattern)
+}
+
+// Test that we can extract the lib name from a lib path.
+func TestLibNameFromFilePath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"dir/libfoo.so", "foo"},
+		{"dir/libbar.a", "bar"},
+		{"dir/baz.so", "baz"},
+		{"dir/libfoo.dylib.so", "foo.dylib"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := libNameFromFilePath(android.PathForTesting(tc.path)); got != tc.expected {
+				t.Errorf("expected lib name %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// Test that we can extract the link path from a lib path.
+func TestLinkPathFromFilePath(t *testing.T) {
+	barPath := android.PathForTesting("out/soong/.intermediates/external/libbar/libbar/linux_glibc_x86_64_shared/libbar.so")
+	libLinkPath := linkPathFromFilePath(barPath)
+	expectedResult := "out/soong/.intermediates/external/libbar/libbar/linux_glibc_x86_64_shared"
+
+	if libLinkPath != expectedResult {
+		t.Errorf("libLinkPath returns %q, expected %q", libLinkPath, expectedResult)
+	}
+}
+
+// Test that the dependencies of a module are passed to rustc and exported to Make.
+func TestDepsTracking(t *testing.T) {
+	ctx := testRust(t, `
+		rust_library_host_dylib {
+			name: "libdylib",
+			srcs: ["foo.rs"],
+		}
+		rust_library_host_rlib {
+			name: "librlib",
+			srcs: ["foo.rs"],
+			crate_name: "rlib_crate",
+		}
+		rust_proc_macro {
+			name: "libpm",
+			srcs: ["foo.rs"],
+		}
+		rust_binary_host {
+			name: "fizz-buzz",
+			dylibs: ["libdylib"],
+			rlibs: ["librlib"],
+			proc_macros: ["libpm"],
+			srcs: ["foo.rs"],
+		}
+	`)
+
+	module := ctx.ModuleForTests("fizz-buzz", "linux_glibc_x86_64").Module().(*Module)
+
+	if !reflect.DeepEqual(module.Properties.AndroidMkDylibs, []string{"libdylib"}) {
+		t.Errorf("Dylib dependency not detected (dependency missing from AndroidMkDylibs)")
+	}
+	if !reflect.DeepEqual(module.Properties.AndroidMkRlibs, []string{"librlib"}) {
+		t.Errorf("Rlib dependency not detected (dependency missing from AndroidMkRlibs)")
+	}
+	if !reflect.DeepEqual(module.Properties.AndroidMkProcMacroLibs, []string{"libpm"}) {
+		t.Errorf("Proc_macro dependency not detected (dependency missing from AndroidMkProcMacroLibs)")
+	}
+
+	rustc := ctx.ModuleForTests("fizz-buzz", "linux_glibc_x86_64").Output("fizz-buzz")
+	libFlags := rustc.Args["libFlags"]
+	for _, expected := range []string{"--extern dylib=", "--extern rlib_crate=", "--extern pm="} {
+		if !strings.Contains(libFlags, expected) {
+			t.Errorf("libFlags %q missing %q", libFlags, expected)
+		}
+	}
+	if !strings.Contains(rustc.Args["rustcFlags"], "--crate-type=bin") {
+		t.Errorf("rustcFlags %q missing --crate-type=bin", rustc.Args["rustcFlags"])
+	}
+}
+
+// Test that device modules can link against cc libraries, and link against the standard libraries
+// and bionic dynamically.
+func TestCcDeps(t *testing.T) {
+	ctx := testRust(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			system_shared_libs: [],
+			stl: "none",
+		}
+		cc_library_static {
+			name: "libbar",
+			srcs: ["foo.c"],
+			system_shared_libs: [],
+			stl: "none",
+		}
+		rust_binary {
+			name: "fizz-buzz",
+			srcs: ["foo.rs"],
+			shared_libs: ["libfoo"],
+			static_libs: ["libbar"],
+		}
+	`)
+
+	binary := ctx.ModuleForTests("fizz-buzz", "android_arm64_armv8-a_core")
+	module := binary.Module().(*Module)
+
+	if !android.InList("libfoo", module.Properties.AndroidMkSharedLibs) {
+		t.Errorf("shared library dependency missing from AndroidMkSharedLibs: %q",
+			module.Properties.AndroidMkSharedLibs)
+	}
+	if !android.InList("libbar", module.Properties.AndroidMkStaticLibs) {
+		t.Errorf("static library dependency missing from AndroidMkStaticLibs: %q",
+			module.Properties.AndroidMkStaticLibs)
+	}
+	if !android.InList("libstd", module.Properties.AndroidMkDylibs) {
+		t.Errorf("device binary does not link against libstd: %q", module.Properties.AndroidMkDylibs)
+	}
+
+	rustc := binary.Output("fizz-buzz")
+	for _, expected := range []string{"-l dylib=foo", "-l static=bar", "-l dylib=c", "--extern std="} {
+		if !strings.Contains(rustc.Args["libFlags"], expected) {
+			t.Errorf("libFlags %q missing %q", rustc.Args["libFlags"], expected)
+		}
+	}
+	if !strings.Contains(rustc.Args["rustcFlags"], "--target=aarch64-linux-android") {
+		t.Errorf("rustcFlags %q missing --target=aarch64-linux-android", rustc.Args["rustcFlags"])
+	}
+	if !strings.Contains(rustc.Args["rustcFlags"], "-C prefer-dynamic") {
+		t.Errorf("rustcFlags %q missing -C prefer-dynamic", rustc.Args["rustcFlags"])
+	}
+	if !strings.HasSuffix(rustc.Args["crtBegin"], "crtbegin_dynamic.o") {
+		t.Errorf("unexpected crtBegin %q", rustc.Args["crtBegin"])
+	}
+}
+
+// Test that only the crate root is allowed in srcs, because rustc finds the other source files
+// itself.
+func TestCrateRootSrcs(t *testing.T) {
+	testRustError(t, "srcs must contain exactly one crate root file", `
+		rust_binary_host {
+			name: "fizz-buzz",
+			srcs: ["foo.rs", "src/bar.rs"],
+		}
+	`)
+}
+
+// Test that the sub-providers run again when AndroidMkEntries is called a second time, as it is by
+// the androidmk and module_info_json singletons.
+func TestAndroidMkEntriesTwice(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	ctx := CreateTestContext(`
+		rust_binary_host {
+			name: "fizz-buzz",
+			srcs: ["foo.rs"],
+		}`)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	module := ctx.ModuleForTests("fizz-buzz", "linux_glibc_x86_64").Module()
+	for i := 0; i < 2; i++ {
+		entries := android.AndroidMkEntriesForTest(t, config, "", module)
+		if entries.Class != "EXECUTABLES" {
+			t.Errorf("expected class %q for call %d, got %q", "EXECUTABLES", i+1, entries.Class)
+		}
+	}
+}
+
+// Test that Rust modules are disabled for riscv64, which has no Rust toolchain.
+func TestRiscv64Disabled(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	config.Targets[android.Android] = []android.Target{
+		{android.Android, android.Arch{ArchType: android.Riscv64, Native: true}},
+	}
+
+	ctx := CreateTestContext(`
+		rust_binary {
+			name: "fizz-buzz",
+			srcs: ["foo.rs"],
+		}`)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	if ctx.ModuleForTests("fizz-buzz", "android_riscv64").Module().Enabled() {
+		t.Errorf("expected the riscv64 variant to be disabled")
+	}
+}
+
+// Test that Rust modules are disabled for musl hosts, which have no Rust toolchain.
+func TestMuslHostDisabled(t *testing.T) {
+	config := android.TestArchConfig(buildDir, nil)
+	config.Targets[android.LinuxMusl] = []android.Target{
+		{android.LinuxMusl, android.Arch{ArchType: android.X86_64}},
+	}
+
+	ctx := CreateTestContext(`
+		rust_binary_host {
+			name: "fizz-buzz",
+			srcs: ["foo.rs"],
+		}`)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	if ctx.ModuleForTests("fizz-buzz", "linux_musl_x86_64").Module().Enabled() {
+		t.Errorf("expected the musl variant to be disabled")
+	}
+	if !ctx.ModuleForTests("fizz-buzz", "linux_glibc_x86_64").Module().Enabled() {
+		t.Errorf("expected the glibc variant to be enabled")
+	}
+}
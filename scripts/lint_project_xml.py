@@ -0,0 +1,114 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2019 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for writing the project and configuration files of Android Lint."""
+
+from __future__ import print_function
+import argparse
+import sys
+from xml.sax.saxutils import quoteattr
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--name', required=True,
+                      help='name of the module')
+  parser.add_argument('--srcs', dest='srcs_list',
+                      help='file containing a whitespace separated list of source files')
+  parser.add_argument('--resource', dest='resources', action='append', default=[],
+                      help='resource file of the module')
+  parser.add_argument('--manifest',
+                      help='AndroidManifest.xml of the module')
+  parser.add_argument('--classes', dest='classes', action='append', default=[],
+                      help='jar containing the classes of the module')
+  parser.add_argument('--classpath', dest='classpath', action='append', default=[],
+                      help='jar on the classpath of the module')
+  parser.add_argument('--extra_checks_jar', dest='extra_checks_jars', action='append', default=[],
+                      help='jar containing extra lint checks')
+  parser.add_argument('--library', dest='library', action='store_true',
+                      help='the module is a library')
+  parser.add_argument('--disable_check', dest='disable_checks', action='append', default=[],
+                      help='id of a check to disable')
+  parser.add_argument('--fatal_check', dest='fatal_checks', action='append', default=[],
+                      help='id of a check whose issues are fatal')
+  parser.add_argument('--project_out', required=True,
+                      help='output project.xml file')
+  parser.add_argument('--config_out', required=True,
+                      help='output lint.xml configuration file')
+  return parser.parse_args()
+
+
+def write_project_xml(f, args, srcs):
+  """Writes the lint project description of the module."""
+
+  f.write('<?xml version="1.0" encoding="utf-8"?>\n')
+  f.write('<project>\n')
+  f.write('  <root dir="." />\n')
+  f.write('  <module name=%s android="true" library=%s>\n' % (
+      quoteattr(args.name), quoteattr('true' if args.library else 'false')))
+  if args.manifest:
+    f.write('    <manifest file=%s />\n' % quoteattr(args.manifest))
+  for src in srcs:
+    f.write('    <src file=%s />\n' % quoteattr(src))
+  for resource in args.resources:
+    f.write('    <resource file=%s />\n' % quoteattr(resource))
+  for classes in args.classes:
+    f.write('    <classes jar=%s />\n' % quoteattr(classes))
+  for classpath in args.classpath:
+    f.write('    <classpath jar=%s />\n' % quoteattr(classpath))
+  for jar in args.extra_checks_jars:
+    f.write('    <lint-checks jar=%s />\n' % quoteattr(jar))
+  f.write('  </module>\n')
+  f.write('</project>\n')
+
+
+def write_config_xml(f, args):
+  """Writes the lint configuration that sets the severity of the checks."""
+
+  f.write('<?xml version="1.0" encoding="utf-8"?>\n')
+  f.write('<lint>\n')
+  for check in args.disable_checks:
+    f.write('  <issue id=%s severity="ignore" />\n' % quoteattr(check))
+  for check in args.fatal_checks:
+    f.write('  <issue id=%s severity="fatal" />\n' % quoteattr(check))
+  f.write('</lint>\n')
+
+
+def main():
+  """Program entry point."""
+  try:
+    args = parse_args()
+
+    srcs = []
+    if args.srcs_list:
+      with open(args.srcs_list) as f:
+        srcs = f.read().split()
+
+    with open(args.project_out, 'w') as f:
+      write_project_xml(f, args, srcs)
+
+    with open(args.config_out, 'w') as f:
+      write_config_xml(f, args)
+
+  # pylint: disable=broad-except
+  except Exception as err:
+    print('error: ' + str(err), file=sys.stderr)
+    sys.exit(-1)
+
+if __name__ == '__main__':
+  main()
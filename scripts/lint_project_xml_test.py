@@ -0,0 +1,91 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2019 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for lint_project_xml.py."""
+
+import argparse
+import sys
+import unittest
+from xml.dom import minidom
+
+try:
+  from StringIO import StringIO
+except ImportError:
+  from io import StringIO
+
+import lint_project_xml
+
+sys.dont_write_bytecode = True
+
+
+def make_args(**kwargs):
+  args = argparse.Namespace(name='foo', manifest=None, resources=[], classes=[],
+                            classpath=[], extra_checks_jars=[], library=False,
+                            disable_checks=[], fatal_checks=[])
+  for key, value in kwargs.items():
+    setattr(args, key, value)
+  return args
+
+
+class WriteProjectXmlTest(unittest.TestCase):
+  """Unit tests for write_project_xml function."""
+
+  def test_module(self):
+    """Test the description of a module."""
+    args = make_args(manifest='AndroidManifest.xml',
+                     resources=['res/values/strings.xml'],
+                     classes=['foo.jar'], classpath=['android.jar'],
+                     extra_checks_jars=['checks.jar'], library=True)
+    f = StringIO()
+    lint_project_xml.write_project_xml(f, args, ['src/Foo.java'])
+
+    module = minidom.parseString(f.getvalue()).getElementsByTagName('module')[0]
+    self.assertEqual(module.getAttribute('name'), 'foo')
+    self.assertEqual(module.getAttribute('library'), 'true')
+
+    def files(tag, attr):
+      return [e.getAttribute(attr) for e in module.getElementsByTagName(tag)]
+
+    self.assertEqual(files('manifest', 'file'), ['AndroidManifest.xml'])
+    self.assertEqual(files('src', 'file'), ['src/Foo.java'])
+    self.assertEqual(files('resource', 'file'), ['res/values/strings.xml'])
+    self.assertEqual(files('classes', 'jar'), ['foo.jar'])
+    self.assertEqual(files('classpath', 'jar'), ['android.jar'])
+    self.assertEqual(files('lint-checks', 'jar'), ['checks.jar'])
+
+  def test_quoting(self):
+    """Test that attributes are quoted."""
+    f = StringIO()
+    lint_project_xml.write_project_xml(f, make_args(name='a"b'), [])
+    module = minidom.parseString(f.getvalue()).getElementsByTagName('module')[0]
+    self.assertEqual(module.getAttribute('name'), 'a"b')
+
+
+class WriteConfigXmlTest(unittest.TestCase):
+  """Unit tests for write_config_xml function."""
+
+  def test_severities(self):
+    """Test disabled and fatal checks."""
+    f = StringIO()
+    lint_project_xml.write_config_xml(
+        f, make_args(disable_checks=['Foo'], fatal_checks=['NewApi']))
+    issues = minidom.parseString(f.getvalue()).getElementsByTagName('issue')
+    self.assertEqual([(i.getAttribute('id'), i.getAttribute('severity')) for i in issues],
+                     [('Foo', 'ignore'), ('NewApi', 'fatal')])
+
+
+if __name__ == '__main__':
+  unittest.main()
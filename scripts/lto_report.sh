@@ -0,0 +1,60 @@
+#!/bin/bash -e
+
+# Copyright 2019 Google Inc. All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Script to summarize the cost of the LTO links in the build
+# Inputs:
+#  Arguments:
+#   -i ${file}: file with one line per LTO link: module, variant, link time file and
+#               statistics file, separated by spaces (required)
+#   -o ${file}: output report, with the links sorted by link time (required)
+
+set -o pipefail
+
+usage() {
+    echo "Usage: lto_report.sh -i links-file -o report-file"
+    exit 1
+}
+
+while getopts i:o: opt; do
+    case "$opt" in
+        i) infile="${OPTARG}" ;;
+        o) outfile="${OPTARG}" ;;
+        ?) usage ;;
+    esac
+done
+
+if [ -z "${infile}" -o -z "${outfile}" ]; then
+    usage
+fi
+
+rows=$(while read -r module variant link_time_file stats_file; do
+    if [ -z "${module}" ]; then
+        continue
+    fi
+    printf '%s\t%s\t%s\t%s\n' "$(cat "${link_time_file}")" "${module}" "${variant}" "${stats_file}"
+done < "${infile}" | sort -t$'\t' -k1,1nr -k2,2)
+
+total=$(echo "${rows}" | awk -F'\t' '{ s += $1 } END { print s + 0 }')
+links=$(echo "${rows}" | grep -c . || true)
+
+{
+    echo "# ${links} LTO links took ${total} ms in total"
+    printf '# link time (ms)\tmodule\tvariant\tstatistics\n'
+    if [ -n "${rows}" ]; then
+        echo "${rows}"
+    fi
+} > "${outfile}.tmp"
+mv "${outfile}.tmp" "${outfile}"
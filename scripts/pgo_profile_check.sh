@@ -0,0 +1,111 @@
+#!/bin/bash -e
+
+# Copyright 2019 Google Inc. All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Script to check how well a PGO profile matches the module it is used for
+# Inputs:
+#  Environment:
+#   CLANG_BIN: path to the clang bin directory
+#  Arguments:
+#   -m ${name}: module name (required)
+#   -p ${file}: profile the module is built with (required)
+#   -b ${file}: unstripped module binary (required)
+#   -o ${file}: output file (required)
+#   -c ${file}: raw or indexed profile collected from an instrumented build of
+#               the current source, to compare the profile against (optional)
+#   -t ${percent}: fail if fewer than percent of the functions in the profile
+#                  are found in the module (optional)
+#   -s: the profiles are sampling (AutoFDO) profiles rather than
+#       instrumentation profiles (optional)
+#
+# The output is a single tab separated line with the module name, the profile,
+# the number of functions in the module, the number of functions in the
+# profile, the number of profile functions found in the module, the percentage
+# of module functions covered by the profile, the percentage of profile
+# functions that no longer match the module, and the similarity of the profile
+# to the profile from the current source reported by llvm-profdata overlap.
+
+set -o pipefail
+
+usage() {
+    echo "Usage: pgo_profile_check.sh -m module -p profile -b binary -o out-file [-c current-profile] [-t min-match-percent] [-s]"
+    exit 1
+}
+
+profile_kind="--instr"
+while getopts m:p:b:o:c:t:s opt; do
+    case "$opt" in
+        m) module="${OPTARG}" ;;
+        p) profile="${OPTARG}" ;;
+        b) binary="${OPTARG}" ;;
+        o) outfile="${OPTARG}" ;;
+        c) current_profile="${OPTARG}" ;;
+        t) min_match="${OPTARG}" ;;
+        s) profile_kind="--sample" ;;
+        ?) usage ;;
+    esac
+done
+
+if [ -z "${module}" -o -z "${profile}" -o -z "${binary}" -o -z "${outfile}" ]; then
+    usage
+fi
+
+rm -f "${outfile}" "${outfile}.module_functions" "${outfile}.profile_functions" "${outfile}.current.profdata"
+
+# Functions defined in the module.
+"${CLANG_BIN}/llvm-nm" --defined-only "${binary}" | \
+    awk '$2 == "T" || $2 == "t" { print $3 }' | sort -u > "${outfile}.module_functions"
+
+# Functions in the profile.  Instrumentation profiles list each function as an
+# indented "name:" line, where functions with internal linkage are prefixed with
+# the name of their source file.  Sampling profiles list each function as a
+# "Function: name: samples, ..." line.
+"${CLANG_BIN}/llvm-profdata" show "${profile_kind}" --all-functions "${profile}" | \
+    awk '/^  [^ ].*:$/ { name = substr($0, 3, length($0) - 3); sub(/.*:/, "", name); print name }
+         /^Function: / { name = $2; sub(/:$/, "", name); print name }' | \
+    sort -u > "${outfile}.profile_functions"
+
+module_functions=$(wc -l < "${outfile}.module_functions")
+profile_functions=$(wc -l < "${outfile}.profile_functions")
+matched=$(comm -12 "${outfile}.module_functions" "${outfile}.profile_functions" | wc -l)
+
+percent() {
+    awk -v n="$1" -v d="$2" 'BEGIN { if (d == 0) print "0.0"; else printf "%.1f\n", 100 * n / d }'
+}
+coverage=$(percent "${matched}" "${module_functions}")
+mismatch=$(percent "$((profile_functions - matched))" "${profile_functions}")
+
+similarity="-"
+if [ -n "${current_profile}" ]; then
+    "${CLANG_BIN}/llvm-profdata" merge "${profile_kind}" -o "${outfile}.current.profdata" "${current_profile}"
+    similarity=$("${CLANG_BIN}/llvm-profdata" overlap "${profile_kind}" "${profile}" "${outfile}.current.profdata" | \
+        awk '/profile similarity:/ { print $NF; exit }')
+    similarity="${similarity:--}"
+fi
+
+rm -f "${outfile}.module_functions" "${outfile}.profile_functions" "${outfile}.current.profdata"
+
+line=$(printf '%s\t%s\t%s\t%s\t%s\t%s%%\t%s%%\t%s' "${module}" "${profile}" "${module_functions}" \
+    "${profile_functions}" "${matched}" "${coverage}" "${mismatch}" "${similarity}")
+
+if [ -n "${min_match}" ]; then
+    if awk -v mismatch="${mismatch}" -v min="${min_match}" 'BEGIN { exit !(100 - mismatch < min) }'; then
+        echo "error: only $(percent "${matched}" "${profile_functions}")% of the functions in the PGO profile ${profile} match ${module}, which is below the threshold of ${min_match}%." >&2
+        echo "error: The profile is stale and should be regenerated." >&2
+        exit 1
+    fi
+fi
+
+echo "${line}" > "${outfile}"
@@ -25,6 +25,7 @@
 #   -o ${file}: output file (required)
 #   -d ${file}: deps file (required)
 #   -k symbols: Symbols to keep (optional)
+#   -g ${file}: debug file to split the debug info of the input file into (optional)
 #   --add-gnu-debuglink
 #   --keep-mini-debug-info
 #   --keep-symbols
@@ -33,12 +34,14 @@
 
 set -o pipefail
 
-OPTSTRING=d:i:o:k:-:
+OPTSTRING=d:i:o:k:g:-:
 
 usage() {
     cat <<EOF
 Usage: strip.sh [options] -k symbols -i in-file -o out-file -d deps-file
 Options:
+        -g debug-file           Split the debug info of in-file into debug-file, and add a
+                                gnu-debuglink section that points to it to out-file
         --add-gnu-debuglink     Add a gnu-debuglink section to out-file
         --keep-mini-debug-info  Keep compressed debug info in out-file
         --keep-symbols          Keep symbols in out-file
@@ -127,6 +130,19 @@ do_add_gnu_debuglink() {
     fi
 }
 
+do_split_debug_info() {
+    # Current prebult llvm-objcopy does not support --only-keep-debug.
+    # The debug file keeps the gnu build-id note of in-file, and the
+    # gnu-debuglink section names the debug file and contains its CRC.
+    rm -f "${debug_file}"
+    "${CROSS_COMPILE}objcopy" --only-keep-debug "${infile}" "${debug_file}"
+    if [ -z "${use_gnu_strip}" ]; then
+        "${CLANG_BIN}/llvm-objcopy" --add-gnu-debuglink="${debug_file}" "${outfile}.tmp"
+    else
+        "${CROSS_COMPILE}objcopy" --add-gnu-debuglink="${debug_file}" "${outfile}.tmp"
+    fi
+}
+
 do_remove_build_id() {
     if [ -z "${use_gnu_strip}" ]; then
         "${CLANG_BIN}/llvm-strip" -remove-section=.note.gnu.build-id "${outfile}.tmp" -o "${outfile}.tmp.no-build-id"
@@ -143,6 +159,7 @@ while getopts $OPTSTRING opt; do
         i) infile="${OPTARG}" ;;
         o) outfile="${OPTARG}" ;;
         k) symbols_to_keep="${OPTARG}" ;;
+        g) debug_file="${OPTARG}" ;;
         -)
             case "${OPTARG}" in
                 add-gnu-debuglink) add_gnu_debuglink=true ;;
@@ -187,6 +204,16 @@ if [ ! -z "${add_gnu_debuglink}" -a ! -z "${keep_mini_debug_info}" ]; then
     usage
 fi
 
+if [ ! -z "${debug_file}" -a ! -z "${add_gnu_debuglink}" ]; then
+    echo "--add-gnu-debuglink and -g cannot be used together"
+    usage
+fi
+
+if [ ! -z "${debug_file}" -a ! -z "${remove_build_id}" ]; then
+    echo "--remove-build-id and -g cannot be used together"
+    usage
+fi
+
 rm -f "${outfile}.tmp"
 
 if [ ! -z "${keep_symbols}" ]; then
@@ -203,6 +230,10 @@ if [ ! -z "${add_gnu_debuglink}" ]; then
     do_add_gnu_debuglink
 fi
 
+if [ ! -z "${debug_file}" ]; then
+    do_split_debug_info
+fi
+
 if [ ! -z "${remove_build_id}" ]; then
     do_remove_build_id
 fi
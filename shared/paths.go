@@ -20,6 +20,10 @@ import (
 	"path/filepath"
 )
 
+// ActionCacheStatsFile is the file, relative to the Soong output directory, that sbox appends action cache statistics
+// to when SOONG_ACTION_CACHE_DIR is set.  soong_ui reports the statistics after each build.
+const ActionCacheStatsFile = "action_cache_stats.log"
+
 // Given the out directory, returns the root of the temp directory (to be cleared at the start of each execution of Soong)
 func TempDirForOutDir(outDir string) (tempPath string) {
 	return filepath.Join(outDir, ".temp")
@@ -22,7 +22,9 @@ import (
 	"strings"
 	"time"
 
+	"android/soong/shared"
 	"android/soong/ui/metrics"
+	"android/soong/ui/metrics/metrics_proto"
 	"android/soong/ui/status"
 )
 
@@ -102,10 +104,44 @@ func runNinja(ctx Context, config Config) {
 		}
 	}()
 
+	// Only report the action cache statistics of this build.
+	statsFile := filepath.Join(config.SoongOutDir(), shared.ActionCacheStatsFile)
+	os.Remove(statsFile)
+	defer reportActionCacheStats(ctx, statsFile)
+
 	ctx.Status.Status("Starting ninja...")
 	cmd.RunAndPrintOrFatal()
 }
 
+// reportActionCacheStats prints a summary of the action cache hits and misses and adds them to the build metrics, if
+// SOONG_ACTION_CACHE_DIR was set for the build.
+func reportActionCacheStats(ctx Context, statsFile string) {
+	stats, err := status.ReadActionCacheStats(statsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ctx.Verbosef("failed to read action cache stats: %v", err)
+		}
+		return
+	}
+	if !stats.Used() {
+		return
+	}
+
+	st := ctx.Status.StartTool()
+	st.Print(stats.String())
+	st.Finish()
+
+	if ctx.Metrics != nil {
+		ctx.Metrics.SetActionCacheMetrics(soong_metrics_proto.ActionCacheInfo{
+			Hits:          &stats.Hits,
+			Misses:        &stats.Misses,
+			RestoredBytes: &stats.RestoredBytes,
+			Evictions:     &stats.Evictions,
+			EvictedBytes:  &stats.EvictedBytes,
+		})
+	}
+}
+
 type statusChecker struct {
 	prevTime time.Time
 }
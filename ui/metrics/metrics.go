@@ -127,6 +127,11 @@ func (m *Metrics) getArch(arch string) *soong_metrics_proto.MetricsBase_Arch {
 	}
 }
 
+// SetActionCacheMetrics records the statistics of the local action cache used by sbox during the build.
+func (m *Metrics) SetActionCacheMetrics(info soong_metrics_proto.ActionCacheInfo) {
+	m.metrics.ActionCache = &info
+}
+
 func (m *Metrics) SetBuildDateTime(date_time string) {
 	if date_time != "" {
 		date_time_timestamp, err := strconv.ParseInt(date_time, 10, 64)
@@ -195,10 +195,12 @@ type MetricsBase struct {
 	// The metrics for calling Soong.
 	SoongRuns []*PerfInfo `protobuf:"bytes,19,rep,name=soong_runs,json=soongRuns" json:"soong_runs,omitempty"`
 	// The metrics for calling Ninja.
-	NinjaRuns            []*PerfInfo `protobuf:"bytes,20,rep,name=ninja_runs,json=ninjaRuns" json:"ninja_runs,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	NinjaRuns []*PerfInfo `protobuf:"bytes,20,rep,name=ninja_runs,json=ninjaRuns" json:"ninja_runs,omitempty"`
+	// The statistics of the local action cache used by sbox.
+	ActionCache          *ActionCacheInfo `protobuf:"bytes,21,opt,name=action_cache,json=actionCache" json:"action_cache,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *MetricsBase) Reset()         { *m = MetricsBase{} }
@@ -371,6 +373,13 @@ func (m *MetricsBase) GetNinjaRuns() []*PerfInfo {
 	return nil
 }
 
+func (m *MetricsBase) GetActionCache() *ActionCacheInfo {
+	if m != nil {
+		return m.ActionCache
+	}
+	return nil
+}
+
 type PerfInfo struct {
 	// The description for the phase/action/part while the tool running.
 	Desc *string `protobuf:"bytes,1,opt,name=desc" json:"desc,omitempty"`
@@ -509,6 +518,82 @@ func (m *ModuleTypeInfo) GetNumOfModules() uint32 {
 	return 0
 }
 
+type ActionCacheInfo struct {
+	// The number of actions whose outputs were restored from the action cache.
+	Hits *uint64 `protobuf:"varint,1,opt,name=hits" json:"hits,omitempty"`
+	// The number of actions that could be cached, but were run and then stored in the action cache.
+	Misses *uint64 `protobuf:"varint,2,opt,name=misses" json:"misses,omitempty"`
+	// The number of bytes of outputs restored from the action cache.
+	RestoredBytes *uint64 `protobuf:"varint,3,opt,name=restored_bytes,json=restoredBytes" json:"restored_bytes,omitempty"`
+	// The number of entries evicted from the action cache.
+	Evictions *uint64 `protobuf:"varint,4,opt,name=evictions" json:"evictions,omitempty"`
+	// The number of bytes evicted from the action cache.
+	EvictedBytes         *uint64  `protobuf:"varint,5,opt,name=evicted_bytes,json=evictedBytes" json:"evicted_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ActionCacheInfo) Reset()         { *m = ActionCacheInfo{} }
+func (m *ActionCacheInfo) String() string { return proto.CompactTextString(m) }
+func (*ActionCacheInfo) ProtoMessage()    {}
+func (*ActionCacheInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{3}
+}
+
+func (m *ActionCacheInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ActionCacheInfo.Unmarshal(m, b)
+}
+func (m *ActionCacheInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ActionCacheInfo.Marshal(b, m, deterministic)
+}
+func (m *ActionCacheInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ActionCacheInfo.Merge(m, src)
+}
+func (m *ActionCacheInfo) XXX_Size() int {
+	return xxx_messageInfo_ActionCacheInfo.Size(m)
+}
+func (m *ActionCacheInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ActionCacheInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ActionCacheInfo proto.InternalMessageInfo
+
+func (m *ActionCacheInfo) GetHits() uint64 {
+	if m != nil && m.Hits != nil {
+		return *m.Hits
+	}
+	return 0
+}
+
+func (m *ActionCacheInfo) GetMisses() uint64 {
+	if m != nil && m.Misses != nil {
+		return *m.Misses
+	}
+	return 0
+}
+
+func (m *ActionCacheInfo) GetRestoredBytes() uint64 {
+	if m != nil && m.RestoredBytes != nil {
+		return *m.RestoredBytes
+	}
+	return 0
+}
+
+func (m *ActionCacheInfo) GetEvictions() uint64 {
+	if m != nil && m.Evictions != nil {
+		return *m.Evictions
+	}
+	return 0
+}
+
+func (m *ActionCacheInfo) GetEvictedBytes() uint64 {
+	if m != nil && m.EvictedBytes != nil {
+		return *m.EvictedBytes
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_BuildVariant", MetricsBase_BuildVariant_name, MetricsBase_BuildVariant_value)
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_Arch", MetricsBase_Arch_name, MetricsBase_Arch_value)
@@ -516,59 +601,66 @@ func init() {
 	proto.RegisterType((*MetricsBase)(nil), "soong_build_metrics.MetricsBase")
 	proto.RegisterType((*PerfInfo)(nil), "soong_build_metrics.PerfInfo")
 	proto.RegisterType((*ModuleTypeInfo)(nil), "soong_build_metrics.ModuleTypeInfo")
+	proto.RegisterType((*ActionCacheInfo)(nil), "soong_build_metrics.ActionCacheInfo")
 }
 
 func init() { proto.RegisterFile("metrics.proto", fileDescriptor_6039342a2ba47b72) }
 
 var fileDescriptor_6039342a2ba47b72 = []byte{
-	// 769 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x55, 0x6f, 0x6b, 0xdb, 0x46,
-	0x18, 0xaf, 0x62, 0x25, 0x96, 0x1e, 0xc5, 0xae, 0x7a, 0xc9, 0xa8, 0xca, 0x08, 0x33, 0x66, 0x1d,
-	0x7e, 0xb1, 0xba, 0xc5, 0x14, 0x53, 0x4c, 0x19, 0xd8, 0x89, 0x29, 0x25, 0xd8, 0x2e, 0x4a, 0xdc,
-	0x95, 0xed, 0xc5, 0xa1, 0x4a, 0xe7, 0x46, 0x9b, 0xa5, 0x13, 0x77, 0xa7, 0x32, 0x7f, 0x88, 0x7d,
-	0x93, 0x7d, 0xad, 0x7d, 0x8f, 0x71, 0xcf, 0x49, 0x8e, 0x02, 0x81, 0x85, 0xbe, 0x3b, 0x3d, 0xbf,
-	0x3f, 0xf7, 0x7b, 0x4e, 0xba, 0x47, 0xd0, 0xc9, 0x98, 0x12, 0x69, 0x2c, 0x87, 0x85, 0xe0, 0x8a,
-	0x93, 0x13, 0xc9, 0x79, 0xfe, 0x85, 0x7e, 0x2e, 0xd3, 0x6d, 0x42, 0x2b, 0xa8, 0xff, 0x8f, 0x0b,
-	0xde, 0xc2, 0xac, 0x67, 0x91, 0x64, 0xe4, 0x15, 0x9c, 0x1a, 0x42, 0x12, 0x29, 0x46, 0x55, 0x9a,
-	0x31, 0xa9, 0xa2, 0xac, 0x08, 0xac, 0x9e, 0x35, 0x68, 0x85, 0x04, 0xb1, 0x8b, 0x48, 0xb1, 0xeb,
-	0x1a, 0x21, 0xcf, 0xc0, 0x31, 0x8a, 0x34, 0x09, 0x0e, 0x7a, 0xd6, 0xc0, 0x0d, 0xdb, 0xf8, 0xfc,
-	0x3e, 0x21, 0x13, 0x78, 0x56, 0x6c, 0x23, 0xb5, 0xe1, 0x22, 0xa3, 0x5f, 0x99, 0x90, 0x29, 0xcf,
-	0x69, 0xcc, 0x13, 0x96, 0x47, 0x19, 0x0b, 0x5a, 0xc8, 0x7d, 0x5a, 0x13, 0x3e, 0x1a, 0xfc, 0xbc,
-	0x82, 0xc9, 0x73, 0xe8, 0xaa, 0x48, 0x7c, 0x61, 0x8a, 0x16, 0x82, 0x27, 0x65, 0xac, 0x02, 0x1b,
-	0x05, 0x1d, 0x53, 0xfd, 0x60, 0x8a, 0x24, 0x81, 0xd3, 0x8a, 0x66, 0x42, 0x7c, 0x8d, 0x44, 0x1a,
-	0xe5, 0x2a, 0x38, 0xec, 0x59, 0x83, 0xee, 0xe8, 0xc5, 0xf0, 0x9e, 0x9e, 0x87, 0x8d, 0x7e, 0x87,
-	0x33, 0x8d, 0x7c, 0x34, 0xa2, 0x49, 0x6b, 0xbe, 0x7c, 0x17, 0x12, 0xe3, 0xd7, 0x04, 0xc8, 0x0a,
-	0xbc, 0x6a, 0x97, 0x48, 0xc4, 0x37, 0xc1, 0x11, 0x9a, 0x3f, 0xff, 0x5f, 0xf3, 0xa9, 0x88, 0x6f,
-	0x26, 0xed, 0xf5, 0xf2, 0x72, 0xb9, 0xfa, 0x75, 0x19, 0x82, 0xb1, 0xd0, 0x45, 0x32, 0x84, 0x93,
-	0x86, 0xe1, 0x3e, 0x75, 0x1b, 0x5b, 0x7c, 0x72, 0x4b, 0xac, 0x03, 0xfc, 0x0c, 0x55, 0x2c, 0x1a,
-	0x17, 0xe5, 0x9e, 0xee, 0x20, 0xdd, 0x37, 0xc8, 0x79, 0x51, 0xd6, 0xec, 0x4b, 0x70, 0x6f, 0xb8,
-	0xac, 0xc2, 0xba, 0xdf, 0x14, 0xd6, 0xd1, 0x06, 0x18, 0x35, 0x84, 0x0e, 0x9a, 0x8d, 0xf2, 0xc4,
-	0x18, 0xc2, 0x37, 0x19, 0x7a, 0xda, 0x64, 0x94, 0x27, 0xe8, 0xf9, 0x14, 0xda, 0xe8, 0xc9, 0x65,
-	0xe0, 0x61, 0x0f, 0x47, 0xfa, 0x71, 0x25, 0x49, 0xbf, 0xda, 0x8c, 0x4b, 0xca, 0xfe, 0x52, 0x22,
-	0x0a, 0x8e, 0x11, 0xf6, 0x0c, 0x3c, 0xd7, 0xa5, 0x3d, 0x27, 0x16, 0x5c, 0x4a, 0x6d, 0xd1, 0xb9,
-	0xe5, 0x9c, 0xeb, 0xda, 0x4a, 0x92, 0x9f, 0xe0, 0x71, 0x83, 0x83, 0xb1, 0xbb, 0xe6, 0xf3, 0xd9,
-	0xb3, 0x30, 0xc8, 0x0b, 0x38, 0x69, 0xf0, 0xf6, 0x2d, 0x3e, 0x36, 0x07, 0xbb, 0xe7, 0x36, 0x72,
-	0xf3, 0x52, 0xd1, 0x24, 0x15, 0x81, 0x6f, 0x72, 0xf3, 0x52, 0x5d, 0xa4, 0x82, 0xfc, 0x02, 0x9e,
-	0x64, 0xaa, 0x2c, 0xa8, 0xe2, 0x7c, 0x2b, 0x83, 0x27, 0xbd, 0xd6, 0xc0, 0x1b, 0x9d, 0xdd, 0x7b,
-	0x44, 0x1f, 0x98, 0xd8, 0xbc, 0xcf, 0x37, 0x3c, 0x04, 0x54, 0x5c, 0x6b, 0x01, 0x99, 0x80, 0xfb,
-	0x67, 0xa4, 0x52, 0x2a, 0xca, 0x5c, 0x06, 0xe4, 0x21, 0x6a, 0x47, 0xf3, 0xc3, 0x32, 0x97, 0xe4,
-	0x2d, 0x80, 0x61, 0xa2, 0xf8, 0xe4, 0x21, 0x62, 0x17, 0xd1, 0x5a, 0x9d, 0xa7, 0xf9, 0x1f, 0x91,
-	0x51, 0x9f, 0x3e, 0x48, 0x8d, 0x02, 0xad, 0xee, 0xbf, 0x82, 0xe3, 0x3b, 0x17, 0xc5, 0x01, 0x7b,
-	0x7d, 0x35, 0x0f, 0xfd, 0x47, 0xa4, 0x03, 0xae, 0x5e, 0x5d, 0xcc, 0x67, 0xeb, 0x77, 0xbe, 0x45,
-	0xda, 0xa0, 0x2f, 0x97, 0x7f, 0xd0, 0x7f, 0x0b, 0x36, 0x1e, 0xa5, 0x07, 0xf5, 0xa7, 0xe1, 0x3f,
-	0xd2, 0xe8, 0x34, 0x5c, 0xf8, 0x16, 0x71, 0xe1, 0x70, 0x1a, 0x2e, 0xc6, 0xaf, 0xfd, 0x03, 0x5d,
-	0xfb, 0xf4, 0x66, 0xec, 0xb7, 0x08, 0xc0, 0xd1, 0xa7, 0x37, 0x63, 0x3a, 0x7e, 0xed, 0xdb, 0xfd,
-	0xbf, 0x2d, 0x70, 0xea, 0x1c, 0x84, 0x80, 0x9d, 0x30, 0x19, 0xe3, 0x6c, 0x72, 0x43, 0x5c, 0xeb,
-	0x1a, 0x4e, 0x17, 0x33, 0x89, 0x70, 0x4d, 0xce, 0x00, 0xa4, 0x8a, 0x84, 0xc2, 0x71, 0x86, 0x73,
-	0xc7, 0x0e, 0x5d, 0xac, 0xe8, 0x29, 0x46, 0xbe, 0x07, 0x57, 0xb0, 0x68, 0x6b, 0x50, 0x1b, 0x51,
-	0x47, 0x17, 0x10, 0x3c, 0x03, 0xc8, 0x58, 0xc6, 0xc5, 0x8e, 0x96, 0x92, 0xe1, 0x54, 0xb1, 0x43,
-	0xd7, 0x54, 0xd6, 0x92, 0xf5, 0xff, 0xb5, 0xa0, 0xbb, 0xe0, 0x49, 0xb9, 0x65, 0xd7, 0xbb, 0x82,
-	0x61, 0xaa, 0xdf, 0xe1, 0xd8, 0x9c, 0x9b, 0xdc, 0x49, 0xc5, 0x32, 0x4c, 0xd7, 0x1d, 0xbd, 0xbc,
-	0xff, 0xba, 0xdc, 0x91, 0x9a, 0x61, 0x74, 0x85, 0xb2, 0xc6, 0xc5, 0xf9, 0x7c, 0x5b, 0x25, 0x3f,
-	0x80, 0x97, 0xa1, 0x86, 0xaa, 0x5d, 0x51, 0x77, 0x09, 0xd9, 0xde, 0x86, 0xfc, 0x08, 0xdd, 0xbc,
-	0xcc, 0x28, 0xdf, 0x50, 0x53, 0x94, 0xd8, 0x6f, 0x27, 0x3c, 0xce, 0xcb, 0x6c, 0xb5, 0x31, 0xfb,
-	0xc9, 0xfe, 0x4b, 0xf0, 0x1a, 0x7b, 0xdd, 0x7d, 0x17, 0x2e, 0x1c, 0x5e, 0xad, 0x56, 0x4b, 0xfd,
-	0xd2, 0x1c, 0xb0, 0x17, 0xd3, 0xcb, 0xb9, 0x7f, 0x30, 0xfb, 0xee, 0xb7, 0xea, 0xef, 0x51, 0x25,
-	0xa7, 0xf8, 0x4b, 0xf9, 0x2f, 0x00, 0x00, 0xff, 0xff, 0x81, 0xd0, 0x84, 0x23, 0x62, 0x06, 0x00,
-	0x00,
+	// 870 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x55, 0xdd, 0x6e, 0xdb, 0x36,
+	0x14, 0xae, 0x62, 0x25, 0xb6, 0x8e, 0x6c, 0x47, 0x65, 0xd2, 0x55, 0xc5, 0x16, 0xcc, 0xf0, 0xda,
+	0xc1, 0x17, 0xab, 0x5b, 0x18, 0x45, 0x50, 0x04, 0xc5, 0x00, 0x3b, 0x31, 0x82, 0x22, 0xb0, 0x5d,
+	0x28, 0x49, 0x57, 0x6c, 0x17, 0x04, 0x23, 0xd1, 0xb5, 0x36, 0x4b, 0x34, 0x48, 0x2a, 0x98, 0x1f,
+	0x62, 0xcf, 0xb1, 0x27, 0xdb, 0x23, 0xec, 0x7e, 0xe0, 0xa1, 0xfc, 0x93, 0x21, 0x40, 0x83, 0xde,
+	0x91, 0xdf, 0x1f, 0xcf, 0x21, 0x45, 0x0a, 0x1a, 0x19, 0xd7, 0x32, 0x8d, 0x55, 0x77, 0x21, 0x85,
+	0x16, 0xe4, 0x40, 0x09, 0x91, 0x7f, 0xa6, 0x37, 0x45, 0x3a, 0x4f, 0x68, 0x49, 0xb5, 0xff, 0xf5,
+	0xc0, 0x1f, 0xd9, 0xf1, 0x80, 0x29, 0x4e, 0x5e, 0xc3, 0xa1, 0x15, 0x24, 0x4c, 0x73, 0xaa, 0xd3,
+	0x8c, 0x2b, 0xcd, 0xb2, 0x45, 0xe8, 0xb4, 0x9c, 0x4e, 0x25, 0x22, 0xc8, 0x9d, 0x31, 0xcd, 0xaf,
+	0x56, 0x0c, 0x79, 0x06, 0x35, 0xeb, 0x48, 0x93, 0x70, 0xa7, 0xe5, 0x74, 0xbc, 0xa8, 0x8a, 0xf3,
+	0xf7, 0x09, 0x39, 0x81, 0x67, 0x8b, 0x39, 0xd3, 0x53, 0x21, 0x33, 0x7a, 0xcb, 0xa5, 0x4a, 0x45,
+	0x4e, 0x63, 0x91, 0xf0, 0x9c, 0x65, 0x3c, 0xac, 0xa0, 0xf6, 0xe9, 0x4a, 0xf0, 0xd1, 0xf2, 0xa7,
+	0x25, 0x4d, 0x5e, 0x40, 0x53, 0x33, 0xf9, 0x99, 0x6b, 0xba, 0x90, 0x22, 0x29, 0x62, 0x1d, 0xba,
+	0x68, 0x68, 0x58, 0xf4, 0x83, 0x05, 0x49, 0x02, 0x87, 0xa5, 0xcc, 0x16, 0x71, 0xcb, 0x64, 0xca,
+	0x72, 0x1d, 0xee, 0xb6, 0x9c, 0x4e, 0xb3, 0xf7, 0xb2, 0x7b, 0x4f, 0xcf, 0xdd, 0xad, 0x7e, 0xbb,
+	0x03, 0xc3, 0x7c, 0xb4, 0xa6, 0x93, 0xca, 0x70, 0x7c, 0x1e, 0x11, 0x9b, 0xb7, 0x4d, 0x90, 0x09,
+	0xf8, 0xe5, 0x2a, 0x4c, 0xc6, 0xb3, 0x70, 0x0f, 0xc3, 0x5f, 0x7c, 0x31, 0xbc, 0x2f, 0xe3, 0xd9,
+	0x49, 0xf5, 0x7a, 0x7c, 0x31, 0x9e, 0xfc, 0x32, 0x8e, 0xc0, 0x46, 0x18, 0x90, 0x74, 0xe1, 0x60,
+	0x2b, 0x70, 0x5d, 0x75, 0x15, 0x5b, 0x7c, 0xbc, 0x11, 0xae, 0x0a, 0xf8, 0x09, 0xca, 0xb2, 0x68,
+	0xbc, 0x28, 0xd6, 0xf2, 0x1a, 0xca, 0x03, 0xcb, 0x9c, 0x2e, 0x8a, 0x95, 0xfa, 0x02, 0xbc, 0x99,
+	0x50, 0x65, 0xb1, 0xde, 0x57, 0x15, 0x5b, 0x33, 0x01, 0x58, 0x6a, 0x04, 0x0d, 0x0c, 0xeb, 0xe5,
+	0x89, 0x0d, 0x84, 0xaf, 0x0a, 0xf4, 0x4d, 0x48, 0x2f, 0x4f, 0x30, 0xf3, 0x29, 0x54, 0x31, 0x53,
+	0xa8, 0xd0, 0xc7, 0x1e, 0xf6, 0xcc, 0x74, 0xa2, 0x48, 0xbb, 0x5c, 0x4c, 0x28, 0xca, 0xff, 0xd4,
+	0x92, 0x85, 0x75, 0xa4, 0x7d, 0x4b, 0x0f, 0x0d, 0xb4, 0xd6, 0xc4, 0x52, 0x28, 0x65, 0x22, 0x1a,
+	0x1b, 0xcd, 0xa9, 0xc1, 0x26, 0x8a, 0xfc, 0x08, 0xfb, 0x5b, 0x1a, 0x2c, 0xbb, 0x69, 0x3f, 0x9f,
+	0xb5, 0x0a, 0x0b, 0x79, 0x09, 0x07, 0x5b, 0xba, 0x75, 0x8b, 0xfb, 0x76, 0x63, 0xd7, 0xda, 0xad,
+	0xba, 0x45, 0xa1, 0x69, 0x92, 0xca, 0x30, 0xb0, 0x75, 0x8b, 0x42, 0x9f, 0xa5, 0x92, 0xfc, 0x0c,
+	0xbe, 0xe2, 0xba, 0x58, 0x50, 0x2d, 0xc4, 0x5c, 0x85, 0x8f, 0x5b, 0x95, 0x8e, 0xdf, 0x3b, 0xba,
+	0x77, 0x8b, 0x3e, 0x70, 0x39, 0x7d, 0x9f, 0x4f, 0x45, 0x04, 0xe8, 0xb8, 0x32, 0x06, 0x72, 0x02,
+	0xde, 0x1f, 0x4c, 0xa7, 0x54, 0x16, 0xb9, 0x0a, 0xc9, 0x43, 0xdc, 0x35, 0xa3, 0x8f, 0x8a, 0x5c,
+	0x91, 0x77, 0x00, 0x56, 0x89, 0xe6, 0x83, 0x87, 0x98, 0x3d, 0x64, 0x57, 0xee, 0x3c, 0xcd, 0x7f,
+	0x67, 0xd6, 0x7d, 0xf8, 0x20, 0x37, 0x1a, 0xd0, 0x7d, 0x0e, 0x75, 0x16, 0x6b, 0xbc, 0xd7, 0x2c,
+	0x9e, 0xf1, 0xf0, 0x49, 0xcb, 0xe9, 0xf8, 0xbd, 0xe7, 0xf7, 0xfa, 0xfb, 0x28, 0x3c, 0x35, 0x3a,
+	0x8c, 0xf1, 0xd9, 0x06, 0x68, 0xbf, 0x86, 0xfa, 0x9d, 0x1b, 0x57, 0x03, 0xf7, 0xfa, 0x72, 0x18,
+	0x05, 0x8f, 0x48, 0x03, 0x3c, 0x33, 0x3a, 0x1b, 0x0e, 0xae, 0xcf, 0x03, 0x87, 0x54, 0xc1, 0xdc,
+	0xd2, 0x60, 0xa7, 0xfd, 0x0e, 0x5c, 0x3c, 0x13, 0x1f, 0x56, 0xdf, 0x58, 0xf0, 0xc8, 0xb0, 0xfd,
+	0x68, 0x14, 0x38, 0xc4, 0x83, 0xdd, 0x7e, 0x34, 0x3a, 0x7e, 0x13, 0xec, 0x18, 0xec, 0xd3, 0xdb,
+	0xe3, 0xa0, 0x42, 0x00, 0xf6, 0x3e, 0xbd, 0x3d, 0xa6, 0xc7, 0x6f, 0x02, 0xb7, 0xfd, 0x97, 0x03,
+	0xb5, 0x55, 0x43, 0x84, 0x80, 0x9b, 0x70, 0x15, 0xe3, 0x23, 0xe7, 0x45, 0x38, 0x36, 0x18, 0x3e,
+	0x53, 0xf6, 0x49, 0xc3, 0x31, 0x39, 0x02, 0x50, 0x9a, 0x49, 0x8d, 0xef, 0x22, 0x3e, 0x60, 0x6e,
+	0xe4, 0x21, 0x62, 0x9e, 0x43, 0xf2, 0x2d, 0x78, 0x92, 0xb3, 0xb9, 0x65, 0x5d, 0x64, 0x6b, 0x06,
+	0x40, 0xf2, 0x08, 0x20, 0xe3, 0x99, 0x90, 0x4b, 0x5a, 0x28, 0x8e, 0xcf, 0x93, 0x1b, 0x79, 0x16,
+	0xb9, 0x56, 0xbc, 0xfd, 0x8f, 0x03, 0xcd, 0x91, 0x48, 0x8a, 0x39, 0xbf, 0x5a, 0x2e, 0x70, 0x7f,
+	0xc8, 0x6f, 0x50, 0xb7, 0x1b, 0xa8, 0x96, 0x4a, 0xf3, 0x0c, 0xab, 0x6b, 0xf6, 0x5e, 0xdd, 0x7f,
+	0xef, 0xee, 0x58, 0xed, 0xab, 0x76, 0x89, 0xb6, 0xad, 0x1b, 0x78, 0xb3, 0x41, 0xc9, 0xf7, 0xe0,
+	0x67, 0xe8, 0xa1, 0x7a, 0xb9, 0x58, 0x75, 0x09, 0xd9, 0x3a, 0x86, 0x3c, 0x87, 0x66, 0x5e, 0x64,
+	0x54, 0x4c, 0xa9, 0x05, 0x15, 0xf6, 0xdb, 0x88, 0xea, 0x79, 0x91, 0x4d, 0xa6, 0x76, 0x3d, 0xd5,
+	0x7e, 0x05, 0xfe, 0xd6, 0x5a, 0x77, 0xcf, 0xc2, 0x83, 0xdd, 0xcb, 0xc9, 0x64, 0x6c, 0x0e, 0xad,
+	0x06, 0xee, 0xa8, 0x7f, 0x31, 0x0c, 0x76, 0xda, 0x7f, 0x3b, 0xb0, 0xff, 0xbf, 0x0f, 0xc1, 0x6c,
+	0xf5, 0x2c, 0xd5, 0x0a, 0x1b, 0x74, 0x23, 0x1c, 0x93, 0x6f, 0x60, 0x2f, 0x4b, 0x95, 0xe2, 0x0a,
+	0x4b, 0x73, 0xa3, 0x72, 0x66, 0x7e, 0x0b, 0x92, 0x2b, 0x2d, 0x24, 0x4f, 0xe8, 0xcd, 0x52, 0x97,
+	0x65, 0xb9, 0x51, 0x63, 0x85, 0x0e, 0x0c, 0x48, 0xbe, 0x03, 0x8f, 0xdf, 0xa6, 0xb8, 0x8e, 0x2a,
+	0x8f, 0x62, 0x03, 0x90, 0x1f, 0xa0, 0x81, 0x93, 0x75, 0x86, 0x3d, 0x8e, 0x7a, 0x09, 0x62, 0xc4,
+	0xe0, 0xc9, 0xaf, 0xe5, 0x0f, 0xb3, 0xdc, 0x63, 0x8a, 0x7f, 0xd1, 0xff, 0x06, 0x00, 0x77, 0x99,
+	0x08, 0x74, 0x55, 0x07, 0x00, 0x00,
 }
@@ -0,0 +1,109 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ActionCacheStats are the statistics of the local action cache used by sbox during a build.
+type ActionCacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	RestoredBytes uint64
+	Evictions     uint64
+	EvictedBytes  uint64
+}
+
+// ReadActionCacheStats reads the file that sbox appends a line to for every action cache hit, miss
+// and eviction.
+func ReadActionCacheStats(file string) (ActionCacheStats, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return ActionCacheStats{}, err
+	}
+	defer f.Close()
+	return parseActionCacheStats(f)
+}
+
+// parseActionCacheStats parses lines of the form "hit <restored bytes>", "miss" and
+// "evict <entries> <evicted bytes>".
+func parseActionCacheStats(r io.Reader) (ActionCacheStats, error) {
+	var stats ActionCacheStats
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var values []uint64
+		for _, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return stats, fmt.Errorf("invalid action cache stats line %q", scanner.Text())
+			}
+			values = append(values, value)
+		}
+
+		switch {
+		case fields[0] == "hit" && len(values) == 1:
+			stats.Hits++
+			stats.RestoredBytes += values[0]
+		case fields[0] == "miss" && len(values) == 0:
+			stats.Misses++
+		case fields[0] == "evict" && len(values) == 2:
+			stats.Evictions += values[0]
+			stats.EvictedBytes += values[1]
+		default:
+			return stats, fmt.Errorf("invalid action cache stats line %q", scanner.Text())
+		}
+	}
+
+	return stats, scanner.Err()
+}
+
+// Used returns true if any action looked up its outputs in the action cache.
+func (s ActionCacheStats) Used() bool {
+	return s.Hits+s.Misses > 0
+}
+
+func (s ActionCacheStats) String() string {
+	ret := fmt.Sprintf("action cache: %d hits, %d misses (%d%% hit rate), restored %s",
+		s.Hits, s.Misses, s.Hits*100/(s.Hits+s.Misses), formatBytes(s.RestoredBytes))
+	if s.Evictions > 0 {
+		ret += fmt.Sprintf(", evicted %d entries (%s)", s.Evictions, formatBytes(s.EvictedBytes))
+	}
+	return ret
+}
+
+func formatBytes(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestActionCacheStats(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		stats  ActionCacheStats
+		output string
+		err    string
+	}{
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name:   "hits and misses",
+			input:  "hit 1024\nmiss\nhit 2048\nhit 1024\n",
+			stats:  ActionCacheStats{Hits: 3, Misses: 1, RestoredBytes: 4096},
+			output: "action cache: 3 hits, 1 misses (75% hit rate), restored 4.0 KiB",
+		},
+		{
+			name:  "evictions",
+			input: "miss\nevict 2 3145728\nmiss\nevict 1 10\n",
+			stats: ActionCacheStats{Misses: 2, Evictions: 3, EvictedBytes: 3145738},
+			output: "action cache: 0 hits, 2 misses (0% hit rate), restored 0 B, " +
+				"evicted 3 entries (3.0 MiB)",
+		},
+		{
+			name:  "invalid",
+			input: "hit\n",
+			err:   `invalid action cache stats line "hit"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats, err := parseActionCacheStats(strings.NewReader(tc.input))
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stats != tc.stats {
+				t.Errorf("expected %#v, got %#v", tc.stats, stats)
+			}
+			if stats.Used() != (tc.output != "") {
+				t.Errorf("expected Used() = %v, got %v", tc.output != "", stats.Used())
+			}
+			if stats.Used() && stats.String() != tc.output {
+				t.Errorf("expected %q, got %q", tc.output, stats.String())
+			}
+		})
+	}
+}